@@ -15,6 +15,10 @@ func main(){
 		fmt.Println("Iniciando servidor TOP CARD")
 		server.Run()
 	case "client":
+		// Com argumentos o cliente executa comandos sem interação (ver client.RunCommands)
+		if len(os.Args) > 1 {
+			os.Exit(client.RunCommands(os.Args[1:], os.Stdout))
+		}
 		fmt.Println("Iniciando cliente TOP CARD")
 		client.Run()
 	default:
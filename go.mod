@@ -2,6 +2,8 @@ module top-card
 
 go 1.25.0
 
-require golang.org/x/net v0.17.0
+require (
+	golang.org/x/net v0.17.0
+	golang.org/x/sys v0.13.0
+)
 
-require golang.org/x/sys v0.13.0 // indirect
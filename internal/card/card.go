@@ -1,9 +1,9 @@
 package card
 
 import (
-    "math/rand"
+    "crypto/rand"
+    "io"
     "sync"
-    "time"
 )
 
 // Tipos de cartas
@@ -19,91 +19,196 @@ type Card struct {
     Rarity string `json:"rarity"` // "comum", "raro", "épico"
 }
 
-// Estrutura para o estoque global de cartas
+// Quantidade de cartas de cada tipo (usada no estoque e na verificação de pacotes)
+type StockCounts struct {
+    Hydra   int `json:"hydra"`
+    Quimera int `json:"quimera"`
+    Gorgona int `json:"gorgona"`
+}
+
+// Estrutura para o estoque de cartas
 type CardStock struct {
-    hydraCount   int
-    quimeraCount int
-    gorgonaCount int
-    mutex        sync.Mutex
+    counts StockCounts
+    random io.Reader // Fonte de aleatoriedade para as sementes do servidor
+    mutex  sync.Mutex
+}
+
+// Estoque inicial padrão
+var DefaultStockCounts = StockCounts{
+    Hydra:   10000, // Mais comum
+    Quimera: 7000,  // Mediano
+    Gorgona: 3000,  // Mais raro
 }
 
 // Instância global do estoque
-var globalStock = &CardStock{
-    hydraCount:   10000,   // Mais comum
-    quimeraCount: 7000,    // Mediano
-    gorgonaCount: 3000,    // Mais raro
+var globalStock = NewCardStock(DefaultStockCounts, nil)
+
+// Função para criar um estoque de cartas. Se random for nil, usa crypto/rand.
+// Nos testes, uma fonte determinística (ex.: math/rand com semente fixa) permite
+// reproduzir exatamente os mesmos pacotes.
+func NewCardStock(counts StockCounts, random io.Reader) *CardStock {
+    if random == nil {
+        random = rand.Reader
+    }
+    return &CardStock{
+        counts: counts,
+        random: random,
+    }
 }
 
-// Inicializa o gerador de números aleatórios
-func init() {
-    rand.Seed(time.Now().UnixNano())
+// Função para obter o estoque global
+func GetStock() *CardStock {
+    return globalStock
+}
+
+// Função para abrir um dos pacotes padrão do estoque global
+func OpenCardPack(packTypeName string, forceEpic bool, seeds *SeedState) ([]Card, StockCounts, bool) {
+    packType, ok := GetPackType(packTypeName)
+    if !ok {
+        return nil, StockCounts{}, false // Tipo de pacote desconhecido
+    }
+    return globalStock.OpenPack(packType, forceEpic, seeds)
 }
 
-// Função para obter cartas do estoque (com mutex para thread safety)
-func OpenCardPack() ([]Card, bool) {
-    globalStock.mutex.Lock()
-    defer globalStock.mutex.Unlock()
+// Função para abrir um pacote do tipo informado (com mutex para thread safety).
+// O sorteio usa as sementes do jogador (commit-reveal), e o nonce é incrementado
+// a cada pacote aberto. Se forceEpic for verdadeiro (pity atingido), o primeiro slot
+// exige uma carta épica. Retorna também o estoque antes do sorteio, necessário para
+// verificar o pacote depois que a semente do servidor for revelada.
+func (cs *CardStock) OpenPack(packType PackType, forceEpic bool, seeds *SeedState) ([]Card, StockCounts, bool) {
+    cs.mutex.Lock()
+    defer cs.mutex.Unlock()
+
+    before := cs.counts
+    counts := before
+    pack, ok := drawPack(packType, &counts, forceEpic, seeds.roller())
+    if !ok {
+        return nil, before, false // Estoque insuficiente
+    }
+
+    // Só retira as cartas do estoque se o pacote inteiro foi sorteado
+    cs.counts = counts
+    seeds.Nonce++
+    return pack, before, true
+}
+
+// Função interna que monta um pacote a partir de um estoque e de uma sequência de
+// sorteios. É determinística: os mesmos parâmetros sempre geram o mesmo pacote.
+func drawPack(packType PackType, counts *StockCounts, forceEpic bool, roll func() float64) ([]Card, bool) {
     // Verifica se há cartas suficientes no estoque
-    totalCards := globalStock.hydraCount + globalStock.quimeraCount + globalStock.gorgonaCount
-    if totalCards < 3 {
+    totalCards := 0
+    for _, cardType := range []string{HYDRA, QUIMERA, GORGONA} {
+        if packType.allows(cardType) {
+            totalCards += counts.count(cardType)
+        }
+    }
+    if totalCards < packType.Size {
         return nil, false // Estoque insuficiente
     }
     var pack []Card
    
-    // Sorteia 3 cartas
-    for i := 0; i < 3; i++ {
-        card := drawRandomCard()
+    // Sorteia as cartas do pacote
+    for i := 0; i < packType.Size; i++ {
+        card := drawRandomCard(packType, counts, packType.minRarity(i, forceEpic), roll)
+        if card.Type == "" {
+            // Slot garantido sem estoque da raridade exigida: sorteia qualquer carta permitida
+            card = drawRandomCard(packType, counts, COMUM, roll)
+        }
         if card.Type == "" {
-            // Se não conseguiu sortear carta (estoque vazio), reverte as cartas já retiradas
-            for _, revertCard := range pack {
-                addCardBackToStock(revertCard)
-            }
-            return nil, false
+            return nil, false // Estoque vazio
         }
         pack = append(pack, card)
     }
     return pack, true
 }
 
-// Função interna para sortear uma carta aleatória
-func drawRandomCard() Card {
-    totalCards := globalStock.hydraCount + globalStock.quimeraCount + globalStock.gorgonaCount
-    if totalCards == 0 {
-        return Card{} // Estoque vazio
+// Função interna para sortear uma carta aleatória respeitando a tabela do pacote
+func drawRandomCard(packType PackType, counts *StockCounts, minRarity string, roll func() float64) Card {
+    candidates := []string{}
+    weights := []int{}
+    totalWeight := 0
+    for _, cardType := range []string{HYDRA, QUIMERA, GORGONA} {
+        stock := counts.count(cardType)
+        rarity := cardRarity[cardType]
+        if stock == 0 || !packType.allows(cardType) || rarityRank[rarity] < rarityRank[minRarity] {
+            continue
+        }
+        // Sem pesos definidos o sorteio é proporcional ao estoque restante
+        weight := stock
+        if packType.Weights != nil {
+            weight = packType.Weights[rarity]
+        }
+        if weight <= 0 {
+            continue
+        }
+        candidates = append(candidates, cardType)
+        weights = append(weights, weight)
+        totalWeight += weight
     }
-    // Sorteia um número baseado no estoque total
-    randomNum := rand.Intn(totalCards)
-    if randomNum < globalStock.hydraCount {
-        globalStock.hydraCount--
-        return Card{Type: HYDRA, Rarity: "comum"}
-    } else if randomNum < globalStock.hydraCount + globalStock.quimeraCount {
-        globalStock.quimeraCount--
-        return Card{Type: QUIMERA, Rarity: "raro"}
-    } else {
-        globalStock.gorgonaCount--
-        return Card{Type: GORGONA, Rarity: "épico"}
+    if totalWeight == 0 {
+        return Card{} // Nenhuma carta disponível
+    }
+    // Sorteia um número baseado no peso total
+    randomNum := int(roll() * float64(totalWeight))
+    for i, cardType := range candidates {
+        if randomNum < weights[i] {
+            counts.take(cardType)
+            return Card{Type: cardType, Rarity: cardRarity[cardType]}
+        }
+        randomNum -= weights[i]
     }
+    return Card{}
 }
 
-// Função para adicionar carta de volta ao estoque (para casos de erro)
-func addCardBackToStock(card Card) {
-    switch card.Type {
+// Quantidade em estoque de um tipo de carta
+func (sc StockCounts) count(cardType string) int {
+    switch cardType {
     case HYDRA:
-        globalStock.hydraCount++
+        return sc.Hydra
     case QUIMERA:
-        globalStock.quimeraCount++
+        return sc.Quimera
     case GORGONA:
-        globalStock.gorgonaCount++
+        return sc.Gorgona
     }
+    return 0
 }
 
-// Função para verificar o estoque atual
+// Retira uma carta do estoque
+func (sc *StockCounts) take(cardType string) {
+    switch cardType {
+    case HYDRA:
+        sc.Hydra--
+    case QUIMERA:
+        sc.Quimera--
+    case GORGONA:
+        sc.Gorgona--
+    }
+}
+
+// Total de cartas
+func (sc StockCounts) Total() int {
+    return sc.Hydra + sc.Quimera + sc.Gorgona
+}
+
+// Função para verificar o estoque global atual
 func GetStockInfo() (int, int, int, int) {
-    globalStock.mutex.Lock()
-    defer globalStock.mutex.Unlock()
+    return globalStock.Info()
+}
+
+// Função para verificar o estoque atual
+func (cs *CardStock) Info() (int, int, int, int) {
+    cs.mutex.Lock()
+    defer cs.mutex.Unlock()
    
-    total := globalStock.hydraCount + globalStock.quimeraCount + globalStock.gorgonaCount
-    return globalStock.hydraCount, globalStock.quimeraCount, globalStock.gorgonaCount, total
+    counts := cs.counts
+    return counts.Hydra, counts.Quimera, counts.Gorgona, counts.Total()
+}
+
+// Função para obter as quantidades atuais do estoque (usada ao salvar o estado do servidor)
+func (cs *CardStock) Counts() StockCounts {
+    cs.mutex.Lock()
+    defer cs.mutex.Unlock()
+    return cs.counts
 }
 
 // Função para determinar o vencedor entre duas cartas (pedra, papel, tesoura)
@@ -0,0 +1,154 @@
+package card
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/binary"
+    "encoding/hex"
+    "fmt"
+    "io"
+)
+
+// Sorteio comprovadamente justo (commit-reveal):
+//  1. o servidor gera uma semente secreta e publica apenas o hash SHA-256 dela;
+//  2. cada pacote é sorteado com HMAC-SHA256(semente do servidor, "clientSeed:nonce:índice");
+//  3. ao rotacionar, a semente antiga é revelada e qualquer um pode recalcular os pacotes
+//     com VerifyPack e conferir que ela corresponde ao hash publicado antes dos sorteios.
+//
+// O compromisso cobre só as sementes. O sorteio também é ponderado pelo estoque global
+// no instante do pacote, que muda com os pacotes dos outros jogadores e não pode ser
+// fixado antes; esse estoque é informado pelo próprio servidor e é confiado, não verificado.
+
+// Estado das sementes de um jogador
+type SeedState struct {
+    ServerSeed     string // Semente secreta do servidor (hex), revelada apenas na rotação
+    ServerSeedHash string // Hash publicado da semente do servidor
+    ClientSeed     string // Semente escolhida pelo jogador
+    Nonce          int    // Quantidade de pacotes já sorteados com este par de sementes
+}
+
+// Semente revelada após a rotação
+type RevealedSeed struct {
+    ServerSeed     string `json:"server_seed"`
+    ServerSeedHash string `json:"server_seed_hash"`
+    ClientSeed     string `json:"client_seed"`
+    Nonce          int    `json:"nonce"` // Pacotes sorteados com esta semente
+}
+
+// Função para gerar um novo estado de sementes com o estoque global
+func NewSeedState(clientSeed string) (SeedState, error) {
+    return globalStock.NewSeedState(clientSeed)
+}
+
+// Função para gerar um novo estado de sementes usando a aleatoriedade do estoque
+func (cs *CardStock) NewSeedState(clientSeed string) (SeedState, error) {
+    cs.mutex.Lock()
+    defer cs.mutex.Unlock()
+
+    serverSeed, err := randomHex(cs.random, 32)
+    if err != nil {
+        return SeedState{}, err
+    }
+    if clientSeed == "" {
+        clientSeed, err = randomHex(cs.random, 8)
+        if err != nil {
+            return SeedState{}, err
+        }
+    }
+    return SeedState{
+        ServerSeed:     serverSeed,
+        ServerSeedHash: HashSeed(serverSeed),
+        ClientSeed:     clientSeed,
+        Nonce:          0,
+    }, nil
+}
+
+// Função para rotacionar a semente do servidor, revelando a anterior
+func (cs *CardStock) RotateSeeds(seeds *SeedState) (RevealedSeed, error) {
+    next, err := cs.NewSeedState(seeds.ClientSeed)
+    if err != nil {
+        return RevealedSeed{}, err
+    }
+    revealed := RevealedSeed{
+        ServerSeed:     seeds.ServerSeed,
+        ServerSeedHash: seeds.ServerSeedHash,
+        ClientSeed:     seeds.ClientSeed,
+        Nonce:          seeds.Nonce,
+    }
+    *seeds = next
+    return revealed, nil
+}
+
+// Verifica se o estado de sementes já foi inicializado
+func (s SeedState) IsZero() bool {
+    return s.ServerSeed == ""
+}
+
+// Função para calcular o hash publicado de uma semente do servidor
+func HashSeed(serverSeed string) string {
+    sum := sha256.Sum256([]byte(serverSeed))
+    return hex.EncodeToString(sum[:])
+}
+
+// Gera a sequência de sorteios em [0, 1) do próximo pacote
+func (s SeedState) roller() func() float64 {
+    index := 0
+    return func() float64 {
+        value := fairRoll(s.ServerSeed, s.ClientSeed, s.Nonce, index)
+        index++
+        return value
+    }
+}
+
+// Função interna que calcula um sorteio a partir das sementes
+func fairRoll(serverSeed, clientSeed string, nonce, index int) float64 {
+    mac := hmac.New(sha256.New, []byte(serverSeed))
+    fmt.Fprintf(mac, "%s:%d:%d", clientSeed, nonce, index)
+    sum := mac.Sum(nil)
+    // Usa 53 bits para obter um float64 uniforme em [0, 1)
+    return float64(binary.BigEndian.Uint64(sum[:8])>>11) / (1 << 53)
+}
+
+// Função para recalcular um pacote a partir das sementes reveladas.
+// stockBefore é o estoque informado na resposta do pacote (confiado: o hash não o
+// cobre) e forcedEpic indica se o pity estava ativo naquele sorteio. O tipo de pacote
+// é buscado entre os pacotes padrão.
+func VerifyPack(serverSeed, serverSeedHash, clientSeed string, nonce int, packTypeName string, stockBefore StockCounts, forcedEpic bool) ([]Card, error) {
+    if HashSeed(serverSeed) != serverSeedHash {
+        return nil, fmt.Errorf("a semente revelada não corresponde ao hash publicado")
+    }
+    packType, ok := GetPackType(packTypeName)
+    if !ok {
+        return nil, fmt.Errorf("tipo de pacote desconhecido: %s", packTypeName)
+    }
+
+    seeds := SeedState{ServerSeed: serverSeed, ServerSeedHash: serverSeedHash, ClientSeed: clientSeed, Nonce: nonce}
+    counts := stockBefore
+    pack, ok := drawPack(packType, &counts, forcedEpic, seeds.roller())
+    if !ok {
+        return nil, fmt.Errorf("estoque insuficiente para o pacote %s", packTypeName)
+    }
+    return pack, nil
+}
+
+// Função para comparar dois pacotes carta a carta
+func SamePack(a, b []Card) bool {
+    if len(a) != len(b) {
+        return false
+    }
+    for i := range a {
+        if a[i] != b[i] {
+            return false
+        }
+    }
+    return true
+}
+
+// Gera uma string hexadecimal aleatória com n bytes
+func randomHex(random io.Reader, n int) (string, error) {
+    buf := make([]byte, n)
+    if _, err := io.ReadFull(random, buf); err != nil {
+        return "", fmt.Errorf("erro ao gerar semente: %v", err)
+    }
+    return hex.EncodeToString(buf), nil
+}
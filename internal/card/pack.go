@@ -0,0 +1,226 @@
+package card
+
+import (
+    "fmt"
+    "sort"
+)
+
+// Raridades das cartas
+const (
+    COMUM = "comum"
+    RARO  = "raro"
+    EPICO = "épico"
+)
+
+// Tipos de pacotes disponíveis por padrão
+const (
+    PACK_STANDARD = "standard"
+    PACK_PREMIUM  = "premium"
+    PACK_HYDRA    = "hydra"
+    PACK_QUIMERA  = "quimera"
+    PACK_GORGONA  = "gorgona"
+)
+
+// Estrutura que define um tipo de pacote (tabela de drop)
+type PackType struct {
+    Name          string
+    Description   string
+    Size          int            // Quantidade de cartas no pacote
+    Weights       map[string]int // Peso de cada raridade (nil = proporcional ao estoque)
+    Guaranteed    []string       // Raridade mínima garantida para os primeiros slots
+    CardTypes     []string       // Tipos de carta permitidos (vazio = todos)
+    PityThreshold int            // Garante um épico após N pacotes sem épico (0 = desativado)
+}
+
+// Raridade de cada tipo de carta
+var cardRarity = map[string]string{
+    HYDRA:   COMUM,
+    QUIMERA: RARO,
+    GORGONA: EPICO,
+}
+
+// Ordem das raridades (usada nos slots garantidos)
+var rarityRank = map[string]int{
+    COMUM: 0,
+    RARO:  1,
+    EPICO: 2,
+}
+
+// Função para criar os pacotes padrão (usados quando o servidor não configura
+// outros). Cada chamada retorna uma cópia nova, que pode ser alterada livremente.
+func DefaultPackTypes() []PackType {
+    return []PackType{
+        {
+            Name:          PACK_STANDARD,
+            Description:   "3 cartas sorteadas de acordo com o estoque",
+            Size:          3,
+            PityThreshold: 10,
+        },
+        {
+            Name:          PACK_PREMIUM,
+            Description:   "5 cartas com pelo menos uma rara",
+            Size:          5,
+            Weights:       map[string]int{COMUM: 50, RARO: 35, EPICO: 15},
+            Guaranteed:    []string{RARO},
+            PityThreshold: 5,
+        },
+        {
+            Name:        PACK_HYDRA,
+            Description: "3 cartas HYDRA",
+            Size:        3,
+            CardTypes:   []string{HYDRA},
+        },
+        {
+            Name:        PACK_QUIMERA,
+            Description: "3 cartas QUIMERA",
+            Size:        3,
+            CardTypes:   []string{QUIMERA},
+        },
+        {
+            Name:        PACK_GORGONA,
+            Description: "1 carta GORGONA",
+            Size:        1,
+            CardTypes:   []string{GORGONA},
+        },
+    }
+}
+
+// Função para validar a definição de um tipo de pacote
+func (packType PackType) Validate() error {
+    if packType.Name == "" {
+        return fmt.Errorf("pacote sem nome")
+    }
+    if packType.Size <= 0 {
+        return fmt.Errorf("pacote %s com tamanho inválido: %d", packType.Name, packType.Size)
+    }
+    if len(packType.Guaranteed) > packType.Size {
+        return fmt.Errorf("pacote %s tem mais slots garantidos do que cartas", packType.Name)
+    }
+    for rarity, weight := range packType.Weights {
+        if _, ok := rarityRank[rarity]; !ok {
+            return fmt.Errorf("pacote %s com raridade desconhecida: %s", packType.Name, rarity)
+        }
+        if weight < 0 {
+            return fmt.Errorf("pacote %s com peso negativo para %s", packType.Name, rarity)
+        }
+    }
+    for _, rarity := range packType.Guaranteed {
+        if _, ok := rarityRank[rarity]; !ok {
+            return fmt.Errorf("pacote %s com raridade garantida desconhecida: %s", packType.Name, rarity)
+        }
+    }
+    for _, cardType := range packType.CardTypes {
+        if _, ok := cardRarity[cardType]; !ok {
+            return fmt.Errorf("pacote %s com tipo de carta desconhecido: %s", packType.Name, cardType)
+        }
+    }
+    if packType.PityThreshold < 0 {
+        return fmt.Errorf("pacote %s com pity inválido: %d", packType.Name, packType.PityThreshold)
+    }
+    return nil
+}
+
+// Tabela de tipos de pacote de um servidor. Não muda depois de criada, então pode
+// ser consultada por várias goroutines sem trava.
+type PackTypes struct {
+    byName map[string]PackType
+}
+
+// Função para criar uma tabela de pacotes, validando cada tipo
+func NewPackTypes(list []PackType) (*PackTypes, error) {
+    table := &PackTypes{byName: make(map[string]PackType, len(list))}
+    for _, packType := range list {
+        if err := packType.Validate(); err != nil {
+            return nil, err
+        }
+        if _, exists := table.byName[packType.Name]; exists {
+            return nil, fmt.Errorf("pacote %s definido mais de uma vez", packType.Name)
+        }
+        table.byName[packType.Name] = packType
+    }
+    return table, nil
+}
+
+// Função para buscar um tipo de pacote pelo nome (vazio = PACK_STANDARD)
+func (t *PackTypes) Get(name string) (PackType, bool) {
+    if name == "" {
+        name = PACK_STANDARD
+    }
+    packType, ok := t.byName[name]
+    return packType, ok
+}
+
+// Função para listar os tipos de pacote (ordenados pelo nome)
+func (t *PackTypes) List() []PackType {
+    list := make([]PackType, 0, len(t.byName))
+    for _, packType := range t.byName {
+        list = append(list, packType)
+    }
+    sort.Slice(list, func(i, j int) bool {
+        return list[i].Name < list[j].Name
+    })
+    return list
+}
+
+// Pacotes padrão, usados pelos clientes e pela verificação dos sorteios
+var defaultPackTypes, _ = NewPackTypes(DefaultPackTypes())
+
+// Função para buscar um dos pacotes padrão pelo nome
+func GetPackType(name string) (PackType, bool) {
+    return defaultPackTypes.Get(name)
+}
+
+// Função para listar os pacotes padrão (ordenados pelo nome)
+func ListPackTypes() []PackType {
+    return defaultPackTypes.List()
+}
+
+// Retorna a raridade de um tipo de carta
+func RarityOf(cardType string) string {
+    return cardRarity[cardType]
+}
+
+// Verifica se alguma das cartas é épica
+func HasEpic(cards []Card) bool {
+    for _, c := range cards {
+        if c.Rarity == EPICO {
+            return true
+        }
+    }
+    return false
+}
+
+// Verifica se um tipo de carta é permitido no pacote
+func (pt PackType) allows(cardType string) bool {
+    if len(pt.CardTypes) == 0 {
+        return true
+    }
+    for _, allowed := range pt.CardTypes {
+        if allowed == cardType {
+            return true
+        }
+    }
+    return false
+}
+
+// Raridade mínima exigida para um slot do pacote
+func (pt PackType) minRarity(slot int, forceEpic bool) string {
+    if forceEpic && slot == 0 {
+        return EPICO
+    }
+    if slot < len(pt.Guaranteed) {
+        return pt.Guaranteed[slot]
+    }
+    return COMUM
+}
+
+// Verifica se o pacote pode conter uma carta épica
+func (pt PackType) CanDropEpic() bool {
+    if !pt.allows(GORGONA) {
+        return false
+    }
+    if pt.Weights != nil && pt.Weights[EPICO] == 0 {
+        return false
+    }
+    return true
+}
@@ -2,93 +2,173 @@ package client
 
 import (
 	"bufio"
-	"net"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 	"strconv"
 	"time"
-	"sync"
-	"top-card/internal/protocol"
-	"golang.org/x/net/icmp"
-	"golang.org/x/net/ipv4"
+	"top-card/internal/card"
+	"top-card/internal/tlsconfig"
+	"top-card/internal/udpecho"
+	"top-card/pkg/topcard"
 )
 
-var currentMatchID int
-var currentUserID int
-var isLoggedIn bool
-var inMatch bool // Flag para indicar se está em partida
-var isMyTurn bool = false  // Flag para controlar se é o turno do jogador
+// Pacote aberto, guardado para verificação quando a semente do servidor for revelada
+type openedPack struct {
+	PackType string
+	Cards    []topcard.Card
+	Fairness topcard.Fairness
+	Forced   bool
+}
 
-var isConnected = true
-var connectionMutex sync.Mutex
+var packHistory []openedPack // Pacotes ainda não verificados
 
-var playerInventory []protocol.CardInfo // Inventário local do jogador
+// Identificação do cliente enviada no HELLO
+const (
+	clientName    = "top-card-cli"
+	clientVersion = "2.0.0"
+)
 
-// Canais para comunicação entre goroutines
-var syncResponseChan = make(chan []byte, 10)
-var asyncMessageChan = make(chan []byte, 10)
+// Opções do SDK para o terminal, com TLS se configurado no ambiente (TLS,
+// TLS_CA_FILE, TLS_PIN, TLS_SERVER_NAME; ver tlsconfig.ClientFromEnv). Os frames
+// binários podem ser desligados com WIRE_FORMAT=json (útil para inspecionar o tráfego).
+func clientOptions() (topcard.Options, error) {
+	tlsConfig, err := tlsconfig.ClientFromEnv()
+	if err != nil {
+		return topcard.Options{}, err
+	}
+	return topcard.Options{
+		ClientName:          clientName,
+		ClientVersion:       clientVersion,
+		TLS:                 tlsConfig,
+		DisableBinaryFrames: os.Getenv("WIRE_FORMAT") == "json",
+	}, nil
+}
 
+// Descreve o resultado do handshake de uma nova conexão
+func serverInfo(client *topcard.Client) string {
+	server := client.Server()
+	if server.ProtocolVersion == 1 {
+		return "⚠️ Servidor não respondeu ao HELLO, usando protocolo versão 1"
+	}
+	return fmt.Sprintf("🤝 %s %s, protocolo versão %d", server.Name, server.Version, server.ProtocolVersion)
+}
+
+// Exibição do menu numerado: eventos e avisos da conexão vão direto para o terminal
+type menuView struct{}
+
+func (menuView) showEvents(client *topcard.Client) error { return processEvents(client) }
+func (menuView) showDisconnected(err error)               { handleDisconnected(err) }
+func (menuView) notice(text string)                       { fmt.Println(text) }
+
+// Inicia o cliente: interface de tela cheia quando o terminal permite, menu
+// numerado caso contrário (ou com CLIENT_UI=menu)
 func Run() {
 	serverAddr := os.Getenv("SERVER_ADDR")
 	if serverAddr == "" {
 		serverAddr = "localhost:8080"
 	}
 
-	conn, err := net.Dial("tcp", serverAddr)
+	if FullScreenSupported(os.Getenv, isTerminal(os.Stdin) && isTerminal(os.Stdout)) {
+		if err := RunFullScreen(serverAddr, ScreenOptions{}); err != nil {
+			fmt.Println("Erro ao conectar no servidor:", err)
+
+			// Sem o servidor configurado, tenta um servidor anunciado na rede local
+			if serverAddr = chooseServer(bufio.NewReader(os.Stdin)); serverAddr == "" {
+				return
+			}
+			if err := RunFullScreen(serverAddr, ScreenOptions{}); err != nil {
+				fmt.Println("Erro ao conectar no servidor:", err)
+			}
+		}
+		return
+	}
+	runMenu(serverAddr)
+}
+
+// Menu numerado lido linha a linha
+func runMenu(serverAddr string) {
+	reader := bufio.NewReader(os.Stdin)
+
+	conn, err := openConnection(serverAddr, menuView{})
 	if err != nil {
 		fmt.Println("Erro ao conectar no servidor:", err)
-		return
+
+		// Sem o servidor configurado, tenta um servidor anunciado na rede local
+		if serverAddr = chooseServer(reader); serverAddr == "" {
+			return
+		}
+		if conn, err = openConnection(serverAddr, menuView{}); err != nil {
+			fmt.Println("Erro ao conectar no servidor:", err)
+			return
+		}
 	}
-	defer conn.Close()
+	defer conn.close()
 
 	fmt.Println("Conectado ao servidor TOP CARD!")
 
-	go messageDistributor(conn)
-	go asyncMessageProcessor()
+	var lastClient *topcard.Client
 
-	reader := bufio.NewReader(os.Stdin)
-	
 	for {
 		clearScreen()
 
-		connectionMutex.Lock()
-		connected := isConnected
-		connectionMutex.Unlock()
-		
+		client := conn.current()
+		if client != lastClient {
+			// Nova conexão sem o login refeito: os pacotes antigos não podem mais ser verificados
+			if lastClient != nil && client.UserID() == 0 {
+				packHistory = nil
+			}
+			lastClient = client
+		}
+
+		connected := client.Err() == nil
+		isLoggedIn := client.UserID() != 0
+		inMatch := client.Match().InMatch
+
 		fmt.Println("\n========================")
 		fmt.Println("Bem vindo ao TOP CARD!")
 		fmt.Println("========================")
-		
+
 		// Mostra status da conexão
 		if !connected {
-			fmt.Println("🔴 DESCONECTADO")
-			fmt.Println("⚠️  Dados perdidos - registre-se novamente")
+			fmt.Println("🔴 DESCONECTADO - reconectando automaticamente...")
 		}
 
 		if inMatch {
 			fmt.Println("🎮 Você está em uma partida!")
 		}
-		
+
 		fmt.Println("1 - Fazer login")
 		fmt.Println("2 - Cadastrar-se")
 		fmt.Println("3 - Abrir pacote de cartas")
 		fmt.Println("4 - Buscar partida")
 		fmt.Println("5 - Verificar ping")
-		fmt.Println("6 - Fazer jogada")        
+		fmt.Println("6 - Fazer jogada")
 		fmt.Println("7 - Ver estatísticas")
+		fmt.Println("10 - Sementes do sorteio (verificar pacotes)")
+		fmt.Println("11 - Ver inventário")
+		fmt.Println("12 - Procurar servidores na rede local")
 		if !connected {
-			fmt.Println("9 - 🔄 RECONECTAR AO SERVIDOR")  // Destaque quando desconectado
+			fmt.Println("9 - 🔄 RECONECTAR AGORA")  // Destaque quando desconectado
 		} else {
 			fmt.Println("9 - Reconectar ao servidor")
 		}
 		fmt.Println("8 - Sair")
-		
+
 		fmt.Print("Insira sua opção: ")
 		input, _ := reader.ReadString('\n')
-		input = strings.TrimSpace(input)             
+		input = strings.TrimSpace(input)
 		choice, _ := strconv.Atoi(input)
 
+		// A conexão pode ter sido refeita enquanto o menu esperava a opção
+		client = conn.current()
+		isLoggedIn = client.UserID() != 0
+		inMatch = client.Match().InMatch
+
 		switch choice {
 		case 1:
 			if isLoggedIn {
@@ -96,20 +176,20 @@ func Run() {
 				continue
 			}
 			handleLogin(conn, reader)
-			
+
 		case 2:
 			if isLoggedIn {
 				fmt.Println("Você já está logado! Faça logout primeiro.")
 				continue
 			}
-			handleRegister(conn, reader)
+			handleRegister(client, reader)
 
 		case 3:
 			if !isLoggedIn{
 				fmt.Println("Você precisa estar logado para abrir os pacotes de cartas!")
 				continue
 			}
-			handleCardPack(conn)
+			handleCardPack(client, reader)
 
 		case 4:
 			if !isLoggedIn {
@@ -120,14 +200,14 @@ func Run() {
 				fmt.Println("Você já está em uma partida!")
 				continue
 			}
-			handleQueue(conn)
-			
+			handleQueue(client)
+
 		case 5:
 			if !isLoggedIn {
 				fmt.Println("Você precisa estar logado para solicitar o ping!")
 				continue
 			}
-			handlePing(conn)
+			handlePing(client)
 
 		case 6:
 			if !isLoggedIn {
@@ -138,153 +218,130 @@ func Run() {
 				fmt.Println("Você precisa estar em uma partida para jogar!")
 				continue
 			}
-			handleGameMove(conn, reader)
+			handleGameMove(client, reader)
 
 		case 7:
 			if !isLoggedIn {
 				fmt.Println("Você precisa estar logado para ver suas estatísticas!")
 				continue
 			}
-			handleStats(conn)
-		
+			handleStats(client)
+
+		case 10:
+			if !isLoggedIn {
+				fmt.Println("Você precisa estar logado para ver as sementes do sorteio!")
+				continue
+			}
+			handleSeeds(client, reader)
+
+		case 11:
+			if !isLoggedIn {
+				fmt.Println("Você precisa estar logado para ver seu inventário!")
+				continue
+			}
+			handleInventory(client)
+
+		case 12:
+			handleDiscovery(conn, reader)
+
 		case 9:
-			attemptReconnection(serverAddr, &conn)
-			
+			conn.reconnectNow()
+
 		case 8:
 			fmt.Println("Você escolheu sair. Saindo...")
 			return
-			
+
 		default:
 			fmt.Println("Opção inválida!")
 		}
 	}
 }
 
-// Goroutine que distribui mensagens entre síncronas e assíncronas
-func messageDistributor(conn net.Conn) {
-	serverReader := bufio.NewScanner(conn)
-	
-	for serverReader.Scan() {
-		responseData := serverReader.Bytes()
-		
-		dataCopy := make([]byte, len(responseData))
-		copy(dataCopy, responseData)
-		
-		message, err := protocol.DecodeMessage(dataCopy)
-		if err != nil {
-			fmt.Printf("\n🔴 Erro ao decodificar mensagem do servidor: %v\n", err)
-			continue
-		}
-
-		switch message.Type {
-		case protocol.MSG_LOGIN_RESPONSE, protocol.MSG_REGISTER_RESPONSE, protocol.MSG_QUEUE_RESPONSE, protocol.MSG_PING_RESPONSE, protocol.MSG_STATS_RESPONSE, protocol.MSG_CARD_PACK_RESPONSE:
-			select {
-			case syncResponseChan <- dataCopy:
-			case <-time.After(100 * time.Millisecond):
-				fmt.Printf("\n⚠️ Timeout ao enviar resposta síncrona\n")
+// Exibe as notificações do servidor até a conexão terminar. Retorna o motivo do
+// fim (nil se encerrada pelo próprio cliente).
+func processEvents(client *topcard.Client) error {
+	var disconnectErr error
+	for event := range client.Events() {
+		switch event := event.(type) {
+		case topcard.MatchFound:
+			handleMatchFound(event)
+		case topcard.MatchStart:
+			handleMatchStart(event)
+		case topcard.MatchEnd:
+			handleMatchEnd(event)
+		case topcard.GameState:
+			handleGameState(event)
+		case topcard.TurnUpdate:
+			handleTurnUpdate(event)
+		case topcard.ServerShutdown:
+			handleServerShutdown(event)
+		case topcard.MoveRejected:
+			handleMoveRejected(event)
+		case topcard.SessionState:
+			handleSessionState(event)
+		case topcard.InventoryUpdate:
+			if event.Reason == topcard.INVENTORY_CARDS_RETURNED {
+				fmt.Printf("\n🎒 Cartas jogadas devolvidas ao inventário (%d cartas)\n", len(event.Cards))
 			}
-		case protocol.MSG_MATCH_FOUND, protocol.MSG_MATCH_START, protocol.MSG_MATCH_END, protocol.MSG_GAME_STATE, protocol.MSG_TURN_UPDATE:
-			select {
-			case asyncMessageChan <- dataCopy:
-			case <-time.After(100 * time.Millisecond):
-				fmt.Printf("\n⚠️ Timeout ao enviar mensagem assíncrona\n")
-			}
-		default:
-			fmt.Printf("\n⚠️ Tipo de mensagem desconhecido: %s\n", message.Type)
+		case topcard.ChatMessage:
+			fmt.Printf("\n💬 %s: %s\n", event.FromName, event.Text)
+		case topcard.ServerError:
+			fmt.Printf("\n🔴 Erro do servidor [%s]: %s\n", event.Err.Code, event.Err.Message)
+		case topcard.Disconnected:
+			disconnectErr = event.Err
 		}
 	}
+	return disconnectErr
+}
 
-	// Quando sair do loop, conexão foi perdida
-	connectionMutex.Lock()
-	isConnected = false
-	connectionMutex.Unlock()
-
-	clearPlayerData()
-	
+// Avisa que a conexão com o servidor foi perdida
+func handleDisconnected(err error) {
 	fmt.Println("\n🔴 ==========================================")
 	fmt.Println("        SERVIDOR DESCONECTADO")
 	fmt.Println("🔴 ==========================================")
 	fmt.Println("❌ Conexão com o servidor foi perdida")
-	fmt.Println("📋 Todos os dados foram perdidos no servidor")
-	fmt.Println("🔄 Você precisará se REGISTRAR novamente")
-	fmt.Println("💡 Use a opção 9 para reconectar")
+	fmt.Println("🔄 Reconectando automaticamente; o login será refeito")
+	fmt.Println("💡 Use a opção 9 para tentar de novo sem esperar")
 	fmt.Println("==========================================")
-	
-	if err := serverReader.Err(); err != nil {
+
+	if !errors.Is(err, topcard.ErrClosed) {
 		fmt.Printf("   Detalhes do erro: %v\n", err)
 	}
 }
 
-// Goroutine para processar mensagens assíncronas
-func asyncMessageProcessor() {
-	for {
-		select {
-		case data := <-asyncMessageChan:
-			// Decodifica a mensagem
-			message, err := protocol.DecodeMessage(data)
-			if err != nil {
-				fmt.Printf("\n🔴 Erro ao decodificar mensagem assíncrona: %v\n", err)
-				continue
-			}
-
-			// Processa mensagens assíncronas
-			switch message.Type {
-			case protocol.MSG_MATCH_FOUND:
-				handleMatchFound(message)
-			case protocol.MSG_MATCH_START:
-				handleMatchStart(message)
-			case protocol.MSG_MATCH_END:
-				handleMatchEnd(message)
-			case protocol.MSG_GAME_STATE:
-				handleGameState(message)
-			case protocol.MSG_TURN_UPDATE:
-				handleTurnUpdate(message)
-			}
-		}
-	}
+// Função helper para exibir o erro de uma requisição: recusas do servidor com a
+// mensagem dele, prazo esgotado e conexão perdida com mensagens próprias
+func printRequestError(client *topcard.Client, err error) {
+	fmt.Println(requestErrorText(client, err))
 }
 
-// Função helper para aguardar resposta síncrona
-func waitForSyncResponse(timeout time.Duration) ([]byte, error) {
-	select {
-	case data := <-syncResponseChan:
-		return data, nil
-	case <-time.After(timeout):
-		connectionMutex.Lock()
-		connected := isConnected
-		connectionMutex.Unlock()
-		
-		if !connected {
-			return nil, fmt.Errorf("servidor desconectado - todos os dados foram perdidos")
-		}
-		return nil, fmt.Errorf("timeout - servidor não respondeu")
+// Texto do erro de uma requisição, usado pelo menu e pela tela cheia
+func requestErrorText(client *topcard.Client, err error) string {
+	var serverErr *topcard.Error
+	switch {
+	case errors.As(err, &serverErr):
+		return "❌ " + serverErr.Message
+	case client.Err() != nil:
+		return "Erro: servidor desconectado - reconectando automaticamente"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "Erro: timeout - servidor não respondeu"
+	default:
+		return fmt.Sprintf("Erro: %v", err)
 	}
 }
 
 // Função helper para verificar conexão antes de fazer requisições
-func checkConnection() bool {
-	connectionMutex.Lock()
-	connected := isConnected
-	connectionMutex.Unlock()
-	
-	if !connected {
-		fmt.Println("❌ Não conectado ao servidor!")
-		fmt.Println("💡 Use a opção 9 para reconectar")
+func checkConnection(client *topcard.Client) bool {
+	if client.Err() != nil {
+		fmt.Println("❌ Não conectado ao servidor! Reconectando automaticamente...")
+		fmt.Println("💡 Use a opção 9 para tentar de novo sem esperar")
 		return false
 	}
 	return true
 }
 
 // Manipula notificação de partida encontrada
-func handleMatchFound(message *protocol.Message) {
-	matchFound, err := protocol.ExtractMatchFound(message)
-	if err != nil {
-		fmt.Printf("\n🔴 Erro ao extrair dados de partida encontrada: %v\n", err)
-		return
-	}
-
-	currentMatchID = matchFound.MatchID // Armazena o ID da partida atual
-
+func handleMatchFound(matchFound topcard.MatchFound) {
 	fmt.Printf("\n\n🎯 ===== PARTIDA ENCONTRADA! =====\n")
 	fmt.Printf("🎮 Match ID: %d\n", matchFound.MatchID)
 	fmt.Printf("⚔️ Oponente: %s (ID: %d)\n", matchFound.OpponentName, matchFound.OpponentID)
@@ -292,115 +349,101 @@ func handleMatchFound(message *protocol.Message) {
 	fmt.Printf("⏳ Preparando a partida...\n")
 	fmt.Printf("==================================\n")
 	// Remove o "Pressione Enter para continuar" para evitar confusão
-	
-	inMatch = true
 }
 
 // Manipula estado do jogo
-func handleGameState(message *protocol.Message) {
-	gameState, err := protocol.ExtractGameState(message)
-	if err != nil {
-		fmt.Printf("\n🔴 Erro ao extrair estado do jogo: %v\n", err)
-		return
-	}
-
-	// Atualiza flag de turno
-	isMyTurn = gameState.YourTurn
-
+func handleGameState(gameState topcard.GameState) {
 	fmt.Printf("\n\n🎮 ===== ESTADO DO JOGO =====\n")
 	fmt.Printf("📝 %s\n", gameState.Message)
-	
+
 	if gameState.YourTurn && !gameState.GameOver {
 		fmt.Printf("🎯 É SEU TURNO! Use a opção 6 do menu para jogar.\n")
 	} else if !gameState.GameOver {
 		fmt.Printf("⏳ Aguardando o oponente jogar...\n")
 	}
-	
+
 	fmt.Printf("============================\n")
 }
 
 // Manipula atualização de turno
-func handleTurnUpdate(message *protocol.Message) {
-	turnUpdate, err := protocol.ExtractTurnUpdate(message)
-	if err != nil {
-		fmt.Printf("\n🔴 Erro ao extrair atualização de turno: %v\n", err)
-		return
-	}
-
-	// Atualiza flag de turno
-	isMyTurn = turnUpdate.YourTurn
-
+func handleTurnUpdate(turnUpdate topcard.TurnUpdate) {
 	fmt.Printf("\n\n🔄 ===== ATUALIZAÇÃO =====\n")
 	fmt.Printf("📝 %s\n", turnUpdate.Message)
-	
+
 	if turnUpdate.YourTurn {
 		fmt.Printf("🎯 É SEU TURNO! Use a opção 6 do menu para jogar.\n")
 	}
-	
+
 	fmt.Printf("========================\n")
 }
 
 // Manipula notificação de início de partida
-func handleMatchStart(message *protocol.Message) {
-	matchStart, err := protocol.ExtractMatchStart(message)
-	if err != nil {
-		fmt.Printf("\n🔴 Erro ao extrair dados de início de partida: %v\n", err)
-		return
-	}
-
+func handleMatchStart(matchStart topcard.MatchStart) {
 	fmt.Printf("\n\n🚀 ===== PARTIDA INICIADA! =====\n")
 	fmt.Printf("🎮 Match ID: %d\n", matchStart.MatchID)
 	fmt.Printf("🎯 %s\n", matchStart.Message)
 	fmt.Printf("⚔️ Que comece a batalha!\n")
 	fmt.Printf("📋 Use a opção 6 do menu quando for seu turno!\n")
 	fmt.Printf("===============================\n")
-	
-	inMatch = true
-	// Inicializa o turno como false - será atualizado pelo GameState
-	isMyTurn = false
 }
 
-// Manipula notificação de fim de partida
-func handleMatchEnd(message *protocol.Message) {
-	matchEnd, err := protocol.ExtractMatchEnd(message)
-	if err != nil {
-		fmt.Printf("\n🔴 Erro ao extrair dados de fim de partida: %v\n", err)
-		return
+// Manipula a recusa de uma jogada (a carta nunca saiu do inventário: o servidor só a
+// retira numa jogada aceita, avisando com INVENTORY_UPDATE)
+func handleMoveRejected(rejected topcard.MoveRejected) {
+	fmt.Printf("\n\n❌ Jogada recusada [%s]: %s\n", rejected.Err.Code, rejected.Err.Message)
+
+	switch rejected.Err.Code {
+	case topcard.ERR_NOT_YOUR_TURN, topcard.ERR_ALREADY_PLAYED:
+		fmt.Printf("⏳ Aguardando o oponente jogar...\n")
+	case topcard.ERR_MATCH_NOT_FOUND, topcard.ERR_MATCH_NOT_IN_PROGRESS, topcard.ERR_NOT_IN_MATCH:
+		fmt.Printf("💡 A partida não está mais em andamento.\n")
+	default:
+		fmt.Printf("🎯 Ainda é seu turno! Use a opção 6 do menu para jogar.\n")
 	}
+}
 
+// Manipula o aviso de encerramento do servidor
+func handleServerShutdown(shutdown topcard.ServerShutdown) {
+	fmt.Printf("\n\n🛑 ===== SERVIDOR EM ENCERRAMENTO =====\n")
+	fmt.Printf("📝 %s\n", shutdown.Message)
+	if !shutdown.Deadline.IsZero() {
+		fmt.Printf("⏳ Prazo para terminar a partida atual: %s\n", shutdown.Deadline.Local().Format("15:04:05"))
+	}
+	fmt.Printf("======================================\n")
+}
+
+// Manipula notificação de fim de partida
+func handleMatchEnd(matchEnd topcard.MatchEnd) {
 	fmt.Printf("\n\n🏆 ===== PARTIDA FINALIZADA! =====\n")
 	fmt.Printf("🎮 Match ID: %d\n", matchEnd.MatchID)
-	
-	if matchEnd.WinnerID == currentUserID {
+
+	if matchEnd.WinnerID == 0 {
+		fmt.Printf("⏹️  Partida interrompida, sem vencedor\n")
+	} else if matchEnd.Won {
 		fmt.Printf("🎉 VITÓRIA! Você ganhou!\n")
 	} else {
 		fmt.Printf("😔 DERROTA! Vencedor: %s (ID: %d)\n", matchEnd.WinnerName, matchEnd.WinnerID)
 	}
-	
+
 	fmt.Printf("📝 %s\n", matchEnd.Message)
 	fmt.Printf("🔄 Voltando ao menu principal...\n")
 	fmt.Printf("=================================\n")
-	
-	inMatch = false
-	currentMatchID = 0
-	// Reseta o turno
-	isMyTurn = false
 }
 
-func handleGameMove(conn net.Conn, reader *bufio.Reader) {
-	if !checkConnection(){
+func handleGameMove(client *topcard.Client, reader *bufio.Reader) {
+	if !checkConnection(client){
 		return
 	}
 
 	// Verifica se é o turno do jogador
-	if !isMyTurn {
+	if !client.Match().YourTurn {
 		fmt.Println("❌ Não é seu turno! Aguarde o oponente jogar.")
 		fmt.Println("💡 Você será notificado quando for sua vez.")
 		return
 	}
 
 	// Verifica se tem cartas
-	hydra, quimera, gorgona := getCurrentPlayerCards()
+	hydra, quimera, gorgona := countCards(client.Inventory())
 	if hydra == 0 && quimera == 0 && gorgona == 0 {
 		fmt.Println("❌ Você não tem cartas! Abra um pacote primeiro.")
 		return
@@ -409,34 +452,34 @@ func handleGameMove(conn net.Conn, reader *bufio.Reader) {
 	fmt.Println("\n--- FAZER JOGADA COM CARTA ---")
 	fmt.Printf("📋 Seu inventário: HYDRA(%d) | QUIMERA(%d) | GORGONA(%d)\n", hydra, quimera, gorgona)
 	fmt.Println("Escolha uma carta para jogar:")
-	
+
 	// Mostra apenas cartas disponíveis
 	validChoices := make(map[int]string)
 	choiceNum := 1
-	
+
 	if hydra > 0 {
 		fmt.Printf("%d - HYDRA (devora QUIMERA) - Disponível: %d\n", choiceNum, hydra)
 		validChoices[choiceNum] = "HYDRA"
 		choiceNum++
 	}
-	
+
 	if quimera > 0 {
 		fmt.Printf("%d - QUIMERA (destrói GORGONA) - Disponível: %d\n", choiceNum, quimera)
 		validChoices[choiceNum] = "QUIMERA"
 		choiceNum++
 	}
-	
+
 	if gorgona > 0 {
 		fmt.Printf("%d - GORGONA (petrifica HYDRA) - Disponível: %d\n", choiceNum, gorgona)
 		validChoices[choiceNum] = "GORGONA"
 		choiceNum++
 	}
-	
+
 	fmt.Printf("Digite sua escolha (1-%d): ", len(validChoices))
-	
+
 	input, _ := reader.ReadString('\n')
 	input = strings.TrimSpace(input)
-	
+
 	choice, err := strconv.Atoi(input)
 	if err != nil || choice < 1 || choice > len(validChoices) {
 		fmt.Printf("❌ Por favor, digite uma opção válida (1-%d)!\n", len(validChoices))
@@ -449,49 +492,26 @@ func handleGameMove(conn net.Conn, reader *bufio.Reader) {
 		return
 	}
 
-	// Verifica novamente se tem a carta (double check)
-	if !hasCardType(cardType) {
-		fmt.Printf("❌ Você não possui cartas do tipo %s!\n", cardType)
-		return
-	}
-
-	// Cria a mensagem de jogada com carta
-	cardMoveMessage, err := protocol.CreateCardMove(currentUserID, currentMatchID, cardType)
-	if err != nil {
-		fmt.Println("Erro ao criar mensagem de jogada:", err)
-		return
-	}
-
-	// Adiciona quebra de linha
-	cardMoveMessage = append(cardMoveMessage, '\n')
-
-	// Envia para o servidor
-	_, err = conn.Write(cardMoveMessage)
-	if err != nil {
+	// Envia a jogada; a carta sai do inventário quando o servidor confirmar
+	if err := client.PlayCard(context.Background(), cardType); err != nil {
 		fmt.Println("Erro ao enviar jogada:", err)
 		return
 	}
 
-	// Remove a carta do inventário local
-	removeCardFromLocal(cardType)
-
-	// Marca que não é mais o turno do jogador
-	isMyTurn = false
-
 	fmt.Printf("✅ Carta jogada: %s\n", cardType)
 	fmt.Println("⏳ Aguardando resposta do servidor...")
 }
 
 // função para lidar com pacotes de cartas
-func handleCardPack(conn net.Conn) {
-	if !checkConnection(){
+func handleCardPack(client *topcard.Client, reader *bufio.Reader) {
+	if !checkConnection(client){
 		return
 	}
 
 	// Verifica se já tem cartas
-	hydra, quimera, gorgona := getCurrentPlayerCards()
+	hydra, quimera, gorgona := countCards(client.Inventory())
 	totalCards := hydra + quimera + gorgona
-	
+
 	if totalCards > 0 {
 		fmt.Println("\n❌ VOCÊ JÁ POSSUI CARTAS!")
 		fmt.Printf("📋 Seu inventário atual: HYDRA(%d) | QUIMERA(%d) | GORGONA(%d)\n", hydra, quimera, gorgona)
@@ -502,88 +522,81 @@ func handleCardPack(conn net.Conn) {
 	}
 
 	fmt.Println("\n--- ABRIR PACOTE DE CARTAS ---")
-	fmt.Println("🎒 Abrindo pacote de cartas...")
 
-	// Cria a mensagem de requisição de pacote
-	cardPackMessage, err := protocol.CreateCardPackRequest(currentUserID)
-	if err != nil {
-		fmt.Println("Erro ao criar mensagem de pacote de cartas:", err)
-		return
+	// Mostra os tipos de pacote disponíveis
+	packTypes := card.ListPackTypes()
+	for i, packType := range packTypes {
+		fmt.Printf("%d - %s: %s\n", i+1, packType.Name, packType.Description)
 	}
+	fmt.Printf("Escolha o pacote (1-%d, Enter = %s): ", len(packTypes), card.PACK_STANDARD)
 
-	// Adiciona quebra de linha
-	cardPackMessage = append(cardPackMessage, '\n')
+	input, _ := reader.ReadString('\n')
+	input = strings.TrimSpace(input)
 
-	// Envia para o servidor
-	_, err = conn.Write(cardPackMessage)
-	if err != nil {
-		fmt.Println("Erro ao enviar requisição de pacote:", err)
-		return
+	packTypeName := card.PACK_STANDARD
+	if input != "" {
+		choice, err := strconv.Atoi(input)
+		if err != nil || choice < 1 || choice > len(packTypes) {
+			fmt.Printf("❌ Por favor, digite uma opção válida (1-%d)!\n", len(packTypes))
+			return
+		}
+		packTypeName = packTypes[choice-1].Name
 	}
 
-	// Aguarda resposta síncrona
-	responseData, err := waitForSyncResponse(5 * time.Second)
-	if err != nil {
-		fmt.Println("Erro:", err)
-		return
+	// Semente do cliente escolhida depois do compromisso do servidor (publicado no login)
+	fmt.Print("Semente do cliente (Enter = gerar aleatória): ")
+	clientSeed, _ := reader.ReadString('\n')
+	clientSeed = strings.TrimSpace(clientSeed)
+	if clientSeed == "" {
+		clientSeed = newClientSeed()
 	}
 
-	// Decodifica a resposta
-	message, err := protocol.DecodeMessage(responseData)
+	fmt.Printf("🎒 Abrindo pacote %s com a semente do cliente %s...\n", packTypeName, clientSeed)
+
+	pack, err := client.OpenPackWithSeed(context.Background(), packTypeName, clientSeed)
 	if err != nil {
-		fmt.Println("Erro ao decodificar resposta:", err)
-		return
-	}
+		printRequestError(client, err)
+	} else {
+		fmt.Printf("✅ %s\n", pack.Message)
 
-	// Processa resposta de pacote de cartas
-	if message.Type == protocol.MSG_CARD_PACK_RESPONSE {
-		cardPackResp, err := protocol.ExtractCardPackResponse(message)
-		if err != nil {
-			fmt.Println("Erro ao extrair resposta de pacote:", err)
-			return
+		if len(pack.Cards) > 0 {
+			fmt.Println("\n🃏 ===== SUAS CARTAS =====")
+			for i, card := range pack.Cards {
+				fmt.Printf("%d. %s %s (%s)\n", i+1, rarityEmoji(card.Rarity), card.Type, card.Rarity)
+			}
+			fmt.Println("========================")
+
+			// Mostra inventário total
+			hydra, quimera, gorgona := countCards(client.Inventory())
+			fmt.Printf("\n📋 SEU INVENTÁRIO TOTAL:\n")
+			fmt.Printf("⚪ HYDRA: %d cartas\n", hydra)
+			fmt.Printf("🔵 QUIMERA: %d cartas\n", quimera)
+			fmt.Printf("🟣 GORGONA: %d cartas\n", gorgona)
+			fmt.Printf("📊 Total: %d cartas\n", hydra+quimera+gorgona)
 		}
 
-		if cardPackResp.Success {
-			fmt.Printf("✅ %s\n", cardPackResp.Message)
-			
-			if len(cardPackResp.Cards) > 0 {
-				// Atualiza o inventário local
-				updateLocalInventory(cardPackResp.Cards)
-				
-				fmt.Println("\n🃏 ===== SUAS CARTAS =====")
-				for i, card := range cardPackResp.Cards {
-					rarityEmoji := ""
-					switch card.Rarity {
-					case "comum":
-						rarityEmoji = "⚪"
-					case "raro":
-						rarityEmoji = "🔵"
-					case "épico":
-						rarityEmoji = "🟣"
-					}
-					fmt.Printf("%d. %s %s (%s)\n", i+1, rarityEmoji, card.Type, card.Rarity)
-				}
-				fmt.Println("========================")
-				
-				// Mostra inventário total
-				hydra, quimera, gorgona := getCurrentPlayerCards()
-				fmt.Printf("\n📋 SEU INVENTÁRIO TOTAL:\n")
-				fmt.Printf("⚪ HYDRA: %d cartas\n", hydra)
-				fmt.Printf("🔵 QUIMERA: %d cartas\n", quimera)
-				fmt.Printf("🟣 GORGONA: %d cartas\n", gorgona)
-				fmt.Printf("📊 Total: %d cartas\n", hydra+quimera+gorgona)
-			}
-			
-			// Mostra informações do estoque
-			stock := cardPackResp.StockInfo
-			fmt.Printf("\n📦 Estoque Global Restante:\n")
-			fmt.Printf("⚪ HYDRA: %d cartas\n", stock.HydraCount)
-			fmt.Printf("🔵 QUIMERA: %d cartas\n", stock.QuimeraCount)
-			fmt.Printf("🟣 GORGONA: %d cartas\n", stock.GorgonaCount)
-			fmt.Printf("📊 Total: %d cartas\n", stock.TotalCards)
-			
-		} else {
-			fmt.Printf("❌ %s\n", cardPackResp.Message)
+		// Mostra informações do estoque
+		stock := pack.Stock
+		fmt.Printf("\n📦 Estoque Global Restante:\n")
+		fmt.Printf("⚪ HYDRA: %d cartas\n", stock.HydraCount)
+		fmt.Printf("🔵 QUIMERA: %d cartas\n", stock.QuimeraCount)
+		fmt.Printf("🟣 GORGONA: %d cartas\n", stock.GorgonaCount)
+		fmt.Printf("📊 Total: %d cartas\n", stock.TotalCards)
+
+		// Mostra o status de pity
+		showPityInfo(pack.Pity)
+
+		// Guarda o pacote para verificar o sorteio depois
+		fairness := pack.Fairness
+		packHistory = append(packHistory, openedPack{
+			PackType: pack.Type,
+			Cards:    pack.Cards,
+			Fairness: fairness,
+			Forced:   pack.Pity.Triggered,
+		})
+		fmt.Printf("\n🔐 Sorteio #%d com semente do servidor %s...\n", fairness.Nonce, shortHash(fairness.ServerSeedHash))
+		if pack.CommittedHash != "" && pack.CommittedHash != fairness.ServerSeedHash {
+			fmt.Printf("⚠️ ATENÇÃO: o servidor havia publicado a semente %s... antes deste sorteio!\n", shortHash(pack.CommittedHash))
 		}
 	}
 
@@ -591,487 +604,367 @@ func handleCardPack(conn net.Conn) {
 	bufio.NewReader(os.Stdin).ReadString('\n')
 }
 
-func handleQueue(conn net.Conn) {
-	if !checkConnection(){
-		return
-	}
+// Função para gerar uma semente do cliente aleatória (16 caracteres hex)
+func newClientSeed() string {
+	seed := make([]byte, 8)
+	rand.Read(seed)
+	return hex.EncodeToString(seed)
+}
 
-	fmt.Println("\n--- BUSCAR PARTIDA ---")
-	fmt.Println("Entrando na fila de partidas...")
+// Emoji de cada raridade de carta
+func rarityEmoji(rarity string) string {
+	switch rarity {
+	case "comum":
+		return "⚪"
+	case "raro":
+		return "🔵"
+	case "épico":
+		return "🟣"
+	}
+	return ""
+}
 
-	// Cria a mensagem de requisição de fila
-	queueMessage, err := protocol.CreateQueueRequest(currentUserID)
-	if err != nil {
-		fmt.Println("Erro ao criar mensagem de fila:", err)
-		return
+// Mostra o estado recebido no login: a tela do menu é remontada a partir dele
+func handleSessionState(state topcard.SessionState) {
+	hydra, quimera, gorgona := countCards(state.Inventory)
+	fmt.Printf("\n📋 %s: %dW-%dL | 💰 %d moedas | HYDRA(%d) QUIMERA(%d) GORGONA(%d)\n",
+		state.Stats.UserName, state.Stats.Wins, state.Stats.Losses, state.Coins, hydra, quimera, gorgona)
+	if state.InQueue {
+		fmt.Printf("🔍 Você continua na fila (%d jogadores)\n", state.QueueSize)
+	}
+	if match := state.Match; match != nil {
+		fmt.Printf("🎮 Partida %d em andamento contra %s\n", match.MatchID, match.OpponentName)
+		switch {
+		case match.YourTurn:
+			fmt.Println("🎯 É SEU TURNO! Use a opção 6 para jogar.")
+		case match.PlayedCard != "":
+			fmt.Printf("⏳ Você já jogou %s; aguardando o oponente...\n", match.PlayedCard)
+		default:
+			fmt.Println("⏳ Aguardando o oponente...")
+		}
 	}
+}
 
-	// Adiciona quebra de linha para o servidor conseguir ler
-	queueMessage = append(queueMessage, '\n')
-
-	// Envia para o servidor
-	_, err = conn.Write(queueMessage)
-	if err != nil {
-		fmt.Println("Erro ao enviar requisição de fila:", err)
+// Mostra o inventário do jogador como está no servidor
+func handleInventory(client *topcard.Client) {
+	if !checkConnection(client) {
 		return
 	}
 
-	// Aguarda resposta síncrona
-	responseData, err := waitForSyncResponse(5 * time.Second)
+	inventory, err := client.RefreshInventory(context.Background())
 	if err != nil {
-		fmt.Println("Erro:", err)
+		printRequestError(client, err)
 		return
 	}
 
-	// Decodifica a resposta
-	message, err := protocol.DecodeMessage(responseData)
-	if err != nil {
-		fmt.Println("Erro ao decodificar resposta:", err)
-		return
+	fmt.Println("\n🎒 ===== SEU INVENTÁRIO =====")
+	if len(inventory) == 0 {
+		fmt.Println("Nenhuma carta. Abra um pacote para receber cartas!")
 	}
-
-	// Processa resposta de fila
-	if message.Type == protocol.MSG_QUEUE_RESPONSE {
-		queueResp, err := protocol.ExtractQueueResponse(message)
-		if err != nil {
-			fmt.Println("Erro ao extrair resposta de fila:", err)
-			return
-		}
-
-		if queueResp.Success {
-			fmt.Printf("✅ %s\n", queueResp.Message)
-			fmt.Printf("Jogadores na fila: %d\n", queueResp.QueueSize)
-			fmt.Println("🔍 Aguardando por oponentes...")
-			fmt.Println("💡 As notificações de partida aparecerão automaticamente!")
-		} else {
-			fmt.Printf("❌ %s\n", queueResp.Message)
-		}
+	for i, card := range inventory {
+		fmt.Printf("%d. %s %s (%s)\n", i+1, rarityEmoji(card.Rarity), card.Type, card.Rarity)
 	}
+	hydra, quimera, gorgona := countCards(inventory)
+	fmt.Printf("📋 HYDRA(%d) | QUIMERA(%d) | GORGONA(%d) | Total: %d\n", hydra, quimera, gorgona, len(inventory))
+	fmt.Println("============================")
 }
 
-func handleRegister(conn net.Conn, reader *bufio.Reader) {
-	if !checkConnection(){
-		return
-	}
-
-	fmt.Println("\n--- CADASTRO ---")
-	fmt.Print("Insira um nome de usuário (mín. 3 caracteres): ")
-	userName, _ := reader.ReadString('\n')
-	userName = strings.TrimSpace(userName)
-	
-	fmt.Print("Digite sua senha (mín. 4 caracteres): ")
-	password, _ := reader.ReadString('\n')
-	password = strings.TrimSpace(password)
-
-	// Validações básicas no cliente
-	if len(userName) < 3 {
-		fmt.Println("❌ Nome de usuário deve ter pelo menos 3 caracteres!")
-		return
+// Mostra o status de pity (épico garantido) de um tipo de pacote
+func showPityInfo(pity topcard.Pity) {
+	if pity.Triggered {
+		fmt.Println("\n✨ Pity ativado! Este pacote garantiu uma carta épica.")
 	}
-	
-	if len(password) < 4 {
-		fmt.Println("❌ Senha deve ter pelo menos 4 caracteres!")
-		return
+	if pity.Threshold > 0 {
+		fmt.Printf("🍀 Pity (%s): %d/%d pacotes sem épico - épico garantido em %d pacote(s)\n",
+			pity.PackType, pity.PacksWithoutEpic, pity.Threshold, pity.PacksUntilEpic)
 	}
+}
 
-	// Cria a mensagem de cadastro
-	registerMessage, err := protocol.CreateRegisterRequest(userName, password)
-	if err != nil {
-		fmt.Println("Erro ao criar mensagem de cadastro:", err)
+// função para consultar as sementes do sorteio e verificar os pacotes abertos
+func handleSeeds(client *topcard.Client, reader *bufio.Reader) {
+	if !checkConnection(client){
 		return
 	}
 
-	// Adiciona quebra de linha para o servidor conseguir ler
-	registerMessage = append(registerMessage, '\n')
+	fmt.Println("\n--- SEMENTES DO SORTEIO ---")
 
-	// Envia para o servidor
-	_, err = conn.Write(registerMessage)
+	seeds, err := client.Seeds(context.Background())
 	if err != nil {
-		fmt.Println("Erro ao enviar cadastro:", err)
+		printRequestError(client, err)
 		return
 	}
 
-	// Aguarda resposta síncrona
-	responseData, err := waitForSyncResponse(5 * time.Second)
-	if err != nil {
-		fmt.Println("Erro:", err)
-		return
-	}
+	fmt.Printf("🔐 Hash da semente do servidor: %s\n", seeds.ServerSeedHash)
+	fmt.Printf("🎲 Semente do cliente: %s\n", seeds.ClientSeed)
+	fmt.Printf("🔢 Pacotes sorteados com esta semente: %d\n", seeds.Nonce)
+	fmt.Printf("📋 Pacotes aguardando verificação: %d\n", len(packHistory))
 
-	// Decodifica a resposta
-	message, err := protocol.DecodeMessage(responseData)
-	if err != nil {
-		fmt.Println("Erro ao decodificar resposta:", err)
-		return
-	}
+	fmt.Println("\n1 - Alterar semente do cliente")
+	fmt.Println("2 - Revelar semente do servidor e verificar pacotes")
+	fmt.Print("Escolha uma opção (Enter para voltar): ")
 
-	// Processa resposta de cadastro
-	if message.Type == protocol.MSG_REGISTER_RESPONSE {
-		registerResp, err := protocol.ExtractRegisterResponse(message)
+	input, _ := reader.ReadString('\n')
+	input = strings.TrimSpace(input)
+
+	switch input {
+	case "1":
+		fmt.Print("Nova semente do cliente: ")
+		clientSeed, _ := reader.ReadString('\n')
+		clientSeed = strings.TrimSpace(clientSeed)
+		if clientSeed == "" {
+			fmt.Println("❌ A semente não pode ser vazia!")
+			return
+		}
+		seeds, err = client.SetClientSeed(context.Background(), clientSeed)
 		if err != nil {
-			fmt.Println("Erro ao extrair resposta de cadastro:", err)
+			printRequestError(client, err)
 			return
 		}
+		fmt.Printf("✅ %s\n", seeds.Message)
 
-		if registerResp.Success {
-			fmt.Printf("✅ %s\n", registerResp.Message)
-			fmt.Printf("Seu ID de usuário é: %d\n", registerResp.UserID)
-			fmt.Println("Agora você pode fazer login!")
-		} else {
-			fmt.Printf("❌ %s\n", registerResp.Message)
+	case "2":
+		seeds, err = client.RotateSeed(context.Background())
+		if err != nil {
+			printRequestError(client, err)
+			return
+		}
+		if seeds.Revealed == nil {
+			fmt.Printf("❌ %s\n", seeds.Message)
+			return
 		}
-	}
-}
-
-func handleLogin(conn net.Conn, reader *bufio.Reader) {
-	if !checkConnection(){
-		return
-	}
-
-	fmt.Println("\n--- LOGIN ---")
-	fmt.Print("Insira seu nome de usuário: ")
-	userName, _ := reader.ReadString('\n')
-	userName = strings.TrimSpace(userName)
-	
-	fmt.Print("Digite sua senha: ")
-	password, _ := reader.ReadString('\n')
-	password = strings.TrimSpace(password)
 
-	// Cria a mensagem de login
-	loginMessage, err := protocol.CreateLoginRequest(userName, password)
-	if err != nil {
-		fmt.Println("Erro ao criar mensagem de login:", err)
-		return
+		revealed := seeds.Revealed
+		fmt.Printf("✅ %s\n", seeds.Message)
+		fmt.Printf("🔓 Semente revelada: %s\n", revealed.ServerSeed)
+		fmt.Printf("🔐 Novo hash publicado: %s\n", seeds.ServerSeedHash)
+		verifyPackHistory(*revealed)
 	}
+}
 
-	// Adiciona quebra de linha para o servidor conseguir ler
-	loginMessage = append(loginMessage, '\n')
+// Recalcula localmente os pacotes sorteados com a semente revelada
+func verifyPackHistory(revealed topcard.RevealedSeed) {
+	fmt.Println("\n🔎 ===== VERIFICAÇÃO DOS PACOTES =====")
 
-	// Envia para o servidor
-	_, err = conn.Write(loginMessage)
-	if err != nil {
-		fmt.Println("Erro ao enviar login:", err)
-		return
-	}
-
-	// Aguarda resposta síncrona
-	responseData, err := waitForSyncResponse(5 * time.Second)
-	if err != nil {
-		fmt.Println("Erro:", err)
-		return
-	}
+	remaining := packHistory[:0]
+	verified := 0
+	for _, pack := range packHistory {
+		if pack.Fairness.ServerSeedHash != revealed.ServerSeedHash {
+			remaining = append(remaining, pack)
+			continue
+		}
 
-	// Decodifica a resposta
-	message, err := protocol.DecodeMessage(responseData)
-	if err != nil {
-		fmt.Println("Erro ao decodificar resposta:", err)
-		return
-	}
+		stock := pack.Fairness.StockBefore
+		expected, err := card.VerifyPack(revealed.ServerSeed, revealed.ServerSeedHash, pack.Fairness.ClientSeed,
+			pack.Fairness.Nonce, pack.PackType,
+			card.StockCounts{Hydra: stock.HydraCount, Quimera: stock.QuimeraCount, Gorgona: stock.GorgonaCount},
+			pack.Forced)
 
-	// Processa resposta de login
-	if message.Type == protocol.MSG_LOGIN_RESPONSE {
-		loginResp, err := protocol.ExtractLoginResponse(message)
-		if err != nil {
-			fmt.Println("Erro ao extrair resposta de login:", err)
-			return
+		var received []card.Card
+		for _, c := range pack.Cards {
+			received = append(received, card.Card{Type: c.Type, Rarity: c.Rarity})
 		}
 
-		if loginResp.Success {
-			fmt.Printf("✅ %s\n", loginResp.Message)
-			currentUserID = loginResp.UserID
-			isLoggedIn = true
-			fmt.Printf("Você está logado com ID: %d\n", currentUserID)
+		if err != nil {
+			fmt.Printf("❌ Pacote #%d (%s): %v\n", pack.Fairness.Nonce, pack.PackType, err)
+		} else if card.SamePack(expected, received) {
+			fmt.Printf("✅ Pacote #%d (%s): sorteio confirmado\n", pack.Fairness.Nonce, pack.PackType)
 		} else {
-			fmt.Printf("❌ %s\n", loginResp.Message)
+			fmt.Printf("❌ Pacote #%d (%s): cartas recebidas %v não conferem com %v\n", pack.Fairness.Nonce, pack.PackType, received, expected)
 		}
+		verified++
 	}
-}
+	packHistory = remaining
 
-func handleStats(conn net.Conn) {
-	if !checkConnection(){
-		return
+	if verified == 0 {
+		fmt.Println("📋 Nenhum pacote aberto com esta semente.")
 	}
+	fmt.Println("=====================================")
+}
 
-	fmt.Println("\n--- SUAS ESTATÍSTICAS ---")
+// Abrevia um hash para exibição
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
 
-	// Cria a mensagem de requisição de estatísticas
-	statsMessage, err := protocol.CreateStatsRequest(currentUserID)
-	if err != nil {
-		fmt.Println("Erro ao criar mensagem de estatísticas:", err)
+func handleQueue(client *topcard.Client) {
+	if !checkConnection(client){
 		return
 	}
 
-	// Adiciona quebra de linha
-	statsMessage = append(statsMessage, '\n')
+	fmt.Println("\n--- BUSCAR PARTIDA ---")
+	fmt.Println("Entrando na fila de partidas...")
 
-	// Envia para o servidor
-	_, err = conn.Write(statsMessage)
+	queueSize, err := client.JoinQueue(context.Background())
 	if err != nil {
-		fmt.Println("Erro ao enviar requisição de estatísticas:", err)
+		printRequestError(client, err)
 		return
 	}
 
-	// Aguarda resposta síncrona
-	responseData, err := waitForSyncResponse(5 * time.Second)
-	if err != nil {
-		fmt.Println("Erro:", err)
-		return
-	}
+	fmt.Println("✅ Você entrou na fila de partidas!")
+	fmt.Printf("Jogadores na fila: %d\n", queueSize)
+	fmt.Println("🔍 Aguardando por oponentes...")
+	fmt.Println("💡 As notificações de partida aparecerão automaticamente!")
+}
 
-	// Decodifica a resposta
-	message, err := protocol.DecodeMessage(responseData)
-	if err != nil {
-		fmt.Println("Erro ao decodificar resposta:", err)
+func handleRegister(client *topcard.Client, reader *bufio.Reader) {
+	if !checkConnection(client){
 		return
 	}
 
-	// Processa resposta de estatísticas
-	if message.Type == protocol.MSG_STATS_RESPONSE {
-		statsResp, err := protocol.ExtractStatsResponse(message)
-		if err != nil {
-			fmt.Println("Erro ao extrair resposta de estatísticas:", err)
-			return
-		}
+	fmt.Println("\n--- CADASTRO ---")
+	fmt.Print("Insira um nome de usuário (mín. 3 caracteres): ")
+	userName, _ := reader.ReadString('\n')
+	userName = strings.TrimSpace(userName)
 
-		if statsResp.Success {
-			fmt.Printf("\n📊 ===== ESTATÍSTICAS DE %s =====\n", statsResp.UserName)
-			fmt.Printf("🏆 Vitórias: %d\n", statsResp.Wins)
-			fmt.Printf("😔 Derrotas: %d\n", statsResp.Losses)
-			fmt.Printf("🎯 Taxa de vitória: %.1f%%\n", statsResp.WinRate)
-			
-			totalGames := statsResp.Wins + statsResp.Losses
-			fmt.Printf("🎮 Total de partidas: %d\n", totalGames)
-			
-			if totalGames == 0 {
-				fmt.Printf("💡 Dica: Jogue algumas partidas para ver suas estatísticas!\n")
-			}
-			fmt.Printf("========================================\n")
-		} else {
-			fmt.Printf("❌ %s\n", statsResp.Message)
-		}
-	}
-}
+	fmt.Print("Digite sua senha (mín. 4 caracteres): ")
+	password, _ := reader.ReadString('\n')
+	password = strings.TrimSpace(password)
 
-// função de ping
-func handlePing(conn net.Conn) {
-	if !checkConnection(){
+	// Validações básicas no cliente
+	if len(userName) < 3 {
+		fmt.Println("❌ Nome de usuário deve ter pelo menos 3 caracteres!")
 		return
 	}
 
-	if !isLoggedIn {
-		fmt.Println("❌ Você precisa estar logado para verificar o ping!")
-		return
-	}
-	
-	if currentUserID <= 0 {
-		fmt.Println("❌ ID de usuário inválido!")
+	if len(password) < 4 {
+		fmt.Println("❌ Senha deve ter pelo menos 4 caracteres!")
 		return
 	}
 
-	fmt.Println("\n--- CONSULTA DE PING ICMP ---")
-	fmt.Println("🏓 Verificando latência via ICMP...")
-
-	// Obtém endereço do servidor da conexão TCP existente
-	serverAddr := conn.RemoteAddr().(*net.TCPAddr).IP.String()
-	
-	// Realiza ping ICMP
-	latencia, err := realizarPingICMP(serverAddr)
+	userID, err := client.Register(context.Background(), userName, password)
 	if err != nil {
-		fmt.Printf("❌ Erro ao realizar ping ICMP: %v\n", err)
-		fmt.Println("💡 Dica: Execute como administrador/root para usar ICMP")
-		fmt.Println("🔄 Tentando fallback para ping TCP...")
-		
-		// Fallback para o ping TCP original se ICMP falhar
-		realizarPingTCPFallback(conn)
+		printRequestError(client, err)
 		return
 	}
 
-	fmt.Printf("✅ Ping ICMP realizado com sucesso!\n")
-	fmt.Printf("🏓 Latência: %d ms\n", latencia)
-	fmt.Printf("📡 Destino: %s\n", serverAddr)
+	fmt.Println("✅ Cadastro realizado com sucesso!")
+	fmt.Printf("Seu ID de usuário é: %d\n", userID)
+	fmt.Println("Agora você pode fazer login!")
 }
 
-// função para realizar ping ICMP real
-func realizarPingICMP(endereco string) (int64, error) {
-	// Resolve o endereço
-	destino, err := net.ResolveIPAddr("ip4", endereco)
-	if err != nil {
-		return 0, fmt.Errorf("erro ao resolver endereço: %v", err)
+func handleLogin(conn *connection, reader *bufio.Reader) {
+	client := conn.current()
+	if !checkConnection(client){
+		return
 	}
 
-	// Cria conexão ICMP
-	conexao, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0")
-	if err != nil {
-		return 0, fmt.Errorf("erro ao criar socket ICMP: %v", err)
-	}
-	defer conexao.Close()
-
-	// Cria mensagem ICMP Echo Request
-	mensagem := &icmp.Message{
-		Type: ipv4.ICMPTypeEcho,
-		Code: 0,
-		Body: &icmp.Echo{
-			ID:   os.Getpid() & 0xffff,
-			Seq:  1,
-			Data: []byte("TOP CARD PING"),
-		},
-	}
+	fmt.Println("\n--- LOGIN ---")
+	fmt.Print("Insira seu nome de usuário: ")
+	userName, _ := reader.ReadString('\n')
+	userName = strings.TrimSpace(userName)
 
-	dados, err := mensagem.Marshal(nil)
+	fmt.Print("Digite sua senha: ")
+	password, _ := reader.ReadString('\n')
+	password = strings.TrimSpace(password)
+
+	userID, err := client.Login(context.Background(), userName, password)
 	if err != nil {
-		return 0, fmt.Errorf("erro ao serializar mensagem ICMP: %v", err)
+		printRequestError(client, err)
+		return
 	}
 
-	// Registra tempo de início
-	inicio := time.Now()
+	conn.rememberLogin(userName, password)
+	fmt.Println("✅ Login realizado com sucesso!")
+	fmt.Printf("Você está logado com ID: %d\n", userID)
+}
 
-	// Envia ping
-	_, err = conexao.WriteTo(dados, destino)
-	if err != nil {
-		return 0, fmt.Errorf("erro ao enviar ping: %v", err)
+func handleStats(client *topcard.Client) {
+	if !checkConnection(client){
+		return
 	}
 
-	// Define timeout para leitura
-	err = conexao.SetReadDeadline(time.Now().Add(3 * time.Second))
-	if err != nil {
-		return 0, fmt.Errorf("erro ao definir timeout: %v", err)
-	}
+	fmt.Println("\n--- SUAS ESTATÍSTICAS ---")
 
-	// Lê resposta
-	resposta := make([]byte, 1500)
-	n, peer, err := conexao.ReadFrom(resposta)
+	stats, err := client.Stats(context.Background())
 	if err != nil {
-		return 0, fmt.Errorf("erro ao receber resposta: %v", err)
+		printRequestError(client, err)
+		return
 	}
 
-	// Calcula duração
-	duracao := time.Since(inicio)
+	fmt.Printf("\n📊 ===== ESTATÍSTICAS DE %s =====\n", stats.UserName)
+	fmt.Printf("🏆 Vitórias: %d\n", stats.Wins)
+	fmt.Printf("😔 Derrotas: %d\n", stats.Losses)
+	fmt.Printf("🎯 Taxa de vitória: %.1f%%\n", stats.WinRate)
 
-	// Analisa resposta (protocolo IPv4 = 1)
-	const protocoloIPv4 = 1
-	mensagemResposta, err := icmp.ParseMessage(protocoloIPv4, resposta[:n])
-	if err != nil {
-		return 0, fmt.Errorf("erro ao analisar resposta: %v", err)
-	}
+	totalGames := stats.Wins + stats.Losses
+	fmt.Printf("🎮 Total de partidas: %d\n", totalGames)
 
-	// Verifica se é Echo Reply (tipo 0)
-	if mensagemResposta.Type != ipv4.ICMPTypeEchoReply {
-		return 0, fmt.Errorf("tipo de resposta inesperado: %v (esperado: %v)", 
-			mensagemResposta.Type, ipv4.ICMPTypeEchoReply)
+	if totalGames == 0 {
+		fmt.Printf("💡 Dica: Jogue algumas partidas para ver suas estatísticas!\n")
 	}
-
-	fmt.Printf("📡 Resposta de: %v\n", peer)
-	
-	return duracao.Milliseconds(), nil
+	fmt.Printf("========================================\n")
 }
 
-func realizarPingTCPFallback(conn net.Conn) {
-	fmt.Println("\n--- FALLBACK: PING TCP ---")
-	fmt.Println("🏓 Verificando latência via TCP...")
-
-	// Registra o tempo de envio
-	tempoInicio := time.Now()
-
-	// Cria a mensagem de requisição do ping
-	mensagemPing, err := protocol.CreatePingRequest(currentUserID)
-	if err != nil {
-		fmt.Println("Erro ao solicitar o ping:", err)
+// função de ping
+func handlePing(client *topcard.Client) {
+	if !checkConnection(client){
 		return
 	}
 
-	// Adiciona quebra de linha
-	mensagemPing = append(mensagemPing, '\n')
+	fmt.Println("\n--- CONSULTA DE PING UDP ---")
+	echoAddr := udpEchoAddr(client)
+	fmt.Printf("🏓 Enviando %d sondas para o eco UDP em %s...\n", pingProbes, echoAddr)
 
-	// Envia para o servidor
-	_, err = conn.Write(mensagemPing)
+	// Sondas UDP ao eco do servidor: não precisam de root nem de CAP_NET_RAW
+	resultado, err := udpecho.Probe(context.Background(), echoAddr, udpecho.ProbeOptions{Count: pingProbes})
 	if err != nil {
-		fmt.Println("Erro ao enviar requisição de ping:", err)
-		return
-	}
+		fmt.Printf("❌ Erro ao medir latência via UDP: %v\n", err)
+		fmt.Println("💡 Dica: verifique se a porta UDP do servidor está liberada (UDP_ECHO_ADDR)")
+		fmt.Println("🔄 Tentando fallback para ping TCP...")
 
-	// Aguarda resposta síncrona
-	dadosResposta, err := waitForSyncResponse(5 * time.Second)
-	if err != nil {
-		fmt.Println("Erro:", err)
+		// Fallback para o ping pela conexão do jogo se nenhuma sonda voltar
+		realizarPingTCPFallback(client)
 		return
 	}
 
-	// Registra o tempo de recebimento
-	tempoFim := time.Now()
-
-	// Decodifica a resposta
-	mensagem, err := protocol.DecodeMessage(dadosResposta)
-	if err != nil {
-		fmt.Println("Erro ao decodificar resposta:", err)
-		return
-	}
+	fmt.Printf("✅ %d/%d sondas respondidas (%.0f%% de perda)\n", resultado.Received, resultado.Sent, resultado.Loss)
+	fmt.Printf("🏓 RTT mín/méd/máx/desvio: %s/%s/%s/%s\n", formatMs(resultado.Min), formatMs(resultado.Avg), formatMs(resultado.Max), formatMs(resultado.StdDev))
+	fmt.Printf("📶 Jitter: %s\n", formatMs(resultado.Jitter))
+	fmt.Printf("🕒 Diferença de relógio do servidor: %s\n", formatMs(resultado.Offset))
+}
 
-	if mensagem.Type == protocol.MSG_PING_RESPONSE {
-		respostaPing, err := protocol.ExtractPingResponse(mensagem)
-		if err != nil {
-			fmt.Println("Erro ao extrair resposta do ping:", err)
-			return
-		}
+// Sondas enviadas pelo ping do menu
+const pingProbes = 10
 
-		if respostaPing.Success {
-			// Calcula a latência
-			latencia := tempoFim.Sub(tempoInicio).Milliseconds()
-			
-			fmt.Printf("✅ %s\n", respostaPing.Message)
-			fmt.Printf("🏓 Latência TCP (round-trip): %d ms\n", latencia)
-		} else {
-			fmt.Printf("❌ %s\n", respostaPing.Message)
-		}
-	} else {
-		fmt.Printf("⚠️  Tipo de resposta inesperado: %s\n", mensagem.Type)
+// Função para obter o endereço do eco UDP: UDP_ECHO_ADDR ou, por padrão, o mesmo
+// host e porta da conexão TCP do jogo
+func udpEchoAddr(client *topcard.Client) string {
+	if addr := os.Getenv("UDP_ECHO_ADDR"); addr != "" {
+		return addr
 	}
+	return client.RemoteAddr().String()
 }
 
-// Função de reconexão
-func attemptReconnection(serverAddr string, conn *net.Conn) {
-	fmt.Println("\n🔄 =============================")
-	fmt.Println("     TENTANDO RECONECTAR...")
-	fmt.Println("===============================")
-	
-	// Fecha conexão anterior se ainda existe
-	if *conn != nil {
-		(*conn).Close()
-	}
-	
-	newConn, err := net.Dial("tcp", serverAddr)
+// Função para formatar uma duração em milissegundos com três casas
+func formatMs(d time.Duration) string {
+	return fmt.Sprintf("%.3f ms", float64(d)/float64(time.Millisecond))
+}
+
+func realizarPingTCPFallback(client *topcard.Client) {
+	fmt.Println("\n--- FALLBACK: PING TCP ---")
+	fmt.Println("🏓 Verificando latência via TCP...")
+
+	// Mede o tempo de ida e volta de uma requisição pela conexão do jogo
+	latencia, err := client.Ping(context.Background())
 	if err != nil {
-		fmt.Printf("❌ Falha na reconexão: %v\n", err)
-		fmt.Println("💡 Verifique se o servidor está rodando")
+		printRequestError(client, err)
 		return
 	}
 
-	*conn = newConn
-	
-	connectionMutex.Lock()
-	isConnected = true
-	connectionMutex.Unlock()
-	
-	fmt.Println("✅ Reconectado com sucesso!")
-	fmt.Println("📋 IMPORTANTE: Todos os dados foram perdidos")
-	fmt.Println("🔄 Você precisa se REGISTRAR novamente")
-	fmt.Println("===============================")
-	
-	// Reinicia goroutines
-	go messageDistributor(*conn)
-	go asyncMessageProcessor()
+	fmt.Println("✅ Servidor respondeu!")
+	fmt.Printf("🏓 Latência TCP (round-trip): %d ms\n", latencia.Milliseconds())
 }
 
-func updateLocalInventory(cards []protocol.CardInfo) {
-	playerInventory = append(playerInventory, cards...)
-}
-
-// Função para verificar cartas do jogador
-func getCurrentPlayerCards() (int, int, int) {
+// Função para contar as cartas do jogador por tipo
+func countCards(inventory []topcard.Card) (int, int, int) {
 	hydraCount := 0
 	quimeraCount := 0
 	gorgonaCount := 0
-	
-	for _, card := range playerInventory {
+
+	for _, card := range inventory {
 		switch card.Type {
 		case "HYDRA":
 			hydraCount++
@@ -1081,39 +974,11 @@ func getCurrentPlayerCards() (int, int, int) {
 			gorgonaCount++
 		}
 	}
-	
-	return hydraCount, quimeraCount, gorgonaCount
-}
-
-func hasCardType(cardType string) bool {
-	for _, card := range playerInventory {
-		if card.Type == cardType {
-			return true
-		}
-	}
-	return false
-}
-
-// Função para remover carta do inventário local
-func removeCardFromLocal(cardType string) {
-	for i, card := range playerInventory {
-		if card.Type == cardType {
-			playerInventory = append(playerInventory[:i], playerInventory[i+1:]...)
-			break
-		}
-	}
-}
 
-func clearPlayerData() {
-	playerInventory = nil
-	isLoggedIn = false
-	inMatch = false
-	currentMatchID = 0
-	currentUserID = 0
-	isMyTurn = false
+	return hydraCount, quimeraCount, gorgonaCount
 }
 
 // Limpar terminal
 func clearScreen() {
 	fmt.Print("\033[2J\033[H")
-}
\ No newline at end of file
+}
@@ -0,0 +1,242 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"top-card/pkg/topcard"
+)
+
+// Tentativas de refazer o login depois de reconectar enquanto o servidor ainda não
+// liberou a sessão anterior (ALREADY_CONNECTED) ou pede para esperar
+const reloginAttempts = 5
+
+// Onde a conexão mostra os eventos do servidor e o andamento da reconexão: o menu
+// imprime no terminal; a interface de tela cheia guarda tudo nos seus painéis.
+type eventView interface {
+	showEvents(client *topcard.Client) error // Consome Events até a conexão terminar
+	showDisconnected(err error)
+	notice(text string)
+}
+
+// Conexão do terminal com o servidor. Uma única goroutine (supervise) lê os eventos
+// do cliente atual e, quando a conexão cai, reconecta com espera exponencial e refaz
+// o login; o menu só consulta o cliente atual.
+type connection struct {
+	options topcard.Options
+	backoff topcard.Backoff
+	view    eventView
+
+	mutex      sync.Mutex
+	serverAddr string // Trocado ao escolher um servidor da rede local
+	client     *topcard.Client
+	userName   string // Credenciais do último login, mantidas só em memória para refazê-lo
+	password   string
+	stopped    bool
+
+	wake chan struct{} // Reconexão manual: pula a espera atual
+	stop chan struct{}
+	done chan struct{} // Fechado quando supervise termina
+}
+
+// Função para conectar ao servidor e iniciar a supervisão da conexão
+func openConnection(serverAddr string, view eventView) (*connection, error) {
+	options, err := clientOptions()
+	if err != nil {
+		return nil, err
+	}
+	client, err := topcard.Dial(serverAddr, options)
+	if err != nil {
+		return nil, err
+	}
+	view.notice(serverInfo(client))
+
+	conn := &connection{
+		serverAddr: serverAddr,
+		options:    options,
+		backoff:    topcard.DefaultBackoff,
+		view:       view,
+		client:     client,
+		wake:       make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go conn.supervise()
+	return conn, nil
+}
+
+// Cliente atual (pode estar desconectado enquanto a reconexão não termina)
+func (c *connection) current() *topcard.Client {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.client
+}
+
+// Endereço do servidor atual
+func (c *connection) addr() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.serverAddr
+}
+
+// Função para trocar de servidor: a conexão atual é encerrada e a próxima vai para
+// serverAddr. As credenciais guardadas valiam só para o servidor anterior.
+func (c *connection) switchServer(serverAddr string) {
+	c.mutex.Lock()
+	c.serverAddr = serverAddr
+	c.userName, c.password = "", ""
+	c.mutex.Unlock()
+	c.reconnectNow()
+}
+
+// Guarda as credenciais de um login bem-sucedido para refazê-lo ao reconectar
+func (c *connection) rememberLogin(userName, password string) {
+	c.mutex.Lock()
+	c.userName, c.password = userName, password
+	c.mutex.Unlock()
+}
+
+// Nome do último login bem-sucedido (vazio se ainda não houve login)
+func (c *connection) loginName() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.userName
+}
+
+// Função para reconectar agora: encerra a conexão atual (se ainda ativa) e pula a
+// espera da próxima tentativa
+func (c *connection) reconnectNow() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+	c.current().Close()
+}
+
+// Função para encerrar a conexão sem reconectar
+func (c *connection) close() {
+	c.mutex.Lock()
+	if !c.stopped {
+		c.stopped = true
+		close(c.stop)
+	}
+	client := c.client
+	c.mutex.Unlock()
+
+	client.Close()
+	<-c.done
+}
+
+// Goroutine dona da conexão: exibe os eventos do cliente atual e reconecta quando
+// ele termina, até close
+func (c *connection) supervise() {
+	defer close(c.done)
+
+	for {
+		err := c.view.showEvents(c.current())
+
+		c.mutex.Lock()
+		stopped := c.stopped
+		c.mutex.Unlock()
+		if stopped {
+			return
+		}
+		if err != nil {
+			c.view.showDisconnected(err)
+		}
+
+		client := c.reconnect()
+		if client == nil {
+			return
+		}
+		c.mutex.Lock()
+		c.client = client
+		c.mutex.Unlock()
+	}
+}
+
+// Reconecta com espera exponencial (reiniciada a cada reconexão manual) e refaz o
+// login. Retorna nil se a conexão for encerrada antes.
+func (c *connection) reconnect() *topcard.Client {
+	for {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			select {
+			case <-c.wake:
+				c.view.notice("🔄 Reconectando agora...")
+			case <-c.stop:
+			case <-ctx.Done():
+			}
+			cancel()
+		}()
+
+		client, err := topcard.Redial(ctx, c.addr(), c.options, c.backoff, func(attempt int, err error, delay time.Duration) {
+			c.view.notice(fmt.Sprintf("🔴 Tentativa %d de reconexão falhou: %v", attempt+1, err))
+			c.view.notice(fmt.Sprintf("🔄 Nova tentativa em %.1fs (ou reconecte agora)", delay.Seconds()))
+		})
+		cancel()
+
+		select {
+		case <-c.stop:
+			if client != nil {
+				client.Close()
+			}
+			return nil
+		default:
+		}
+		if err != nil {
+			continue // Reconexão manual: recomeça com a espera inicial
+		}
+
+		// Um pedido de reconexão feito durante as tentativas já foi atendido
+		select {
+		case <-c.wake:
+		default:
+		}
+
+		c.view.notice("✅ Reconectado ao servidor TOP CARD!")
+		c.view.notice(serverInfo(client))
+		c.relogin(client)
+		return client
+	}
+}
+
+// Refaz o login com as credenciais guardadas, se houver
+func (c *connection) relogin(client *topcard.Client) {
+	c.mutex.Lock()
+	userName, password := c.userName, c.password
+	c.mutex.Unlock()
+	if userName == "" {
+		c.view.notice("💡 Faça login para continuar jogando")
+		return
+	}
+
+	for attempt := 0; ; attempt++ {
+		userID, err := client.Login(context.Background(), userName, password)
+		if err == nil {
+			c.view.notice(fmt.Sprintf("✅ Login refeito automaticamente como %s (ID: %d)", userName, userID))
+			return
+		}
+
+		// O servidor pode ainda não ter notado a queda da conexão anterior
+		var serverErr *topcard.Error
+		retry := errors.As(err, &serverErr) && attempt+1 < reloginAttempts &&
+			(serverErr.Code == topcard.ERR_ALREADY_CONNECTED || serverErr.Code == topcard.ERR_LOGIN_BACKOFF || serverErr.Code == topcard.ERR_RATE_LIMITED)
+		if !retry {
+			c.view.notice(fmt.Sprintf("⚠️ Não foi possível refazer o login: %v", err))
+			c.view.notice("🔄 Faça login ou cadastre-se novamente")
+			if code := topcard.ErrorCode(err); code == topcard.ERR_INVALID_CREDENTIALS || code == topcard.ERR_USER_NOT_FOUND {
+				c.rememberLogin("", "") // Servidor sem os dados antigos: não adianta tentar de novo
+			}
+			return
+		}
+
+		select {
+		case <-time.After(max(serverErr.RetryAfter, c.backoff.Delay(attempt))):
+		case <-client.Done():
+			return
+		}
+	}
+}
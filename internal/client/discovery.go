@@ -0,0 +1,80 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"top-card/internal/discovery"
+	"top-card/internal/tlsconfig"
+)
+
+// Tempo ouvindo anúncios: um pouco mais que o intervalo entre anúncios do servidor
+const discoveryWait = discovery.DEFAULT_INTERVAL + time.Second
+
+// Função para procurar servidores na rede local no grupo de DISCOVERY_GROUP (padrão:
+// discovery.DEFAULT_GROUP)
+func findServers() ([]discovery.Announcement, error) {
+	group := os.Getenv("DISCOVERY_GROUP")
+	if group == "" {
+		group = discovery.DEFAULT_GROUP
+	}
+	return discovery.Discover(context.Background(), group, discoveryWait)
+}
+
+// Descreve um servidor encontrado em uma linha
+func describeServer(server discovery.Announcement) string {
+	description := fmt.Sprintf("%s (%s) - %d jogador(es) online, versão %s", server.Name, server.Addr, server.Players, server.Version)
+	if server.TLS {
+		description += " 🔐 TLS"
+	}
+	return description
+}
+
+// Função para procurar servidores e perguntar em qual conectar. Retorna o endereço
+// escolhido (vazio se nenhum foi encontrado ou o jogador cancelou).
+func chooseServer(reader *bufio.Reader) string {
+	fmt.Printf("🔎 Procurando servidores na rede local (%.0fs)...\n", discoveryWait.Seconds())
+	servers, err := findServers()
+	if err != nil {
+		fmt.Println("❌ Erro ao procurar servidores:", err)
+		return ""
+	}
+	if len(servers) == 0 {
+		fmt.Println("Nenhum servidor encontrado. Verifique se o servidor está na mesma rede (ou use SERVER_ADDR).")
+		return ""
+	}
+
+	for i, server := range servers {
+		fmt.Printf("%d - %s\n", i+1, describeServer(server))
+	}
+	fmt.Print("Escolha o servidor (Enter para cancelar): ")
+	input, _ := reader.ReadString('\n')
+	choice, err := strconv.Atoi(strings.TrimSpace(input))
+	if err != nil || choice < 1 || choice > len(servers) {
+		return ""
+	}
+	if tlsConfig, _ := tlsconfig.ClientFromEnv(); servers[choice-1].TLS && tlsConfig == nil {
+		fmt.Println("⚠️ O servidor usa TLS: configure TLS, TLS_CA_FILE ou TLS_PIN no cliente para conectar")
+	}
+	return servers[choice-1].Addr
+}
+
+// Opção do menu: troca a conexão para um servidor encontrado na rede local
+func handleDiscovery(conn *connection, reader *bufio.Reader) {
+	fmt.Println("\n--- SERVIDORES NA REDE LOCAL ---")
+	fmt.Printf("📡 Servidor atual: %s\n", conn.addr())
+
+	addr := chooseServer(reader)
+	if addr == "" {
+		return
+	}
+	if conn.current().UserID() != 0 {
+		fmt.Println("💡 A sessão atual será encerrada; faça login no novo servidor")
+	}
+	fmt.Printf("🔄 Conectando a %s...\n", addr)
+	conn.switchServer(addr)
+}
@@ -0,0 +1,749 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"top-card/internal/card"
+	"top-card/internal/protocol"
+	"top-card/pkg/topcard"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limites da interface de tela cheia
+const (
+	logLimit     = 200             // Linhas guardadas no registro de eventos
+	chatLimit    = 100             // Mensagens guardadas no chat
+	pingInterval = 5 * time.Second // Intervalo entre as medições de ping
+	minWidth     = 60              // Abaixo disso a tela pede um terminal maior
+	minHeight    = 18
+)
+
+// Cartas na ordem das teclas 1, 2 e 3, com o que cada uma vence
+var playableCards = []struct{ Type, Beats string }{
+	{"HYDRA", "devora QUIMERA"},
+	{"QUIMERA", "destrói GORGONA"},
+	{"GORGONA", "petrifica HYDRA"},
+}
+
+// Atalhos mostrados na última linha da tela
+const shortcutHelp = "l login  r cadastro  p pacote  f buscar partida  1-3 jogar  c chat  e estatísticas  s servidores  x reconectar  q sair"
+
+// Verifica se o terminal permite a tela cheia: entrada e saída num terminal
+// (interactive) que entende sequências ANSI, segundo TERM lido com getenv.
+// CLIENT_UI=menu força o menu numerado.
+func FullScreenSupported(getenv func(string) string, interactive bool) bool {
+	if getenv("CLIENT_UI") == "menu" {
+		return false
+	}
+	switch getenv("TERM") {
+	case "", "dumb":
+		return false
+	}
+	return interactive
+}
+
+// Terminal da interface de tela cheia. Campos vazios usam o terminal do processo
+// (teclado em modo raw, saída padrão e tamanho da janela); os testes passam um
+// leitor de teclas, um buffer e um tamanho fixo.
+type ScreenOptions struct {
+	Keys   io.Reader         // Bytes das teclas, como chegam em modo raw
+	Output io.Writer         // Onde os quadros são escritos
+	Size   func() (int, int) // Colunas e linhas da tela
+}
+
+// Campo de texto na linha de entrada (login, tipo de pacote, chat)
+type prompt struct {
+	label  string
+	text   []rune
+	secret bool // Mostra asteriscos (senha)
+	submit func(text string)
+}
+
+// Situação da partida mostrada no painel do tabuleiro
+type boardState struct {
+	message string // Última mensagem do servidor sobre a partida
+	played  string // Carta jogada por você na partida atual
+	result  string // Resultado da última partida
+}
+
+// Interface de tela cheia. Os eventos da conexão e as respostas das requisições só
+// alteram o estado (protegido por mutex) e pedem um redesenho; a tela é desenhada
+// apenas pelo laço principal, que também lê o teclado.
+type fullScreen struct {
+	conn *connection
+	out  io.Writer
+	size func() (int, int)
+
+	mutex  sync.Mutex
+	log    []string
+	chat   []string
+	board  boardState
+	ping   time.Duration // Zero = sem medição
+	coins  int           // Saldo recebido no último login
+	prompt *prompt
+
+	redraw chan struct{}
+}
+
+// Função para rodar a interface de tela cheia até o jogador sair ou as teclas
+// acabarem. Retorna erro só se não conseguir conectar.
+func RunFullScreen(serverAddr string, options ScreenOptions) error {
+	screen := &fullScreen{out: options.Output, size: options.Size, redraw: make(chan struct{}, 1)}
+	if screen.out == nil {
+		screen.out = os.Stdout
+	}
+	if screen.size == nil {
+		screen.size = func() (int, int) {
+			width, height, err := terminalSize(os.Stdout)
+			if err != nil || width <= 0 || height <= 0 {
+				return 80, 24
+			}
+			return width, height
+		}
+	}
+
+	fmt.Fprintln(screen.out, "Conectando ao servidor TOP CARD...")
+	conn, err := openConnection(serverAddr, screen)
+	if err != nil {
+		return err
+	}
+	defer conn.close()
+	screen.conn = conn
+
+	// Sem leitor de teclas, o teclado do processo em modo raw, avisando quando a
+	// janela muda de tamanho
+	input := options.Keys
+	resized := make(chan os.Signal, 1)
+	if input == nil {
+		term, err := makeRaw(os.Stdin)
+		if err != nil {
+			fmt.Println("Terminal sem modo raw, usando o menu:", err)
+			conn.close()
+			runMenu(serverAddr)
+			return nil
+		}
+		defer term.restore()
+		input = os.Stdin
+
+		notifyResize(resized)
+		defer signal.Stop(resized)
+	}
+
+	// Tela alternativa e cursor escondido; o terminal volta ao normal ao sair
+	io.WriteString(screen.out, "\x1b[?1049h\x1b[?25l")
+	defer io.WriteString(screen.out, "\x1b[?25h\x1b[?1049l")
+
+	keys := make(chan []byte)
+	go readKeys(input, keys)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go screen.measurePing(stop)
+
+	screen.logf("Bem vindo ao TOP CARD! Use as teclas abaixo; q sai.")
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+	for {
+		screen.draw()
+		select {
+		case data, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			for _, key := range parseKeys(data) {
+				if !screen.handleKey(key) {
+					return nil
+				}
+			}
+		case <-resized:
+		case <-screen.redraw:
+		case <-tick.C:
+		}
+	}
+}
+
+// Lê o teclado em blocos até a entrada terminar
+func readKeys(input io.Reader, keys chan<- []byte) {
+	defer close(keys)
+	buffer := make([]byte, 256)
+	for {
+		n, err := input.Read(buffer)
+		if n > 0 {
+			keys <- slices.Clone(buffer[:n])
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Converte os bytes lidos em modo raw em teclas. Sequências de escape (setas,
+// teclas de função) são descartadas; Esc sozinho vira o rune 27.
+func parseKeys(data []byte) []rune {
+	var keys []rune
+	for len(data) > 0 {
+		if data[0] == 0x1b && len(data) > 1 && (data[1] == '[' || data[1] == 'O') {
+			i := 2
+			for i < len(data) && (data[i] < 0x40 || data[i] > 0x7e) {
+				i++
+			}
+			data = data[min(i+1, len(data)):]
+			continue
+		}
+		r, size := utf8.DecodeRune(data)
+		data = data[size:]
+		if r != utf8.RuneError {
+			keys = append(keys, r)
+		}
+	}
+	return keys
+}
+
+// Pede um redesenho sem bloquear
+func (s *fullScreen) requestRedraw() {
+	select {
+	case s.redraw <- struct{}{}:
+	default:
+	}
+}
+
+// Acrescenta uma linha com horário ao registro de eventos
+func (s *fullScreen) logf(format string, args ...any) {
+	s.mutex.Lock()
+	s.log = append(s.log, time.Now().Format("15:04:05 ")+fmt.Sprintf(format, args...))
+	if len(s.log) > logLimit {
+		s.log = slices.Delete(s.log, 0, len(s.log)-logLimit)
+	}
+	s.mutex.Unlock()
+	s.requestRedraw()
+}
+
+// Acrescenta uma mensagem ao painel do chat
+func (s *fullScreen) addChat(from, text string) {
+	s.mutex.Lock()
+	s.chat = append(s.chat, time.Now().Format("15:04 ")+from+": "+text)
+	if len(s.chat) > chatLimit {
+		s.chat = slices.Delete(s.chat, 0, len(s.chat)-chatLimit)
+	}
+	s.mutex.Unlock()
+	s.requestRedraw()
+}
+
+// Avisos da conexão vão para o registro de eventos
+func (s *fullScreen) notice(text string) {
+	s.logf("%s", text)
+}
+
+// Registra a queda da conexão
+func (s *fullScreen) showDisconnected(err error) {
+	s.logf("🔴 Conexão perdida: %v", err)
+	s.logf("🔄 Reconectando automaticamente; x tenta agora")
+}
+
+// Atualiza os painéis com as notificações do servidor até a conexão terminar
+func (s *fullScreen) showEvents(client *topcard.Client) error {
+	s.mutex.Lock()
+	s.ping = 0
+	s.mutex.Unlock()
+	s.requestRedraw()
+
+	var disconnectErr error
+	for event := range client.Events() {
+		switch event := event.(type) {
+		case topcard.MatchFound:
+			s.mutex.Lock()
+			s.board = boardState{message: event.Message}
+			s.chat = nil
+			s.mutex.Unlock()
+			s.logf("🎯 Partida %d encontrada contra %s", event.MatchID, event.OpponentName)
+		case topcard.MatchStart:
+			s.setBoardMessage(event.Message)
+			s.logf("🚀 Partida %d iniciada", event.MatchID)
+		case topcard.GameState:
+			s.setBoardMessage(event.Message)
+			if event.YourTurn && !event.GameOver {
+				s.logf("🎯 Seu turno! Jogue com 1, 2 ou 3")
+			}
+		case topcard.TurnUpdate:
+			s.setBoardMessage(event.Message)
+			if event.YourTurn {
+				s.logf("🎯 Seu turno! Jogue com 1, 2 ou 3")
+			}
+		case topcard.MatchEnd:
+			var result string
+			switch {
+			case event.WinnerID == 0:
+				result = "Partida interrompida, sem vencedor"
+			case event.Won:
+				result = "VITÓRIA! Você ganhou"
+			default:
+				result = "DERROTA - vencedor: " + event.WinnerName
+			}
+			s.mutex.Lock()
+			s.board = boardState{message: event.Message, result: result}
+			s.mutex.Unlock()
+			s.logf("🏆 Partida %d finalizada: %s", event.MatchID, result)
+		case topcard.MoveRejected:
+			s.mutex.Lock()
+			s.board.played = ""
+			s.mutex.Unlock()
+			s.logf("❌ Jogada recusada [%s]: %s", event.Err.Code, event.Err.Message)
+		case topcard.SessionState:
+			// O login (inclusive o refeito depois de uma queda) remonta os painéis
+			board := boardState{message: "Sessão restaurada"}
+			if event.Match != nil {
+				board.played = event.Match.PlayedCard
+			}
+			s.mutex.Lock()
+			s.board = board
+			s.coins = event.Coins
+			if event.Match == nil {
+				s.chat = nil
+			}
+			s.mutex.Unlock()
+			if event.Match != nil {
+				s.logf("🎮 Partida %d em andamento contra %s", event.Match.MatchID, event.Match.OpponentName)
+			}
+			if event.InQueue {
+				s.logf("🔍 Na fila de partidas (%d jogadores)", event.QueueSize)
+			}
+			s.requestRedraw()
+		case topcard.InventoryUpdate:
+			if event.Reason == topcard.INVENTORY_CARDS_RETURNED {
+				s.logf("🎒 Cartas jogadas devolvidas ao inventário")
+			}
+			s.requestRedraw() // O painel do inventário lê client.Inventory()
+		case topcard.ChatMessage:
+			s.addChat(event.FromName, event.Text)
+		case topcard.ServerShutdown:
+			if event.Deadline.IsZero() {
+				s.logf("🛑 %s", event.Message)
+			} else {
+				s.logf("🛑 %s (prazo: %s)", event.Message, event.Deadline.Local().Format("15:04:05"))
+			}
+		case topcard.ServerError:
+			s.logf("🔴 Erro do servidor [%s]: %s", event.Err.Code, event.Err.Message)
+		case topcard.Disconnected:
+			disconnectErr = event.Err
+		}
+	}
+	return disconnectErr
+}
+
+func (s *fullScreen) setBoardMessage(message string) {
+	s.mutex.Lock()
+	s.board.message = message
+	s.mutex.Unlock()
+	s.requestRedraw()
+}
+
+// Mede o ping pela conexão do jogo enquanto houver login, até stop
+func (s *fullScreen) measurePing(stop <-chan struct{}) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		client := s.conn.current()
+		var rtt time.Duration
+		if client.UserID() != 0 {
+			rtt, _ = client.Ping(context.Background())
+		}
+		s.mutex.Lock()
+		s.ping = rtt
+		s.mutex.Unlock()
+		s.requestRedraw()
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Abre um campo de texto na linha de entrada
+func (s *fullScreen) ask(label string, secret bool, submit func(text string)) {
+	s.mutex.Lock()
+	s.prompt = &prompt{label: label, secret: secret, submit: submit}
+	s.mutex.Unlock()
+}
+
+// Executa uma requisição fora do laço da tela, para a interface continuar respondendo
+func (s *fullScreen) background(action func(client *topcard.Client)) {
+	client := s.conn.current()
+	go func() {
+		action(client)
+		s.requestRedraw()
+	}()
+}
+
+// Trata uma tecla. Retorna false quando o jogador pede para sair.
+func (s *fullScreen) handleKey(key rune) bool {
+	s.mutex.Lock()
+	current := s.prompt
+	s.mutex.Unlock()
+	if current != nil {
+		s.editPrompt(current, key)
+		return true
+	}
+
+	client := s.conn.current()
+	loggedIn := client.UserID() != 0
+	match := client.Match()
+
+	switch unicode.ToLower(key) {
+	case 'q', 3: // q ou Ctrl-C
+		return false
+	case 'l':
+		if loggedIn {
+			s.logf("Você já está logado!")
+			return true
+		}
+		s.askCredentials(s.login)
+	case 'r':
+		if loggedIn {
+			s.logf("Você já está logado!")
+			return true
+		}
+		s.askCredentials(s.register)
+	case 'p':
+		if !loggedIn {
+			s.logf("Você precisa estar logado para abrir os pacotes de cartas!")
+			return true
+		}
+		if len(client.Inventory()) > 0 {
+			s.logf("❌ Use suas cartas em partidas antes de abrir novos pacotes")
+			return true
+		}
+		var names []string
+		for _, packType := range card.ListPackTypes() {
+			names = append(names, packType.Name)
+		}
+		s.ask(fmt.Sprintf("Pacote (%s; Enter = %s)", strings.Join(names, ", "), card.PACK_STANDARD), false, s.openPack)
+	case 'f':
+		if !loggedIn {
+			s.logf("Você precisa estar logado para buscar partida!")
+			return true
+		}
+		if match.InMatch {
+			s.logf("Você já está em uma partida!")
+			return true
+		}
+		s.background(s.joinQueue)
+	case '1', '2', '3':
+		s.playCard(client, playableCards[key-'1'].Type)
+	case 'c':
+		if !match.InMatch {
+			s.logf("O chat só funciona durante uma partida")
+			return true
+		}
+		s.ask("Chat", false, s.sendChat)
+	case 'e':
+		if !loggedIn {
+			s.logf("Você precisa estar logado para ver suas estatísticas!")
+			return true
+		}
+		s.background(s.showStats)
+	case 's':
+		go s.findServers()
+	case 'x':
+		s.logf("🔄 Reconectando...")
+		go s.conn.reconnectNow()
+	}
+	return true
+}
+
+// Edita o campo de texto aberto: Enter confirma, Esc ou Ctrl-C cancela
+func (s *fullScreen) editPrompt(current *prompt, key rune) {
+	switch key {
+	case '\r', '\n':
+		s.mutex.Lock()
+		s.prompt = nil
+		s.mutex.Unlock()
+		current.submit(strings.TrimSpace(string(current.text)))
+		return
+	case 27, 3:
+		s.mutex.Lock()
+		s.prompt = nil
+		s.mutex.Unlock()
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	switch {
+	case key == 127 || key == 8: // Backspace
+		if len(current.text) > 0 {
+			current.text = current.text[:len(current.text)-1]
+		}
+	case unicode.IsPrint(key) && len(current.text) < protocol.MAX_CHAT_LENGTH:
+		current.text = append(current.text, key)
+	}
+}
+
+// Pede usuário e senha e passa os dois para action
+func (s *fullScreen) askCredentials(action func(userName, password string)) {
+	s.ask("Usuário", false, func(userName string) {
+		if userName == "" {
+			return
+		}
+		s.ask("Senha", true, func(password string) {
+			action(userName, password)
+		})
+	})
+}
+
+// Procura servidores na rede local e pergunta para qual trocar a conexão
+func (s *fullScreen) findServers() {
+	s.logf("🔎 Procurando servidores na rede local (%.0fs)...", discoveryWait.Seconds())
+	servers, err := findServers()
+	if err != nil {
+		s.logf("❌ Erro ao procurar servidores: %v", err)
+		return
+	}
+	if len(servers) == 0 {
+		s.logf("Nenhum servidor encontrado na rede local")
+		return
+	}
+	for i, server := range servers {
+		s.logf("%d - %s", i+1, describeServer(server))
+	}
+	s.ask(fmt.Sprintf("Servidor (1-%d; Esc cancela)", len(servers)), false, func(text string) {
+		choice, err := strconv.Atoi(text)
+		if err != nil || choice < 1 || choice > len(servers) {
+			return
+		}
+		s.logf("🔄 Conectando a %s...", servers[choice-1].Addr)
+		go s.conn.switchServer(servers[choice-1].Addr)
+	})
+	s.requestRedraw()
+}
+
+func (s *fullScreen) login(userName, password string) {
+	s.background(func(client *topcard.Client) {
+		userID, err := client.Login(context.Background(), userName, password)
+		if err != nil {
+			s.logf("%s", requestErrorText(client, err))
+			return
+		}
+		s.conn.rememberLogin(userName, password)
+		s.logf("✅ Login realizado como %s (ID: %d)", userName, userID)
+	})
+}
+
+func (s *fullScreen) register(userName, password string) {
+	s.background(func(client *topcard.Client) {
+		userID, err := client.Register(context.Background(), userName, password)
+		if err != nil {
+			s.logf("%s", requestErrorText(client, err))
+			return
+		}
+		s.logf("✅ Cadastro realizado (ID: %d). Agora faça login com l", userID)
+	})
+}
+
+func (s *fullScreen) openPack(packType string) {
+	if packType == "" {
+		packType = card.PACK_STANDARD
+	}
+	s.background(func(client *topcard.Client) {
+		pack, err := client.OpenPackWithSeed(context.Background(), packType, newClientSeed())
+		if err != nil {
+			s.logf("%s", requestErrorText(client, err))
+			return
+		}
+		if pack.CommittedHash != "" && pack.CommittedHash != pack.Fairness.ServerSeedHash {
+			s.logf("⚠️ Sorteio com semente do servidor diferente da publicada antes do pacote!")
+		}
+		var cards []string
+		for _, opened := range pack.Cards {
+			cards = append(cards, fmt.Sprintf("%s (%s)", opened.Type, opened.Rarity))
+		}
+		s.logf("🎒 Pacote %s: %s", pack.Type, strings.Join(cards, ", "))
+		if pack.Pity.Triggered {
+			s.logf("✨ Pity ativado! Este pacote garantiu uma carta épica")
+		}
+	})
+}
+
+func (s *fullScreen) joinQueue(client *topcard.Client) {
+	queueSize, err := client.JoinQueue(context.Background())
+	if err != nil {
+		s.logf("%s", requestErrorText(client, err))
+		return
+	}
+	s.setBoardMessage("Procurando oponente...")
+	s.logf("🔍 Na fila de partidas (%d jogador(es))", queueSize)
+}
+
+func (s *fullScreen) playCard(client *topcard.Client, cardType string) {
+	match := client.Match()
+	switch {
+	case !match.InMatch:
+		s.logf("Você precisa estar em uma partida para jogar!")
+		return
+	case !match.YourTurn:
+		s.logf("❌ Não é seu turno! Aguarde o oponente jogar")
+		return
+	case !slices.ContainsFunc(client.Inventory(), func(owned topcard.Card) bool { return owned.Type == cardType }):
+		s.logf("❌ Você não tem nenhuma %s", cardType)
+		return
+	}
+
+	if err := client.PlayCard(context.Background(), cardType); err != nil {
+		s.logf("%s", requestErrorText(client, err))
+		return
+	}
+	s.mutex.Lock()
+	s.board.played = cardType
+	s.mutex.Unlock()
+	s.logf("✅ Carta jogada: %s", cardType)
+}
+
+func (s *fullScreen) sendChat(text string) {
+	if text == "" {
+		return
+	}
+	client := s.conn.current()
+	if err := client.SendChat(context.Background(), text); err != nil {
+		s.logf("%s", requestErrorText(client, err))
+		return
+	}
+	s.addChat("você", text)
+}
+
+func (s *fullScreen) showStats(client *topcard.Client) {
+	stats, err := client.Stats(context.Background())
+	if err != nil {
+		s.logf("%s", requestErrorText(client, err))
+		return
+	}
+	s.logf("📊 %s: %d vitória(s), %d derrota(s), taxa de vitória %.1f%%", stats.UserName, stats.Wins, stats.Losses, stats.WinRate)
+}
+
+// Desenha a tela inteira com o estado atual
+func (s *fullScreen) draw() {
+	width, height := s.size()
+	client := s.conn.current()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var f frame
+	f.WriteString("\x1b[H\x1b[2J")
+	if width < minWidth || height < minHeight {
+		f.put(1, 1, fmt.Sprintf("Aumente o terminal para pelo menos %dx%d", minWidth, minHeight), width)
+		io.WriteString(s.out, f.String())
+		return
+	}
+
+	// Status na primeira linha, em vídeo reverso
+	status := truncate(" "+s.statusLine(client), width)
+	fmt.Fprintf(&f, "\x1b[1;1H\x1b[7m%s%s\x1b[0m", status, strings.Repeat(" ", max(width-textWidth(status), 0)))
+
+	body := height - 3 // Status, entrada e atalhos
+	upper := max(body*11/20, 8)
+	lower := body - upper
+	left := max(width/3, 28)
+	logWidth := width * 3 / 5
+
+	f.box(1, 2, left, upper, "Inventário", s.inventoryLines(client), false)
+	f.box(left+1, 2, width-left, upper, "Partida", s.boardLines(client), false)
+	f.box(1, 2+upper, logWidth, lower, "Eventos", s.log, true)
+	f.box(logWidth+1, 2+upper, width-logWidth, lower, "Chat", s.chat, true)
+
+	// Linha de entrada: campo de texto aberto ou dica
+	if s.prompt != nil {
+		text := string(s.prompt.text)
+		if s.prompt.secret {
+			text = strings.Repeat("*", len(s.prompt.text))
+		}
+		f.put(1, height-1, "> "+s.prompt.label+": "+lastColumns(text, width-textWidth(s.prompt.label)-6)+"▏", width)
+	} else {
+		f.put(1, height-1, "Pressione uma tecla de atalho", width)
+	}
+	fmt.Fprintf(&f, "\x1b[%d;1H\x1b[2m%s\x1b[0m", height, truncate(shortcutHelp, width))
+
+	io.WriteString(s.out, f.String())
+}
+
+// Conexão, ping e usuário
+func (s *fullScreen) statusLine(client *topcard.Client) string {
+	parts := []string{"TOP CARD"}
+	if client.Err() != nil {
+		parts = append(parts, "● reconectando a "+s.conn.addr())
+	} else {
+		parts = append(parts, fmt.Sprintf("● conectado a %s (protocolo %d)", s.conn.addr(), client.Server().ProtocolVersion))
+	}
+	if s.ping > 0 {
+		parts = append(parts, fmt.Sprintf("ping %d ms", s.ping.Milliseconds()))
+	} else {
+		parts = append(parts, "ping -")
+	}
+	if userID := client.UserID(); userID != 0 {
+		parts = append(parts, fmt.Sprintf("%s (ID %d) 💰 %d", s.conn.loginName(), userID, s.coins))
+	} else {
+		parts = append(parts, "sem login")
+	}
+	return strings.Join(parts, " │ ")
+}
+
+// Cartas por tipo, com a tecla de cada uma, e a lista completa
+func (s *fullScreen) inventoryLines(client *topcard.Client) []string {
+	if client.UserID() == 0 {
+		return []string{"Faça login (l) ou cadastre-se (r)"}
+	}
+	inventory := client.Inventory()
+	if len(inventory) == 0 {
+		return []string{"Sem cartas: abra um pacote (p)"}
+	}
+
+	hydra, quimera, gorgona := countCards(inventory)
+	counts := []int{hydra, quimera, gorgona}
+	var lines []string
+	for i, playable := range playableCards {
+		lines = append(lines, fmt.Sprintf("[%d] %-8s x%d  %s", i+1, playable.Type, counts[i], playable.Beats))
+	}
+	lines = append(lines, "")
+	for _, owned := range inventory {
+		lines = append(lines, fmt.Sprintf("%s (%s)", owned.Type, owned.Rarity))
+	}
+	return lines
+}
+
+// Partida atual ou resultado da última
+func (s *fullScreen) boardLines(client *topcard.Client) []string {
+	match := client.Match()
+	if !match.InMatch {
+		lines := []string{"Nenhuma partida em andamento", "Busque uma partida com f"}
+		if s.board.result != "" {
+			lines = append(lines, "", "Última partida: "+s.board.result)
+		}
+		if s.board.message != "" {
+			lines = append(lines, "", s.board.message)
+		}
+		return lines
+	}
+
+	lines := []string{fmt.Sprintf("Partida #%d contra %s", match.MatchID, match.OpponentName), ""}
+	if match.YourTurn {
+		lines = append(lines, "► SEU TURNO: jogue com 1, 2 ou 3")
+	} else {
+		lines = append(lines, "Aguardando o oponente...")
+	}
+	if s.board.played != "" {
+		lines = append(lines, "Sua carta: "+s.board.played)
+	}
+	if s.board.message != "" {
+		lines = append(lines, "", s.board.message)
+	}
+	return lines
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Faixas de caracteres que ocupam duas colunas no terminal (CJK e emojis)
+var wideRunes = []struct{ lo, hi rune }{
+	{0x1100, 0x115F}, {0x231A, 0x231B}, {0x23E9, 0x23EC}, {0x23F0, 0x23F0}, {0x23F3, 0x23F3},
+	{0x25FD, 0x25FE}, {0x2614, 0x2615}, {0x2648, 0x2653}, {0x267F, 0x267F}, {0x2693, 0x2693},
+	{0x26A1, 0x26A1}, {0x26AA, 0x26AB}, {0x26BD, 0x26BE}, {0x26C4, 0x26C5}, {0x26CE, 0x26CE},
+	{0x26D4, 0x26D4}, {0x26EA, 0x26EA}, {0x26F2, 0x26F3}, {0x26F5, 0x26F5}, {0x26FA, 0x26FA},
+	{0x26FD, 0x26FD}, {0x2705, 0x2705}, {0x270A, 0x270B}, {0x2728, 0x2728}, {0x274C, 0x274C},
+	{0x274E, 0x274E}, {0x2753, 0x2755}, {0x2757, 0x2757}, {0x2795, 0x2797}, {0x27B0, 0x27B0},
+	{0x27BF, 0x27BF}, {0x2E80, 0x303E}, {0x3041, 0xA4CF}, {0xAC00, 0xD7A3}, {0xF900, 0xFAFF},
+	{0xFE30, 0xFE4F}, {0xFF00, 0xFF60}, {0xFFE0, 0xFFE6}, {0x1F000, 0x1FAFF}, {0x20000, 0x3FFFD},
+}
+
+// Colunas ocupadas por um caractere: 0 para marcas combinantes e seletores de
+// variação, 2 para caracteres largos, 1 para o resto
+func runeWidth(r rune) int {
+	if r == 0x200D || (r >= 0xFE00 && r <= 0xFE0F) || unicode.Is(unicode.Mn, r) {
+		return 0
+	}
+	for _, wide := range wideRunes {
+		if r >= wide.lo && r <= wide.hi {
+			return 2
+		}
+	}
+	return 1
+}
+
+// Colunas ocupadas por um texto
+func textWidth(text string) int {
+	width := 0
+	for _, r := range text {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// Corta o texto para caber em width colunas
+func truncate(text string, width int) string {
+	used := 0
+	for i, r := range text {
+		used += runeWidth(r)
+		if used > width {
+			return text[:i]
+		}
+	}
+	return text
+}
+
+// Últimas colunas do texto que cabem em width (o fim de um campo sendo digitado)
+func lastColumns(text string, width int) string {
+	for textWidth(text) > max(width, 0) {
+		_, size := utf8.DecodeRuneInString(text)
+		text = text[size:]
+	}
+	return text
+}
+
+// Quebra o texto em linhas de no máximo width colunas
+func wrap(text string, width int) []string {
+	if width <= 0 {
+		return nil
+	}
+	var lines []string
+	for text != "" {
+		line := truncate(text, width)
+		if line == "" { // Caractere mais largo que a linha inteira
+			_, size := utf8.DecodeRuneInString(text)
+			line = text[:size]
+		}
+		lines = append(lines, line)
+		text = text[len(line):]
+	}
+	if lines == nil {
+		lines = []string{""}
+	}
+	return lines
+}
+
+// Quadro de uma tela inteira, montado em memória e escrito de uma vez para não piscar
+type frame struct {
+	strings.Builder
+}
+
+// Escreve o texto na linha y, coluna x (a partir de 1), cortado em width colunas
+func (f *frame) put(x, y int, text string, width int) {
+	fmt.Fprintf(f, "\x1b[%d;%dH%s", y, x, truncate(text, width))
+}
+
+// Desenha um painel com borda e título. Com fromBottom mostra as últimas linhas
+// (registros), senão as primeiras.
+func (f *frame) box(x, y, width, height int, title string, lines []string, fromBottom bool) {
+	inner := width - 2
+	var wrapped []string
+	for _, line := range lines {
+		wrapped = append(wrapped, wrap(line, inner-1)...)
+	}
+	rows := height - 2
+	if len(wrapped) > rows {
+		if fromBottom {
+			wrapped = wrapped[len(wrapped)-rows:]
+		} else {
+			wrapped = wrapped[:rows]
+		}
+	}
+	for i, line := range wrapped {
+		f.put(x+2, y+1+i, line, inner-1)
+	}
+
+	// Borda por último: cobre o que um emoji de largura incerta tenha invadido
+	top := "┌─ " + title + " " + strings.Repeat("─", max(inner-textWidth(title)-3, 0)) + "┐"
+	f.put(x, y, top, width)
+	for row := 1; row < height-1; row++ {
+		f.put(x, y+row, "│", 1)
+		f.put(x+width-1, y+row, "│", 1)
+	}
+	f.put(x, y+height-1, "└"+strings.Repeat("─", inner)+"┘", width)
+}
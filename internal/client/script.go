@@ -0,0 +1,506 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"top-card/internal/card"
+	"top-card/internal/protocol"
+	"top-card/pkg/topcard"
+)
+
+// Códigos de saída dos comandos não interativos
+const (
+	exitOK     = 0
+	exitFailed = 1 // Comando recusado pelo servidor, tempo esgotado ou conexão perdida
+	exitUsage  = 2 // Opções ou comandos inválidos
+)
+
+// Espera padrão do comando queue pela partida inteira
+const defaultQueueWait = 2 * time.Minute
+
+// Estratégias de jogada do comando queue
+const (
+	playRandom = "random" // Carta sorteada entre as que o jogador tem
+	playFirst  = "first"  // Primeira carta do inventário
+)
+
+const scriptUsage = `Uso: MODE=client app [opções] comando [opções do comando] [comando ...]
+
+Comandos (encadeados na mesma conexão, na ordem dada):
+  register                  cadastra --user com --password
+  login                     faz login (os demais comandos fazem login sozinhos)
+  open-pack [--pack T] [--seed S]
+                            abre um pacote (tipos: %s)
+  queue [--play P] [--wait D]
+                            entra na fila e joga uma partida inteira; P é random,
+                            first ou o tipo da carta (HYDRA, QUIMERA, GORGONA)
+  stats                     mostra as estatísticas
+
+Exemplo:
+  MODE=client app --json --user ana --password senha123 register open-pack queue stats
+
+Opções:
+`
+
+// Comandos que precisam de login sem usuário informado
+var errNoCredentials = errors.New("informe --user e --password (ou TOPCARD_USER e TOPCARD_PASSWORD)")
+
+// Comando já validado, executado depois de conectar
+type scriptCommand struct {
+	name string
+	run  func(s *script) error
+}
+
+// Sessão dos comandos: uma conexão, as credenciais e a saída
+type script struct {
+	client   *topcard.Client
+	out      *scriptOutput
+	userName string
+	password string
+
+	events   chan topcard.Event // Eventos repassados ao comando queue
+	finished chan struct{}      // Fechado no fim dos comandos, encerra o repasse
+}
+
+// Função para executar comandos sem interação (register, login, open-pack, queue,
+// stats) e retornar o código de saída. Com --json cada evento do servidor e cada
+// resultado é escrito em out como uma linha JSON.
+func RunCommands(args []string, out io.Writer) int {
+	serverAddr := os.Getenv("SERVER_ADDR")
+	if serverAddr == "" {
+		serverAddr = "localhost:8080"
+	}
+
+	global := flag.NewFlagSet("client", flag.ContinueOnError)
+	global.StringVar(&serverAddr, "server", serverAddr, "endereço do servidor (padrão: SERVER_ADDR)")
+	jsonOutput := global.Bool("json", false, "escreve eventos e resultados como linhas JSON")
+	userName := global.String("user", os.Getenv("TOPCARD_USER"), "usuário (padrão: TOPCARD_USER)")
+	password := global.String("password", os.Getenv("TOPCARD_PASSWORD"), "senha (padrão: TOPCARD_PASSWORD)")
+	timeout := global.Duration("timeout", topcard.DefaultRequestTimeout, "prazo de cada requisição")
+	global.Usage = func() {
+		var packTypes []string
+		for _, packType := range card.ListPackTypes() {
+			packTypes = append(packTypes, packType.Name)
+		}
+		fmt.Fprintf(global.Output(), scriptUsage, strings.Join(packTypes, ", "))
+		global.PrintDefaults()
+	}
+	if err := global.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	commands, err := parseCommands(global.Args())
+	if err != nil {
+		fmt.Fprintln(global.Output(), err)
+		return exitUsage
+	}
+	if len(commands) == 0 {
+		global.Usage()
+		return exitUsage
+	}
+
+	output := &scriptOutput{writer: out, json: *jsonOutput}
+	options, err := clientOptions()
+	if err != nil {
+		output.failure("connect", err)
+		return exitFailed
+	}
+	options.RequestTimeout = *timeout
+	client, err := topcard.Dial(serverAddr, options)
+	if err != nil {
+		output.failure("connect", err)
+		return exitFailed
+	}
+
+	s := &script{
+		client:   client,
+		out:      output,
+		userName: *userName,
+		password: *password,
+		events:   make(chan topcard.Event, 64),
+		finished: make(chan struct{}),
+	}
+	pumped := make(chan struct{})
+	go s.pumpEvents(pumped)
+	defer func() {
+		close(s.finished)
+		client.Close()
+		<-pumped
+	}()
+
+	for _, command := range commands {
+		if err := command.run(s); err != nil {
+			output.failure(command.name, err)
+			return exitFailed
+		}
+	}
+	return exitOK
+}
+
+// Função para validar a lista de comandos e suas opções
+func parseCommands(args []string) ([]scriptCommand, error) {
+	var commands []scriptCommand
+	for len(args) > 0 {
+		name := args[0]
+		flags := flag.NewFlagSet(name, flag.ContinueOnError)
+		var run func(s *script) error
+		validate := func() error { return nil }
+
+		switch name {
+		case "register":
+			run = (*script).register
+		case "login":
+			run = (*script).login
+		case "stats":
+			run = (*script).stats
+		case "open-pack":
+			packType := flags.String("pack", card.PACK_STANDARD, "tipo do pacote")
+			seed := flags.String("seed", "", "semente do cliente para o sorteio (padrão: gerada no cliente)")
+			run = func(s *script) error { return s.openPack(*packType, *seed) }
+		case "queue":
+			play := flags.String("play", playRandom, "random, first ou o tipo da carta")
+			wait := flags.Duration("wait", defaultQueueWait, "espera máxima pela partida inteira")
+			run = func(s *script) error { return s.queue(*play, *wait) }
+			validate = func() error {
+				if *play != playRandom && *play != playFirst && !slices.Contains([]string{card.HYDRA, card.QUIMERA, card.GORGONA}, *play) {
+					return fmt.Errorf("queue: estratégia de jogada inválida: %s", *play)
+				}
+				return nil
+			}
+		default:
+			return nil, fmt.Errorf("comando desconhecido: %s", name)
+		}
+
+		if err := flags.Parse(args[1:]); err != nil {
+			return nil, err
+		}
+		if err := validate(); err != nil {
+			return nil, err
+		}
+		commands = append(commands, scriptCommand{name: name, run: run})
+		args = flags.Args()
+	}
+	return commands, nil
+}
+
+// Escreve os eventos do servidor e repassa ao comando queue. O repasse espera o
+// comando ler (até 64 eventos no buffer) ou o fim dos comandos.
+func (s *script) pumpEvents(done chan<- struct{}) {
+	defer close(done)
+	for event := range s.client.Events() {
+		s.out.event(event)
+		select {
+		case s.events <- event:
+		case <-s.finished:
+		}
+	}
+}
+
+// Faz login com as credenciais das opções, se ainda não houver login
+func (s *script) ensureLogin() error {
+	if s.client.UserID() != 0 {
+		return nil
+	}
+	if s.userName == "" {
+		return errNoCredentials
+	}
+	_, err := s.client.Login(context.Background(), s.userName, s.password)
+	return err
+}
+
+func (s *script) register() error {
+	if s.userName == "" {
+		return errNoCredentials
+	}
+	userID, err := s.client.Register(context.Background(), s.userName, s.password)
+	if err != nil {
+		return err
+	}
+	s.out.result("register", map[string]int{"user_id": userID},
+		fmt.Sprintf("✅ Cadastro de %s realizado (ID: %d)", s.userName, userID))
+	return nil
+}
+
+func (s *script) login() error {
+	if err := s.ensureLogin(); err != nil {
+		return err
+	}
+	userID := s.client.UserID()
+	s.out.result("login", map[string]int{"user_id": userID},
+		fmt.Sprintf("✅ Login realizado como %s (ID: %d)", s.userName, userID))
+	return nil
+}
+
+func (s *script) openPack(packType, seed string) error {
+	if err := s.ensureLogin(); err != nil {
+		return err
+	}
+	if seed == "" {
+		seed = newClientSeed()
+	}
+	pack, err := s.client.OpenPackWithSeed(context.Background(), packType, seed)
+	if err != nil {
+		return err
+	}
+
+	var cards []string
+	for _, opened := range pack.Cards {
+		cards = append(cards, fmt.Sprintf("%s (%s)", opened.Type, opened.Rarity))
+	}
+	s.out.result("open-pack", pack, fmt.Sprintf("🎒 Pacote %s: %s", pack.Type, strings.Join(cards, ", ")))
+	return nil
+}
+
+func (s *script) stats() error {
+	if err := s.ensureLogin(); err != nil {
+		return err
+	}
+	stats, err := s.client.Stats(context.Background())
+	if err != nil {
+		return err
+	}
+	s.out.result("stats", stats, fmt.Sprintf("📊 %s: %d vitória(s), %d derrota(s), taxa de vitória %.1f%%",
+		stats.UserName, stats.Wins, stats.Losses, stats.WinRate))
+	return nil
+}
+
+// Resultado do comando queue: o fim da partida e a carta jogada
+type queueResult struct {
+	topcard.MatchEnd
+	Played string `json:"played,omitempty"`
+}
+
+// Entra na fila, joga a partida com a estratégia play e espera o fim (até wait)
+func (s *script) queue(play string, wait time.Duration) error {
+	if err := s.ensureLogin(); err != nil {
+		return err
+	}
+
+	// Eventos de comandos anteriores não são desta partida
+	for drained := false; !drained; {
+		select {
+		case <-s.events:
+		default:
+			drained = true
+		}
+	}
+
+	queueSize, err := s.client.JoinQueue(context.Background())
+	if err != nil {
+		return err
+	}
+	s.out.info(fmt.Sprintf("🔍 Na fila de partidas (%d jogador(es))", queueSize))
+
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+	var played string
+	var rejected []string // Cartas recusadas por não estarem no inventário
+	for {
+		var yourTurn bool
+		select {
+		case event := <-s.events:
+			switch event := event.(type) {
+			case topcard.GameState:
+				yourTurn = event.YourTurn && !event.GameOver
+			case topcard.TurnUpdate:
+				yourTurn = event.YourTurn
+			case topcard.MoveRejected:
+				switch event.Err.Code {
+				case topcard.ERR_CARD_NOT_OWNED, topcard.ERR_INVALID_CARD:
+					rejected = append(rejected, event.CardType)
+					yourTurn = true
+				case topcard.ERR_NOT_YOUR_TURN, topcard.ERR_ALREADY_PLAYED:
+				default:
+					return event.Err
+				}
+			case topcard.MatchEnd:
+				s.out.result("queue", queueResult{MatchEnd: event, Played: played}, matchEndText(event))
+				return nil
+			case topcard.Disconnected:
+				if event.Err != nil {
+					return event.Err
+				}
+				return topcard.ErrClosed
+			}
+		case <-deadline.C:
+			return fmt.Errorf("tempo esgotado (%v) esperando o fim da partida", wait)
+		}
+
+		if !yourTurn {
+			continue
+		}
+		cardType, err := chooseCard(play, s.client.Inventory(), rejected)
+		if err != nil {
+			return err
+		}
+		if err := s.client.PlayCard(context.Background(), cardType); err != nil {
+			return err
+		}
+		played = cardType
+		s.out.info("✅ Carta jogada: " + cardType)
+	}
+}
+
+// Função para escolher a carta da jogada. Sem inventário (ex.: servidor sem a
+// capacidade inventory) considera todos os tipos; cartas já recusadas ficam de fora.
+func chooseCard(play string, inventory []topcard.Card, rejected []string) (string, error) {
+	if play != playRandom && play != playFirst {
+		if slices.Contains(rejected, play) {
+			return "", fmt.Errorf("você não tem nenhuma %s", play)
+		}
+		return play, nil
+	}
+
+	var candidates []string
+	for _, owned := range inventory {
+		if !slices.Contains(candidates, owned.Type) && !slices.Contains(rejected, owned.Type) {
+			candidates = append(candidates, owned.Type)
+		}
+	}
+	if len(candidates) == 0 {
+		for _, cardType := range []string{card.HYDRA, card.QUIMERA, card.GORGONA} {
+			if !slices.Contains(rejected, cardType) {
+				candidates = append(candidates, cardType)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return "", errors.New("nenhuma carta disponível para jogar")
+	}
+	if play == playFirst {
+		return candidates[0], nil
+	}
+	return candidates[rand.IntN(len(candidates))], nil
+}
+
+// Saída dos comandos: texto para pessoas ou uma linha JSON por registro
+type scriptOutput struct {
+	mutex  sync.Mutex
+	writer io.Writer
+	json   bool
+}
+
+// Linha JSON da saída. Type é o tipo da mensagem do protocolo para eventos,
+// RESULT para o resultado de um comando e ERROR para a falha de um comando.
+type scriptRecord struct {
+	Type    string `json:"type"`
+	Command string `json:"command,omitempty"`
+	Data    any    `json:"data,omitempty"`
+	Error   any    `json:"error,omitempty"`
+}
+
+func (o *scriptOutput) write(record scriptRecord, text string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if !o.json {
+		fmt.Fprintln(o.writer, text)
+		return
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		line, _ = json.Marshal(scriptRecord{Type: "ERROR", Command: record.Command, Error: map[string]string{"message": err.Error()}})
+	}
+	fmt.Fprintf(o.writer, "%s\n", line)
+}
+
+// Mensagem de andamento, só na saída em texto
+func (o *scriptOutput) info(text string) {
+	if !o.json {
+		o.write(scriptRecord{}, text)
+	}
+}
+
+func (o *scriptOutput) result(command string, data any, text string) {
+	o.write(scriptRecord{Type: "RESULT", Command: command, Data: data}, text)
+}
+
+func (o *scriptOutput) failure(command string, err error) {
+	var serverErr *topcard.Error
+	var details any = map[string]string{"message": err.Error()}
+	if errors.As(err, &serverErr) {
+		details = serverErr
+	}
+	o.write(scriptRecord{Type: "ERROR", Command: command, Error: details}, fmt.Sprintf("❌ %s: %v", command, err))
+}
+
+// Escreve um evento do servidor. O fim da conexão pedido pelo próprio cliente não
+// é escrito.
+func (o *scriptOutput) event(event topcard.Event) {
+	record := scriptRecord{Data: event}
+	var text string
+	switch event := event.(type) {
+	case topcard.MatchFound:
+		record.Type = protocol.MSG_MATCH_FOUND
+		text = fmt.Sprintf("🎯 Partida %d encontrada contra %s", event.MatchID, event.OpponentName)
+	case topcard.MatchStart:
+		record.Type = protocol.MSG_MATCH_START
+		text = fmt.Sprintf("🚀 Partida %d iniciada", event.MatchID)
+	case topcard.GameState:
+		record.Type = protocol.MSG_GAME_STATE
+		text = "🎮 " + event.Message
+	case topcard.TurnUpdate:
+		record.Type = protocol.MSG_TURN_UPDATE
+		text = "🔄 " + event.Message
+	case topcard.MatchEnd:
+		record.Type = protocol.MSG_MATCH_END
+		text = matchEndText(event)
+	case topcard.MoveRejected:
+		record.Type = protocol.MSG_MOVE_REJECTED
+		text = fmt.Sprintf("❌ Jogada recusada: %v", event.Err)
+	case topcard.SessionState:
+		record.Type = protocol.MSG_SESSION_STATE
+		hydra, quimera, gorgona := countCards(event.Inventory)
+		text = fmt.Sprintf("📋 %dW-%dL | 💰 %d moedas | HYDRA(%d) QUIMERA(%d) GORGONA(%d)",
+			event.Stats.Wins, event.Stats.Losses, event.Coins, hydra, quimera, gorgona)
+		if event.Match != nil {
+			text += fmt.Sprintf(" | partida %d contra %s", event.Match.MatchID, event.Match.OpponentName)
+		}
+	case topcard.InventoryUpdate:
+		record.Type = protocol.MSG_INVENTORY_UPDATE
+		hydra, quimera, gorgona := countCards(event.Cards)
+		text = fmt.Sprintf("🎒 Inventário: HYDRA(%d) | QUIMERA(%d) | GORGONA(%d)", hydra, quimera, gorgona)
+	case topcard.ChatMessage:
+		record.Type = protocol.MSG_CHAT_MESSAGE
+		text = fmt.Sprintf("💬 %s: %s", event.FromName, event.Text)
+	case topcard.ServerShutdown:
+		record.Type = protocol.MSG_SERVER_SHUTDOWN
+		text = "🛑 " + event.Message
+	case topcard.ServerError:
+		record.Type = protocol.MSG_ERROR
+		text = fmt.Sprintf("🔴 Erro do servidor: %v", event.Err)
+	case topcard.Disconnected:
+		if event.Err == nil {
+			return
+		}
+		record = scriptRecord{Type: "DISCONNECTED", Error: map[string]string{"message": event.Err.Error()}}
+		text = fmt.Sprintf("🔴 Conexão perdida: %v", event.Err)
+	default:
+		return
+	}
+	o.write(record, text)
+}
+
+// Resumo do fim de uma partida
+func matchEndText(matchEnd topcard.MatchEnd) string {
+	switch {
+	case matchEnd.WinnerID == 0:
+		return fmt.Sprintf("⏹️ Partida %d interrompida, sem vencedor", matchEnd.MatchID)
+	case matchEnd.Won:
+		return fmt.Sprintf("🏆 Partida %d: VITÓRIA!", matchEnd.MatchID)
+	default:
+		return fmt.Sprintf("😔 Partida %d: derrota, vencedor %s", matchEnd.MatchID, matchEnd.WinnerName)
+	}
+}
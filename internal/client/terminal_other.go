@@ -0,0 +1,23 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package client
+
+import (
+	"errors"
+	"os"
+)
+
+// Sem modo raw nesta plataforma: o cliente usa sempre o menu numerado
+var errNoRawMode = errors.New("modo raw não suportado nesta plataforma")
+
+type terminal struct{}
+
+func isTerminal(file *os.File) bool { return false }
+
+func makeRaw(file *os.File) (*terminal, error) { return nil, errNoRawMode }
+
+func (t *terminal) restore() error { return errNoRawMode }
+
+func terminalSize(file *os.File) (int, int, error) { return 0, 0, errNoRawMode }
+
+func notifyResize(resized chan<- os.Signal) {}
@@ -0,0 +1,64 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package client
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Terminal em modo raw: cada tecla chega sem esperar Enter e sem eco
+type terminal struct {
+	fd    int
+	saved unix.Termios
+}
+
+// Informa se o descritor é um terminal
+func isTerminal(file *os.File) bool {
+	_, err := unix.IoctlGetTermios(int(file.Fd()), ioctlGetTermios)
+	return err == nil
+}
+
+// Função para colocar a entrada em modo raw, guardando o modo anterior para restore
+func makeRaw(file *os.File) (*terminal, error) {
+	fd := int(file.Fd())
+	saved, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := *saved
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Oflag &^= unix.OPOST
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, ioctlSetTermios, &raw); err != nil {
+		return nil, err
+	}
+	return &terminal{fd: fd, saved: *saved}, nil
+}
+
+// Volta o terminal ao modo anterior
+func (t *terminal) restore() error {
+	return unix.IoctlSetTermios(t.fd, ioctlSetTermios, &t.saved)
+}
+
+// Tamanho da janela em colunas e linhas
+func terminalSize(file *os.File) (int, int, error) {
+	size, err := unix.IoctlGetWinsize(int(file.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(size.Col), int(size.Row), nil
+}
+
+// Avisa em resized sempre que a janela do terminal muda de tamanho
+func notifyResize(resized chan<- os.Signal) {
+	signal.Notify(resized, syscall.SIGWINCH)
+}
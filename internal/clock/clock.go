@@ -0,0 +1,209 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Interface de relógio usada pelo servidor, matchmaker e partidas.
+// Em produção usa o relógio real; nos testes o relógio falso permite avançar o tempo instantaneamente.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Interface de ticker (equivalente ao time.Ticker)
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Relógio real, baseado no pacote time
+type realClock struct{}
+
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+// Função para obter o relógio real
+func Real() Clock {
+	return realClock{}
+}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return &realTicker{ticker: time.NewTicker(d)}
+}
+
+func (t *realTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t *realTicker) Stop() {
+	t.ticker.Stop()
+}
+
+// Relógio falso para testes: o tempo só anda quando Advance é chamado
+type Fake struct {
+	mutex   sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+	changed chan struct{} // Sinaliza mudanças na lista de waiters (usado por BlockUntil)
+}
+
+// Um Sleep, After ou Ticker aguardando o relógio falso
+type fakeWaiter struct {
+	deadline time.Time
+	period   time.Duration // 0 = dispara uma vez só
+	ch       chan time.Time
+	stopped  bool
+}
+
+type fakeTicker struct {
+	clock  *Fake
+	waiter *fakeWaiter
+}
+
+// Função para criar um relógio falso começando no instante informado
+func NewFake(start time.Time) *Fake {
+	return &Fake{
+		now:     start,
+		changed: make(chan struct{}),
+	}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.now
+}
+
+func (f *Fake) Sleep(d time.Duration) {
+	<-f.After(d)
+}
+
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	waiter := &fakeWaiter{deadline: f.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		waiter.ch <- f.now
+		return waiter.ch
+	}
+	f.addWaiter(waiter)
+	return waiter.ch
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: intervalo do ticker deve ser positivo")
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	waiter := &fakeWaiter{deadline: f.now.Add(d), period: d, ch: make(chan time.Time, 1)}
+	f.addWaiter(waiter)
+	return &fakeTicker{clock: f, waiter: waiter}
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.waiter.ch
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	t.waiter.stopped = true
+	t.clock.removeWaiter(t.waiter)
+}
+
+// Avança o relógio falso, disparando em ordem todos os timers vencidos
+func (f *Fake) Advance(d time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	target := f.now.Add(d)
+	for {
+		sort.Slice(f.waiters, func(i, j int) bool {
+			return f.waiters[i].deadline.Before(f.waiters[j].deadline)
+		})
+		if len(f.waiters) == 0 || f.waiters[0].deadline.After(target) {
+			break
+		}
+
+		waiter := f.waiters[0]
+		f.now = waiter.deadline
+		// Como no time.Ticker, descarta o tick se ninguém leu o anterior
+		select {
+		case waiter.ch <- f.now:
+		default:
+		}
+
+		if waiter.period > 0 && !waiter.stopped {
+			waiter.deadline = waiter.deadline.Add(waiter.period)
+		} else {
+			f.removeWaiter(waiter)
+		}
+	}
+	f.now = target
+}
+
+// Bloqueia até que existam pelo menos n timers aguardando o relógio.
+// Permite que o teste espere uma goroutine chegar no Sleep/Ticker antes de avançar o tempo.
+func (f *Fake) BlockUntil(n int) {
+	for {
+		f.mutex.Lock()
+		count := len(f.waiters)
+		changed := f.changed
+		f.mutex.Unlock()
+
+		if count >= n {
+			return
+		}
+		<-changed
+	}
+}
+
+// Quantidade de timers aguardando o relógio
+func (f *Fake) Waiters() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return len(f.waiters)
+}
+
+// Funções internas (chamadas com o mutex travado)
+func (f *Fake) addWaiter(waiter *fakeWaiter) {
+	f.waiters = append(f.waiters, waiter)
+	f.notifyChange()
+}
+
+func (f *Fake) removeWaiter(waiter *fakeWaiter) {
+	for i, w := range f.waiters {
+		if w == waiter {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			f.notifyChange()
+			return
+		}
+	}
+}
+
+func (f *Fake) notifyChange() {
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
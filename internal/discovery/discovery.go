@@ -0,0 +1,163 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Grupo multicast padrão dos anúncios (escopo local da organização, TTL 1: não sai
+// da rede local). Um endereço de broadcast (ex.: "255.255.255.255:8099") também
+// funciona, para redes sem multicast.
+const DEFAULT_GROUP = "239.255.67.80:8099"
+
+// Intervalo padrão entre anúncios; quem procura deve esperar um pouco mais que isso
+const DEFAULT_INTERVAL = 2 * time.Second
+
+// Identifica os anúncios do TOP CARD entre outros pacotes no mesmo grupo
+const service = "top-card"
+
+// Tamanho máximo de um anúncio
+const maxAnnouncementSize = 1024
+
+// Anúncio de um servidor na rede local
+type Announcement struct {
+	Service         string `json:"service"`
+	Name            string `json:"name"`
+	Addr            string `json:"addr"`    // host:porta TCP; sem host, vale o IP de origem do anúncio
+	Players         int    `json:"players"` // Jogadores logados
+	Version         string `json:"version"`
+	ProtocolVersion int    `json:"protocol_version"`
+	TLS             bool   `json:"tls,omitempty"`
+}
+
+// Função para decodificar um anúncio recebido de from. Endereços sem host ou com
+// host não especificado (ex.: ":8080", "0.0.0.0:8080") recebem o IP de origem.
+func Parse(data []byte, from net.Addr) (Announcement, bool) {
+	var announcement Announcement
+	if err := json.Unmarshal(data, &announcement); err != nil || announcement.Service != service {
+		return Announcement{}, false
+	}
+	host, port, err := net.SplitHostPort(announcement.Addr)
+	if err != nil || port == "" {
+		return Announcement{}, false
+	}
+	if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+		udpAddr, ok := from.(*net.UDPAddr)
+		if !ok {
+			return Announcement{}, false
+		}
+		announcement.Addr = net.JoinHostPort(udpAddr.IP.String(), port)
+	}
+	if announcement.Name == "" {
+		announcement.Name = announcement.Addr
+	}
+	return announcement, true
+}
+
+// Envio periódico do anúncio de um servidor
+type Announcer struct {
+	Conn     net.PacketConn
+	Target   net.Addr                    // Grupo multicast ou endereço de broadcast
+	Interval time.Duration               // Intervalo entre anúncios (padrão: DEFAULT_INTERVAL)
+	Info     func() (Announcement, bool) // Estado atual do servidor, consultado a cada anúncio; false pula o anúncio
+}
+
+// Função para anunciar o servidor até stop ser fechado (retorna nil nesse caso).
+// Falhas de envio (ex.: rede sem rota multicast) não interrompem os anúncios.
+func (a *Announcer) Run(stop <-chan struct{}) error {
+	interval := a.Interval
+	if interval <= 0 {
+		interval = DEFAULT_INTERVAL
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if announcement, ok := a.Info(); ok {
+			announcement.Service = service
+			data, err := json.Marshal(announcement)
+			if err != nil {
+				return err
+			}
+			a.Conn.WriteTo(data, a.Target)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+// Função para ouvir anúncios em conn durante wait (ou até ctx terminar) e retornar
+// os servidores encontrados, um por endereço, ordenados por nome
+func Listen(ctx context.Context, conn net.PacketConn, wait time.Duration) ([]Announcement, error) {
+	deadline := time.Now().Add(wait)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	conn.SetReadDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	found := make(map[string]Announcement)
+	buffer := make([]byte, maxAnnouncementSize)
+	for {
+		n, from, err := conn.ReadFrom(buffer)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				break
+			}
+			return nil, err
+		}
+		if announcement, ok := Parse(buffer[:n], from); ok {
+			found[announcement.Addr] = announcement // O anúncio mais recente prevalece
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	servers := make([]Announcement, 0, len(found))
+	for _, announcement := range found {
+		servers = append(servers, announcement)
+	}
+	slices.SortFunc(servers, func(a, b Announcement) int {
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Addr, b.Addr)
+	})
+	return servers, nil
+}
+
+// Função para procurar servidores no grupo informado (multicast ou broadcast)
+// durante wait
+func Discover(ctx context.Context, group string, wait time.Duration) ([]Announcement, error) {
+	conn, err := ListenGroup(group)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return Listen(ctx, conn, wait)
+}
+
+// Função para abrir um socket que recebe os anúncios do grupo: entra no grupo
+// multicast ou, com endereço de broadcast, ouve na porta do grupo
+func ListenGroup(group string) (net.PacketConn, error) {
+	addr, err := net.ResolveUDPAddr("udp4", group)
+	if err != nil {
+		return nil, err
+	}
+	if addr.IP.IsMulticast() {
+		return net.ListenMulticastUDP("udp4", nil, addr)
+	}
+	return net.ListenUDP("udp4", &net.UDPAddr{Port: addr.Port})
+}
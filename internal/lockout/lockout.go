@@ -0,0 +1,148 @@
+package lockout
+
+import (
+	"sort"
+	"sync"
+	"time"
+	"top-card/internal/clock"
+)
+
+// Política de tentativas falhas: depois de cada falha a próxima tentativa só é aceita
+// após uma espera que dobra a cada falha; ao atingir MaxFailures a chave fica bloqueada
+// por LockoutDuration.
+type Policy struct {
+	MaxFailures     int           // Falhas seguidas até o bloqueio
+	BaseDelay       time.Duration // Espera depois da primeira falha
+	MaxDelay        time.Duration // Espera máxima entre tentativas antes do bloqueio
+	LockoutDuration time.Duration // Duração do bloqueio
+	ForgetAfter     time.Duration // Falhas mais antigas que isso são esquecidas
+}
+
+// Estado das falhas de uma chave (usuário ou IP)
+type Entry struct {
+	Key         string    `json:"key"`
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"last_failure"`
+	LockedUntil time.Time `json:"locked_until,omitzero"` // Zero = não bloqueada
+}
+
+// Função para verificar se a chave está bloqueada no instante informado
+func (e Entry) Locked(now time.Time) bool {
+	return now.Before(e.LockedUntil)
+}
+
+// Registro de tentativas falhas por chave
+type Tracker struct {
+	clock   clock.Clock
+	policy  Policy
+	mutex   sync.Mutex
+	entries map[string]*Entry
+}
+
+// Função para criar um registro de tentativas com a política informada
+func NewTracker(clk clock.Clock, policy Policy) *Tracker {
+	return &Tracker{
+		clock:   clk,
+		policy:  policy,
+		entries: make(map[string]*Entry),
+	}
+}
+
+// Espera exigida depois de n falhas seguidas
+func (t *Tracker) delay(failures int) time.Duration {
+	delay := t.policy.BaseDelay
+	for i := 1; i < failures && delay < t.policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, t.policy.MaxDelay)
+}
+
+// Descarta a entrada se o bloqueio já terminou ou se as falhas são antigas.
+// Deve ser chamada com o mutex travado.
+func (t *Tracker) expire(key string, entry *Entry, now time.Time) bool {
+	lockExpired := !entry.LockedUntil.IsZero() && !entry.Locked(now)
+	stale := t.policy.ForgetAfter > 0 && now.Sub(entry.LastFailure) >= t.policy.ForgetAfter && !entry.Locked(now)
+	if lockExpired || stale {
+		delete(t.entries, key)
+		return true
+	}
+	return false
+}
+
+// Função para verificar se uma nova tentativa pode ser feita agora. Retorna quanto
+// falta esperar (0 = pode tentar) e se a espera é um bloqueio.
+func (t *Tracker) Wait(key string) (time.Duration, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	entry, exists := t.entries[key]
+	now := t.clock.Now()
+	if !exists || t.expire(key, entry, now) {
+		return 0, false
+	}
+	if entry.Locked(now) {
+		return entry.LockedUntil.Sub(now), true
+	}
+	if next := entry.LastFailure.Add(t.delay(entry.Failures)); now.Before(next) {
+		return next.Sub(now), false
+	}
+	return 0, false
+}
+
+// Função para registrar uma tentativa falha. Retorna o estado atualizado da chave.
+func (t *Tracker) Fail(key string) Entry {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := t.clock.Now()
+	entry, exists := t.entries[key]
+	if !exists || t.expire(key, entry, now) {
+		entry = &Entry{Key: key}
+		t.entries[key] = entry
+	}
+
+	entry.Failures++
+	entry.LastFailure = now
+	if t.policy.MaxFailures > 0 && entry.Failures >= t.policy.MaxFailures {
+		entry.LockedUntil = now.Add(t.policy.LockoutDuration)
+	}
+	return *entry
+}
+
+// Função para zerar as falhas de uma chave (login bem-sucedido ou desbloqueio manual).
+// Retorna false se a chave não tinha falhas registradas.
+func (t *Tracker) Reset(key string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	_, exists := t.entries[key]
+	delete(t.entries, key)
+	return exists
+}
+
+// Função para listar as chaves com falhas registradas, em ordem alfabética
+func (t *Tracker) Entries() []Entry {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := t.clock.Now()
+	entries := make([]Entry, 0, len(t.entries))
+	for key, entry := range t.entries {
+		if !t.expire(key, entry, now) {
+			entries = append(entries, *entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries
+}
+
+// Função para descartar as chaves cujo bloqueio terminou ou cujas falhas são antigas
+func (t *Tracker) Prune() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := t.clock.Now()
+	for key, entry := range t.entries {
+		t.expire(key, entry, now)
+	}
+}
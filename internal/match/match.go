@@ -3,8 +3,11 @@ package match
 import (
 	"fmt"
 	"sync"
+	"time"
+	"top-card/internal/clock"
 	"top-card/internal/player"
 	"top-card/internal/card"
+	"top-card/internal/protocol"
 )
 
 // Estrutura para representar uma partida (MODIFICADA)
@@ -21,19 +24,35 @@ type Match struct {
 	Player2Card    *card.Card // Carta jogada pelo Player2 (ponteiro para nil = não jogou)
 	GameStarted    bool       // Se o jogo já começou
 	GameType       string     // "cards" para jogo de cartas, "numbers" para números
+
+	// Horários da partida (obtidos do relógio do gerenciador)
+	CreatedAt  time.Time
+	StartedAt  time.Time
+	LastMoveAt time.Time
+	FinishedAt time.Time
 }
 
 // Gerenciador de partidas
 type MatchManager struct {
-	matches    []Match
+	matches    []*Match
 	nextID     int
+	clock      clock.Clock
 	mutex      sync.Mutex
 }
 
 // Instância global do gerenciador
-var manager = &MatchManager{
-	matches: make([]Match, 0),
-	nextID:  1,
+var manager = NewMatchManager(clock.Real())
+
+// Função para criar um gerenciador de partidas com o relógio informado
+func NewMatchManager(clk clock.Clock) *MatchManager {
+	if clk == nil {
+		clk = clock.Real()
+	}
+	return &MatchManager{
+		matches: make([]*Match, 0),
+		nextID:  1,
+		clock:   clk,
+	}
 }
 
 // Função para obter o gerenciador
@@ -46,7 +65,7 @@ func (mm *MatchManager) CreateMatch(player1, player2 *player.Player) *Match {
     mm.mutex.Lock()
     defer mm.mutex.Unlock()
 
-    newMatch := Match{
+    newMatch := &Match{
         ID:            mm.nextID,
         Player1:       player1, 
         Player2:       player2,  
@@ -57,6 +76,7 @@ func (mm *MatchManager) CreateMatch(player1, player2 *player.Player) *Match {
         Player2Card:   nil,
         GameStarted:   false,
         GameType:      "cards",
+        CreatedAt:     mm.clock.Now(),
     }
 
     mm.matches = append(mm.matches, newMatch)
@@ -65,7 +85,7 @@ func (mm *MatchManager) CreateMatch(player1, player2 *player.Player) *Match {
     fmt.Printf("🎮 Nova partida criada! ID: %d - %s vs %s\n", 
         newMatch.ID, player1.GetUserName(), player2.GetUserName())
 
-    return &newMatch
+    return newMatch
 }
 
 // Busca uma partida por ID
@@ -75,7 +95,7 @@ func (mm *MatchManager) GetMatch(matchID int) *Match {
 
 	for i := range mm.matches {
 		if mm.matches[i].ID == matchID {
-			return &mm.matches[i]
+			return mm.matches[i]
 		}
 	}
 	return nil
@@ -90,6 +110,7 @@ func (mm *MatchManager) ForceWin(matchID int, winnerID int) bool {
 		if mm.matches[i].ID == matchID {
 			mm.matches[i].Status = "finished"
 			mm.matches[i].Winner = winnerID
+			mm.matches[i].FinishedAt = mm.clock.Now()
 			
 			var winnerName string
 			if mm.matches[i].Player1.GetID() == winnerID {
@@ -114,6 +135,7 @@ func (mm *MatchManager) CancelMatch(matchID int) bool {
 	for i := range mm.matches {
 		if mm.matches[i].ID == matchID {
 			mm.matches[i].Status = "cancelled"
+			mm.matches[i].FinishedAt = mm.clock.Now()
 			
 			fmt.Printf("❌ Partida %d cancelada\n", matchID)
 			return true
@@ -122,21 +144,56 @@ func (mm *MatchManager) CancelMatch(matchID int) bool {
 	return false
 }
 
+// Interrompe uma partida ainda não finalizada (usado no encerramento do servidor).
+// Retorna uma cópia da partida como estava, com as cartas já jogadas, para que
+// possam ser devolvidas aos jogadores.
+func (mm *MatchManager) InterruptMatch(matchID int) (Match, bool) {
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+
+	for _, match := range mm.matches {
+		if match.ID == matchID && (match.Status == "waiting" || match.Status == "playing") {
+			snapshot := *match
+			match.Status = "cancelled"
+			match.FinishedAt = mm.clock.Now()
+
+			fmt.Printf("⏹️  Partida %d interrompida pelo encerramento do servidor\n", matchID)
+			return snapshot, true
+		}
+	}
+	return Match{}, false
+}
+
 // Busca partida onde um jogador está participando
 func (mm *MatchManager) GetPlayerMatch(playerID int) *Match {
 	mm.mutex.Lock()
 	defer mm.mutex.Unlock()
 
 	for i := range mm.matches {
-		match := &mm.matches[i]
+		match := mm.matches[i]
 		if (match.Player1.GetID() == playerID || match.Player2.GetID() == playerID) && 
-		   match.Status != "finished" {
+		   match.Status != "finished" && match.Status != "cancelled" {
 			return match
 		}
 	}
 	return nil
 }
 
+// Retorna uma cópia da partida em andamento de um jogador, lida com o gerenciador
+// travado (para montar o estado da sessão sem disputar com as jogadas)
+func (mm *MatchManager) GetPlayerMatchSnapshot(playerID int) (Match, bool) {
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+
+	for _, match := range mm.matches {
+		if (match.Player1.GetID() == playerID || match.Player2.GetID() == playerID) &&
+			match.Status != "finished" && match.Status != "cancelled" {
+			return *match, true
+		}
+	}
+	return Match{}, false
+}
+
 // Inicia uma partida
 func (mm *MatchManager) StartMatch(matchID int) bool {
 	mm.mutex.Lock()
@@ -145,6 +202,7 @@ func (mm *MatchManager) StartMatch(matchID int) bool {
 	for i := range mm.matches {
 		if mm.matches[i].ID == matchID {
 			mm.matches[i].Status = "playing"
+			mm.matches[i].StartedAt = mm.clock.Now()
 			fmt.Printf("🚀 Partida %d iniciada!\n", matchID)
 			return true
 		}
@@ -161,6 +219,7 @@ func (mm *MatchManager) FinishMatch(matchID int, winnerID int) bool {
 		if mm.matches[i].ID == matchID {
 			mm.matches[i].Status = "finished"
 			mm.matches[i].Winner = winnerID
+			mm.matches[i].FinishedAt = mm.clock.Now()
 			
 			var winnerName string
 			if mm.matches[i].Player1.GetID() == winnerID {
@@ -195,62 +254,63 @@ func (mm *MatchManager) StartGame(matchID int) bool {
 	return false
 }
 
-// Processa uma jogada com carta
-func (mm *MatchManager) MakeCardMove(matchID, playerID int, cardType string) (bool, string) {
+// Processa uma jogada com carta. Retorna sucesso, mensagem e, em caso de falha,
+// o código de erro do protocolo.
+func (mm *MatchManager) MakeCardMove(matchID, playerID int, cardType string) (bool, string, string) {
 	mm.mutex.Lock()
 	defer mm.mutex.Unlock()
 
 	for i := range mm.matches {
-		match := &mm.matches[i]
+		match := mm.matches[i]
 		if match.ID == matchID {
 			// Verificações básicas
 			if match.Status != "playing" {
-				return false, "Partida não está em andamento"
+				return false, "Partida não está em andamento", protocol.ERR_MATCH_NOT_IN_PROGRESS
 			}
 			
 			if !match.GameStarted {
-				return false, "Jogo ainda não foi iniciado"
+				return false, "Jogo ainda não foi iniciado", protocol.ERR_MATCH_NOT_IN_PROGRESS
 			}
 			
 			if match.CurrentTurn != playerID {
-				return false, "Não é seu turno"
+				return false, "Não é seu turno", protocol.ERR_NOT_YOUR_TURN
 			}
 
 			// Verifica se é uma carta válida
 			if cardType != card.HYDRA && cardType != card.QUIMERA && cardType != card.GORGONA {
-				return false, "Tipo de carta inválido"
+				return false, "Tipo de carta inválido", protocol.ERR_INVALID_CARD
 			}
 
 			// NOVA VALIDAÇÃO: Verifica se o jogador possui a carta
 			var currentPlayer *player.Player
 			if match.Player1.GetID() == playerID {
 				if match.Player1Card != nil {
-					return false, "Você já fez sua jogada"
+					return false, "Você já fez sua jogada", protocol.ERR_ALREADY_PLAYED
 				}
 				currentPlayer = match.Player1
 			} else if match.Player2.GetID() == playerID {
 				if match.Player2Card != nil {
-					return false, "Você já fez sua jogada"
+					return false, "Você já fez sua jogada", protocol.ERR_ALREADY_PLAYED
 				}
 				currentPlayer = match.Player2
 			} else {
-				return false, "Você não faz parte desta partida"
+				return false, "Você não faz parte desta partida", protocol.ERR_NOT_IN_MATCH
 			}
 
 			// Verifica se o jogador tem a carta no inventário
 			if !currentPlayer.HasCardType(cardType) {
-				return false, fmt.Sprintf("Você não possui cartas do tipo %s no seu inventário!", cardType)
+				return false, fmt.Sprintf("Você não possui cartas do tipo %s no seu inventário!", cardType), protocol.ERR_CARD_NOT_OWNED
 			}
 
-			// Remove a carta do inventário do jogador
-			if !currentPlayer.RemoveCard(cardType) {
-				return false, "Erro ao remover carta do inventário"
+			// Remove a carta do inventário do jogador (guarda a carta para poder devolvê-la
+			// se a partida for interrompida; a raridade não importa no jogo)
+			playedCard, removed := currentPlayer.TakeCard(cardType)
+			if !removed {
+				return false, "Erro ao remover carta do inventário", protocol.ERR_INTERNAL
 			}
 
-			// Cria a carta jogada
-			playedCard := card.Card{Type: cardType, Rarity: "comum"} // Rarity não importa no jogo
-
 			// Registra a jogada
+			match.LastMoveAt = mm.clock.Now()
 			if match.Player1.GetID() == playerID {
 				match.Player1Card = &playedCard
 				fmt.Printf("🃏 Player1 (ID: %d) jogou: %s (removida do inventário)\n", playerID, cardType)
@@ -261,7 +321,8 @@ func (mm *MatchManager) MakeCardMove(matchID, playerID int, cardType string) (bo
 
 			// Verifica se ambos jogaram para determinar vencedor
 			if match.Player1Card != nil && match.Player2Card != nil {
-				return mm.finishCardGame(match)
+				success, message := mm.finishCardGame(match)
+				return success, message, ""
 			} else {
 				// Passa o turno para o outro jogador
 				if match.CurrentTurn == match.Player1.GetID() {
@@ -269,11 +330,11 @@ func (mm *MatchManager) MakeCardMove(matchID, playerID int, cardType string) (bo
 				} else {
 					match.CurrentTurn = match.Player1.GetID()
 				}
-				return true, fmt.Sprintf("Carta %s jogada com sucesso! Aguardando o oponente...", cardType)
+				return true, fmt.Sprintf("Carta %s jogada com sucesso! Aguardando o oponente...", cardType), ""
 			}
 		}
 	}
-	return false, "Partida não encontrada"
+	return false, "Partida não encontrada", protocol.ERR_MATCH_NOT_FOUND
 }
 
 
@@ -310,6 +371,7 @@ func (mm *MatchManager) finishCardGame(match *Match) (bool, string) {
 	
 	match.Status = "finished"
 	match.Winner = winnerID
+	match.FinishedAt = mm.clock.Now()
 	
 	fmt.Printf("🏆 %s\n", finalMessage)
 	return true, finalMessage
@@ -336,7 +398,7 @@ func (mm *MatchManager) GetAllActiveMatches() []Match {
 	var activeMatches []Match
 	for _, match := range mm.matches {
 		if match.Status == "waiting" || match.Status == "playing" {
-			activeMatches = append(activeMatches, match)
+			activeMatches = append(activeMatches, *match)
 		}
 	}
 	return activeMatches
@@ -1,58 +1,92 @@
 package player
 
-import "top-card/internal/card"
+import (
+    "sync"
+
+    "top-card/internal/card"
+)
+
+// Moedas recebidas pelo vencedor de uma partida
+const WIN_COINS = 10
 
 type Player struct {
+    mutex    sync.Mutex // Protege estatísticas, moedas, inventário, pity e sementes
     id       int
     userName string
     password string
     wins     int
     losses   int
+    coins    int          // Saldo de moedas (ganhas nas vitórias)
     inventory []card.Card // Inventário de cartas do jogador
+    pity     map[string]int // Pacotes abertos sem épico, por tipo de pacote
+    seeds    card.SeedState // Sementes do sorteio comprovadamente justo
 }
 
-func NewPlayer(id int, userName string, password string) Player {
-    return Player {
+func NewPlayer(id int, userName string, password string) *Player {
+    return &Player {
         id:       id,
         userName: userName,
         password: password,
         wins:     0,
         losses:   0,
         inventory: make([]card.Card, 0), // Inicializa inventário vazio
+        pity:     make(map[string]int),
     }
 }
 
 // Métodos getters públicos existentes
-func (p Player) GetUserName() string {
+func (p *Player) GetUserName() string {
     return p.userName
 }
 
-func (p Player) GetPassword() string {
+func (p *Player) GetPassword() string {
     return p.password
 }
 
-func (p Player) GetID() int {
+func (p *Player) GetID() int {
     return p.id
 }
 
 // Novos métodos para estatísticas
-func (p Player) GetWins() int {
+func (p *Player) GetWins() int {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
     return p.wins
 }
 
-func (p Player) GetLosses() int {
+func (p *Player) GetLosses() int {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
     return p.losses
 }
 
 func (p *Player) AddWin() {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
     p.wins++
 }
 
 func (p *Player) AddLoss() {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
     p.losses++
 }
 
-func (p Player) GetWinRate() float64 {
+func (p *Player) GetCoins() int {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+    return p.coins
+}
+
+func (p *Player) AddCoins(amount int) {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+    p.coins += amount
+}
+
+func (p *Player) GetWinRate() float64 {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
     totalGames := p.wins + p.losses
     if totalGames == 0 {
         return 0.0
@@ -60,21 +94,29 @@ func (p Player) GetWinRate() float64 {
     return float64(p.wins) / float64(totalGames) * 100
 }
 
-// Novos métodos para o sistema de cartas
-func (p Player) GetInventory() []card.Card {
-    return p.inventory
+// Novos métodos para o sistema de cartas (o inventário retornado é uma cópia)
+func (p *Player) GetInventory() []card.Card {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+    return append([]card.Card(nil), p.inventory...)
 }
 
 func (p *Player) AddCards(cards []card.Card) {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
     p.inventory = append(p.inventory, cards...)
 }
 
-func (p Player) GetInventorySize() int {
+func (p *Player) GetInventorySize() int {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
     return len(p.inventory)
 }
 
 // Método para contar cartas por tipo
-func (p Player) CountCardsByType() (int, int, int) {
+func (p *Player) CountCardsByType() (int, int, int) {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
     hydraCount := 0
     quimeraCount := 0
     gorgonaCount := 0
@@ -94,7 +136,9 @@ func (p Player) CountCardsByType() (int, int, int) {
 }
 
 // Método para verificar se tem carta específica
-func (p Player) HasCardType(cardType string) bool {
+func (p *Player) HasCardType(cardType string) bool {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
     for _, c := range p.inventory {
         if c.Type == cardType {
             return true
@@ -105,12 +149,115 @@ func (p Player) HasCardType(cardType string) bool {
 
 // Método para remover uma carta do inventário (para jogar)
 func (p *Player) RemoveCard(cardType string) bool {
+    _, ok := p.TakeCard(cardType)
+    return ok
+}
+
+// Método para retirar uma carta do tipo informado, retornando a carta retirada
+func (p *Player) TakeCard(cardType string) (card.Card, bool) {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
     for i, c := range p.inventory {
         if c.Type == cardType {
             // Remove a carta do slice
             p.inventory = append(p.inventory[:i], p.inventory[i+1:]...)
-            return true
+            return c, true
         }
     }
-    return false
+    return card.Card{}, false
+}
+
+// Métodos para o contador de pity (pacotes seguidos sem carta épica)
+func (p *Player) GetPity(packType string) int {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+    return p.pity[packType]
+}
+
+// Registra a abertura de um pacote: zera o pity se veio épico, senão incrementa
+func (p *Player) RegisterPackOpened(packType string, gotEpic bool) {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+    p.registerPackOpened(packType, gotEpic)
+}
+
+func (p *Player) registerPackOpened(packType string, gotEpic bool) {
+    if p.pity == nil {
+        p.pity = make(map[string]int)
+    }
+    if gotEpic {
+        p.pity[packType] = 0
+    } else {
+        p.pity[packType]++
+    }
+}
+
+// Entrega as cartas de um pacote aberto, atualizando pity e sementes de uma vez só
+func (p *Player) ReceivePack(packType string, cards []card.Card, seeds card.SeedState) {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+    p.inventory = append(p.inventory, cards...)
+    p.seeds = seeds
+    p.registerPackOpened(packType, card.HasEpic(cards))
+}
+
+// Métodos para as sementes do sorteio de pacotes
+func (p *Player) GetSeeds() card.SeedState {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+    return p.seeds
+}
+
+func (p *Player) SetSeeds(seeds card.SeedState) {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+    p.seeds = seeds
+}
+
+// Dados do jogador em formato serializável (usado para salvar e restaurar o estado do servidor)
+type Record struct {
+    ID        int            `json:"id"`
+    UserName  string         `json:"user_name"`
+    Password  string         `json:"password"`
+    Wins      int            `json:"wins"`
+    Losses    int            `json:"losses"`
+    Coins     int            `json:"coins,omitempty"`
+    Inventory []card.Card    `json:"inventory"`
+    Pity      map[string]int `json:"pity,omitempty"`
+    Seeds     card.SeedState `json:"seeds"`
+}
+
+// Método para exportar os dados do jogador
+func (p *Player) Record() Record {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+    pity := make(map[string]int, len(p.pity))
+    for packType, count := range p.pity {
+        pity[packType] = count
+    }
+    return Record{
+        ID:        p.id,
+        UserName:  p.userName,
+        Password:  p.password,
+        Wins:      p.wins,
+        Losses:    p.losses,
+        Coins:     p.coins,
+        Inventory: append([]card.Card(nil), p.inventory...),
+        Pity:      pity,
+        Seeds:     p.seeds,
+    }
+}
+
+// Função para recriar um jogador a partir dos dados salvos
+func FromRecord(r Record) *Player {
+    p := NewPlayer(r.ID, r.UserName, r.Password)
+    p.wins = r.Wins
+    p.losses = r.Losses
+    p.coins = r.Coins
+    p.inventory = append(p.inventory, r.Inventory...)
+    for packType, count := range r.Pity {
+        p.pity[packType] = count
+    }
+    p.seeds = r.Seeds
+    return p
 }
\ No newline at end of file
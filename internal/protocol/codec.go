@@ -0,0 +1,234 @@
+package protocol
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Tamanho máximo padrão de um frame (sem o delimitador ou o prefixo de tamanho)
+const MAX_FRAME_SIZE = 64 * 1024
+
+// Frame maior que o limite do leitor. O frame é descartado por inteiro, então a
+// leitura pode continuar no próximo.
+var ErrFrameTooLarge = errors.New("frame maior que o limite")
+
+// Função para verificar se um erro de ReadMessage afeta só o frame lido (mensagem
+// malformada, tipo desconhecido ou frame grande demais) e a conexão pode continuar
+func IsFrameError(err error) bool {
+	return errors.Is(err, ErrInvalidMessage) || errors.Is(err, ErrUnknownType) || errors.Is(err, ErrFrameTooLarge)
+}
+
+// Codec de transporte: define como as mensagens são escritas e lidas na conexão.
+// As mensagens continuam sendo montadas em JSON (Encode, Create*); o codec só muda
+// o formato no fio. A conexão começa sempre em JSON e troca de codec depois do
+// WELCOME, se a capacidade correspondente for negociada.
+type Codec interface {
+	// Nome do codec (igual ao nome da capacidade negociada no HELLO)
+	Name() string
+	// Converte uma mensagem codificada em JSON (com ou sem '\n' no final) em um frame
+	Frame(data []byte) ([]byte, error)
+	// Converte uma mensagem já montada em um frame: o envelope não passa por JSON e
+	// o payload (message.Data) vai direto para o formato do codec
+	FrameMessage(message *Message) ([]byte, error)
+	// Cria um leitor de frames de até maxFrameSize bytes (0 = sem limite). O
+	// bufio.Reader é compartilhado entre codecs para que nenhum byte já lido se
+	// perca na troca de codec.
+	NewFrameReader(r *bufio.Reader, maxFrameSize int) FrameReader
+}
+
+// Leitor de frames de uma conexão
+type FrameReader interface {
+	// Lê a próxima mensagem. Erros para os quais IsFrameError é verdadeiro afetam
+	// só aquele frame e a leitura pode continuar; qualquer outro erro (io.EOF,
+	// erro de rede) encerra a conexão.
+	ReadMessage() (*Message, error)
+}
+
+// Codecs disponíveis
+var (
+	JSONCodec   Codec = jsonCodec{}
+	BinaryCodec Codec = binaryCodec{}
+)
+
+// Função para obter o codec pelo nome negociado
+func CodecByName(name string) (Codec, bool) {
+	switch name {
+	case JSONCodec.Name():
+		return JSONCodec, true
+	case BinaryCodec.Name():
+		return BinaryCodec, true
+	}
+	return nil, false
+}
+
+// Codec original: uma mensagem JSON por linha
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return "json" }
+
+func (jsonCodec) Frame(data []byte) ([]byte, error) {
+	data = bytes.TrimRight(data, "\r\n")
+	frame := make([]byte, len(data), len(data)+1)
+	copy(frame, data)
+	return append(frame, '\n'), nil
+}
+
+func (jsonCodec) FrameMessage(message *Message) ([]byte, error) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func (jsonCodec) NewFrameReader(r *bufio.Reader, maxFrameSize int) FrameReader {
+	return &jsonFrameReader{reader: r, maxFrameSize: maxFrameSize}
+}
+
+type jsonFrameReader struct {
+	reader       *bufio.Reader
+	maxFrameSize int
+}
+
+func (fr *jsonFrameReader) ReadMessage() (*Message, error) {
+	// Lê a linha em pedaços para não acumular mais que o limite na memória;
+	// uma linha grande demais é descartada até o '\n'
+	var line []byte
+	size := 0
+	tooLarge := false
+	for {
+		chunk, err := fr.reader.ReadSlice('\n')
+		size += len(chunk)
+		if fr.maxFrameSize > 0 && size > fr.maxFrameSize+2 { // +2: "\r\n" no final
+			tooLarge = true
+			line = nil
+		}
+		if !tooLarge {
+			line = append(line, chunk...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil && (err != io.EOF || size == 0) {
+			return nil, err
+		}
+		break
+	}
+
+	line = bytes.TrimRight(line, "\r\n")
+	if tooLarge || (fr.maxFrameSize > 0 && len(line) > fr.maxFrameSize) {
+		return nil, fmt.Errorf("%w: linha com mais de %d bytes", ErrFrameTooLarge, fr.maxFrameSize)
+	}
+	return DecodeMessage(line)
+}
+
+// Codec binário: frames com prefixo de tamanho (uint32 big-endian) e corpo no
+// formato MessagePack: um array [type, request_id, error_code, data].
+type binaryCodec struct{}
+
+func (binaryCodec) Name() string { return CAP_BINARY_FRAMES }
+
+func (binaryCodec) Frame(data []byte) ([]byte, error) {
+	// O envelope é convertido direto para MessagePack, sem decodificar a mensagem
+	frame, err := appendPackEnvelope(make([]byte, 4, 4+len(data)), data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+
+	binary.BigEndian.PutUint32(frame, uint32(len(frame)-4))
+	return frame, nil
+}
+
+func (binaryCodec) FrameMessage(message *Message) ([]byte, error) {
+	frame := make([]byte, 4, 8+len(message.Type)+len(message.RequestID)+len(message.ErrorCode)+len(message.Data))
+	frame = append(frame, 0x94) // fixarray de 4 elementos
+	frame = appendPackString(frame, message.Type)
+	frame = appendPackString(frame, message.RequestID)
+	frame = appendPackString(frame, message.ErrorCode)
+	if len(message.Data) == 0 {
+		frame = append(frame, packNil)
+	} else {
+		var err error
+		if frame, err = appendPackJSON(frame, message.Data); err != nil {
+			return nil, fmt.Errorf("%w: payload de %s: %v", ErrInvalidMessage, message.Type, err)
+		}
+	}
+
+	binary.BigEndian.PutUint32(frame, uint32(len(frame)-4))
+	return frame, nil
+}
+
+func (binaryCodec) NewFrameReader(r *bufio.Reader, maxFrameSize int) FrameReader {
+	return &binaryFrameReader{reader: r, maxFrameSize: maxFrameSize}
+}
+
+type binaryFrameReader struct {
+	reader       *bufio.Reader
+	maxFrameSize int
+}
+
+func (fr *binaryFrameReader) ReadMessage() (*Message, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(fr.reader, header[:]); err != nil {
+		return nil, err
+	}
+	size := int64(binary.BigEndian.Uint32(header[:]))
+
+	// Frame grande demais: descarta o corpo sem alocar e segue para o próximo
+	if fr.maxFrameSize > 0 && size > int64(fr.maxFrameSize) {
+		if _, err := io.CopyN(io.Discard, fr.reader, size); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %d bytes (limite %d)", ErrFrameTooLarge, size, fr.maxFrameSize)
+	}
+
+	body := make([]byte, size)
+	if _, err := io.ReadFull(fr.reader, body); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return decodeBinaryMessage(body)
+}
+
+// Decodifica o corpo de um frame binário
+func decodeBinaryMessage(body []byte) (*Message, error) {
+	d := packDecoder{data: body}
+	if count, err := d.arrayHeader(); err != nil || count != 4 {
+		return nil, fmt.Errorf("%w: envelope binário inválido", ErrInvalidMessage)
+	}
+
+	var message Message
+	var err error
+	if message.Type, err = d.string(); err != nil {
+		return nil, fmt.Errorf("%w: tipo: %v", ErrInvalidMessage, err)
+	}
+	if message.RequestID, err = d.string(); err != nil {
+		return nil, fmt.Errorf("%w: request_id: %v", ErrInvalidMessage, err)
+	}
+	if message.ErrorCode, err = d.string(); err != nil {
+		return nil, fmt.Errorf("%w: error_code: %v", ErrInvalidMessage, err)
+	}
+	if d.peekNil() {
+		d.pos++
+	} else if message.Data, err = d.appendJSON(nil); err != nil {
+		return nil, fmt.Errorf("%w: payload de %s: %v", ErrInvalidMessage, message.Type, err)
+	}
+	if d.pos != len(d.data) {
+		return nil, fmt.Errorf("%w: %d bytes sobrando no frame", ErrInvalidMessage, len(d.data)-d.pos)
+	}
+
+	if _, ok := PayloadType(message.Type); !ok {
+		return &message, fmt.Errorf("%w: %q", ErrUnknownType, message.Type)
+	}
+	return &message, nil
+}
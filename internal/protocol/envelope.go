@@ -0,0 +1,148 @@
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Erros de decodificação do envelope. O servidor usa errors.Is para escolher o
+// código de erro (INVALID_MESSAGE ou UNKNOWN_MESSAGE_TYPE) enviado ao cliente.
+var (
+	ErrInvalidMessage  = errors.New("mensagem inválida")
+	ErrUnknownType     = errors.New("tipo de mensagem desconhecido")
+	ErrPayloadMismatch = errors.New("payload incompatível com o tipo da mensagem")
+)
+
+// Registro dos tipos de mensagem: cada tipo tem exatamente uma struct de payload
+var (
+	registry      = make(map[string]reflect.Type)
+	registryMutex sync.RWMutex
+)
+
+func init() {
+	Register[LoginRequest](MSG_LOGIN_REQUEST)
+	Register[LoginResponse](MSG_LOGIN_RESPONSE)
+	Register[PingRequest](MSG_PING_REQUEST)
+	Register[PingResponse](MSG_PING_RESPONSE)
+	Register[RegisterRequest](MSG_REGISTER_REQUEST)
+	Register[RegisterResponse](MSG_REGISTER_RESPONSE)
+	Register[QueueRequest](MSG_QUEUE_REQUEST)
+	Register[QueueResponse](MSG_QUEUE_RESPONSE)
+	Register[MatchFound](MSG_MATCH_FOUND)
+	Register[MatchStart](MSG_MATCH_START)
+	Register[MatchEnd](MSG_MATCH_END)
+	Register[GameMove](MSG_GAME_MOVE)
+	Register[GameState](MSG_GAME_STATE)
+	Register[TurnUpdate](MSG_TURN_UPDATE)
+	Register[StatsRequest](MSG_STATS_REQUEST)
+	Register[StatsResponse](MSG_STATS_RESPONSE)
+	Register[CardPackRequest](MSG_CARD_PACK_REQUEST)
+	Register[CardPackResponse](MSG_CARD_PACK_RESPONSE)
+	Register[CardMove](MSG_CARD_MOVE)
+	Register[SeedRequest](MSG_SEED_REQUEST)
+	Register[SeedResponse](MSG_SEED_RESPONSE)
+	Register[ServerShutdown](MSG_SERVER_SHUTDOWN)
+	Register[ErrorInfo](MSG_ERROR)
+	Register[MoveRejected](MSG_MOVE_REJECTED)
+	Register[Hello](MSG_HELLO)
+	Register[Welcome](MSG_WELCOME)
+	Register[ChatMessage](MSG_CHAT_MESSAGE)
+	Register[InventoryRequest](MSG_INVENTORY_REQUEST)
+	Register[InventoryUpdate](MSG_INVENTORY_UPDATE)
+	Register[SessionState](MSG_SESSION_STATE)
+}
+
+// Função para registrar a struct de payload de um tipo de mensagem.
+// Registrar o mesmo tipo duas vezes é erro de programação e causa panic.
+func Register[T any](msgType string) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	if _, exists := registry[msgType]; exists {
+		panic(fmt.Sprintf("protocol: tipo de mensagem %s registrado duas vezes", msgType))
+	}
+	registry[msgType] = reflect.TypeFor[T]()
+}
+
+// Função para listar os tipos de mensagem registrados, em ordem alfabética
+func MessageTypes() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	types := make([]string, 0, len(registry))
+	for msgType := range registry {
+		types = append(types, msgType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Função para obter a struct de payload registrada para um tipo de mensagem
+func PayloadType(msgType string) (reflect.Type, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	payloadType, ok := registry[msgType]
+	return payloadType, ok
+}
+
+// Verifica se T é o payload registrado para o tipo de mensagem
+func checkPayload[T any](msgType string) error {
+	payloadType, ok := PayloadType(msgType)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownType, msgType)
+	}
+	if want := reflect.TypeFor[T](); payloadType != want {
+		return fmt.Errorf("%w: %s espera %s, recebido %s", ErrPayloadMismatch, msgType, payloadType, want)
+	}
+	return nil
+}
+
+// Função para montar o envelope de uma mensagem com o payload já codificado
+func NewMessage[T any](msgType string, payload T) (*Message, error) {
+	if err := checkPayload[T](msgType); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &Message{Type: msgType, Data: data}, nil
+}
+
+// Função para codificar uma mensagem completa (envelope + payload)
+func Encode[T any](msgType string, payload T) ([]byte, error) {
+	message, err := NewMessage(msgType, payload)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(message)
+}
+
+// Função para decodificar o payload de uma mensagem. O payload é lido direto dos
+// bytes recebidos (json.RawMessage), sem passar por interface{}.
+func Decode[T any](message *Message) (*T, error) {
+	if err := checkPayload[T](message.Type); err != nil {
+		return nil, err
+	}
+
+	var payload T
+	if len(message.Data) > 0 {
+		if err := json.Unmarshal(message.Data, &payload); err != nil {
+			return nil, fmt.Errorf("%w: payload de %s: %v", ErrInvalidMessage, message.Type, err)
+		}
+	}
+	return &payload, nil
+}
+
+// Função para codificar uma mensagem recém-montada, repassando o erro da montagem
+func marshalMessage(message *Message, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(message)
+}
@@ -0,0 +1,52 @@
+package protocol
+
+// Códigos de erro estáveis enviados no campo error_code do envelope.
+// As mensagens em português continuam no campo Message das respostas e podem mudar;
+// clientes devem decidir o que fazer a partir do código.
+const (
+	// Erros de protocolo (mensagem ERROR)
+	ERR_INVALID_MESSAGE      = "INVALID_MESSAGE"      // Mensagem que não pôde ser decodificada
+	ERR_UNKNOWN_MESSAGE_TYPE = "UNKNOWN_MESSAGE_TYPE" // Tipo de mensagem não suportado pelo servidor
+	ERR_INTERNAL             = "INTERNAL_ERROR"       // Falha inesperada no servidor
+	ERR_FRAME_TOO_LARGE      = "FRAME_TOO_LARGE"      // Mensagem maior que o limite do servidor
+	ERR_TOO_MANY_VIOLATIONS  = "TOO_MANY_VIOLATIONS"  // Conexão encerrada por excesso de mensagens inválidas
+	ERR_RATE_LIMITED         = "RATE_LIMITED"         // Requisições demais; ver retry_after_ms
+
+	// Handshake (mensagem WELCOME)
+	ERR_UNSUPPORTED_VERSION    = "UNSUPPORTED_VERSION"    // Versão do protocolo do cliente não é aceita
+	ERR_HANDSHAKE_OUT_OF_ORDER = "HANDSHAKE_OUT_OF_ORDER" // HELLO só pode ser a primeira mensagem da conexão
+
+	// Cadastro e login
+	ERR_USERNAME_TAKEN      = "USERNAME_TAKEN"
+	ERR_USERNAME_TOO_SHORT  = "USERNAME_TOO_SHORT"
+	ERR_PASSWORD_TOO_SHORT  = "PASSWORD_TOO_SHORT"
+	ERR_INVALID_CREDENTIALS = "INVALID_CREDENTIALS"
+	ERR_ALREADY_CONNECTED   = "ALREADY_CONNECTED"
+	ERR_NOT_LOGGED_IN       = "NOT_LOGGED_IN"
+	ERR_USER_NOT_FOUND      = "USER_NOT_FOUND"
+	ERR_LOGIN_BACKOFF       = "LOGIN_BACKOFF"  // Tentativa cedo demais depois de uma falha; ver retry_after_ms
+	ERR_ACCOUNT_LOCKED      = "ACCOUNT_LOCKED" // Bloqueio temporário após muitas falhas; ver retry_after_ms
+
+	// Fila de partidas
+	ERR_ALREADY_IN_QUEUE     = "ALREADY_IN_QUEUE"
+	ERR_ALREADY_IN_MATCH     = "ALREADY_IN_MATCH"
+	ERR_NO_CARDS             = "NO_CARDS"
+	ERR_SERVER_SHUTTING_DOWN = "SERVER_SHUTTING_DOWN"
+
+	// Pacotes e sementes
+	ERR_INVALID_PACK_TYPE   = "INVALID_PACK_TYPE"
+	ERR_OUT_OF_STOCK        = "OUT_OF_STOCK"
+	ERR_INVENTORY_NOT_EMPTY = "INVENTORY_NOT_EMPTY" // Só é possível abrir um pacote com o inventário vazio
+
+	// Jogadas (mensagem MOVE_REJECTED)
+	ERR_MATCH_NOT_FOUND       = "MATCH_NOT_FOUND"
+	ERR_MATCH_NOT_IN_PROGRESS = "MATCH_NOT_IN_PROGRESS"
+	ERR_NOT_IN_MATCH          = "NOT_IN_MATCH"
+	ERR_NOT_YOUR_TURN         = "NOT_YOUR_TURN"
+	ERR_ALREADY_PLAYED        = "ALREADY_PLAYED"
+	ERR_INVALID_CARD          = "INVALID_CARD"
+	ERR_CARD_NOT_OWNED        = "CARD_NOT_OWNED"
+
+	// Chat da partida (mensagem ERROR)
+	ERR_INVALID_CHAT = "INVALID_CHAT" // Texto vazio ou maior que MAX_CHAT_LENGTH
+)
@@ -0,0 +1,659 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Subconjunto do MessagePack usado pelo codec binário: nil, bool, inteiros, float64,
+// strings, arrays e maps com chaves string. Os payloads continuam sendo structs
+// serializadas em JSON, então o codec converte JSON <-> MessagePack direto nos
+// bytes, sem montar valores intermediários.
+
+// Marcadores do MessagePack
+const (
+	packNil     = 0xc0
+	packFalse   = 0xc2
+	packTrue    = 0xc3
+	packFloat64 = 0xcb
+	packUint8   = 0xcc
+	packUint16  = 0xcd
+	packUint32  = 0xce
+	packUint64  = 0xcf
+	packInt8    = 0xd0
+	packInt16   = 0xd1
+	packInt32   = 0xd2
+	packInt64   = 0xd3
+	packStr8    = 0xd9
+	packStr16   = 0xda
+	packStr32   = 0xdb
+	packArray16 = 0xdc
+	packArray32 = 0xdd
+	packMap16   = 0xde
+	packMap32   = 0xdf
+)
+
+// Profundidade máxima de aninhamento aceita nos dois sentidos da conversão
+const maxPackDepth = 32
+
+var errPackTruncated = errors.New("dados truncados")
+
+// Função para escrever uma string no formato MessagePack
+func appendPackString[T string | []byte](b []byte, s T) []byte {
+	n := len(s)
+	switch {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, packStr8, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, packStr16)
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b = append(b, packStr32)
+		b = binary.BigEndian.AppendUint32(b, uint32(n))
+	}
+	return append(b, s...)
+}
+
+// Função para escrever um inteiro no menor formato MessagePack que o comporta
+func appendPackInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0 && v <= 0x7f:
+		return append(b, byte(v))
+	case v >= -32 && v < 0:
+		return append(b, byte(v)) // fixint negativo (0xe0-0xff)
+	case v >= 0 && v <= math.MaxUint8:
+		return append(b, packUint8, byte(v))
+	case v >= 0 && v <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, packUint16), uint16(v))
+	case v >= 0 && v <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, packUint32), uint32(v))
+	case v >= 0:
+		return binary.BigEndian.AppendUint64(append(b, packUint64), uint64(v))
+	case v >= math.MinInt8:
+		return append(b, packInt8, byte(v))
+	case v >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, packInt16), uint16(v))
+	case v >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, packInt32), uint32(v))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, packInt64), uint64(v))
+	}
+}
+
+// Função para converter um valor JSON em MessagePack
+func appendPackJSON(b []byte, data []byte) ([]byte, error) {
+	p := jsonPacker{data: data}
+	b, err := p.value(b, 0)
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos != len(p.data) {
+		return nil, fmt.Errorf("conteúdo inesperado na posição %d", p.pos)
+	}
+	return b, nil
+}
+
+// Função para converter o envelope JSON de uma mensagem direto no array MessagePack
+// [type, request_id, error_code, data], numa única passada pelos bytes. O payload é
+// convertido no próprio buffer, na ordem em que aparece; os campos do envelope são
+// inseridos na frente dele no final.
+func appendPackEnvelope(b []byte, data []byte) ([]byte, error) {
+	p := jsonPacker{data: data}
+	var fields [3][]byte // type, request_id, error_code
+	start := len(b)
+	hasData := false
+
+	p.skipSpace()
+	if p.pos >= len(p.data) || p.data[p.pos] != '{' {
+		return nil, errors.New("envelope deve ser um objeto")
+	}
+	p.pos++
+	p.skipSpace()
+	if p.pos < len(p.data) && p.data[p.pos] == '}' {
+		p.pos++
+	} else {
+		for {
+			p.skipSpace()
+			if p.pos >= len(p.data) || p.data[p.pos] != '"' {
+				return nil, fmt.Errorf("chave esperada na posição %d", p.pos)
+			}
+			key, err := p.stringBytes()
+			if err != nil {
+				return nil, err
+			}
+			p.skipSpace()
+			if p.pos >= len(p.data) || p.data[p.pos] != ':' {
+				return nil, fmt.Errorf("':' esperado na posição %d", p.pos)
+			}
+			p.pos++
+			p.skipSpace()
+
+			field := -1
+			switch string(key) {
+			case "type":
+				field = 0
+			case "request_id":
+				field = 1
+			case "error_code":
+				field = 2
+			}
+			switch {
+			case field >= 0:
+				if fields[field], err = p.envelopeString(); err != nil {
+					return nil, fmt.Errorf("%s: %v", key, err)
+				}
+			case string(key) == "data":
+				// Como no encoding/json, a última ocorrência da chave prevalece
+				b, hasData = b[:start], true
+				if b, err = p.value(b, 1); err != nil {
+					return nil, fmt.Errorf("payload de %s: %v", fields[0], err)
+				}
+			default:
+				// Chave desconhecida: valida e descarta o valor
+				if _, err = p.value(nil, 1); err != nil {
+					return nil, err
+				}
+			}
+
+			p.skipSpace()
+			if p.pos >= len(p.data) {
+				return nil, errPackTruncated
+			}
+			if p.data[p.pos] == '}' {
+				p.pos++
+				break
+			}
+			if p.data[p.pos] != ',' {
+				return nil, fmt.Errorf("',' esperado na posição %d", p.pos)
+			}
+			p.pos++
+		}
+	}
+	p.skipSpace()
+	if p.pos != len(p.data) {
+		return nil, fmt.Errorf("conteúdo inesperado na posição %d", p.pos)
+	}
+	if !hasData {
+		b = append(b, packNil)
+	}
+
+	// Abre espaço antes do payload e escreve o cabeçalho do array e os campos
+	var headerBuffer [64]byte
+	header := append(headerBuffer[:0], 0x94) // fixarray de 4 elementos
+	for _, field := range fields {
+		header = appendPackString(header, field)
+	}
+	payloadEnd := len(b)
+	b = append(b, header...)
+	copy(b[start+len(header):], b[start:payloadEnd])
+	copy(b[start:], header)
+	return b, nil
+}
+
+// Conversor JSON -> MessagePack
+type jsonPacker struct {
+	data []byte
+	pos  int
+}
+
+func (p *jsonPacker) skipSpace() {
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case ' ', '\t', '\r', '\n':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *jsonPacker) value(b []byte, depth int) ([]byte, error) {
+	if depth > maxPackDepth {
+		return nil, errors.New("aninhamento excessivo")
+	}
+	p.skipSpace()
+	if p.pos >= len(p.data) {
+		return nil, errPackTruncated
+	}
+
+	switch c := p.data[p.pos]; {
+	case c == '{':
+		return p.container(b, depth, '}', 0x80, packMap16, packMap32)
+	case c == '[':
+		return p.container(b, depth, ']', 0x90, packArray16, packArray32)
+	case c == '"':
+		return p.string(b)
+	case c == 't':
+		return p.literal(b, "true", packTrue)
+	case c == 'f':
+		return p.literal(b, "false", packFalse)
+	case c == 'n':
+		return p.literal(b, "null", packNil)
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.number(b)
+	default:
+		return nil, fmt.Errorf("caractere inesperado %q na posição %d", c, p.pos)
+	}
+}
+
+// Converte um objeto ou array. O cabeçalho só é conhecido depois de contar os
+// elementos: reserva 1 byte (fixmap/fixarray) e abre espaço se precisar de mais.
+func (p *jsonPacker) container(b []byte, depth int, end byte, fix, header16, header32 byte) ([]byte, error) {
+	p.pos++ // '{' ou '['
+	headerPos := len(b)
+	b = append(b, fix)
+
+	isMap := end == '}'
+	count := 0
+	p.skipSpace()
+	if p.pos < len(p.data) && p.data[p.pos] == end {
+		p.pos++
+	} else {
+		for {
+			var err error
+			if isMap {
+				p.skipSpace()
+				if p.pos >= len(p.data) || p.data[p.pos] != '"' {
+					return nil, fmt.Errorf("chave esperada na posição %d", p.pos)
+				}
+				if b, err = p.string(b); err != nil {
+					return nil, err
+				}
+				p.skipSpace()
+				if p.pos >= len(p.data) || p.data[p.pos] != ':' {
+					return nil, fmt.Errorf("':' esperado na posição %d", p.pos)
+				}
+				p.pos++
+			}
+			if b, err = p.value(b, depth+1); err != nil {
+				return nil, err
+			}
+			count++
+
+			p.skipSpace()
+			if p.pos >= len(p.data) {
+				return nil, errPackTruncated
+			}
+			if p.data[p.pos] == end {
+				p.pos++
+				break
+			}
+			if p.data[p.pos] != ',' {
+				return nil, fmt.Errorf("',' esperado na posição %d", p.pos)
+			}
+			p.pos++
+		}
+	}
+
+	switch {
+	case count < 16:
+		b[headerPos] = fix | byte(count)
+	case count <= math.MaxUint16:
+		b = append(b, 0, 0)
+		copy(b[headerPos+3:], b[headerPos+1:len(b)-2])
+		b[headerPos] = header16
+		binary.BigEndian.PutUint16(b[headerPos+1:], uint16(count))
+	default:
+		b = append(b, 0, 0, 0, 0)
+		copy(b[headerPos+5:], b[headerPos+1:len(b)-4])
+		b[headerPos] = header32
+		binary.BigEndian.PutUint32(b[headerPos+1:], uint32(count))
+	}
+	return b, nil
+}
+
+func (p *jsonPacker) string(b []byte) ([]byte, error) {
+	s, err := p.stringBytes()
+	if err != nil {
+		return nil, err
+	}
+	return appendPackString(b, s), nil
+}
+
+// Lê uma string JSON e retorna o conteúdo. Sem escapes o resultado aponta para os
+// próprios bytes de entrada, sem cópia.
+func (p *jsonPacker) stringBytes() ([]byte, error) {
+	start := p.pos
+	rest := p.data[start+1:]
+	if end := bytes.IndexByte(rest, '"'); end >= 0 && bytes.IndexByte(rest[:end], '\\') < 0 {
+		p.pos = start + 1 + end + 1
+		return rest[:end], nil
+	}
+
+	// Strings com escapes são raras no protocolo: acha o fim e deixa o encoding/json resolver
+	for i := start + 1; i < len(p.data); i++ {
+		switch p.data[i] {
+		case '\\':
+			i++
+		case '"':
+			p.pos = i + 1
+			var s string
+			if err := json.Unmarshal(p.data[start:i+1], &s); err != nil {
+				return nil, err
+			}
+			return []byte(s), nil
+		}
+	}
+	return nil, errPackTruncated
+}
+
+// Lê um campo string do envelope; null equivale a vazio, como no encoding/json
+func (p *jsonPacker) envelopeString() ([]byte, error) {
+	if p.pos < len(p.data) && p.data[p.pos] == 'n' {
+		_, err := p.literal(nil, "null", packNil)
+		return nil, err
+	}
+	if p.pos >= len(p.data) || p.data[p.pos] != '"' {
+		return nil, errors.New("string esperada")
+	}
+	return p.stringBytes()
+}
+
+func (p *jsonPacker) literal(b []byte, literal string, marker byte) ([]byte, error) {
+	end := p.pos + len(literal)
+	if end > len(p.data) || string(p.data[p.pos:end]) != literal {
+		return nil, fmt.Errorf("literal inválido na posição %d", p.pos)
+	}
+	p.pos = end
+	return append(b, marker), nil
+}
+
+func (p *jsonPacker) number(b []byte) ([]byte, error) {
+	start := p.pos
+	integer := true
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if c == '.' || c == 'e' || c == 'E' {
+			integer = false
+		} else if c != '-' && c != '+' && (c < '0' || c > '9') {
+			break
+		}
+		p.pos++
+	}
+	text := p.data[start:p.pos]
+
+	// Inteiros pequenos (o caso comum: IDs, contadores) sem passar por strconv
+	if v, ok := parseSmallInt(text); integer && ok {
+		return appendPackInt(b, v), nil
+	}
+	if integer {
+		if v, err := strconv.ParseInt(string(text), 10, 64); err == nil {
+			return appendPackInt(b, v), nil
+		}
+	}
+	f, err := strconv.ParseFloat(string(text), 64)
+	if err != nil {
+		return nil, fmt.Errorf("número inválido %q", text)
+	}
+	return binary.BigEndian.AppendUint64(append(b, packFloat64), math.Float64bits(f)), nil
+}
+
+// Converte um inteiro decimal de até 18 dígitos no formato do JSON (sem zeros à
+// esquerda nem '+'); qualquer outra forma fica para o strconv
+func parseSmallInt(text []byte) (int64, bool) {
+	negative := len(text) > 0 && text[0] == '-'
+	digits := text
+	if negative {
+		digits = text[1:]
+	}
+	if len(digits) == 0 || len(digits) > 18 || (len(digits) > 1 && digits[0] == '0') {
+		return 0, false
+	}
+	var v int64
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		v = v*10 + int64(c-'0')
+	}
+	if negative {
+		v = -v
+	}
+	return v, true
+}
+
+// Leitor de MessagePack que converte os valores de volta para JSON
+type packDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *packDecoder) next(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.pos < n {
+		return nil, errPackTruncated
+	}
+	chunk := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return chunk, nil
+}
+
+func (d *packDecoder) byte() (byte, error) {
+	chunk, err := d.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return chunk[0], nil
+}
+
+// Lê um tamanho de 1, 2 ou 4 bytes
+func (d *packDecoder) length(size int) (int, error) {
+	chunk, err := d.next(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return int(chunk[0]), nil
+	case 2:
+		return int(binary.BigEndian.Uint16(chunk)), nil
+	default:
+		return int(binary.BigEndian.Uint32(chunk)), nil
+	}
+}
+
+func (d *packDecoder) peekNil() bool {
+	return d.pos < len(d.data) && d.data[d.pos] == packNil
+}
+
+func (d *packDecoder) arrayHeader() (int, error) {
+	c, err := d.byte()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case c&0xf0 == 0x90:
+		return int(c & 0x0f), nil
+	case c == packArray16:
+		return d.length(2)
+	case c == packArray32:
+		return d.length(4)
+	}
+	return 0, fmt.Errorf("array esperado, recebido 0x%02x", c)
+}
+
+func (d *packDecoder) stringBytes() ([]byte, error) {
+	c, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	var n int
+	switch {
+	case c&0xe0 == 0xa0:
+		n = int(c & 0x1f)
+	case c == packStr8:
+		n, err = d.length(1)
+	case c == packStr16:
+		n, err = d.length(2)
+	case c == packStr32:
+		n, err = d.length(4)
+	default:
+		return nil, fmt.Errorf("string esperada, recebido 0x%02x", c)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d.next(n)
+}
+
+func (d *packDecoder) string() (string, error) {
+	s, err := d.stringBytes()
+	return string(s), err
+}
+
+// Converte o próximo valor para JSON
+func (d *packDecoder) appendJSON(dst []byte) ([]byte, error) {
+	return d.appendValue(dst, 0)
+}
+
+func (d *packDecoder) appendValue(dst []byte, depth int) ([]byte, error) {
+	if depth > maxPackDepth {
+		return nil, errors.New("aninhamento excessivo")
+	}
+	if d.pos >= len(d.data) {
+		return nil, errPackTruncated
+	}
+
+	c := d.data[d.pos]
+	switch {
+	case c <= 0x7f:
+		d.pos++
+		return strconv.AppendInt(dst, int64(c), 10), nil
+	case c >= 0xe0:
+		d.pos++
+		return strconv.AppendInt(dst, int64(int8(c)), 10), nil
+	case c&0xf0 == 0x80 || c == packMap16 || c == packMap32:
+		return d.appendMap(dst, depth)
+	case c&0xf0 == 0x90 || c == packArray16 || c == packArray32:
+		count, err := d.arrayHeader()
+		if err != nil {
+			return nil, err
+		}
+		dst = append(dst, '[')
+		for i := 0; i < count; i++ {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			if dst, err = d.appendValue(dst, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return append(dst, ']'), nil
+	case c&0xe0 == 0xa0 || c == packStr8 || c == packStr16 || c == packStr32:
+		s, err := d.stringBytes()
+		if err != nil {
+			return nil, err
+		}
+		return appendJSONString(dst, s), nil
+	}
+
+	d.pos++
+	switch c {
+	case packNil:
+		return append(dst, "null"...), nil
+	case packFalse:
+		return append(dst, "false"...), nil
+	case packTrue:
+		return append(dst, "true"...), nil
+	case packFloat64:
+		chunk, err := d.next(8)
+		if err != nil {
+			return nil, err
+		}
+		f := math.Float64frombits(binary.BigEndian.Uint64(chunk))
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, errors.New("float inválido em JSON")
+		}
+		return strconv.AppendFloat(dst, f, 'g', -1, 64), nil
+	case packUint8, packUint16, packUint32, packUint64:
+		size := 1 << (c - packUint8)
+		chunk, err := d.next(size)
+		if err != nil {
+			return nil, err
+		}
+		return strconv.AppendUint(dst, readUint(chunk), 10), nil
+	case packInt8, packInt16, packInt32, packInt64:
+		size := 1 << (c - packInt8)
+		chunk, err := d.next(size)
+		if err != nil {
+			return nil, err
+		}
+		v := readUint(chunk)
+		shift := 64 - 8*size
+		return strconv.AppendInt(dst, int64(v<<shift)>>shift, 10), nil
+	}
+	return nil, fmt.Errorf("marcador não suportado 0x%02x", c)
+}
+
+func (d *packDecoder) appendMap(dst []byte, depth int) ([]byte, error) {
+	c, _ := d.byte()
+	var count int
+	var err error
+	switch {
+	case c&0xf0 == 0x80:
+		count = int(c & 0x0f)
+	case c == packMap16:
+		count, err = d.length(2)
+	default:
+		count, err = d.length(4)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dst = append(dst, '{')
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		key, err := d.stringBytes()
+		if err != nil {
+			return nil, fmt.Errorf("chave: %v", err)
+		}
+		dst = appendJSONString(dst, key)
+		dst = append(dst, ':')
+		if dst, err = d.appendValue(dst, depth+1); err != nil {
+			return nil, err
+		}
+	}
+	return append(dst, '}'), nil
+}
+
+func readUint(chunk []byte) uint64 {
+	switch len(chunk) {
+	case 1:
+		return uint64(chunk[0])
+	case 2:
+		return uint64(binary.BigEndian.Uint16(chunk))
+	case 4:
+		return uint64(binary.BigEndian.Uint32(chunk))
+	default:
+		return binary.BigEndian.Uint64(chunk)
+	}
+}
+
+// Escreve uma string JSON; só recorre ao encoding/json quando há escapes ou UTF-8 inválido
+func appendJSONString(dst []byte, s []byte) []byte {
+	plain := utf8.Valid(s)
+	for _, c := range s {
+		if c < 0x20 || c == '"' || c == '\\' {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		dst = append(dst, '"')
+		dst = append(dst, s...)
+		return append(dst, '"')
+	}
+	quoted, _ := json.Marshal(string(s))
+	return append(dst, quoted...)
+}
@@ -2,6 +2,8 @@ package protocol
 
 import (
 	"encoding/json"
+	"fmt"
+	"time"
 )
 
 // Tipos de mensagens do protocolo
@@ -25,17 +27,61 @@ const (
 	MSG_CARD_PACK_REQUEST  = "CARD_PACK_REQUEST"
 	MSG_CARD_PACK_RESPONSE = "CARD_PACK_RESPONSE"
 	MSG_CARD_MOVE         = "CARD_MOVE"
+	MSG_SEED_REQUEST      = "SEED_REQUEST"
+	MSG_SEED_RESPONSE     = "SEED_RESPONSE"
+	MSG_SERVER_SHUTDOWN   = "SERVER_SHUTDOWN"
+	MSG_ERROR             = "ERROR"
+	MSG_MOVE_REJECTED     = "MOVE_REJECTED"
+	MSG_HELLO             = "HELLO"
+	MSG_WELCOME           = "WELCOME"
+	MSG_CHAT_MESSAGE      = "CHAT_MESSAGE"
+	MSG_INVENTORY_REQUEST = "INVENTORY_REQUEST"
+	MSG_INVENTORY_UPDATE  = "INVENTORY_UPDATE"
+	MSG_SESSION_STATE     = "SESSION_STATE"
 )
 
+// Versões do protocolo. A versão 1 é a do protocolo original, sem HELLO: conexões
+// que não enviam HELLO são tratadas como versão 1, sem nenhuma capacidade extra.
+const (
+	PROTOCOL_VERSION     = 2 // Versão falada por este código
+	MIN_PROTOCOL_VERSION = 1 // Versão mais antiga ainda aceita pelo servidor
+)
+
+// Capacidades negociadas no HELLO/WELCOME. O servidor só usa numa conexão as
+// capacidades que o cliente anunciou e que ele próprio suporta.
+const (
+	CAP_REQUEST_ID    = "request_id"    // Respostas repetem o request_id da requisição
+	CAP_ERROR_CODES   = "error_codes"   // Mensagens ERROR e MOVE_REJECTED com error_code
+	CAP_BINARY_FRAMES = "binary_frames" // Frames binários depois do WELCOME (BinaryCodec)
+	CAP_CHAT          = "chat"          // Mensagens de chat entre os jogadores da partida
+	CAP_INVENTORY     = "inventory"     // INVENTORY_UPDATE enviado sempre que o inventário muda
+	CAP_SESSION_STATE = "session_state" // SESSION_STATE com o estado do jogador antes da LOGIN_RESPONSE
+)
+
+// Motivos de um INVENTORY_UPDATE
+const (
+	INVENTORY_REQUESTED      = "request"        // Resposta a INVENTORY_REQUEST
+	INVENTORY_PACK_OPENED    = "pack_opened"    // Cartas de um pacote entraram no inventário
+	INVENTORY_CARD_PLAYED    = "card_played"    // Uma carta saiu do inventário numa jogada
+	INVENTORY_CARDS_RETURNED = "cards_returned" // Cartas jogadas voltaram (partida interrompida)
+)
+
+// Tamanho máximo do texto de uma mensagem de chat, em caracteres
+const MAX_CHAT_LENGTH = 200
+
 // Estrutura base para todas as mensagens
 type Message struct {
-	Type string      `json:"type"`
-	Data interface{} `json:"data"`
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`                 // Payload codificado; ver Decode e o registro de tipos
+	RequestID string          `json:"request_id,omitempty"` // Opcional: repetido pelo servidor na resposta
+	ErrorCode string          `json:"error_code,omitempty"` // Código estável do erro (ver errors.go); vazio = sucesso
 }
 
 // Estrutura para requisição de pacote de cartas
 type CardPackRequest struct {
-	UserID int `json:"user_id"`
+	UserID   int    `json:"user_id"`
+	PackType   string `json:"pack_type,omitempty"`   // Vazio = pacote padrão
+	ClientSeed string `json:"client_seed,omitempty"` // Se informado, passa a ser a semente do jogador
 }
 
 
@@ -43,8 +89,53 @@ type CardPackRequest struct {
 type CardPackResponse struct {
 	Success   bool        `json:"success"`
 	Message   string      `json:"message"`
+	PackType  string      `json:"pack_type,omitempty"`
 	Cards     []CardInfo  `json:"cards,omitempty"`
 	StockInfo StockInfo   `json:"stock_info,omitempty"`
+	Pity      PityInfo    `json:"pity"`
+	Fairness  FairnessInfo `json:"fairness"`
+}
+
+// Estrutura com os dados para verificar um pacote depois da revelação da semente
+type FairnessInfo struct {
+	ServerSeedHash string    `json:"server_seed_hash"`
+	ClientSeed     string    `json:"client_seed"`
+	Nonce          int       `json:"nonce"`
+	StockBefore    StockInfo `json:"stock_before"` // Estoque usado no sorteio; informado pelo servidor, fora do compromisso
+}
+
+// Estrutura para requisição de sementes (consulta, troca da semente do cliente ou rotação)
+type SeedRequest struct {
+	UserID     int    `json:"user_id"`
+	ClientSeed string `json:"client_seed,omitempty"`
+	Rotate     bool   `json:"rotate,omitempty"` // Revela a semente atual e gera uma nova
+}
+
+// Estrutura para resposta de sementes
+type SeedResponse struct {
+	Success        bool          `json:"success"`
+	Message        string        `json:"message"`
+	ServerSeedHash string        `json:"server_seed_hash,omitempty"`
+	ClientSeed     string        `json:"client_seed,omitempty"`
+	Nonce          int           `json:"nonce"`
+	Revealed       *RevealedSeed `json:"revealed,omitempty"` // Apenas após rotação
+}
+
+// Estrutura para a semente revelada na rotação
+type RevealedSeed struct {
+	ServerSeed     string `json:"server_seed"`
+	ServerSeedHash string `json:"server_seed_hash"`
+	ClientSeed     string `json:"client_seed"`
+	Nonce          int    `json:"nonce"`
+}
+
+// Estrutura para o status de pity do jogador (épico garantido após N pacotes)
+type PityInfo struct {
+	PackType         string `json:"pack_type"`
+	PacksWithoutEpic int    `json:"packs_without_epic"`
+	Threshold        int    `json:"threshold"`           // 0 = pacote sem pity
+	PacksUntilEpic   int    `json:"packs_until_epic"`    // Pacotes até o épico garantido
+	Triggered        bool   `json:"triggered,omitempty"` // Se o pity garantiu o épico deste pacote
 }
 
 // Estrutura para informações de carta
@@ -102,9 +193,12 @@ type LoginRequest struct {
 
 // Estrutura para resposta de login
 type LoginResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message"`
-	UserID  int    `json:"user_id,omitempty"` // Apenas se login bem-sucedido
+	Success      bool   `json:"success"`
+	Message      string `json:"message"`
+	UserID       int    `json:"user_id,omitempty"`        // Apenas se login bem-sucedido
+	RetryAfterMs int64  `json:"retry_after_ms,omitempty"` // Espera até a próxima tentativa (muitas falhas)
+
+	ServerSeedHash string `json:"server_seed_hash,omitempty"` // Compromisso da semente do sorteio, publicado antes de qualquer pacote
 }
 
 // Estrutura para requisição de cadastro
@@ -154,6 +248,97 @@ type MatchEnd struct {
 	Message    string `json:"message"`
 }
 
+// Estrutura para o aviso de encerramento do servidor
+type ServerShutdown struct {
+	Message  string    `json:"message"`
+	Deadline time.Time `json:"deadline,omitempty"` // Prazo para as partidas em andamento terminarem
+}
+
+// Estrutura para erros de protocolo (o código vai no error_code do envelope)
+type ErrorInfo struct {
+	Message      string `json:"message"`
+	RetryAfterMs int64  `json:"retry_after_ms,omitempty"` // RATE_LIMITED: espera sugerida antes de repetir
+}
+
+// Estrutura para jogada recusada pelo servidor. Não altera o turno: o jogador
+// continua podendo jogar (o código vai no error_code do envelope).
+type MoveRejected struct {
+	MatchID  int    `json:"match_id"`
+	CardType string `json:"card_type"`
+	Message  string `json:"message"`
+}
+
+// Estrutura para o início da conexão: o cliente anuncia versão e capacidades
+type Hello struct {
+	ProtocolVersion int      `json:"protocol_version"`
+	ClientName      string   `json:"client_name"`
+	ClientVersion   string   `json:"client_version"`
+	Capabilities    []string `json:"capabilities,omitempty"`
+}
+
+// Estrutura para a resposta do servidor ao HELLO
+type Welcome struct {
+	Accepted           bool     `json:"accepted"`
+	Message            string   `json:"message"`
+	ProtocolVersion    int      `json:"protocol_version"`     // Versão falada pelo servidor
+	MinProtocolVersion int      `json:"min_protocol_version"` // Versão mais antiga aceita
+	ServerName         string   `json:"server_name"`
+	ServerVersion      string   `json:"server_version"`
+	Capabilities       []string `json:"capabilities,omitempty"` // Capacidades ativas nesta conexão
+}
+
+// Estrutura para mensagem de chat da partida. O cliente envia UserID, MatchID e
+// Text; o servidor repassa ao oponente com FromID e FromName preenchidos.
+type ChatMessage struct {
+	UserID   int    `json:"user_id,omitempty"`
+	MatchID  int    `json:"match_id"`
+	FromID   int    `json:"from_id,omitempty"`
+	FromName string `json:"from_name,omitempty"`
+	Text     string `json:"text"`
+}
+
+// Estrutura para requisição do inventário
+type InventoryRequest struct {
+	UserID int `json:"user_id"`
+}
+
+// Estrutura para o inventário do jogador, mantido pelo servidor. É a resposta a
+// INVENTORY_REQUEST e também é enviada sem pedido quando o inventário muda.
+type InventoryUpdate struct {
+	Success bool       `json:"success"`
+	Message string     `json:"message"`
+	Cards   []CardInfo `json:"cards"`
+	Reason  string     `json:"reason,omitempty"` // Um dos INVENTORY_*
+}
+
+// Estrutura para o estado completo do jogador, enviado no login para o cliente
+// montar a tela do zero (inclusive ao refazer o login depois de uma queda)
+type SessionState struct {
+	UserID    int            `json:"user_id"`
+	UserName  string         `json:"username"`
+	Inventory []CardInfo     `json:"inventory"`
+	Wins      int            `json:"wins"`
+	Losses    int            `json:"losses"`
+	WinRate   float64        `json:"win_rate"`
+	Coins     int            `json:"coins"`
+	InQueue   bool           `json:"in_queue"`
+	QueueSize int            `json:"queue_size,omitempty"` // Apenas se InQueue
+	Match     *MatchSnapshot `json:"match,omitempty"`      // Partida ainda não finalizada do jogador
+
+	ServerSeedHash string `json:"server_seed_hash"` // Compromisso da semente do sorteio (ver LoginResponse)
+}
+
+// Estrutura para a situação de uma partida em andamento vista por um dos jogadores
+type MatchSnapshot struct {
+	MatchID        int    `json:"match_id"`
+	OpponentID     int    `json:"opponent_id"`
+	OpponentName   string `json:"opponent_name"`
+	Status         string `json:"status"` // "waiting" ou "playing"
+	YourTurn       bool   `json:"your_turn"`
+	PlayedCard     string `json:"played_card,omitempty"` // Carta já jogada pelo jogador
+	OpponentPlayed bool   `json:"opponent_played"`
+}
+
 // Estrutura para jogada do jogo
 type GameMove struct {
 	UserID   int    `json:"user_id"`
@@ -183,43 +368,38 @@ func CreateStatsRequest(userID int) ([]byte, error) {
 		UserID: userID,
 	}
 
-	message := Message{
-		Type: MSG_STATS_REQUEST,
-		Data: statsReq,
-	}
-
-	return json.Marshal(message)
+	return Encode(MSG_STATS_REQUEST, statsReq)
 }
 
 // Função para criar mensagem de requisição de pacote de cartas
-func CreateCardPackRequest(userID int) ([]byte, error) {
+func CreateCardPackRequest(userID int, packType, clientSeed string) ([]byte, error) {
 	cardPackReq := CardPackRequest{
-		UserID: userID,
-	}
-
-	message := Message{
-		Type: MSG_CARD_PACK_REQUEST,
-		Data: cardPackReq,
+		UserID:     userID,
+		PackType:   packType,
+		ClientSeed: clientSeed,
 	}
 
-	return json.Marshal(message)
+	return Encode(MSG_CARD_PACK_REQUEST, cardPackReq)
 }
 
 // Função para criar mensagem de resposta de pacote de cartas
-func CreateCardPackResponse(success bool, message string, cards []CardInfo, stockInfo StockInfo) ([]byte, error) {
+func NewCardPackResponse(success bool, message, packType string, cards []CardInfo, stockInfo StockInfo, pity PityInfo, fairness FairnessInfo) (*Message, error) {
 	cardPackResp := CardPackResponse{
 		Success:   success,
 		Message:   message,
+		PackType:  packType,
 		Cards:     cards,
 		StockInfo: stockInfo,
+		Pity:      pity,
+		Fairness:  fairness,
 	}
 
-	msg := Message{
-		Type: MSG_CARD_PACK_RESPONSE,
-		Data: cardPackResp,
-	}
+	return NewMessage(MSG_CARD_PACK_RESPONSE, cardPackResp)
+}
 
-	return json.Marshal(msg)
+// Função para criar mensagem de resposta de pacote de cartas, já codificada
+func CreateCardPackResponse(success bool, message, packType string, cards []CardInfo, stockInfo StockInfo, pity PityInfo, fairness FairnessInfo) ([]byte, error) {
+	return marshalMessage(NewCardPackResponse(success, message, packType, cards, stockInfo, pity, fairness))
 }
 
 // Função para criar mensagem de jogada com carta
@@ -230,64 +410,147 @@ func CreateCardMove(userID, matchID int, cardType string) ([]byte, error) {
 		CardType: cardType,
 	}
 
-	message := Message{
-		Type: MSG_CARD_MOVE,
-		Data: cardMove,
+	return Encode(MSG_CARD_MOVE, cardMove)
+}
+
+// Função para criar mensagem de requisição de sementes
+func CreateSeedRequest(userID int, clientSeed string, rotate bool) ([]byte, error) {
+	seedReq := SeedRequest{
+		UserID:     userID,
+		ClientSeed: clientSeed,
+		Rotate:     rotate,
 	}
 
-	return json.Marshal(message)
+	return Encode(MSG_SEED_REQUEST, seedReq)
 }
 
-// Função para extrair dados de requisição de pacote de cartas
-func ExtractCardPackRequest(message *Message) (*CardPackRequest, error) {
-	dataBytes, err := json.Marshal(message.Data)
-	if err != nil {
-		return nil, err
+// Função para criar mensagem de resposta de sementes
+func NewSeedResponse(success bool, message, serverSeedHash, clientSeed string, nonce int, revealed *RevealedSeed) (*Message, error) {
+	seedResp := SeedResponse{
+		Success:        success,
+		Message:        message,
+		ServerSeedHash: serverSeedHash,
+		ClientSeed:     clientSeed,
+		Nonce:          nonce,
+		Revealed:       revealed,
 	}
 
-	var cardPackReq CardPackRequest
-	err = json.Unmarshal(dataBytes, &cardPackReq)
-	if err != nil {
-		return nil, err
+	return NewMessage(MSG_SEED_RESPONSE, seedResp)
+}
+
+// Função para criar mensagem de resposta de sementes, já codificada
+func CreateSeedResponse(success bool, message, serverSeedHash, clientSeed string, nonce int, revealed *RevealedSeed) ([]byte, error) {
+	return marshalMessage(NewSeedResponse(success, message, serverSeedHash, clientSeed, nonce, revealed))
+}
+
+// Função para criar a mensagem de chat enviada pelo jogador
+func CreateChatMessage(userID, matchID int, text string) ([]byte, error) {
+	chatMessage := ChatMessage{
+		UserID:  userID,
+		MatchID: matchID,
+		Text:    text,
 	}
 
-	return &cardPackReq, nil
+	return Encode(MSG_CHAT_MESSAGE, chatMessage)
 }
 
-// Função para extrair dados de resposta de pacote de cartas
-func ExtractCardPackResponse(message *Message) (*CardPackResponse, error) {
-	dataBytes, err := json.Marshal(message.Data)
-	if err != nil {
-		return nil, err
+// Função para criar a mensagem de chat repassada ao oponente
+func CreateChatRelay(matchID, fromID int, fromName, text string) ([]byte, error) {
+	chatMessage := ChatMessage{
+		MatchID:  matchID,
+		FromID:   fromID,
+		FromName: fromName,
+		Text:     text,
 	}
 
-	var cardPackResp CardPackResponse
-	err = json.Unmarshal(dataBytes, &cardPackResp)
-	if err != nil {
-		return nil, err
+	return Encode(MSG_CHAT_MESSAGE, chatMessage)
+}
+
+// Função para extrair dados de mensagem de chat
+func ExtractChatMessage(message *Message) (*ChatMessage, error) {
+	return Decode[ChatMessage](message)
+}
+
+// Função para criar mensagem de requisição do inventário
+func CreateInventoryRequest(userID int) ([]byte, error) {
+	inventoryReq := InventoryRequest{
+		UserID: userID,
 	}
 
-	return &cardPackResp, nil
+	return Encode(MSG_INVENTORY_REQUEST, inventoryReq)
 }
 
-// Função para extrair dados de jogada com carta
-func ExtractCardMove(message *Message) (*CardMove, error) {
-	dataBytes, err := json.Marshal(message.Data)
-	if err != nil {
-		return nil, err
+// Função para criar mensagem com o inventário do jogador
+func NewInventoryUpdate(success bool, message, reason string, cards []CardInfo) (*Message, error) {
+	if cards == nil {
+		cards = []CardInfo{} // Inventário vazio vai como lista vazia, não null
+	}
+	inventoryUpdate := InventoryUpdate{
+		Success: success,
+		Message: message,
+		Cards:   cards,
+		Reason:  reason,
 	}
 
-	var cardMove CardMove
-	err = json.Unmarshal(dataBytes, &cardMove)
-	if err != nil {
-		return nil, err
+	return NewMessage(MSG_INVENTORY_UPDATE, inventoryUpdate)
+}
+
+// Função para criar mensagem com o inventário do jogador, já codificada
+func CreateInventoryUpdate(success bool, message, reason string, cards []CardInfo) ([]byte, error) {
+	return marshalMessage(NewInventoryUpdate(success, message, reason, cards))
+}
+
+// Função para extrair dados de requisição do inventário
+func ExtractInventoryRequest(message *Message) (*InventoryRequest, error) {
+	return Decode[InventoryRequest](message)
+}
+
+// Função para extrair dados de atualização do inventário
+func ExtractInventoryUpdate(message *Message) (*InventoryUpdate, error) {
+	return Decode[InventoryUpdate](message)
+}
+
+// Função para criar mensagem com o estado da sessão
+func CreateSessionState(state SessionState) ([]byte, error) {
+	if state.Inventory == nil {
+		state.Inventory = []CardInfo{}
 	}
 
-	return &cardMove, nil
+	return Encode(MSG_SESSION_STATE, state)
+}
+
+// Função para extrair dados do estado da sessão
+func ExtractSessionState(message *Message) (*SessionState, error) {
+	return Decode[SessionState](message)
+}
+
+// Função para extrair dados de requisição de sementes
+func ExtractSeedRequest(message *Message) (*SeedRequest, error) {
+	return Decode[SeedRequest](message)
+}
+
+// Função para extrair dados de resposta de sementes
+func ExtractSeedResponse(message *Message) (*SeedResponse, error) {
+	return Decode[SeedResponse](message)
+}
+
+// Função para extrair dados de requisição de pacote de cartas
+func ExtractCardPackRequest(message *Message) (*CardPackRequest, error) {
+	return Decode[CardPackRequest](message)
+}
+
+// Função para extrair dados de resposta de pacote de cartas
+func ExtractCardPackResponse(message *Message) (*CardPackResponse, error) {
+	return Decode[CardPackResponse](message)
+}
+
+// Função para extrair dados de jogada com carta
+func ExtractCardMove(message *Message) (*CardMove, error) {
+	return Decode[CardMove](message)
 }
 
 // Função para criar mensagem de resposta de estatísticas
-func CreateStatsResponse(success bool, message, userName string, wins, losses int, winRate float64) ([]byte, error) {
+func NewStatsResponse(success bool, message, userName string, wins, losses int, winRate float64) (*Message, error) {
 	statsResp := StatsResponse{
 		Success:  success,
 		Message:  message,
@@ -297,12 +560,12 @@ func CreateStatsResponse(success bool, message, userName string, wins, losses in
 		WinRate:  winRate,
 	}
 
-	msg := Message{
-		Type: MSG_STATS_RESPONSE,
-		Data: statsResp,
-	}
+	return NewMessage(MSG_STATS_RESPONSE, statsResp)
+}
 
-	return json.Marshal(msg)
+// Função para criar mensagem de resposta de estatísticas, já codificada
+func CreateStatsResponse(success bool, message, userName string, wins, losses int, winRate float64) ([]byte, error) {
+	return marshalMessage(NewStatsResponse(success, message, userName, wins, losses, winRate))
 }
 
 // Função para criar mensagem de requisição de ping
@@ -311,12 +574,7 @@ func CreatePingRequest(userID int) ([]byte, error) {
 		UserID:    userID,
 	}
 
-	message := Message{
-		Type: MSG_PING_REQUEST,
-		Data: pingReq,
-	}
-
-	return json.Marshal(message)
+	return Encode(MSG_PING_REQUEST, pingReq)
 }
 
 // Função para criar mensagem de reposta de ping
@@ -326,12 +584,7 @@ func CreatePingResponse(success bool, message string) ([]byte, error) {
 		Message:   message,
 	}
 
-	msg := Message{
-		Type: MSG_PING_RESPONSE,
-		Data: pingResponse,
-	}
-
-	return json.Marshal(msg)
+	return Encode(MSG_PING_RESPONSE, pingResponse)
 }
 
 // Função para criar mensagem de requisição de login
@@ -341,28 +594,46 @@ func CreateLoginRequest(userName, password string) ([]byte, error) {
 		Password: password,
 	}
 	
-	message := Message{
-		Type: MSG_LOGIN_REQUEST,
-		Data: loginReq,
-	}
-	
-	return json.Marshal(message)
+	return Encode(MSG_LOGIN_REQUEST, loginReq)
 }
 
 // Função para criar mensagem de resposta de login
-func CreateLoginResponse(success bool, message string, userID int) ([]byte, error) {
+func NewLoginResponse(success bool, message string, userID int, serverSeedHash string) (*Message, error) {
 	loginResp := LoginResponse{
 		Success: success,
 		Message: message,
 		UserID:  userID,
+
+		ServerSeedHash: serverSeedHash,
 	}
 	
-	msg := Message{
-		Type: MSG_LOGIN_RESPONSE,
-		Data: loginResp,
+	return NewMessage(MSG_LOGIN_RESPONSE, loginResp)
+}
+
+// Função para criar mensagem de resposta de login, já codificada
+func CreateLoginResponse(success bool, message string, userID int, serverSeedHash string) ([]byte, error) {
+	return marshalMessage(NewLoginResponse(success, message, userID, serverSeedHash))
+}
+
+// Função para criar a resposta de login recusado por excesso de tentativas falhas
+func NewLoginThrottled(message, code string, retryAfter time.Duration) (*Message, error) {
+	loginResp := LoginResponse{
+		Success:      false,
+		Message:      message,
+		RetryAfterMs: retryAfter.Milliseconds(),
 	}
-	
-	return json.Marshal(msg)
+	msg, err := NewMessage(MSG_LOGIN_RESPONSE, loginResp)
+	if err != nil {
+		return nil, err
+	}
+	msg.ErrorCode = code
+
+	return msg, nil
+}
+
+// Função para criar a resposta de login recusado por excesso de tentativas falhas, já codificada
+func CreateLoginThrottled(message, code string, retryAfter time.Duration) ([]byte, error) {
+	return marshalMessage(NewLoginThrottled(message, code, retryAfter))
 }
 
 // Função para criar mensagem de requisição de cadastro
@@ -372,28 +643,23 @@ func CreateRegisterRequest(userName, password string) ([]byte, error) {
 		Password: password,
 	}
 	
-	message := Message{
-		Type: MSG_REGISTER_REQUEST,
-		Data: registerReq,
-	}
-	
-	return json.Marshal(message)
+	return Encode(MSG_REGISTER_REQUEST, registerReq)
 }
 
 // Função para criar mensagem de resposta de cadastro
-func CreateRegisterResponse(success bool, message string, userID int) ([]byte, error) {
+func NewRegisterResponse(success bool, message string, userID int) (*Message, error) {
 	registerResp := RegisterResponse{
 		Success: success,
 		Message: message,
 		UserID:  userID,
 	}
 	
-	msg := Message{
-		Type: MSG_REGISTER_RESPONSE,
-		Data: registerResp,
-	}
-	
-	return json.Marshal(msg)
+	return NewMessage(MSG_REGISTER_RESPONSE, registerResp)
+}
+
+// Função para criar mensagem de resposta de cadastro, já codificada
+func CreateRegisterResponse(success bool, message string, userID int) ([]byte, error) {
+	return marshalMessage(NewRegisterResponse(success, message, userID))
 }
 
 // Função para criar mensagem de requisição de fila
@@ -402,28 +668,23 @@ func CreateQueueRequest(userID int) ([]byte, error) {
 		UserID: userID,
 	}
 	
-	message := Message{
-		Type: MSG_QUEUE_REQUEST,
-		Data: queueReq,
-	}
-	
-	return json.Marshal(message)
+	return Encode(MSG_QUEUE_REQUEST, queueReq)
 }
 
 // Função para criar mensagem de resposta de fila
-func CreateQueueResponse(success bool, message string, queueSize int) ([]byte, error) {
+func NewQueueResponse(success bool, message string, queueSize int) (*Message, error) {
 	queueResp := QueueResponse{
 		Success:   success,
 		Message:   message,
 		QueueSize: queueSize,
 	}
 	
-	msg := Message{
-		Type: MSG_QUEUE_RESPONSE,
-		Data: queueResp,
-	}
-	
-	return json.Marshal(msg)
+	return NewMessage(MSG_QUEUE_RESPONSE, queueResp)
+}
+
+// Função para criar mensagem de resposta de fila, já codificada
+func CreateQueueResponse(success bool, message string, queueSize int) ([]byte, error) {
+	return marshalMessage(NewQueueResponse(success, message, queueSize))
 }
 
 // Função para criar mensagem de partida encontrada
@@ -435,12 +696,7 @@ func CreateMatchFound(matchID, opponentID int, opponentName, message string) ([]
 		Message:      message,
 	}
 	
-	msg := Message{
-		Type: MSG_MATCH_FOUND,
-		Data: matchFound,
-	}
-	
-	return json.Marshal(msg)
+	return Encode(MSG_MATCH_FOUND, matchFound)
 }
 
 // Função para criar mensagem de início de partida
@@ -450,12 +706,7 @@ func CreateMatchStart(matchID int, message string) ([]byte, error) {
 		Message: message,
 	}
 	
-	msg := Message{
-		Type: MSG_MATCH_START,
-		Data: matchStart,
-	}
-	
-	return json.Marshal(msg)
+	return Encode(MSG_MATCH_START, matchStart)
 }
 
 // Função para criar mensagem de fim de partida
@@ -467,235 +718,239 @@ func CreateMatchEnd(matchID, winnerID int, winnerName, message string) ([]byte,
 		Message:    message,
 	}
 	
-	msg := Message{
-		Type: MSG_MATCH_END,
-		Data: matchEnd,
+	return Encode(MSG_MATCH_END, matchEnd)
+}
+
+// Função para criar o aviso de encerramento do servidor
+func CreateServerShutdown(message string, deadline time.Time) ([]byte, error) {
+	shutdown := ServerShutdown{
+		Message:  message,
+		Deadline: deadline,
 	}
 	
-	return json.Marshal(msg)
+	return Encode(MSG_SERVER_SHUTDOWN, shutdown)
 }
 
-// Função para decodificar mensagem recebida
-func DecodeMessage(data []byte) (*Message, error) {
-	var message Message
-	err := json.Unmarshal(data, &message)
-	if err != nil {
-		return nil, err
+// Função para criar a mensagem HELLO
+func CreateHello(clientName, clientVersion string, capabilities []string) ([]byte, error) {
+	hello := Hello{
+		ProtocolVersion: PROTOCOL_VERSION,
+		ClientName:      clientName,
+		ClientVersion:   clientVersion,
+		Capabilities:    capabilities,
 	}
-	return &message, nil
+	return Encode(MSG_HELLO, hello)
 }
 
-// Função para extrair dados de requisição de estatísticas
-func ExtractStatsRequest(message *Message) (*StatsRequest, error) {
-	dataBytes, err := json.Marshal(message.Data)
-	if err != nil {
-		return nil, err
+// Função para criar a mensagem WELCOME
+func NewWelcome(accepted bool, message string, minVersion int, serverName, serverVersion string, capabilities []string) (*Message, error) {
+	welcome := Welcome{
+		Accepted:           accepted,
+		Message:            message,
+		ProtocolVersion:    PROTOCOL_VERSION,
+		MinProtocolVersion: minVersion,
+		ServerName:         serverName,
+		ServerVersion:      serverVersion,
+		Capabilities:       capabilities,
 	}
+	return NewMessage(MSG_WELCOME, welcome)
+}
 
-	var statsReq StatsRequest
-	err = json.Unmarshal(dataBytes, &statsReq)
-	if err != nil {
-		return nil, err
+// Função para criar a mensagem WELCOME, já codificada
+func CreateWelcome(accepted bool, message string, minVersion int, serverName, serverVersion string, capabilities []string) ([]byte, error) {
+	return marshalMessage(NewWelcome(accepted, message, minVersion, serverName, serverVersion, capabilities))
+}
+
+// Função para extrair dados do HELLO
+func ExtractHello(message *Message) (*Hello, error) {
+	return Decode[Hello](message)
+}
+
+// Função para extrair dados do WELCOME
+func ExtractWelcome(message *Message) (*Welcome, error) {
+	return Decode[Welcome](message)
+}
+
+// Função para calcular as capacidades em comum entre cliente e servidor
+func NegotiateCapabilities(client, server []string) []string {
+	supported := make(map[string]bool, len(server))
+	for _, capability := range server {
+		supported[capability] = true
 	}
 
-	return &statsReq, nil
+	var negotiated []string
+	for _, capability := range client {
+		if supported[capability] {
+			negotiated = append(negotiated, capability)
+			delete(supported, capability) // Ignora repetidas
+		}
+	}
+	return negotiated
 }
 
-// Função para extrair dados de resposta de estatísticas
-func ExtractStatsResponse(message *Message) (*StatsResponse, error) {
-	dataBytes, err := json.Marshal(message.Data)
+// Função para definir o request_id de uma mensagem já codificada.
+// O servidor monta as respostas como *Message e define o campo direto.
+func WithRequestID(data []byte, requestID string) ([]byte, error) {
+	message, err := DecodeMessage(data)
 	if err != nil {
 		return nil, err
 	}
+	message.RequestID = requestID
+	return json.Marshal(message)
+}
 
-	var statsResp StatsResponse
-	err = json.Unmarshal(dataBytes, &statsResp)
+// Função para criar mensagem de erro de protocolo
+func NewError(code, message string) (*Message, error) {
+	msg, err := NewMessage(MSG_ERROR, ErrorInfo{Message: message})
 	if err != nil {
 		return nil, err
 	}
+	msg.ErrorCode = code
+	
+	return msg, nil
+}
 
-	return &statsResp, nil
+// Função para criar mensagem de erro de protocolo, já codificada
+func CreateError(code, message string) ([]byte, error) {
+	return marshalMessage(NewError(code, message))
 }
 
-// Função para extrair dados de ping request
-func ExtractPingRequest(message *Message) (*PingRequest, error) {
-	dataBytes, err := json.Marshal(message.Data)
+// Função para criar o erro de requisição limitada, com a espera sugerida
+func NewRateLimited(retryAfter time.Duration, message string) (*Message, error) {
+	msg, err := NewMessage(MSG_ERROR, ErrorInfo{Message: message, RetryAfterMs: retryAfter.Milliseconds()})
 	if err != nil {
 		return nil, err
 	}
+	msg.ErrorCode = ERR_RATE_LIMITED
+
+	return msg, nil
+}
+
+// Função para criar o erro de requisição limitada, com a espera sugerida, já codificada
+func CreateRateLimited(retryAfter time.Duration, message string) ([]byte, error) {
+	return marshalMessage(NewRateLimited(retryAfter, message))
+}
+
+// Função para criar mensagem de jogada recusada
+func NewMoveRejected(matchID int, cardType, code, message string) (*Message, error) {
+	moveRejected := MoveRejected{
+		MatchID:  matchID,
+		CardType: cardType,
+		Message:  message,
+	}
 	
-	var PingRequest PingRequest
-	err = json.Unmarshal(dataBytes, &PingRequest)
+	msg, err := NewMessage(MSG_MOVE_REJECTED, moveRejected)
 	if err != nil {
 		return nil, err
 	}
+	msg.ErrorCode = code
 	
-	return &PingRequest, nil
+	return msg, nil
 }
 
-// Função para extrair dados de ping response
-func ExtractPingResponse(message *Message) (*PingResponse, error) { 
-	dataBytes, err := json.Marshal(message.Data)
+// Função para criar mensagem de jogada recusada, já codificada
+func CreateMoveRejected(matchID int, cardType, code, message string) ([]byte, error) {
+	return marshalMessage(NewMoveRejected(matchID, cardType, code, message))
+}
+
+// Função para decodificar o envelope de uma mensagem recebida. O payload fica em
+// Data (json.RawMessage) e é lido depois com Decode/Extract*. Mensagens malformadas
+// retornam ErrInvalidMessage e tipos não registrados retornam ErrUnknownType.
+func DecodeMessage(data []byte) (*Message, error) {
+	var message Message
+	err := json.Unmarshal(data, &message)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
 	}
-	
-	var pingResponse PingResponse
-	err = json.Unmarshal(dataBytes, &pingResponse)
-	if err != nil {
-		return nil, err
+	if _, ok := PayloadType(message.Type); !ok {
+		return &message, fmt.Errorf("%w: %q", ErrUnknownType, message.Type)
 	}
-	
-	return &pingResponse, nil
+	return &message, nil
+}
+
+// Função para extrair dados de erro de protocolo
+func ExtractError(message *Message) (*ErrorInfo, error) {
+	return Decode[ErrorInfo](message)
+}
+
+// Função para extrair dados de jogada recusada
+func ExtractMoveRejected(message *Message) (*MoveRejected, error) {
+	return Decode[MoveRejected](message)
+}
+
+// Função para extrair o aviso de encerramento do servidor
+func ExtractServerShutdown(message *Message) (*ServerShutdown, error) {
+	return Decode[ServerShutdown](message)
+}
+
+// Função para extrair dados de requisição de estatísticas
+func ExtractStatsRequest(message *Message) (*StatsRequest, error) {
+	return Decode[StatsRequest](message)
+}
+
+// Função para extrair dados de resposta de estatísticas
+func ExtractStatsResponse(message *Message) (*StatsResponse, error) {
+	return Decode[StatsResponse](message)
+}
+
+// Função para extrair dados de ping request
+func ExtractPingRequest(message *Message) (*PingRequest, error) {
+	return Decode[PingRequest](message)
+}
+
+// Função para extrair dados de ping response
+func ExtractPingResponse(message *Message) (*PingResponse, error) {
+	return Decode[PingResponse](message)
 }
 
 // Função para extrair dados de login request
 func ExtractLoginRequest(message *Message) (*LoginRequest, error) {
-	dataBytes, err := json.Marshal(message.Data)
-	if err != nil {
-		return nil, err
-	}
-	
-	var loginReq LoginRequest
-	err = json.Unmarshal(dataBytes, &loginReq)
-	if err != nil {
-		return nil, err
-	}
-	
-	return &loginReq, nil
+	return Decode[LoginRequest](message)
 }
 
 // Função para extrair dados de login response
 func ExtractLoginResponse(message *Message) (*LoginResponse, error) {
-	dataBytes, err := json.Marshal(message.Data)
-	if err != nil {
-		return nil, err
-	}
-	
-	var loginResp LoginResponse
-	err = json.Unmarshal(dataBytes, &loginResp)
-	if err != nil {
-		return nil, err
-	}
-	
-	return &loginResp, nil
+	return Decode[LoginResponse](message)
 }
 
 // Função para extrair dados de cadastro request
 func ExtractRegisterRequest(message *Message) (*RegisterRequest, error) {
-	dataBytes, err := json.Marshal(message.Data)
-	if err != nil {
-		return nil, err
-	}
-	
-	var registerReq RegisterRequest
-	err = json.Unmarshal(dataBytes, &registerReq)
-	if err != nil {
-		return nil, err
-	}
-	
-	return &registerReq, nil
+	return Decode[RegisterRequest](message)
 }
 
 // Função para extrair dados de cadastro response
 func ExtractRegisterResponse(message *Message) (*RegisterResponse, error) {
-	dataBytes, err := json.Marshal(message.Data)
-	if err != nil {
-		return nil, err
-	}
-	
-	var registerResp RegisterResponse
-	err = json.Unmarshal(dataBytes, &registerResp)
-	if err != nil {
-		return nil, err
-	}
-	
-	return &registerResp, nil
+	return Decode[RegisterResponse](message)
 }
 
 // Função para extrair dados de fila request
 func ExtractQueueRequest(message *Message) (*QueueRequest, error) {
-	dataBytes, err := json.Marshal(message.Data)
-	if err != nil {
-		return nil, err
-	}
-	
-	var queueReq QueueRequest
-	err = json.Unmarshal(dataBytes, &queueReq)
-	if err != nil {
-		return nil, err
-	}
-	
-	return &queueReq, nil
+	return Decode[QueueRequest](message)
 }
 
 // Função para extrair dados de fila response
 func ExtractQueueResponse(message *Message) (*QueueResponse, error) {
-	dataBytes, err := json.Marshal(message.Data)
-	if err != nil {
-		return nil, err
-	}
-	
-	var queueResp QueueResponse
-	err = json.Unmarshal(dataBytes, &queueResp)
-	if err != nil {
-		return nil, err
-	}
-	
-	return &queueResp, nil
+	return Decode[QueueResponse](message)
 }
 
 // Função para extrair dados de partida encontrada
 func ExtractMatchFound(message *Message) (*MatchFound, error) {
-	dataBytes, err := json.Marshal(message.Data)
-	if err != nil {
-		return nil, err
-	}
-	
-	var matchFound MatchFound
-	err = json.Unmarshal(dataBytes, &matchFound)
-	if err != nil {
-		return nil, err
-	}
-	
-	return &matchFound, nil
+	return Decode[MatchFound](message)
 }
 
 // Função para extrair dados de início de partida
 func ExtractMatchStart(message *Message) (*MatchStart, error) {
-	dataBytes, err := json.Marshal(message.Data)
-	if err != nil {
-		return nil, err
-	}
-	
-	var matchStart MatchStart
-	err = json.Unmarshal(dataBytes, &matchStart)
-	if err != nil {
-		return nil, err
-	}
-	
-	return &matchStart, nil
+	return Decode[MatchStart](message)
 }
 
 // Função para extrair dados de fim de partida
 func ExtractMatchEnd(message *Message) (*MatchEnd, error) {
-	dataBytes, err := json.Marshal(message.Data)
-	if err != nil {
-		return nil, err
-	}
-	
-	var matchEnd MatchEnd
-	err = json.Unmarshal(dataBytes, &matchEnd)
-	if err != nil {
-		return nil, err
-	}
-	
-	return &matchEnd, nil
+	return Decode[MatchEnd](message)
 }
 
 
 // Função para criar mensagem de estado do jogo
-func CreateGameState(matchID int, message string, yourTurn, opponentMoved, gameOver bool) ([]byte, error) {
+func NewGameState(matchID int, message string, yourTurn, opponentMoved, gameOver bool) (*Message, error) {
 	gameState := GameState{
 		MatchID:       matchID,
 		Message:       message,
@@ -704,12 +959,12 @@ func CreateGameState(matchID int, message string, yourTurn, opponentMoved, gameO
 		GameOver:      gameOver,
 	}
 	
-	msg := Message{
-		Type: MSG_GAME_STATE,
-		Data: gameState,
-	}
-	
-	return json.Marshal(msg)
+	return NewMessage(MSG_GAME_STATE, gameState)
+}
+
+// Função para criar mensagem de estado do jogo, já codificada
+func CreateGameState(matchID int, message string, yourTurn, opponentMoved, gameOver bool) ([]byte, error) {
+	return marshalMessage(NewGameState(matchID, message, yourTurn, opponentMoved, gameOver))
 }
 
 // Função para criar mensagem de atualização de turno
@@ -720,58 +975,20 @@ func CreateTurnUpdate(matchID int, message string, yourTurn bool) ([]byte, error
 		YourTurn: yourTurn,
 	}
 	
-	msg := Message{
-		Type: MSG_TURN_UPDATE,
-		Data: turnUpdate,
-	}
-	
-	return json.Marshal(msg)
+	return Encode(MSG_TURN_UPDATE, turnUpdate)
 }
 
 // Função para extrair dados de jogada
 func ExtractGameMove(message *Message) (*GameMove, error) {
-	dataBytes, err := json.Marshal(message.Data)
-	if err != nil {
-		return nil, err
-	}
-	
-	var gameMove GameMove
-	err = json.Unmarshal(dataBytes, &gameMove)
-	if err != nil {
-		return nil, err
-	}
-	
-	return &gameMove, nil
+	return Decode[GameMove](message)
 }
 
 // Função para extrair dados de estado do jogo
 func ExtractGameState(message *Message) (*GameState, error) {
-	dataBytes, err := json.Marshal(message.Data)
-	if err != nil {
-		return nil, err
-	}
-	
-	var gameState GameState
-	err = json.Unmarshal(dataBytes, &gameState)
-	if err != nil {
-		return nil, err
-	}
-	
-	return &gameState, nil
+	return Decode[GameState](message)
 }
 
 // Função para extrair dados de atualização de turno
 func ExtractTurnUpdate(message *Message) (*TurnUpdate, error) {
-	dataBytes, err := json.Marshal(message.Data)
-	if err != nil {
-		return nil, err
-	}
-	
-	var turnUpdate TurnUpdate
-	err = json.Unmarshal(dataBytes, &turnUpdate)
-	if err != nil {
-		return nil, err
-	}
-	
-	return &turnUpdate, nil
+	return Decode[TurnUpdate](message)
 }
\ No newline at end of file
@@ -0,0 +1,142 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+	"top-card/internal/clock"
+)
+
+// Chave de Limits usada para os tipos de mensagem sem limite próprio
+const ANY = "*"
+
+// Orçamento de um balde de fichas: Burst requisições de uma vez, repostas à taxa
+// de Rate fichas por segundo
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Limites por tipo de mensagem. Cada tipo tem o próprio balde; tipos sem entrada
+// usam o limite de ANY e, sem ANY, não têm limite.
+type Limits map[string]Limit
+
+// Balde de fichas
+type Bucket struct {
+	limit  Limit
+	tokens float64
+	last   time.Time
+}
+
+// Função para criar um balde cheio
+func NewBucket(limit Limit, now time.Time) *Bucket {
+	return &Bucket{limit: limit, tokens: float64(limit.Burst), last: now}
+}
+
+// Repõe as fichas acumuladas desde a última chamada
+func (b *Bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed*b.limit.Rate)
+		b.last = now
+	}
+}
+
+// Função para consumir uma ficha. Se não houver, retorna false e quanto tempo
+// falta para a próxima ficha.
+func (b *Bucket) Take(now time.Time) (bool, time.Duration) {
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	if b.limit.Rate <= 0 {
+		return false, time.Duration(math.MaxInt64)
+	}
+	wait := (1 - b.tokens) / b.limit.Rate
+	return false, time.Duration(math.Ceil(wait * float64(time.Second)))
+}
+
+// Verifica se o balde já se recuperou totalmente (pode ser descartado)
+func (b *Bucket) Full(now time.Time) bool {
+	b.refill(now)
+	return b.tokens >= float64(b.limit.Burst)
+}
+
+// Conjunto de baldes por identificador (sessão, IP, usuário) e tipo de mensagem
+type Limiter struct {
+	clock   clock.Clock
+	limits  Limits
+	mutex   sync.Mutex
+	buckets map[string]map[string]*Bucket // id -> tipo de mensagem -> balde
+}
+
+// Função para criar um limitador com os limites informados
+func NewLimiter(clk clock.Clock, limits Limits) *Limiter {
+	return &Limiter{
+		clock:   clk,
+		limits:  limits,
+		buckets: make(map[string]map[string]*Bucket),
+	}
+}
+
+// Função para obter o limite de um tipo de mensagem
+func (l *Limiter) limitFor(msgType string) (Limit, bool) {
+	if limit, ok := l.limits[msgType]; ok {
+		return limit, true
+	}
+	limit, ok := l.limits[ANY]
+	return limit, ok
+}
+
+// Função para registrar uma requisição do id. Retorna false e o tempo de espera
+// sugerido se o orçamento daquele tipo de mensagem estiver esgotado.
+func (l *Limiter) Allow(id, msgType string) (bool, time.Duration) {
+	limit, ok := l.limitFor(msgType)
+	if !ok {
+		return true, 0
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.clock.Now()
+	byType, exists := l.buckets[id]
+	if !exists {
+		byType = make(map[string]*Bucket)
+		l.buckets[id] = byType
+	}
+	bucket, exists := byType[msgType]
+	if !exists {
+		bucket = NewBucket(limit, now)
+		byType[msgType] = bucket
+	}
+	return bucket.Take(now)
+}
+
+// Função para descartar os baldes de um id (ex.: sessão encerrada)
+func (l *Limiter) Forget(id string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	delete(l.buckets, id)
+}
+
+// Função para descartar os baldes cheios, que se comportariam igual a baldes novos.
+// Retorna quantos ids ainda têm baldes.
+func (l *Limiter) Prune() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.clock.Now()
+	for id, byType := range l.buckets {
+		for msgType, bucket := range byType {
+			if bucket.Full(now) {
+				delete(byType, msgType)
+			}
+		}
+		if len(byType) == 0 {
+			delete(l.buckets, id)
+		}
+	}
+	return len(l.buckets)
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"top-card/internal/lockout"
+)
+
+// Falhas de login registradas, para administradores. Entradas com LockedUntil no
+// futuro estão bloqueadas.
+type LoginLockouts struct {
+	Users []lockout.Entry `json:"users"`
+	IPs   []lockout.Entry `json:"ips"`
+}
+
+// Função para consultar as falhas de login e os bloqueios ativos
+func (s *Server) LoginLockouts() LoginLockouts {
+	return LoginLockouts{
+		Users: s.userFailures.Entries(),
+		IPs:   s.ipFailures.Entries(),
+	}
+}
+
+// Função para desbloquear um usuário manualmente. Retorna false se não havia falhas.
+func (s *Server) UnlockUser(userName string) bool {
+	return s.userFailures.Reset(userName)
+}
+
+// Função para desbloquear um IP manualmente. Retorna false se não havia falhas.
+func (s *Server) UnlockIP(ip string) bool {
+	return s.ipFailures.Reset(ip)
+}
+
+// Interface HTTP de administração. Toda requisição precisa do cabeçalho
+// "Authorization: Bearer <Config.AdminToken>"; sem token configurado nenhuma é aceita.
+//
+//	GET  /admin/lockouts            falhas de login e bloqueios (JSON)
+//	POST /admin/unlock?user=<nome>  desbloqueia um usuário
+//	POST /admin/unlock?ip=<ip>      desbloqueia um IP
+func (s *Server) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /admin/lockouts", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.LoginLockouts())
+	})
+
+	mux.HandleFunc("POST /admin/unlock", func(w http.ResponseWriter, r *http.Request) {
+		var unlocked bool
+		switch user, ip := r.URL.Query().Get("user"), r.URL.Query().Get("ip"); {
+		case user != "":
+			unlocked = s.UnlockUser(user)
+			fmt.Printf("🔓 Usuário %s desbloqueado pelo administrador\n", user)
+		case ip != "":
+			unlocked = s.UnlockIP(ip)
+			fmt.Printf("🔓 IP %s desbloqueado pelo administrador\n", ip)
+		default:
+			http.Error(w, "informe user ou ip", http.StatusBadRequest)
+			return
+		}
+		if !unlocked {
+			http.Error(w, "nenhuma falha registrada", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return s.requireAdminToken(mux)
+}
+
+// Recusa com 401 as requisições sem o token de administração. O token num cabeçalho
+// também impede que páginas de outros sites usem o navegador do administrador.
+func (s *Server) requireAdminToken(next http.Handler) http.Handler {
+	expected := []byte("Bearer " + s.config.AdminToken)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received := []byte(r.Header.Get("Authorization"))
+		if s.config.AdminToken == "" || subtle.ConstantTimeCompare(received, expected) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="top-card-admin"`)
+			http.Error(w, "token de administração inválido", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -2,100 +2,893 @@ package server
 
 import (
 	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
+	"unicode/utf8"
 	"top-card/internal/player"
 	"top-card/internal/protocol"
 	"top-card/internal/match"
 	"top-card/internal/card"
+	"top-card/internal/clock"
+	"top-card/internal/lockout"
+	"top-card/internal/ratelimit"
+	"top-card/internal/storage"
+	"top-card/internal/tlsconfig"
+	"top-card/internal/udpecho"
+	"top-card/internal/discovery"
 )
 
-var players []player.Player
-var nextID = 1
-var queue []int // Fila de jogadores esperando partida
-var queueMutex sync.Mutex
-var connectedUsers = make(map[int]bool) // Mapa para rastrear usuários conectados por ID
-var connectedMutex sync.Mutex           // Mutex para proteger acesso concurrent ao mapa
-var userConnections = make(map[int]net.Conn) // Mapa para armazenar conexões dos usuários
-var connectionsMutex sync.Mutex              // Mutex para proteger acesso às conexões
+// Erro retornado por Serve depois que o servidor foi encerrado
+var ErrServerClosed = errors.New("servidor TOP CARD encerrado")
 
-func Run() {
-	// Criação do servidor (ouvindo na porta 8080)
-	ln, err := net.Listen("tcp", ":8080")
+// Pacote ou sementes pedidos antes de o hash da semente ser publicado no login
+var errSeedsNotPublished = errors.New("semente do sorteio não publicada")
+
+// Prazo padrão para as partidas em andamento terminarem no encerramento
+const defaultShutdownTimeout = 30 * time.Second
+
+// Identificação do servidor enviada no WELCOME
+const (
+	serverName    = "top-card-server"
+	serverVersion = "2.0.0"
+)
+
+// Capacidades que o servidor sabe usar; cada conexão ativa só as que o cliente anunciou
+var serverCapabilities = []string{protocol.CAP_REQUEST_ID, protocol.CAP_ERROR_CODES, protocol.CAP_BINARY_FRAMES, protocol.CAP_CHAT, protocol.CAP_INVENTORY, protocol.CAP_SESSION_STATE}
+
+// Configuração do servidor. Campos vazios usam os valores padrão.
+type Config struct {
+	Addr               string           // Endereço TCP (padrão: ":8080")
+	Clock              clock.Clock      // Relógio do matchmaker e das partidas (padrão: relógio real)
+	Random             io.Reader        // Aleatoriedade das sementes de sorteio (padrão: crypto/rand)
+	Stock              card.StockCounts // Estoque inicial de cartas (padrão: card.DefaultStockCounts)
+	PackTypes          []card.PackType  // Tipos de pacote oferecidos (padrão: card.DefaultPackTypes); New entra em pânico se algum for inválido
+	MatchmakerInterval time.Duration    // Intervalo entre verificações da fila (padrão: 1s)
+	MatchStartDelay    time.Duration    // Espera entre "partida encontrada" e o início (padrão: 2s)
+	GameStartDelay     time.Duration    // Espera entre o início da partida e o primeiro turno (padrão: 1s)
+	CleanupInterval    time.Duration    // Intervalo da limpeza de partidas órfãs (padrão: 5s)
+	DrainPollInterval  time.Duration    // Intervalo entre verificações das partidas no encerramento (padrão: 200ms)
+	DataFile           string           // Arquivo de estado (jogadores e estoque); vazio = sem persistência
+	WriteTimeout       time.Duration    // Prazo de escrita de cada mensagem para o cliente (padrão: 10s)
+	OutboundQueueSize  int              // Mensagens pendentes por conexão antes de derrubar o cliente lento (padrão: 64)
+	MinProtocolVersion int              // Versão mais antiga aceita; acima de 1 exige HELLO (padrão: protocol.MIN_PROTOCOL_VERSION)
+	MaxFrameSize       int              // Tamanho máximo de uma mensagem recebida, em bytes (padrão: protocol.MAX_FRAME_SIZE)
+	MaxViolations      int              // Violações de protocolo toleradas antes de derrubar a conexão (padrão: 10)
+	RateLimits         RateLimits       // Limites de requisições (padrão: DefaultRateLimits)
+	UserLockout        lockout.Policy   // Falhas de login por usuário (padrão: DefaultUserLockout)
+	IPLockout          lockout.Policy   // Falhas de login por IP (padrão: DefaultIPLockout)
+	TLS                *tls.Config      // Se definido, as conexões usam TLS (ver tlsconfig.NewServer)
+	WebSocketPath      string           // Caminho HTTP do gateway WebSocket (padrão: "/ws")
+	AdminToken         string           // Token exigido pela interface de administração; vazio recusa todas as requisições
+	Name               string           // Nome anunciado na rede local (padrão: nome da máquina)
+	AdvertiseAddr      string           // Endereço TCP anunciado na rede local (padrão: porta do listener, com o IP de origem do anúncio)
+	AnnounceInterval   time.Duration    // Intervalo entre anúncios na rede local (padrão: discovery.DEFAULT_INTERVAL)
+}
+
+// Política padrão por usuário: espera de 1s a 30s entre tentativas e bloqueio de
+// 15 minutos na 5ª falha seguida
+var DefaultUserLockout = lockout.Policy{
+	MaxFailures:     5,
+	BaseDelay:       time.Second,
+	MaxDelay:        30 * time.Second,
+	LockoutDuration: 15 * time.Minute,
+	ForgetAfter:     time.Hour,
+}
+
+// Política padrão por IP: mais tolerante, já que vários jogadores podem dividir o mesmo IP
+var DefaultIPLockout = lockout.Policy{
+	MaxFailures:     20,
+	BaseDelay:       500 * time.Millisecond,
+	MaxDelay:        10 * time.Second,
+	LockoutDuration: 15 * time.Minute,
+	ForgetAfter:     time.Hour,
+}
+
+// Limites de requisições por tipo de mensagem (ver ratelimit.Limits). Os dois
+// limites valem ao mesmo tempo; sem nenhum dos dois são usados os padrões.
+type RateLimits struct {
+	PerSession ratelimit.Limits // Por conexão
+	PerIP      ratelimit.Limits // Somando todas as conexões de um mesmo IP
+	Disabled   bool             // Desliga os limites (ex.: testes de stress de uma única máquina)
+}
+
+// Limites padrão: pacotes, login, cadastro e chat têm orçamentos menores que as demais mensagens
+var DefaultRateLimits = RateLimits{
+	PerSession: ratelimit.Limits{
+		protocol.MSG_CARD_PACK_REQUEST: {Rate: 2, Burst: 5},
+		protocol.MSG_LOGIN_REQUEST:     {Rate: 1, Burst: 5},
+		protocol.MSG_REGISTER_REQUEST:  {Rate: 0.5, Burst: 3},
+		protocol.MSG_CHAT_MESSAGE:      {Rate: 1, Burst: 5},
+		ratelimit.ANY:                  {Rate: 20, Burst: 40},
+	},
+	PerIP: ratelimit.Limits{
+		protocol.MSG_CARD_PACK_REQUEST: {Rate: 10, Burst: 30},
+		protocol.MSG_LOGIN_REQUEST:     {Rate: 5, Burst: 20},
+		protocol.MSG_REGISTER_REQUEST:  {Rate: 2, Burst: 10},
+		protocol.MSG_CHAT_MESSAGE:      {Rate: 5, Burst: 20},
+		udpecho.RATE_KEY:               {Rate: 20, Burst: 40},
+		ratelimit.ANY:                  {Rate: 100, Burst: 200},
+	},
+}
+
+// Servidor TOP CARD com estado próprio (jogadores, fila, partidas e estoque)
+type Server struct {
+	config    Config
+	clock     clock.Clock
+	stock     *card.CardStock
+	packTypes *card.PackTypes // Tipos de pacote deste servidor
+	matches   *match.MatchManager
+
+	sessionLimits *ratelimit.Limiter // Requisições por conexão
+	ipLimits      *ratelimit.Limiter // Requisições por IP
+	userFailures  *lockout.Tracker   // Falhas de login por usuário
+	ipFailures    *lockout.Tracker   // Falhas de login por IP
+
+	players      []*player.Player
+	nextID       int
+	playersMutex sync.Mutex // Protege players e nextID
+
+	queue      []int // Fila de jogadores esperando partida
+	queueMutex sync.Mutex
+
+	connectedUsers   map[int]bool     // Mapa para rastrear usuários conectados por ID
+	connectedMutex   sync.Mutex       // Mutex para proteger acesso concurrent ao mapa
+	userConnections  map[int]*session // Mapa para armazenar conexões dos usuários
+	connectionsMutex sync.Mutex       // Mutex para proteger acesso às conexões
+
+	listener    net.Listener
+	echoConn    net.PacketConn        // Serviço de eco UDP (ver ServeEcho)
+	activeConns map[*session]struct{} // Todas as conexões abertas (para o encerramento)
+	stateMutex  sync.Mutex            // Protege listener, echoConn, activeConns e closed
+	closed      bool
+
+	interrupted []storage.InterruptedMatch // Partidas interrompidas no encerramento
+
+	startOnce sync.Once
+	draining  chan struct{}  // Fechado no início do encerramento: para o matchmaker e a fila
+	done      chan struct{}  // Fechado no fim do encerramento para parar as goroutines de fundo
+	wg        sync.WaitGroup // Goroutines de fundo e conexões em andamento
+}
+
+// Função para criar um servidor com a configuração informada
+func New(config Config) *Server {
+	if config.Addr == "" {
+		config.Addr = ":8080"
+	}
+	if config.Clock == nil {
+		config.Clock = clock.Real()
+	}
+	if config.Stock == (card.StockCounts{}) {
+		config.Stock = card.DefaultStockCounts
+	}
+	if config.MatchmakerInterval <= 0 {
+		config.MatchmakerInterval = 1 * time.Second
+	}
+	if config.MatchStartDelay <= 0 {
+		config.MatchStartDelay = 2 * time.Second
+	}
+	if config.GameStartDelay <= 0 {
+		config.GameStartDelay = 1 * time.Second
+	}
+	if config.CleanupInterval <= 0 {
+		config.CleanupInterval = 5 * time.Second
+	}
+	if config.DrainPollInterval <= 0 {
+		config.DrainPollInterval = 200 * time.Millisecond
+	}
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = 10 * time.Second
+	}
+	if config.OutboundQueueSize <= 0 {
+		config.OutboundQueueSize = 64
+	}
+	if config.MinProtocolVersion <= 0 {
+		config.MinProtocolVersion = protocol.MIN_PROTOCOL_VERSION
+	}
+	if config.MaxFrameSize <= 0 {
+		config.MaxFrameSize = protocol.MAX_FRAME_SIZE
+	}
+	if config.MaxViolations <= 0 {
+		config.MaxViolations = 10
+	}
+	if !config.RateLimits.Disabled && config.RateLimits.PerSession == nil && config.RateLimits.PerIP == nil {
+		config.RateLimits = DefaultRateLimits
+	}
+	if config.WebSocketPath == "" {
+		config.WebSocketPath = "/ws"
+	}
+	if config.Name == "" {
+		config.Name, _ = os.Hostname()
+	}
+	if config.UserLockout.MaxFailures <= 0 {
+		config.UserLockout = DefaultUserLockout
+	}
+	if config.IPLockout.MaxFailures <= 0 {
+		config.IPLockout = DefaultIPLockout
+	}
+	if config.PackTypes == nil {
+		config.PackTypes = card.DefaultPackTypes()
+	}
+	packTypes, err := card.NewPackTypes(config.PackTypes)
 	if err != nil {
-		fmt.Println("Erro do tipo: ", err)
+		panic(fmt.Sprintf("configuração de pacotes inválida: %v", err))
+	}
+	var sessionLimits, ipLimits ratelimit.Limits
+	if !config.RateLimits.Disabled {
+		sessionLimits, ipLimits = config.RateLimits.PerSession, config.RateLimits.PerIP
+	}
+
+	return &Server{
+		config:          config,
+		clock:           config.Clock,
+		stock:           card.NewCardStock(config.Stock, config.Random),
+		packTypes:       packTypes,
+		matches:         match.NewMatchManager(config.Clock),
+		sessionLimits:   ratelimit.NewLimiter(config.Clock, sessionLimits),
+		ipLimits:        ratelimit.NewLimiter(config.Clock, ipLimits),
+		userFailures:    lockout.NewTracker(config.Clock, config.UserLockout),
+		ipFailures:      lockout.NewTracker(config.Clock, config.IPLockout),
+		nextID:          1,
+		connectedUsers:  make(map[int]bool),
+		userConnections: make(map[int]*session),
+		activeConns:     make(map[*session]struct{}),
+		draining:        make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+}
+
+// Função principal do modo servidor: configurada pelas variáveis de ambiente (ver a
+// tabela no README), encerra de forma graciosa ao receber SIGINT/SIGTERM
+func Run() {
+	addr := os.Getenv("SERVER_ADDR")
+	if addr == "" {
+		addr = ":8080"
+	}
+
+	shutdownTimeout := defaultShutdownTimeout
+	if value := os.Getenv("SHUTDOWN_TIMEOUT"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			fmt.Printf("SHUTDOWN_TIMEOUT inválido (%s), usando %v\n", value, defaultShutdownTimeout)
+		} else {
+			shutdownTimeout = parsed
+		}
+	}
+
+	var rateLimits RateLimits
+	if os.Getenv("RATE_LIMITS") == "off" {
+		rateLimits.Disabled = true
+	}
+
+	maxFrameSize := protocol.MAX_FRAME_SIZE
+	if value := os.Getenv("MAX_FRAME_SIZE"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			fmt.Printf("MAX_FRAME_SIZE inválido (%s), usando %d\n", value, maxFrameSize)
+		} else {
+			maxFrameSize = parsed
+		}
+	}
+
+	minVersion := protocol.MIN_PROTOCOL_VERSION
+	if value := os.Getenv("MIN_PROTOCOL_VERSION"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > protocol.PROTOCOL_VERSION {
+			fmt.Printf("MIN_PROTOCOL_VERSION inválido (%s), usando %d\n", value, minVersion)
+		} else {
+			minVersion = parsed
+		}
+	}
+
+	var tlsConfig *tls.Config
+	if os.Getenv("TLS_CERT_FILE") != "" || os.Getenv("TLS_KEY_FILE") != "" || os.Getenv("TLS_SELF_SIGNED") == "on" {
+		options := tlsconfig.ServerOptions{
+			CertFile:   os.Getenv("TLS_CERT_FILE"),
+			KeyFile:    os.Getenv("TLS_KEY_FILE"),
+			SelfSigned: os.Getenv("TLS_SELF_SIGNED") == "on",
+		}
+		if hosts := os.Getenv("TLS_HOSTS"); hosts != "" {
+			options.Hosts = strings.Split(hosts, ",")
+		}
+		var err error
+		if tlsConfig, err = tlsconfig.NewServer(options); err != nil {
+			fmt.Println("Erro ao configurar TLS:", err)
+			return
+		}
+		fmt.Printf("🔐 TLS ativo, certificado SHA-256 %s\n", tlsconfig.ServerFingerprint(tlsConfig))
+	}
+
+	srv := New(Config{Addr: addr, DataFile: os.Getenv("DATA_FILE"), MinProtocolVersion: minVersion, MaxFrameSize: maxFrameSize, RateLimits: rateLimits, TLS: tlsConfig, WebSocketPath: os.Getenv("WS_PATH"), AdminToken: os.Getenv("ADMIN_TOKEN"), Name: os.Getenv("SERVER_NAME"), AdvertiseAddr: os.Getenv("ADVERTISE_ADDR")})
+	if err := srv.LoadState(); err != nil {
+		fmt.Println("Erro ao carregar estado:", err)
 		return
 	}
-	defer ln.Close()
 
-	fmt.Println("Servidor TOP CARD ouvindo na porta 8080...")
+	// Interface de administração (opcional, só com token)
+	if adminAddr := os.Getenv("ADMIN_ADDR"); adminAddr != "" && srv.config.AdminToken == "" {
+		fmt.Println("ADMIN_ADDR definido sem ADMIN_TOKEN: interface de administração desativada")
+	} else if adminAddr != "" {
+		admin := &http.Server{Addr: adminAddr, Handler: srv.AdminHandler()}
+		go func() {
+			fmt.Printf("🛠️ Administração em http://%s/admin/lockouts\n", adminAddr)
+			if err := admin.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fmt.Println("Erro na interface de administração:", err)
+			}
+		}()
+		defer admin.Close()
+	}
+
+	// Cliente web e gateway WebSocket (opcional), para jogar pelo navegador
+	if wsAddr := os.Getenv("WS_ADDR"); wsAddr != "" {
+		gateway := &http.Server{Addr: wsAddr, Handler: srv.WebHandler(), TLSConfig: tlsConfig}
+		go func() {
+			var err error
+			if tlsConfig != nil {
+				fmt.Printf("🌐 Cliente web em https://%s/ (WebSocket em %s)\n", wsAddr, srv.config.WebSocketPath)
+				err = gateway.ListenAndServeTLS("", "")
+			} else {
+				fmt.Printf("🌐 Cliente web em http://%s/ (WebSocket em %s)\n", wsAddr, srv.config.WebSocketPath)
+				err = gateway.ListenAndServe()
+			}
+			if err != nil && err != http.ErrServerClosed {
+				fmt.Println("Erro no gateway WebSocket:", err)
+			}
+		}()
+		defer gateway.Close()
+	}
+
+	// Eco UDP para o cliente medir latência, jitter e perda sem socket ICMP
+	echoAddr := os.Getenv("UDP_ECHO_ADDR")
+	if echoAddr == "" {
+		echoAddr = addr
+	}
+	if echoAddr != "off" {
+		go func() {
+			if err := srv.ListenAndServeEcho(echoAddr); err != nil && err != ErrServerClosed {
+				fmt.Println("Erro no eco UDP:", err)
+			}
+		}()
+	}
+
+	// Anúncios para os clientes encontrarem o servidor sem digitar o IP
+	group := os.Getenv("DISCOVERY_GROUP")
+	if group == "" {
+		group = discovery.DEFAULT_GROUP
+	}
+	if group != "off" {
+		go func() {
+			if err := srv.ListenAndAnnounce(group); err != nil && err != ErrServerClosed {
+				fmt.Println("Erro nos anúncios na rede local:", err)
+			}
+		}()
+	}
 
-	// Inicia o matchmaker em uma goroutine separada
-	go matchmaker()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	go cleanupOrphanedMatches()
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		if err != nil && err != ErrServerClosed {
+			fmt.Println("Erro do tipo: ", err)
+		}
+		return
+	case <-ctx.Done():
+	}
+	stop() // Um segundo sinal encerra o processo imediatamente
+
+	fmt.Printf("🛑 Sinal recebido, encerrando servidor (prazo de %v)...\n", shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		fmt.Println("Encerramento incompleto:", err)
+	}
+	<-serveErr
+}
+
+// Abre o listener TCP no endereço configurado e atende conexões
+func (s *Server) ListenAndServe() error {
+	ln, err := net.Listen("tcp", s.config.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Função para abrir o serviço de eco UDP em addr e atendê-lo até o encerramento
+func (s *Server) ListenAndServeEcho(addr string) error {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	return s.ServeEcho(conn)
+}
+
+// Responde às sondas de latência do cliente em conn (ver udpecho) até o servidor ser
+// encerrado. As sondas contam nos limites por IP com o tipo udpecho.RATE_KEY.
+func (s *Server) ServeEcho(conn net.PacketConn) error {
+	s.stateMutex.Lock()
+	if s.closed {
+		s.stateMutex.Unlock()
+		conn.Close()
+		return ErrServerClosed
+	}
+	s.echoConn = conn
+	s.stateMutex.Unlock()
+	defer conn.Close()
+
+	fmt.Printf("📡 Eco UDP de latência em %s\n", conn.LocalAddr())
+	echo := &udpecho.Server{
+		Clock: s.clock,
+		Allow: func(ip string) bool {
+			allowed, _ := s.ipLimits.Allow(ip, udpecho.RATE_KEY)
+			return allowed
+		},
+	}
+	if err := echo.Serve(conn); err != nil {
+		return err
+	}
+	return ErrServerClosed
+}
+
+// Função para anunciar o servidor no grupo informado (multicast ou broadcast) até
+// o encerramento
+func (s *Server) ListenAndAnnounce(group string) error {
+	target, err := net.ResolveUDPAddr("udp4", group)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return err
+	}
+	return s.Announce(conn, target)
+}
+
+// Envia por conn, a cada Config.AnnounceInterval, o anúncio do servidor para target
+// (ver discovery) até o início do encerramento: servidores encerrando não aparecem
+// mais para quem procura.
+func (s *Server) Announce(conn net.PacketConn, target net.Addr) error {
+	defer conn.Close()
+	if s.isClosed() {
+		return ErrServerClosed
+	}
+
+	fmt.Printf("📣 Anunciando \"%s\" na rede local em %s\n", s.config.Name, target)
+	announcer := &discovery.Announcer{
+		Conn:     conn,
+		Target:   target,
+		Interval: s.config.AnnounceInterval,
+		Info:     s.announcement,
+	}
+	if err := announcer.Run(s.draining); err != nil {
+		return err
+	}
+	return ErrServerClosed
+}
+
+// Anúncio com o estado atual do servidor; false enquanto Serve ainda não começou a
+// aceitar conexões
+func (s *Server) announcement() (discovery.Announcement, bool) {
+	s.stateMutex.Lock()
+	listener := s.listener
+	s.stateMutex.Unlock()
+	if listener == nil {
+		return discovery.Announcement{}, false
+	}
+	addr := s.config.AdvertiseAddr
+	if addr == "" {
+		addr = listener.Addr().String()
+	}
+
+	s.connectionsMutex.Lock()
+	players := len(s.userConnections)
+	s.connectionsMutex.Unlock()
+
+	return discovery.Announcement{
+		Name:            s.config.Name,
+		Addr:            addr,
+		Players:         players,
+		Version:         serverVersion,
+		ProtocolVersion: protocol.PROTOCOL_VERSION,
+		TLS:             s.config.TLS != nil,
+	}, true
+}
+
+// Atende conexões no listener informado até o servidor ser encerrado. Com Config.TLS
+// o listener é envolvido por TLS.
+func (s *Server) Serve(ln net.Listener) error {
+	if s.config.TLS != nil {
+		ln = tls.NewListener(ln, s.config.TLS)
+	}
+
+	s.stateMutex.Lock()
+	if s.closed {
+		s.stateMutex.Unlock()
+		ln.Close()
+		return ErrServerClosed
+	}
+	s.listener = ln
+	s.stateMutex.Unlock()
+	defer ln.Close()
+
+	fmt.Printf("Servidor TOP CARD ouvindo em %s...\n", ln.Addr())
+
+	s.startBackground()
 
 	for {
 		conn, err := ln.Accept()
 		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
 			fmt.Println("Erro do tipo: ", err)
-			continue
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return err
+		}
+
+		sess := newSession(conn, s.config.OutboundQueueSize, s.config.WriteTimeout)
+		if !s.trackConn(sess, true) {
+			conn.Close()
+			return ErrServerClosed
 		}
 
 		fmt.Println("Cliente conectado")
-		go handleConnection(conn)
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serveSession(sess)
+		}()
+	}
+}
+
+// Inicia o matchmaker e a limpeza de partidas em goroutines separadas (uma única vez,
+// qualquer que seja o transporte da primeira conexão)
+func (s *Server) startBackground() {
+	s.startOnce.Do(func() {
+		s.wg.Add(2)
+		go s.matchmaker()
+		go s.cleanupOrphanedMatches()
+	})
+}
+
+// Atende uma sessão já registrada até a conexão terminar: a escrita roda em outra
+// goroutine e a leitura na goroutine de quem chamou. Só retorna depois que a escrita
+// enviou as mensagens pendentes e fechou a conexão.
+func (s *Server) serveSession(sess *session) {
+	written := make(chan struct{})
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer close(written)
+		sess.writeLoop()
+	}()
+
+	s.handleConnection(sess)
+	<-written
+	s.trackConn(sess, false)
+}
+
+// Endereço em que o servidor está ouvindo (nil antes de Serve)
+func (s *Server) Addr() net.Addr {
+	s.stateMutex.Lock()
+	defer s.stateMutex.Unlock()
+
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Encerra o servidor de forma graciosa:
+//  1. para de aceitar conexões, para o matchmaker e esvazia a fila;
+//  2. avisa os clientes conectados com SERVER_SHUTDOWN;
+//  3. aguarda as partidas em andamento terminarem até o prazo do contexto;
+//  4. interrompe as partidas restantes, devolvendo as cartas já jogadas;
+//  5. fecha as conexões, aguarda os handlers e salva o estado (se DataFile estiver configurado).
+// Retorna ctx.Err() se alguma partida precisou ser interrompida.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.stateMutex.Lock()
+	if s.closed {
+		s.stateMutex.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.draining)
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	if s.echoConn != nil {
+		s.echoConn.Close()
+	}
+	s.stateMutex.Unlock()
+
+	s.queueMutex.Lock()
+	s.queue = nil
+	s.queueMutex.Unlock()
+
+	deadline, _ := ctx.Deadline()
+	s.broadcastShutdown("O servidor está sendo encerrado. Partidas em andamento podem terminar; novas partidas não serão criadas.", deadline)
+
+	drainErr := s.drainMatches(ctx)
+	if drainErr != nil {
+		s.interruptMatches()
+	}
+
+	close(s.done)
+	s.stateMutex.Lock()
+	for sess := range s.activeConns {
+		sess.close() // Envia o que estiver pendente e fecha a conexão
+	}
+	s.stateMutex.Unlock()
+	s.wg.Wait()
+
+	// Só salva depois que nenhum handler pode mais alterar os jogadores
+	var saveErr error
+	if s.config.DataFile != "" {
+		saveErr = s.SaveState()
+		if saveErr == nil {
+			fmt.Printf("💾 Estado salvo em %s\n", s.config.DataFile)
+		}
+	}
+
+	fmt.Println("🛑 Servidor TOP CARD encerrado")
+	if saveErr != nil {
+		return saveErr
+	}
+	return drainErr
+}
+
+// Envia o aviso de encerramento para todos os usuários conectados
+func (s *Server) broadcastShutdown(message string, deadline time.Time) {
+	notice, err := protocol.CreateServerShutdown(message, deadline)
+	if err != nil {
+		fmt.Println("Erro ao criar aviso de encerramento:", err)
+		return
+	}
+	notice = append(notice, '\n')
+
+	s.connectionsMutex.Lock()
+	defer s.connectionsMutex.Unlock()
+
+	for _, conn := range s.userConnections {
+		conn.Write(notice)
+	}
+}
+
+// Aguarda todas as partidas ativas terminarem ou o contexto expirar
+func (s *Server) drainMatches(ctx context.Context) error {
+	for {
+		active := s.matches.GetAllActiveMatches()
+		if len(active) == 0 {
+			return nil
+		}
+		fmt.Printf("⏳ Aguardando %d partida(s) em andamento...\n", len(active))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.clock.After(s.config.DrainPollInterval):
+		}
+	}
+}
+
+// Interrompe as partidas que não terminaram no prazo: devolve as cartas jogadas,
+// avisa os jogadores e registra as partidas para o estado salvo
+func (s *Server) interruptMatches() {
+	for _, active := range s.matches.GetAllActiveMatches() {
+		interrupted, ok := s.matches.InterruptMatch(active.ID)
+		if !ok {
+			continue // Terminou entre a listagem e a interrupção
+		}
+
+		if interrupted.Player1Card != nil {
+			interrupted.Player1.AddCards([]card.Card{*interrupted.Player1Card})
+		}
+		if interrupted.Player2Card != nil {
+			interrupted.Player2.AddCards([]card.Card{*interrupted.Player2Card})
+		}
+		if interrupted.Player1Card != nil {
+			s.notifyInventory(interrupted.Player1, protocol.INVENTORY_CARDS_RETURNED)
+		}
+		if interrupted.Player2Card != nil {
+			s.notifyInventory(interrupted.Player2, protocol.INVENTORY_CARDS_RETURNED)
+		}
+
+		s.stateMutex.Lock()
+		s.interrupted = append(s.interrupted, storage.InterruptedMatch{
+			MatchID:     interrupted.ID,
+			Player1ID:   interrupted.Player1.GetID(),
+			Player2ID:   interrupted.Player2.GetID(),
+			Status:      interrupted.Status,
+			Player1Card: interrupted.Player1Card,
+			Player2Card: interrupted.Player2Card,
+			StartedAt:   interrupted.StartedAt,
+			Interrupted: s.clock.Now(),
+		})
+		s.stateMutex.Unlock()
+
+		message := "Partida interrompida pelo encerramento do servidor. As cartas jogadas foram devolvidas."
+		response, err := protocol.CreateMatchEnd(interrupted.ID, 0, "", message)
+		if err != nil {
+			continue
+		}
+		response = append(response, '\n')
+
+		s.connectionsMutex.Lock()
+		for _, playerID := range []int{interrupted.Player1.GetID(), interrupted.Player2.GetID()} {
+			if conn, exists := s.userConnections[playerID]; exists {
+				conn.Write(response)
+			}
+		}
+		s.connectionsMutex.Unlock()
 	}
 }
 
+// Carrega o estado salvo em DataFile (jogadores e estoque), se existir
+func (s *Server) LoadState() error {
+	if s.config.DataFile == "" {
+		return nil
+	}
+
+	snapshot, ok, err := storage.Load(s.config.DataFile)
+	if err != nil || !ok {
+		return err
+	}
+
+	s.playersMutex.Lock()
+	defer s.playersMutex.Unlock()
+
+	s.players = s.players[:0]
+	for _, record := range snapshot.Players {
+		s.players = append(s.players, player.FromRecord(record))
+		if record.ID >= s.nextID {
+			s.nextID = record.ID + 1
+		}
+	}
+	if snapshot.NextID > s.nextID {
+		s.nextID = snapshot.NextID
+	}
+	s.stock = card.NewCardStock(snapshot.Stock, s.config.Random)
+
+	s.stateMutex.Lock()
+	s.interrupted = snapshot.Interrupted
+	s.stateMutex.Unlock()
+
+	fmt.Printf("📂 Estado carregado de %s: %d jogador(es)\n", s.config.DataFile, len(snapshot.Players))
+	return nil
+}
+
+// Salva o estado atual (jogadores, estoque e partidas interrompidas) em DataFile
+func (s *Server) SaveState() error {
+	if s.config.DataFile == "" {
+		return errors.New("arquivo de estado não configurado")
+	}
+
+	s.playersMutex.Lock()
+	snapshot := storage.Snapshot{
+		SavedAt: s.clock.Now(),
+		NextID:  s.nextID,
+		Stock:   s.stock.Counts(),
+	}
+	for _, p := range s.players {
+		snapshot.Players = append(snapshot.Players, p.Record())
+	}
+	s.playersMutex.Unlock()
+
+	s.stateMutex.Lock()
+	snapshot.Interrupted = append(snapshot.Interrupted, s.interrupted...)
+	s.stateMutex.Unlock()
+
+	return storage.Save(s.config.DataFile, snapshot)
+}
+
+// Verifica se o servidor já foi encerrado
+func (s *Server) isClosed() bool {
+	s.stateMutex.Lock()
+	defer s.stateMutex.Unlock()
+	return s.closed
+}
+
+// Registra (ou remove) uma conexão aberta. Retorna false se o servidor já foi encerrado.
+func (s *Server) trackConn(conn *session, add bool) bool {
+	s.stateMutex.Lock()
+	defer s.stateMutex.Unlock()
+
+	if add {
+		if s.closed {
+			return false
+		}
+		s.activeConns[conn] = struct{}{}
+	} else {
+		delete(s.activeConns, conn)
+	}
+	return true
+}
+
 // Matchmaker - processa a fila e cria partidas quando há 2 jogadores
-func matchmaker() {
-	ticker := time.NewTicker(1 * time.Second) // Verifica a cada segundo
+func (s *Server) matchmaker() {
+	defer s.wg.Done()
+
+	ticker := s.clock.NewTicker(s.config.MatchmakerInterval) // Verifica a cada segundo
 	defer ticker.Stop()
 
-	for range ticker.C {
-		queueMutex.Lock()
-		if len(queue) >= 2 {
-			// Pega os dois primeiros jogadores da fila
-			player1ID := queue[0]
-			player2ID := queue[1]
-			
-			// Remove eles da fila
-			queue = queue[2:]
-			
-			fmt.Printf("🎯 Matchmaker: Criando partida entre %d e %d\n", player1ID, player2ID)
+	for {
+		select {
+		case <-s.draining:
+			return
+		case <-ticker.C():
+		}
+
+		s.queueMutex.Lock()
+		if len(s.queue) < 2 {
+			s.queueMutex.Unlock()
+			continue
+		}
+
+		// Pega os dois primeiros jogadores da fila
+		player1ID := s.queue[0]
+		player2ID := s.queue[1]
+		
+		// Remove eles da fila
+		s.queue = s.queue[2:]
+		s.queueMutex.Unlock()
+		
+		fmt.Printf("🎯 Matchmaker: Criando partida entre %d e %d\n", player1ID, player2ID)
+		
+		// Busca os objetos Player
+		player1, found1 := s.findPlayerByID(player1ID)
+		player2, found2 := s.findPlayerByID(player2ID)
+		
+		if found1 && found2 {
+			// Cria a partida
+			newMatch := s.matches.CreateMatch(player1, player2)
 			
-			// Busca os objetos Player
-			player1, found1 := findPlayerByID(player1ID)
-			player2, found2 := findPlayerByID(player2ID)
+			// Notifica os jogadores sobre a partida encontrada
+			go s.notifyMatchFound(player1ID, player2ID, player2.GetUserName(), newMatch.ID)
+			go s.notifyMatchFound(player2ID, player1ID, player1.GetUserName(), newMatch.ID)
 			
-			if found1 && found2 {
-				// Cria a partida
-				newMatch := match.GetManager().CreateMatch(player1, player2)
-				
-				// Notifica os jogadores sobre a partida encontrada
-				go notifyMatchFound(player1ID, player2ID, player2.GetUserName(), newMatch.ID)
-				go notifyMatchFound(player2ID, player1ID, player1.GetUserName(), newMatch.ID)
-				
-				// Aguarda um pouco e inicia a partida
-				time.Sleep(2 * time.Second)
-				match.GetManager().StartMatch(newMatch.ID)
-				
-				// Notifica o início da partida
-				go notifyMatchStart(player1ID, player2ID, newMatch.ID)
+			// Aguarda um pouco e inicia a partida
+			select {
+			case <-s.done:
+				return
+			case <-s.clock.After(s.config.MatchStartDelay):
 			}
+			s.matches.StartMatch(newMatch.ID)
+			
+			// Notifica o início da partida
+			go s.notifyMatchStart(player1ID, player2ID, newMatch.ID)
 		}
-		queueMutex.Unlock()
 	}
 }
 
 // Notifica jogador sobre partida encontrada
-func notifyMatchFound(playerID, opponentID int, opponentName string, matchID int) {
-	connectionsMutex.Lock()
-	conn, exists := userConnections[playerID]
-	connectionsMutex.Unlock()
+func (s *Server) notifyMatchFound(playerID, opponentID int, opponentName string, matchID int) {
+	s.connectionsMutex.Lock()
+	conn, exists := s.userConnections[playerID]
+	s.connectionsMutex.Unlock()
 	
 	if !exists {
 		fmt.Printf("⚠️  Conexão não encontrada para jogador %d\n", playerID)
@@ -117,17 +910,17 @@ func notifyMatchFound(playerID, opponentID int, opponentName string, matchID int
 }
 
 // Notifica jogadores sobre início da partida
-func notifyMatchStart(player1ID, player2ID, matchID int) {
+func (s *Server) notifyMatchStart(player1ID, player2ID, matchID int) {
 	message := "A partida começou! Boa sorte!"
 	
 	// Inicia o jogo
-	time.Sleep(1 * time.Second)
-	match.GetManager().StartGame(matchID)
+	s.clock.Sleep(s.config.GameStartDelay)
+	s.matches.StartGame(matchID)
 	
 	// Notifica jogador 1 (é o primeiro a jogar)
-	connectionsMutex.Lock()
-	conn1, exists1 := userConnections[player1ID]
-	connectionsMutex.Unlock()
+	s.connectionsMutex.Lock()
+	conn1, exists1 := s.userConnections[player1ID]
+	s.connectionsMutex.Unlock()
 	
 	if exists1 {
 		response, err := protocol.CreateMatchStart(matchID, message)
@@ -145,9 +938,9 @@ func notifyMatchStart(player1ID, player2ID, matchID int) {
 	}
 
 	// Notifica jogador 2
-	connectionsMutex.Lock()
-	conn2, exists2 := userConnections[player2ID]
-	connectionsMutex.Unlock()
+	s.connectionsMutex.Lock()
+	conn2, exists2 := s.userConnections[player2ID]
+	s.connectionsMutex.Unlock()
 	
 	if exists2 {
 		response, err := protocol.CreateMatchStart(matchID, message)
@@ -166,75 +959,279 @@ func notifyMatchStart(player1ID, player2ID, matchID int) {
 }
 
 
-func handleConnection(conn net.Conn) {
+func (s *Server) handleConnection(conn *session) {
 	defer func() {
-		conn.Close()
+		conn.close()
+		s.sessionLimits.Forget(conn.id())
 		
 		// Remove o usuário das estruturas quando desconectar
-		connectionsMutex.Lock()
-		for userID, userConn := range userConnections {
+		s.connectionsMutex.Lock()
+		for userID, userConn := range s.userConnections {
 			if userConn == conn {
-				delete(userConnections, userID)
+				delete(s.userConnections, userID)
 				
-				connectedMutex.Lock()
-				delete(connectedUsers, userID)
-				connectedMutex.Unlock()
+				s.connectedMutex.Lock()
+				delete(s.connectedUsers, userID)
+				s.connectedMutex.Unlock()
 				
 				// Remove da fila se estiver lá
-				queueMutex.Lock()
-				for i, playerID := range queue {
+				s.queueMutex.Lock()
+				for i, playerID := range s.queue {
 					if playerID == userID {
-						queue = append(queue[:i], queue[i+1:]...)
+						s.queue = append(s.queue[:i], s.queue[i+1:]...)
 						break
 					}
 				}
-				queueMutex.Unlock()
+				s.queueMutex.Unlock()
 				
 				fmt.Printf("👋 Usuário %d desconectado\n", userID)
 				break
 			}
 		}
-		connectionsMutex.Unlock()
+		s.connectionsMutex.Unlock()
 	}()
 	
-	scanner := bufio.NewScanner(conn)
+	// A conexão começa em JSON; o leitor é recriado se o HELLO negociar outro codec
+	reader := bufio.NewReader(conn.conn)
+	frames := protocol.JSONCodec.NewFrameReader(reader, s.config.MaxFrameSize)
+	firstMessage := true
+	violations := 0 // Mensagens malformadas, grandes demais ou de tipo desconhecido
 
-	for scanner.Scan() {
-		data := scanner.Bytes()
-		
-		// Decodifica a mensagem recebida
-		message, err := protocol.DecodeMessage(data)
+	for {
+		// Lê e decodifica a próxima mensagem
+		message, err := frames.ReadMessage()
+		if err != nil && !protocol.IsFrameError(err) {
+			if err != io.EOF {
+				fmt.Println("Erro ao ler do cliente:", err)
+			}
+			return
+		}
+
+		// HELLO só é aceito como primeira mensagem; sem ele a conexão fala a versão 1.
+		// HELLO repetido ou inválido conta como violação de protocolo.
+		if err == nil && message.Type == protocol.MSG_HELLO {
+			if !s.handleHello(responder{session: conn, requestID: message.RequestID}, message, firstMessage, &violations) {
+				return
+			}
+			frames = conn.currentCodec().NewFrameReader(reader, s.config.MaxFrameSize)
+			firstMessage = false
+			continue
+		}
+		if firstMessage && s.config.MinProtocolVersion > 1 {
+			s.refuseLegacy(conn)
+			return
+		}
+		firstMessage = false
+
+		if errors.Is(err, protocol.ErrUnknownType) {
+			fmt.Println("Tipo de mensagem não reconhecido:", message.Type)
+			if !s.protocolViolation(responder{session: conn, requestID: message.RequestID}, &violations, protocol.ERR_UNKNOWN_MESSAGE_TYPE, "Tipo de mensagem não reconhecido: "+message.Type) {
+				return
+			}
+			continue
+		}
+		if errors.Is(err, protocol.ErrFrameTooLarge) {
+			fmt.Println("Mensagem grande demais:", err)
+			if !s.protocolViolation(conn, &violations, protocol.ERR_FRAME_TOO_LARGE,
+				fmt.Sprintf("Mensagem maior que o limite de %d bytes", s.config.MaxFrameSize)) {
+				return
+			}
+			continue
+		}
 		if err != nil {
 			fmt.Println("Erro ao decodificar mensagem:", err)
+			if !s.protocolViolation(conn, &violations, protocol.ERR_INVALID_MESSAGE, "Mensagem inválida: "+err.Error()) {
+				return
+			}
+			continue
+		}
+
+		// Respostas da requisição repetem o request_id recebido
+		reply := responder{session: conn, requestID: message.RequestID}
+
+		// Requisições acima do orçamento da conexão ou do IP não são processadas
+		if !s.allowRequest(reply, message.Type) {
 			continue
 		}
 
 		// Processa baseado no tipo da mensagem
 		switch message.Type {
 		case protocol.MSG_LOGIN_REQUEST:
-			handleLogin(conn, message)
+			s.handleLogin(reply, message)
 		case protocol.MSG_REGISTER_REQUEST:
-			handleRegister(conn, message)
+			s.handleRegister(reply, message)
 		case protocol.MSG_QUEUE_REQUEST:
-			handleQueue(conn, message)
+			s.handleQueue(reply, message)
 		case protocol.MSG_STATS_REQUEST:  
-			handleStats(conn, message)
+			s.handleStats(reply, message)
 		case protocol.MSG_CARD_PACK_REQUEST:
-			handleCardPack(conn, message)
+			s.handleCardPack(reply, message)
 		case protocol.MSG_CARD_MOVE:
-			handleCardMove(conn, message)
+			s.handleCardMove(reply, message)
+		case protocol.MSG_SEED_REQUEST:
+			s.handleSeed(reply, message)
+		case protocol.MSG_CHAT_MESSAGE:
+			s.handleChat(reply, message)
+		case protocol.MSG_INVENTORY_REQUEST:
+			s.handleInventory(reply, message)
 		default:
 			fmt.Println("Tipo de mensagem não reconhecido:", message.Type)
+			if !s.protocolViolation(reply, &violations, protocol.ERR_UNKNOWN_MESSAGE_TYPE, "Tipo de mensagem não reconhecido: "+message.Type) {
+				return
+			}
 		}
 	}
+}
+
+
+// Conexão que sabe quais capacidades o cliente negociou (session ou responder)
+type clientConn interface {
+	WriteMessage(message *protocol.Message) error
+	supports(capability string) bool
+}
+
+// Trata o HELLO: negocia versão e capacidades e responde com WELCOME. HELLO fora
+// de ordem ou inválido conta em violations. Retorna false se a conexão deve ser
+// encerrada (versão não suportada ou limite de violações).
+func (s *Server) handleHello(conn responder, message *protocol.Message, firstMessage bool, violations *int) bool {
+	hello, err := protocol.ExtractHello(message)
+	if err != nil {
+		fmt.Println("Erro ao extrair dados do HELLO:", err)
+		return s.protocolViolation(conn, violations, protocol.ERR_INVALID_MESSAGE, "Dados inválidos: "+err.Error())
+	}
+
+	var (
+		accepted     bool
+		responseMsg  string
+		errorCode    string
+		capabilities []string
+	)
+
+	switch {
+	case !firstMessage:
+		// Versão e capacidades não mudam no meio da conexão
+		responseMsg = "HELLO deve ser a primeira mensagem da conexão"
+		errorCode = protocol.ERR_HANDSHAKE_OUT_OF_ORDER
+	case hello.ProtocolVersion < s.config.MinProtocolVersion:
+		responseMsg = fmt.Sprintf("Versão do protocolo %d não é mais suportada (mínima: %d). Atualize o cliente TOP CARD.",
+			hello.ProtocolVersion, s.config.MinProtocolVersion)
+		errorCode = protocol.ERR_UNSUPPORTED_VERSION
+	default:
+		// Clientes mais novos falam a versão do servidor
+		version := min(hello.ProtocolVersion, protocol.PROTOCOL_VERSION)
+		capabilities = protocol.NegotiateCapabilities(hello.Capabilities, conn.offered)
+		conn.negotiate(version, capabilities)
+
+		accepted = true
+		responseMsg = fmt.Sprintf("Bem-vindo ao TOP CARD! Protocolo versão %d", version)
+		fmt.Printf("🤝 %s %s conectado (protocolo %d, capacidades: %v)\n",
+			hello.ClientName, hello.ClientVersion, version, capabilities)
+	}
+
+	response, err := protocol.NewWelcome(accepted, responseMsg, s.config.MinProtocolVersion, serverName, serverVersion, capabilities)
+	if err != nil {
+		fmt.Println("Erro ao criar WELCOME:", err)
+		return accepted
+	}
+	// O WELCOME sempre repete o request_id: quem envia HELLO já conhece o campo,
+	// mesmo que a conexão seja recusada antes de negociar as capacidades
+	response.ErrorCode = errorCode
+	response.RequestID = conn.requestID
+	conn.session.WriteMessage(response)
+
+	// O WELCOME vai em JSON; só depois dele a conexão passa para frames binários
+	if conn.supports(protocol.CAP_BINARY_FRAMES) {
+		conn.setCodec(protocol.BinaryCodec)
+	}
+
+	switch errorCode {
+	case protocol.ERR_UNSUPPORTED_VERSION:
+		// Cliente incompatível: encerra depois de enviar o WELCOME recusado
+		fmt.Printf("⛔ Cliente %s %s recusado: protocolo %d\n", hello.ClientName, hello.ClientVersion, hello.ProtocolVersion)
+		return false
+	case protocol.ERR_HANDSHAKE_OUT_OF_ORDER:
+		// O WELCOME recusado já é a resposta; só conta a violação
+		return s.countViolation(conn, violations)
+	}
+	return true
+}
+
+// Consome o orçamento da requisição na conexão e no IP. Se algum estiver esgotado
+// responde RATE_LIMITED com a espera sugerida e retorna false.
+func (s *Server) allowRequest(conn responder, msgType string) bool {
+	sessionAllowed, sessionWait := s.sessionLimits.Allow(conn.id(), msgType)
+	ipAllowed, ipWait := s.ipLimits.Allow(conn.remoteIP(), msgType)
+	if sessionAllowed && ipAllowed {
+		return true
+	}
+
+	retryAfter := max(sessionWait, ipWait)
+	fmt.Printf("🚦 %s de %s limitado, próxima em %v\n", msgType, conn.conn.RemoteAddr(), retryAfter)
+	if !conn.supports(protocol.CAP_ERROR_CODES) {
+		return false
+	}
 
-	if err := scanner.Err(); err != nil {
-		fmt.Println("Erro ao ler do cliente:", err)
+	response, err := protocol.NewRateLimited(retryAfter,
+		fmt.Sprintf("Muitas requisições. Tente novamente em %.1fs", retryAfter.Seconds()))
+	if err != nil {
+		fmt.Println("Erro ao criar mensagem de erro:", err)
+		return false
 	}
+	conn.WriteMessage(response)
+	return false
+}
+
+// Registra uma violação de protocolo da conexão e responde com ERROR. Ao atingir
+// MaxViolations avisa o cliente e retorna false: a conexão deve ser encerrada.
+func (s *Server) protocolViolation(conn clientConn, violations *int, code, message string) bool {
+	s.sendError(conn, code, message)
+	return s.countViolation(conn, violations)
 }
 
+// Conta uma violação já respondida. Ao atingir MaxViolations avisa o cliente e
+// retorna false: a conexão deve ser encerrada.
+func (s *Server) countViolation(conn clientConn, violations *int) bool {
+	*violations++
+	if *violations < s.config.MaxViolations {
+		return true
+	}
 
-func notifyMatchEnd(player1ID, player2ID int, currentMatch *match.Match) {
+	fmt.Printf("⛔ Conexão encerrada após %d violações de protocolo\n", *violations)
+	s.sendError(conn, protocol.ERR_TOO_MANY_VIOLATIONS,
+		fmt.Sprintf("Conexão encerrada após %d mensagens inválidas", *violations))
+	return false
+}
+
+// Recusa um cliente antigo, que não enviou HELLO, quando a versão 1 não é mais aceita
+func (s *Server) refuseLegacy(conn *session) {
+	fmt.Printf("⛔ Cliente sem HELLO recusado: %s\n", conn.conn.RemoteAddr())
+	response, err := protocol.CreateError(protocol.ERR_UNSUPPORTED_VERSION,
+		fmt.Sprintf("Este servidor exige o protocolo versão %d ou superior. Atualize o cliente TOP CARD.", s.config.MinProtocolVersion))
+	if err != nil {
+		fmt.Println("Erro ao criar mensagem de erro:", err)
+		return
+	}
+	response = append(response, '\n')
+	conn.Write(response)
+}
+
+// Envia uma mensagem ERROR com o código informado. Clientes que não negociaram
+// error_codes não conhecem a mensagem ERROR, então o erro só é registrado no log.
+func (s *Server) sendError(conn clientConn, code, message string) {
+	if !conn.supports(protocol.CAP_ERROR_CODES) {
+		fmt.Printf("Erro %s não enviado (cliente sem error_codes): %s\n", code, message)
+		return
+	}
+
+	response, err := protocol.NewError(code, message)
+	if err != nil {
+		fmt.Println("Erro ao criar mensagem de erro:", err)
+		return
+	}
+	conn.WriteMessage(response)
+}
+
+func (s *Server) notifyMatchEnd(player1ID, player2ID int, currentMatch *match.Match) {
 	var winnerName string
 	if currentMatch.Player1.GetID() == currentMatch.Winner {
 		winnerName = currentMatch.Player1.GetUserName()
@@ -261,12 +1258,12 @@ func notifyMatchEnd(player1ID, player2ID int, currentMatch *match.Match) {
 		currentMatch.Player2.GetUserName(), player2Card)
 	
 	// Atualiza as estatísticas dos jogadores
-	updatePlayerStats(currentMatch.Player1.GetID(), currentMatch.Player2.GetID(), currentMatch.Winner)
+	s.updatePlayerStats(currentMatch.Player1.GetID(), currentMatch.Player2.GetID(), currentMatch.Winner)
 	
 	// Notifica jogador 1
-	connectionsMutex.Lock()
-	conn1, exists1 := userConnections[player1ID]
-	connectionsMutex.Unlock()
+	s.connectionsMutex.Lock()
+	conn1, exists1 := s.userConnections[player1ID]
+	s.connectionsMutex.Unlock()
 	
 	if exists1 {
 		response, err := protocol.CreateMatchEnd(currentMatch.ID, currentMatch.Winner, winnerName, message)
@@ -277,9 +1274,9 @@ func notifyMatchEnd(player1ID, player2ID int, currentMatch *match.Match) {
 	}
 
 	// Notifica jogador 2
-	connectionsMutex.Lock()
-	conn2, exists2 := userConnections[player2ID]
-	connectionsMutex.Unlock()
+	s.connectionsMutex.Lock()
+	conn2, exists2 := s.userConnections[player2ID]
+	s.connectionsMutex.Unlock()
 	
 	if exists2 {
 		response, err := protocol.CreateMatchEnd(currentMatch.ID, currentMatch.Winner, winnerName, message)
@@ -291,36 +1288,42 @@ func notifyMatchEnd(player1ID, player2ID int, currentMatch *match.Match) {
 }
 
 
-func updatePlayerStats(player1ID, player2ID, winnerID int) {
+func (s *Server) updatePlayerStats(player1ID, player2ID, winnerID int) {
+	s.playersMutex.Lock()
+	defer s.playersMutex.Unlock()
+
 	// Encontra os players no slice e atualiza suas estatísticas
-	for i := range players {
-		if players[i].GetID() == player1ID {
+	for i := range s.players {
+		if s.players[i].GetID() == player1ID {
 			if winnerID == player1ID {
-				players[i].AddWin()
+				s.players[i].AddWin()
+				s.players[i].AddCoins(player.WIN_COINS)
 			} else {
-				players[i].AddLoss()
+				s.players[i].AddLoss()
 			}
 			fmt.Printf("📊 Estatísticas atualizadas para %s: %dW-%dL\n", 
-				players[i].GetUserName(), players[i].GetWins(), players[i].GetLosses())
-		} else if players[i].GetID() == player2ID {
+				s.players[i].GetUserName(), s.players[i].GetWins(), s.players[i].GetLosses())
+		} else if s.players[i].GetID() == player2ID {
 			if winnerID == player2ID {
-				players[i].AddWin()
+				s.players[i].AddWin()
+				s.players[i].AddCoins(player.WIN_COINS)
 			} else {
-				players[i].AddLoss()
+				s.players[i].AddLoss()
 			}
 			fmt.Printf("📊 Estatísticas atualizadas para %s: %dW-%dL\n", 
-				players[i].GetUserName(), players[i].GetWins(), players[i].GetLosses())
+				s.players[i].GetUserName(), s.players[i].GetWins(), s.players[i].GetLosses())
 		}
 	}
 }
 
 
 // função para lidar com jogadas no servidor
-func handleCardMove(conn net.Conn, message *protocol.Message) {
+func (s *Server) handleCardMove(conn responder, message *protocol.Message) {
 	// Extrai os dados da jogada com carta
 	cardMove, err := protocol.ExtractCardMove(message)
 	if err != nil {
 		fmt.Println("Erro ao extrair dados da jogada de carta:", err)
+		s.sendError(conn, protocol.ERR_INVALID_MESSAGE, "Dados inválidos: "+err.Error())
 		return
 	}
 
@@ -328,87 +1331,255 @@ func handleCardMove(conn net.Conn, message *protocol.Message) {
 		cardMove.UserID, cardMove.MatchID, cardMove.CardType)
 
 	// Processa a jogada de carta
-	success, responseMessage := match.GetManager().MakeCardMove(cardMove.MatchID, cardMove.UserID, cardMove.CardType)
+	success, responseMessage, errorCode := s.matches.MakeCardMove(cardMove.MatchID, cardMove.UserID, cardMove.CardType)
 	
 	if !success {
-		// Envia mensagem de erro para o jogador
-		response, err := protocol.CreateGameState(cardMove.MatchID, responseMessage, false, false, false)
+		// Recusa a jogada sem mexer no turno do jogador. Clientes sem error_codes
+		// recebem o GAME_STATE da versão 1.
+		var response *protocol.Message
+		if conn.supports(protocol.CAP_ERROR_CODES) {
+			response, err = protocol.NewMoveRejected(cardMove.MatchID, cardMove.CardType, errorCode, responseMessage)
+		} else {
+			response, err = protocol.NewGameState(cardMove.MatchID, responseMessage, false, false, false)
+		}
 		if err != nil {
 			fmt.Printf("Erro ao criar resposta de erro: %v\n", err)
 			return
 		}
 		
-		response = append(response, '\n')
-		conn.Write(response)
+		conn.WriteMessage(response)
 		return
 	}
 
 	// Se a jogada foi bem-sucedida, verifica o estado da partida
-	currentMatch := match.GetManager().GetMatch(cardMove.MatchID)
+	currentMatch := s.matches.GetMatch(cardMove.MatchID)
 	if currentMatch == nil {
 		fmt.Printf("Partida %d não encontrada\n", cardMove.MatchID)
 		return
 	}
 
+	// A carta jogada saiu do inventário: avisa antes das notificações da partida
+	if mover, found := s.findPlayerByID(cardMove.UserID); found {
+		s.notifyInventory(mover, protocol.INVENTORY_CARD_PLAYED)
+	}
+
 	// Se o jogo terminou (ambos jogaram)
 	if currentMatch.Status == "finished" {
 		// Notifica fim de partida para ambos jogadores
-		go notifyMatchEnd(currentMatch.Player1.GetID(), currentMatch.Player2.GetID(), currentMatch)
+		go s.notifyMatchEnd(currentMatch.Player1.GetID(), currentMatch.Player2.GetID(), currentMatch)
 	} else {
 		// Notifica atualização de turno para ambos jogadores
-		go notifyTurnUpdate(currentMatch)
+		go s.notifyTurnUpdate(currentMatch)
 	}
 }
 
-func handleCardPack(conn net.Conn, message *protocol.Message) {
+// Repassa uma mensagem de chat ao oponente da partida. Só quem está logado nesta
+// conexão e joga a partida pode falar nela; oponentes sem a capacidade chat não
+// recebem a mensagem.
+func (s *Server) handleChat(conn responder, message *protocol.Message) {
+	chat, err := protocol.ExtractChatMessage(message)
+	if err != nil {
+		s.sendError(conn, protocol.ERR_INVALID_MESSAGE, "Dados inválidos: "+err.Error())
+		return
+	}
+
+	text := strings.TrimSpace(chat.Text)
+	if text == "" || utf8.RuneCountInString(text) > protocol.MAX_CHAT_LENGTH {
+		s.sendError(conn, protocol.ERR_INVALID_CHAT,
+			fmt.Sprintf("A mensagem deve ter entre 1 e %d caracteres", protocol.MAX_CHAT_LENGTH))
+		return
+	}
+
+	s.connectionsMutex.Lock()
+	sender, loggedIn := s.userConnections[chat.UserID]
+	s.connectionsMutex.Unlock()
+	if !loggedIn || sender != conn.session {
+		s.sendError(conn, protocol.ERR_NOT_LOGGED_IN, "Faça login para usar o chat")
+		return
+	}
+
+	currentMatch := s.matches.GetMatch(chat.MatchID)
+	if currentMatch == nil || currentMatch.Status == "finished" || currentMatch.Status == "cancelled" {
+		s.sendError(conn, protocol.ERR_MATCH_NOT_IN_PROGRESS, "Nenhuma partida em andamento para o chat")
+		return
+	}
+
+	var from, opponent *player.Player
+	switch chat.UserID {
+	case currentMatch.Player1.GetID():
+		from, opponent = currentMatch.Player1, currentMatch.Player2
+	case currentMatch.Player2.GetID():
+		from, opponent = currentMatch.Player2, currentMatch.Player1
+	default:
+		s.sendError(conn, protocol.ERR_NOT_IN_MATCH, "Você não está nesta partida")
+		return
+	}
+
+	s.connectionsMutex.Lock()
+	opponentConn, exists := s.userConnections[opponent.GetID()]
+	s.connectionsMutex.Unlock()
+	if !exists || !opponentConn.supports(protocol.CAP_CHAT) {
+		return
+	}
+
+	relay, err := protocol.CreateChatRelay(currentMatch.ID, from.GetID(), from.GetUserName(), text)
+	if err != nil {
+		fmt.Println("Erro ao criar mensagem de chat:", err)
+		return
+	}
+	relay = append(relay, '\n')
+	opponentConn.Write(relay)
+}
+
+// Responde com o inventário do jogador logado nesta conexão
+func (s *Server) handleInventory(conn responder, message *protocol.Message) {
+	inventoryReq, err := protocol.ExtractInventoryRequest(message)
+	if err != nil {
+		fmt.Println("Erro ao extrair dados de inventário:", err)
+		s.sendError(conn, protocol.ERR_INVALID_MESSAGE, "Dados inválidos: "+err.Error())
+		return
+	}
+
+	s.connectionsMutex.Lock()
+	owner, loggedIn := s.userConnections[inventoryReq.UserID]
+	s.connectionsMutex.Unlock()
+
+	var response *protocol.Message
+	var errorCode string
+	if !loggedIn || owner != conn.session {
+		response, err = protocol.NewInventoryUpdate(false, "Usuário não está conectado!", protocol.INVENTORY_REQUESTED, nil)
+		errorCode = protocol.ERR_NOT_LOGGED_IN
+	} else if foundPlayer, found := s.findPlayerByID(inventoryReq.UserID); !found {
+		response, err = protocol.NewInventoryUpdate(false, "Usuário não encontrado!", protocol.INVENTORY_REQUESTED, nil)
+		errorCode = protocol.ERR_USER_NOT_FOUND
+	} else {
+		response, err = protocol.NewInventoryUpdate(true, "Inventário obtido com sucesso!", protocol.INVENTORY_REQUESTED, inventoryCards(foundPlayer))
+	}
+
+	if err != nil {
+		fmt.Println("Erro ao criar resposta de inventário:", err)
+		return
+	}
+	response.ErrorCode = errorCode
+	conn.WriteMessage(response)
+}
+
+// Envia o inventário atual ao jogador depois de uma mudança, se a conexão dele
+// anunciou a capacidade inventory
+func (s *Server) notifyInventory(p *player.Player, reason string) {
+	s.connectionsMutex.Lock()
+	conn, exists := s.userConnections[p.GetID()]
+	s.connectionsMutex.Unlock()
+	if !exists || !conn.supports(protocol.CAP_INVENTORY) {
+		return
+	}
+
+	update, err := protocol.CreateInventoryUpdate(true, "Inventário atualizado", reason, inventoryCards(p))
+	if err != nil {
+		fmt.Println("Erro ao criar atualização de inventário:", err)
+		return
+	}
+	update = append(update, '\n')
+	conn.Write(update)
+}
+
+// Função para verificar se o usuário está logado na conexão que enviou a requisição
+func (s *Server) loggedInOn(userID int, conn *session) bool {
+	s.connectionsMutex.Lock()
+	defer s.connectionsMutex.Unlock()
+	owner, loggedIn := s.userConnections[userID]
+	return loggedIn && owner == conn
+}
+
+// Função para converter o inventário do jogador em protocol.CardInfo
+func inventoryCards(p *player.Player) []protocol.CardInfo {
+	inventory := p.GetInventory()
+	cards := make([]protocol.CardInfo, 0, len(inventory))
+	for _, c := range inventory {
+		cards = append(cards, protocol.CardInfo{Type: c.Type, Rarity: c.Rarity})
+	}
+	return cards
+}
+
+func (s *Server) handleCardPack(conn responder, message *protocol.Message) {
 	// Extrai os dados da requisição de pacote
 	cardPackReq, err := protocol.ExtractCardPackRequest(message)
 	if err != nil {
 		fmt.Println("Erro ao extrair dados de pacote de cartas:", err)
+		s.sendError(conn, protocol.ERR_INVALID_MESSAGE, "Dados inválidos: "+err.Error())
 		return
 	}
 
-	fmt.Printf("Requisição de pacote de cartas - UserID: %d\n", cardPackReq.UserID)
+	packType, validPack := s.packTypes.Get(cardPackReq.PackType)
 
-	// Verifica se o usuário está conectado
-	connectedMutex.Lock()
-	isConnected := connectedUsers[cardPackReq.UserID]
-	connectedMutex.Unlock()
+	fmt.Printf("Requisição de pacote de cartas - UserID: %d, Pacote: %s\n", cardPackReq.UserID, packType.Name)
+
+	// Verifica se o usuário está logado nesta conexão
+	isConnected := s.loggedInOn(cardPackReq.UserID, conn.session)
 
-	var response []byte
+	var response *protocol.Message
+	var errorCode string
 
 	if !isConnected {
 		// Usuário não está conectado/autenticado
-		response, err = protocol.CreateCardPackResponse(false, "Usuário não está conectado!", nil, protocol.StockInfo{})
+		response, err = protocol.NewCardPackResponse(false, "Usuário não está conectado!", cardPackReq.PackType, nil, protocol.StockInfo{}, protocol.PityInfo{}, protocol.FairnessInfo{})
+		errorCode = protocol.ERR_NOT_LOGGED_IN
 		fmt.Printf("Pacote de cartas negado - usuário %d não está conectado\n", cardPackReq.UserID)
+	} else if !validPack {
+		// Tipo de pacote desconhecido
+		message := fmt.Sprintf("Tipo de pacote desconhecido: %s", cardPackReq.PackType)
+		response, err = protocol.NewCardPackResponse(false, message, cardPackReq.PackType, nil, protocol.StockInfo{}, protocol.PityInfo{}, protocol.FairnessInfo{})
+		errorCode = protocol.ERR_INVALID_PACK_TYPE
+		fmt.Printf("Pacote de cartas negado - tipo %s inválido para usuário %d\n", cardPackReq.PackType, cardPackReq.UserID)
 	} else {
 		// Busca o player
-		foundPlayer, found := findPlayerByID(cardPackReq.UserID)
+		foundPlayer, found := s.findPlayerByID(cardPackReq.UserID)
 		if !found {
-			response, err = protocol.CreateCardPackResponse(false, "Usuário não encontrado!", nil, protocol.StockInfo{})
+			response, err = protocol.NewCardPackResponse(false, "Usuário não encontrado!", packType.Name, nil, protocol.StockInfo{}, protocol.PityInfo{}, protocol.FairnessInfo{})
+			errorCode = protocol.ERR_USER_NOT_FOUND
 			fmt.Printf("Pacote de cartas negado - usuário %d não encontrado\n", cardPackReq.UserID)
 		} else {
 			// NOVA VALIDAÇÃO: Verifica se o jogador já tem cartas
 			if foundPlayer.GetInventorySize() > 0 {
 				hydra, quimera, gorgona := foundPlayer.CountCardsByType()
 				message := fmt.Sprintf("Você já possui %d cartas! Use-as em partidas antes de abrir novos pacotes.", foundPlayer.GetInventorySize())
-				response, err = protocol.CreateCardPackResponse(false, message, nil, protocol.StockInfo{})
+				response, err = protocol.NewCardPackResponse(false, message, packType.Name, nil, protocol.StockInfo{}, buildPityInfo(foundPlayer, packType, false), protocol.FairnessInfo{})
+				errorCode = protocol.ERR_INVENTORY_NOT_EMPTY
 				fmt.Printf("Pacote de cartas negado - usuário %d já possui cartas (H:%d Q:%d G:%d)\n", 
 					cardPackReq.UserID, hydra, quimera, gorgona)
 			} else {
+				// Verifica se o pity garante um épico neste pacote
+				forceEpic := packType.PityThreshold > 0 && packType.CanDropEpic() &&
+					foundPlayer.GetPity(packType.Name)+1 >= packType.PityThreshold
+
+				// Sementes do sorteio (commit-reveal): o hash já foi publicado no login
+				seeds, seedErr := publishedSeeds(foundPlayer)
+				if seedErr == nil && cardPackReq.ClientSeed != "" {
+					seeds.ClientSeed = cardPackReq.ClientSeed
+				}
+
 				// Tenta abrir um pacote de cartas
-				cards, success := card.OpenCardPack()
-				if !success {
-					response, err = protocol.CreateCardPackResponse(false, "Estoque insuficiente! Tente novamente mais tarde.", nil, protocol.StockInfo{})
+				var cards []card.Card
+				var stockBefore card.StockCounts
+				success := false
+				if seedErr != nil {
+					fmt.Printf("Erro ao gerar sementes para usuário %d: %v\n", cardPackReq.UserID, seedErr)
+				} else {
+					cards, stockBefore, success = s.stock.OpenPack(packType, forceEpic, &seeds)
+				}
+				if seedErr != nil {
+					response, err = protocol.NewCardPackResponse(false, "Erro ao preparar o sorteio! Tente novamente mais tarde.", packType.Name, nil, protocol.StockInfo{}, buildPityInfo(foundPlayer, packType, false), protocol.FairnessInfo{})
+					errorCode = protocol.ERR_INTERNAL
+				} else if !success {
+					response, err = protocol.NewCardPackResponse(false, "Estoque insuficiente! Tente novamente mais tarde.", packType.Name, nil, protocol.StockInfo{}, buildPityInfo(foundPlayer, packType, false), protocol.FairnessInfo{})
+					errorCode = protocol.ERR_OUT_OF_STOCK
 					fmt.Printf("Pacote de cartas negado - estoque insuficiente para usuário %d\n", cardPackReq.UserID)
 				} else {
-					// Adiciona as cartas ao inventário do jogador
-					for i := range players {
-						if players[i].GetID() == cardPackReq.UserID {
-							players[i].AddCards(cards)
-							break
-						}
-					}
+					// Adiciona as cartas ao inventário do jogador e atualiza o pity e as sementes
+					foundPlayer.ReceivePack(packType.Name, cards, seeds)
+
+					// O inventário novo chega antes da resposta do pacote
+					s.notifyInventory(foundPlayer, protocol.INVENTORY_PACK_OPENED)
 
 					// Converte cartas para protocol.CardInfo
 					var cardInfos []protocol.CardInfo
@@ -420,7 +1591,7 @@ func handleCardPack(conn net.Conn, message *protocol.Message) {
 					}
 
 					// Obtém informações do estoque
-					hydra, quimera, gorgona, total := card.GetStockInfo()
+					hydra, quimera, gorgona, total := s.stock.Info()
 					stockInfo := protocol.StockInfo{
 						HydraCount:   hydra,
 						QuimeraCount: quimera,
@@ -428,11 +1599,24 @@ func handleCardPack(conn net.Conn, message *protocol.Message) {
 						TotalCards:   total,
 					}
 
-					message := fmt.Sprintf("Pacote aberto com sucesso! Você recebeu %d cartas. Agora você deve usá-las antes de abrir outro pacote.", len(cards))
-					response, err = protocol.CreateCardPackResponse(true, message, cardInfos, stockInfo)
+					// Dados para verificar o sorteio quando a semente for revelada
+					fairness := protocol.FairnessInfo{
+						ServerSeedHash: seeds.ServerSeedHash,
+						ClientSeed:     seeds.ClientSeed,
+						Nonce:          seeds.Nonce - 1,
+						StockBefore: protocol.StockInfo{
+							HydraCount:   stockBefore.Hydra,
+							QuimeraCount: stockBefore.Quimera,
+							GorgonaCount: stockBefore.Gorgona,
+							TotalCards:   stockBefore.Total(),
+						},
+					}
+
+					message := fmt.Sprintf("Pacote %s aberto com sucesso! Você recebeu %d cartas. Agora você deve usá-las antes de abrir outro pacote.", packType.Name, len(cards))
+					response, err = protocol.NewCardPackResponse(true, message, packType.Name, cardInfos, stockInfo, buildPityInfo(foundPlayer, packType, forceEpic), fairness)
 					
-					fmt.Printf("Pacote de cartas aberto para usuário %d: %v (inventário: %d cartas)\n", 
-						cardPackReq.UserID, cardInfos, foundPlayer.GetInventorySize()+len(cards))
+					fmt.Printf("Pacote %s aberto para usuário %d: %v (inventário: %d cartas, pity: %d)\n", 
+						packType.Name, cardPackReq.UserID, cardInfos, foundPlayer.GetInventorySize(), foundPlayer.GetPity(packType.Name))
 				}
 			}
 		}
@@ -442,24 +1626,138 @@ func handleCardPack(conn net.Conn, message *protocol.Message) {
 		fmt.Println("Erro ao criar resposta de pacote de cartas:", err)
 		return
 	}
+	response.ErrorCode = errorCode
 
 	// Envia a resposta
-	response = append(response, '\n')
-	_, err = conn.Write(response)
+	err = conn.WriteMessage(response)
 	if err != nil {
 		fmt.Println("Erro ao enviar resposta de pacote de cartas:", err)
 	}
 }
 
+// Monta o status de pity do jogador para um tipo de pacote
+func buildPityInfo(p *player.Player, packType card.PackType, triggered bool) protocol.PityInfo {
+	pity := protocol.PityInfo{
+		PackType:         packType.Name,
+		PacksWithoutEpic: p.GetPity(packType.Name),
+		Threshold:        packType.PityThreshold,
+		Triggered:        triggered,
+	}
+	if packType.PityThreshold > 0 {
+		pity.PacksUntilEpic = packType.PityThreshold - pity.PacksWithoutEpic
+		if pity.PacksUntilEpic < 1 {
+			pity.PacksUntilEpic = 1
+		}
+	}
+	return pity
+}
+
+// Sementes do jogador publicadas no login; sem elas não há compromisso para o sorteio
+func publishedSeeds(p *player.Player) (card.SeedState, error) {
+	seeds := p.GetSeeds()
+	if seeds.IsZero() {
+		return card.SeedState{}, errSeedsNotPublished
+	}
+	return seeds, nil
+}
+
+// Garante que o jogador tenha sementes de sorteio (gera no cadastro ou no login)
+func (s *Server) ensureSeeds(p *player.Player) (card.SeedState, error) {
+	seeds := p.GetSeeds()
+	if !seeds.IsZero() {
+		return seeds, nil
+	}
+	seeds, err := s.stock.NewSeedState("")
+	if err != nil {
+		return card.SeedState{}, err
+	}
+	p.SetSeeds(seeds)
+	return seeds, nil
+}
+
+// Função para consultar, alterar ou rotacionar as sementes do sorteio
+func (s *Server) handleSeed(conn responder, message *protocol.Message) {
+	seedReq, err := protocol.ExtractSeedRequest(message)
+	if err != nil {
+		fmt.Println("Erro ao extrair dados de sementes:", err)
+		s.sendError(conn, protocol.ERR_INVALID_MESSAGE, "Dados inválidos: "+err.Error())
+		return
+	}
+
+	fmt.Printf("Requisição de sementes - UserID: %d, Rotação: %v\n", seedReq.UserID, seedReq.Rotate)
+
+	// Verifica se o usuário está logado nesta conexão
+	isConnected := s.loggedInOn(seedReq.UserID, conn.session)
+
+	var response *protocol.Message
+	var errorCode string
+
+	foundPlayer, found := s.findPlayerByID(seedReq.UserID)
+	if !isConnected {
+		response, err = protocol.NewSeedResponse(false, "Usuário não está conectado!", "", "", 0, nil)
+		errorCode = protocol.ERR_NOT_LOGGED_IN
+	} else if !found {
+		response, err = protocol.NewSeedResponse(false, "Usuário não encontrado!", "", "", 0, nil)
+		errorCode = protocol.ERR_USER_NOT_FOUND
+	} else if seeds, seedErr := publishedSeeds(foundPlayer); seedErr != nil {
+		fmt.Printf("Erro ao gerar sementes para usuário %d: %v\n", seedReq.UserID, seedErr)
+		response, err = protocol.NewSeedResponse(false, "Erro ao gerar sementes!", "", "", 0, nil)
+		errorCode = protocol.ERR_INTERNAL
+	} else {
+		var revealed *protocol.RevealedSeed
+		message := "Sementes atuais do sorteio."
+
+		if seedReq.ClientSeed != "" {
+			seeds.ClientSeed = seedReq.ClientSeed
+			message = "Semente do cliente atualizada."
+		}
+
+		if seedReq.Rotate {
+			old, rotateErr := s.stock.RotateSeeds(&seeds)
+			if rotateErr != nil {
+				fmt.Printf("Erro ao rotacionar sementes do usuário %d: %v\n", seedReq.UserID, rotateErr)
+				response, err = protocol.NewSeedResponse(false, "Erro ao rotacionar sementes!", "", "", 0, nil)
+				errorCode = protocol.ERR_INTERNAL
+			} else {
+				revealed = &protocol.RevealedSeed{
+					ServerSeed:     old.ServerSeed,
+					ServerSeedHash: old.ServerSeedHash,
+					ClientSeed:     old.ClientSeed,
+					Nonce:          old.Nonce,
+				}
+				message = "Semente do servidor revelada! Uma nova semente foi gerada."
+				fmt.Printf("🔑 Semente do usuário %d revelada após %d pacotes\n", seedReq.UserID, old.Nonce)
+			}
+		}
+
+		if response == nil {
+			foundPlayer.SetSeeds(seeds)
+			response, err = protocol.NewSeedResponse(true, message, seeds.ServerSeedHash, seeds.ClientSeed, seeds.Nonce, revealed)
+		}
+	}
+
+	if err != nil {
+		fmt.Println("Erro ao criar resposta de sementes:", err)
+		return
+	}
+	response.ErrorCode = errorCode
+
+	// Envia a resposta
+	err = conn.WriteMessage(response)
+	if err != nil {
+		fmt.Println("Erro ao enviar resposta de sementes:", err)
+	}
+}
+
 // Função para notificar atualização de turno
-func notifyTurnUpdate(currentMatch *match.Match) {
+func (s *Server) notifyTurnUpdate(currentMatch *match.Match) {
 	player1ID := currentMatch.Player1.GetID()
 	player2ID := currentMatch.Player2.GetID()
 	
 	// Mensagem para Player1
-	connectionsMutex.Lock()
-	conn1, exists1 := userConnections[player1ID]
-	connectionsMutex.Unlock()
+	s.connectionsMutex.Lock()
+	conn1, exists1 := s.userConnections[player1ID]
+	s.connectionsMutex.Unlock()
 	
 	if exists1 {
 		isPlayer1Turn := currentMatch.CurrentTurn == player1ID
@@ -478,9 +1776,9 @@ func notifyTurnUpdate(currentMatch *match.Match) {
 	}
 
 	// Mensagem para Player2
-	connectionsMutex.Lock()
-	conn2, exists2 := userConnections[player2ID]
-	connectionsMutex.Unlock()
+	s.connectionsMutex.Lock()
+	conn2, exists2 := s.userConnections[player2ID]
+	s.connectionsMutex.Unlock()
 	
 	if exists2 {
 		isPlayer2Turn := currentMatch.CurrentTurn == player2ID
@@ -499,24 +1797,26 @@ func notifyTurnUpdate(currentMatch *match.Match) {
 	}
 }
 
-func handleQueue(conn net.Conn, message *protocol.Message) {
+func (s *Server) handleQueue(conn responder, message *protocol.Message) {
 	// Extrai os dados da requisição de fila
 	queueReq, err := protocol.ExtractQueueRequest(message)
 	if err != nil {
 		fmt.Println("Erro ao extrair dados de fila:", err)
+		s.sendError(conn, protocol.ERR_INVALID_MESSAGE, "Dados inválidos: "+err.Error())
 		return
 	}
 
 	fmt.Printf("Tentativa de enfileirar - UserID: %d\n", queueReq.UserID)
 
-	queueMutex.Lock()
-	defer queueMutex.Unlock()
+	s.queueMutex.Lock()
+	defer s.queueMutex.Unlock()
 
-	var response []byte
+	var response *protocol.Message
+	var errorCode string
 	
 	// Verifica se o jogador já está na fila
 	playerInQueue := false
-	for _, playerID := range queue {
+	for _, playerID := range s.queue {
 		if playerID == queueReq.UserID {
 			playerInQueue = true
 			break
@@ -524,29 +1824,26 @@ func handleQueue(conn net.Conn, message *protocol.Message) {
 	}
 
 	// Verifica se o jogador já está em uma partida
-	currentMatch := match.GetManager().GetPlayerMatch(queueReq.UserID)
-	if currentMatch != nil {
-		response, err = protocol.CreateQueueResponse(false, "Você já está em uma partida!", len(queue))
+	currentMatch := s.matches.GetPlayerMatch(queueReq.UserID)
+	if s.isClosed() {
+		response, err = protocol.NewQueueResponse(false, "Servidor em encerramento, novas partidas não estão sendo criadas.", 0)
+		errorCode = protocol.ERR_SERVER_SHUTTING_DOWN
+		fmt.Printf("Jogador %d recusado na fila - servidor em encerramento\n", queueReq.UserID)
+	} else if currentMatch != nil {
+		response, err = protocol.NewQueueResponse(false, "Você já está em uma partida!", len(s.queue))
+		errorCode = protocol.ERR_ALREADY_IN_MATCH
 		fmt.Printf("Jogador %d já está em partida (ID: %d)\n", queueReq.UserID, currentMatch.ID)
 	} else if playerInQueue {
-		response, err = protocol.CreateQueueResponse(false, "Você já está na fila!", len(queue))
+		response, err = protocol.NewQueueResponse(false, "Você já está na fila!", len(s.queue))
+		errorCode = protocol.ERR_ALREADY_IN_QUEUE
 		fmt.Printf("Jogador %d já está na fila\n", queueReq.UserID)
 	} else {
 		// VALIDAÇÃO ATUALIZADA: Busca o player mais recente e verifica cartas
-		var foundPlayer *player.Player
-		found := false
-		
-		// Busca o player atualizado no slice global
-		for i := range players {
-			if players[i].GetID() == queueReq.UserID {
-				foundPlayer = &players[i]
-				found = true
-				break
-			}
-		}
+		foundPlayer, found := s.findPlayerByID(queueReq.UserID)
 		
 		if !found {
-			response, err = protocol.CreateQueueResponse(false, "Jogador não encontrado!", len(queue))
+			response, err = protocol.NewQueueResponse(false, "Jogador não encontrado!", len(s.queue))
+			errorCode = protocol.ERR_USER_NOT_FOUND
 			fmt.Printf("Jogador %d não encontrado\n", queueReq.UserID)
 		} else {
 			// Verifica cartas em tempo real
@@ -554,15 +1851,16 @@ func handleQueue(conn net.Conn, message *protocol.Message) {
 			hydra, quimera, gorgona := foundPlayer.CountCardsByType()
 			
 			if currentInventorySize == 0 {
-				response, err = protocol.CreateQueueResponse(false, "Você não tem cartas! Abra um pacote primeiro para jogar.", len(queue))
+				response, err = protocol.NewQueueResponse(false, "Você não tem cartas! Abra um pacote primeiro para jogar.", len(s.queue))
+				errorCode = protocol.ERR_NO_CARDS
 				fmt.Printf("Jogador %d tentou entrar na fila SEM cartas (H:%d Q:%d G:%d)\n", 
 					queueReq.UserID, hydra, quimera, gorgona)
 			} else {
 				// Adiciona o jogador à fila
-				queue = append(queue, queueReq.UserID)
-				response, err = protocol.CreateQueueResponse(true, "Você foi adicionado à fila de partidas!", len(queue))
+				s.queue = append(s.queue, queueReq.UserID)
+				response, err = protocol.NewQueueResponse(true, "Você foi adicionado à fila de partidas!", len(s.queue))
 				fmt.Printf("Jogador %d adicionado à fila. Total na fila: %d (cartas: H:%d Q:%d G:%d = %d total)\n", 
-					queueReq.UserID, len(queue), hydra, quimera, gorgona, currentInventorySize)
+					queueReq.UserID, len(s.queue), hydra, quimera, gorgona, currentInventorySize)
 			}
 		}
 	}
@@ -571,117 +1869,164 @@ func handleQueue(conn net.Conn, message *protocol.Message) {
 		fmt.Println("Erro ao criar resposta:", err)
 		return
 	}
+	response.ErrorCode = errorCode
 
 	// Envia a resposta
-	response = append(response, '\n')
-	_, err = conn.Write(response)
+	err = conn.WriteMessage(response)
 	if err != nil {
 		fmt.Println("Erro ao enviar resposta:", err)
 	}
 }
 
-func handleRegister(conn net.Conn, message *protocol.Message) {
+func (s *Server) handleRegister(conn responder, message *protocol.Message) {
 	// Extrai os dados do register request
 	registerReq, err := protocol.ExtractRegisterRequest(message)
 	if err != nil {
 		fmt.Println("Erro ao extrair dados de cadastro:", err)
+		s.sendError(conn, protocol.ERR_INVALID_MESSAGE, "Dados inválidos: "+err.Error())
 		return
 	}
 
 	fmt.Printf("Tentativa de cadastro - Usuário: %s\n", registerReq.UserName)
 
 	// Validações
-	var response []byte
+	var response *protocol.Message
+	var errorCode string
 	
+	s.playersMutex.Lock()
+
 	// Verifica se username já existe
-	if userExists(registerReq.UserName) {
-		response, err = protocol.CreateRegisterResponse(false, "Nome de usuário já existe!", 0)
+	if s.userExists(registerReq.UserName) {
+		response, err = protocol.NewRegisterResponse(false, "Nome de usuário já existe!", 0)
+		errorCode = protocol.ERR_USERNAME_TAKEN
 		fmt.Printf("Cadastro falhou - usuário já existe: %s\n", registerReq.UserName)
 	} else if len(strings.TrimSpace(registerReq.UserName)) < 3 {
-		response, err = protocol.CreateRegisterResponse(false, "Nome de usuário deve ter pelo menos 3 caracteres!", 0)
+		response, err = protocol.NewRegisterResponse(false, "Nome de usuário deve ter pelo menos 3 caracteres!", 0)
+		errorCode = protocol.ERR_USERNAME_TOO_SHORT
 		fmt.Printf("Cadastro falhou - username muito curto: %s\n", registerReq.UserName)
 	} else if len(strings.TrimSpace(registerReq.Password)) < 4 {
-		response, err = protocol.CreateRegisterResponse(false, "Senha deve ter pelo menos 4 caracteres!", 0)
+		response, err = protocol.NewRegisterResponse(false, "Senha deve ter pelo menos 4 caracteres!", 0)
+		errorCode = protocol.ERR_PASSWORD_TOO_SHORT
 		fmt.Printf("Cadastro falhou - senha muito curta para usuário: %s\n", registerReq.UserName)
 	} else {
 		// Cria novo player
-		newPlayer := player.NewPlayer(nextID, registerReq.UserName, registerReq.Password)
-		players = append(players, newPlayer)
+		newPlayer := player.NewPlayer(s.nextID, registerReq.UserName, registerReq.Password)
+		s.players = append(s.players, newPlayer)
+
+		// A semente do sorteio já existe antes do primeiro login (falhas são refeitas no login)
+		if _, seedErr := s.ensureSeeds(newPlayer); seedErr != nil {
+			fmt.Printf("Erro ao gerar sementes do usuário %d: %v\n", s.nextID, seedErr)
+		}
 		
-		response, err = protocol.CreateRegisterResponse(true, "Cadastro realizado com sucesso!", nextID)
-		fmt.Printf("Cadastro bem-sucedido - Usuário: %s (ID: %d)\n", registerReq.UserName, nextID)
+		response, err = protocol.NewRegisterResponse(true, "Cadastro realizado com sucesso!", s.nextID)
+		fmt.Printf("Cadastro bem-sucedido - Usuário: %s (ID: %d)\n", registerReq.UserName, s.nextID)
 		
-		nextID++
+		s.nextID++
 	}
+	s.playersMutex.Unlock()
 
 	if err != nil {
 		fmt.Println("Erro ao criar resposta:", err)
 		return
 	}
+	response.ErrorCode = errorCode
 
 	// Envia a resposta
-	response = append(response, '\n') // Adiciona quebra de linha para o scanner
-	_, err = conn.Write(response)
+	err = conn.WriteMessage(response)
 	if err != nil {
 		fmt.Println("Erro ao enviar resposta:", err)
 	}
 }
 
-func handleLogin(conn net.Conn, message *protocol.Message) int {
+func (s *Server) handleLogin(conn responder, message *protocol.Message) int {
 	// Extrai os dados do login request
 	loginReq, err := protocol.ExtractLoginRequest(message)
 	if err != nil {
 		fmt.Println("Erro ao extrair dados de login:", err)
+		s.sendError(conn, protocol.ERR_INVALID_MESSAGE, "Dados inválidos: "+err.Error())
 		return 0
 	}
 
 	fmt.Printf("Tentativa de login - Usuário: %s\n", loginReq.UserName)
 
+	// Depois de falhas recentes a senha nem é verificada até a espera terminar
+	ip := conn.remoteIP()
+	if wait, locked := s.loginWait(loginReq.UserName, ip); wait > 0 {
+		s.sendLoginThrottled(conn, loginReq.UserName, wait, locked)
+		return 0
+	}
+
 	// Busca o player na lista
-	player, found := findPlayer(loginReq.UserName, loginReq.Password)
+	player, found := s.findPlayer(loginReq.UserName, loginReq.Password)
 	
-	var response []byte
+	var response *protocol.Message
+	var errorCode string
 	var userID int
 	
 	if found {
+		// Senha correta: zera as falhas do usuário. As do IP só expiram pela política,
+		// senão quem adivinha senhas zeraria o contador entrando na própria conta
+		s.userFailures.Reset(loginReq.UserName)
+
+
 		// Verifica se o usuário já está conectado
-		connectedMutex.Lock()
-		alreadyConnected := connectedUsers[player.GetID()]
+		s.connectedMutex.Lock()
+		alreadyConnected := s.connectedUsers[player.GetID()]
+		var seeds card.SeedState
+		var seedErr error
 		if !alreadyConnected {
+			// O hash da semente do sorteio vai na resposta: o compromisso é publicado
+			// antes de qualquer pacote
+			seeds, seedErr = s.ensureSeeds(player)
+		}
+		if !alreadyConnected && seedErr == nil {
 			// Marca como conectado
-			connectedUsers[player.GetID()] = true
+			s.connectedUsers[player.GetID()] = true
 			userID = player.GetID()
 			
 			// Armazena a conexão
-			connectionsMutex.Lock()
-			userConnections[player.GetID()] = conn
-			connectionsMutex.Unlock()
+			s.connectionsMutex.Lock()
+			s.userConnections[player.GetID()] = conn.session
+			s.connectionsMutex.Unlock()
 		}
-		connectedMutex.Unlock()
+		s.connectedMutex.Unlock()
 		
-		if alreadyConnected {
+		if seedErr != nil {
+			response, err = protocol.NewLoginResponse(false, "Erro ao preparar o sorteio! Tente novamente mais tarde.", 0, "")
+			errorCode = protocol.ERR_INTERNAL
+			fmt.Printf("Login negado - erro ao gerar sementes do usuário %d: %v\n", player.GetID(), seedErr)
+		} else if alreadyConnected {
 			// Usuário já está conectado
-			response, err = protocol.CreateLoginResponse(false, "Usuário já está conectado em outra sessão!", 0)
+			response, err = protocol.NewLoginResponse(false, "Usuário já está conectado em outra sessão!", 0, "")
+			errorCode = protocol.ERR_ALREADY_CONNECTED
 			fmt.Printf("Login negado - usuário %s já está conectado (ID: %d)\n", loginReq.UserName, player.GetID())
 		} else {
 			// Login bem-sucedido
-			response, err = protocol.CreateLoginResponse(true, "Login realizado com sucesso!", player.GetID())
+			response, err = protocol.NewLoginResponse(true, "Login realizado com sucesso!", player.GetID(), seeds.ServerSeedHash)
 			fmt.Printf("Login bem-sucedido para usuário: %s (ID: %d)\n", loginReq.UserName, player.GetID())
+
+			// O estado da sessão vai antes da resposta: quando o login termina o
+			// cliente já tem tudo para montar a tela
+			if conn.supports(protocol.CAP_SESSION_STATE) {
+				s.sendSessionState(conn.session, player)
+			}
 		}
 	} else {
 		// Login falhou
-		response, err = protocol.CreateLoginResponse(false, "Usuário ou senha incorretos!", 0)
+		response, err = protocol.NewLoginResponse(false, "Usuário ou senha incorretos!", 0, "")
+		errorCode = protocol.ERR_INVALID_CREDENTIALS
 		fmt.Printf("Login falhou para usuário: %s\n", loginReq.UserName)
+		s.recordLoginFailure(loginReq.UserName, ip)
 	}
 
 	if err != nil {
 		fmt.Println("Erro ao criar resposta:", err)
 		return 0
 	}
+	response.ErrorCode = errorCode
 
 	// Envia a resposta
-	response = append(response, '\n') // Adiciona quebra de linha para o scanner
-	_, err = conn.Write(response)
+	err = conn.WriteMessage(response)
 	if err != nil {
 		fmt.Println("Erro ao enviar resposta:", err)
 		return 0
@@ -690,32 +2035,75 @@ func handleLogin(conn net.Conn, message *protocol.Message) int {
 	return userID
 }
 
-func handleStats(conn net.Conn, message *protocol.Message) {
+// Espera exigida antes da próxima tentativa de login do usuário a partir do IP.
+// locked indica que a espera é um bloqueio (não só o intervalo entre tentativas).
+func (s *Server) loginWait(userName, ip string) (time.Duration, bool) {
+	userWait, userLocked := s.userFailures.Wait(userName)
+	ipWait, ipLocked := s.ipFailures.Wait(ip)
+	return max(userWait, ipWait), userLocked || ipLocked
+}
+
+// Registra uma senha incorreta para o usuário e para o IP
+func (s *Server) recordLoginFailure(userName, ip string) {
+	now := s.clock.Now()
+	if entry := s.userFailures.Fail(userName); entry.Locked(now) && entry.Failures == s.config.UserLockout.MaxFailures {
+		fmt.Printf("🔒 Usuário %s bloqueado até %s após %d falhas de login\n",
+			userName, entry.LockedUntil.Format("15:04:05"), entry.Failures)
+	}
+	if entry := s.ipFailures.Fail(ip); entry.Locked(now) && entry.Failures == s.config.IPLockout.MaxFailures {
+		fmt.Printf("🔒 IP %s bloqueado até %s após %d falhas de login\n",
+			ip, entry.LockedUntil.Format("15:04:05"), entry.Failures)
+	}
+}
+
+// Recusa a tentativa de login feita antes do fim da espera ou durante o bloqueio
+func (s *Server) sendLoginThrottled(conn responder, userName string, wait time.Duration, locked bool) {
+	errorCode := protocol.ERR_LOGIN_BACKOFF
+	message := fmt.Sprintf("Muitas tentativas de login. Aguarde %.0fs para tentar de novo.", math.Ceil(wait.Seconds()))
+	if locked {
+		errorCode = protocol.ERR_ACCOUNT_LOCKED
+		message = fmt.Sprintf("Login bloqueado temporariamente após muitas falhas. Tente novamente em %s.", wait.Round(time.Second))
+	}
+	fmt.Printf("Login recusado para usuário %s (%s): aguardar %v\n", userName, errorCode, wait)
+
+	response, err := protocol.NewLoginThrottled(message, errorCode, wait)
+	if err != nil {
+		fmt.Println("Erro ao criar resposta:", err)
+		return
+	}
+	conn.WriteMessage(response)
+}
+
+func (s *Server) handleStats(conn responder, message *protocol.Message) {
 	// Extrai os dados da requisição de estatísticas
 	statsReq, err := protocol.ExtractStatsRequest(message)
 	if err != nil {
 		fmt.Println("Erro ao extrair dados de estatísticas:", err)
+		s.sendError(conn, protocol.ERR_INVALID_MESSAGE, "Dados inválidos: "+err.Error())
 		return
 	}
 
 	fmt.Printf("Requisição de estatísticas - UserID: %d\n", statsReq.UserID)
 
 	// Verifica se o usuário está conectado
-	connectedMutex.Lock()
-	isConnected := connectedUsers[statsReq.UserID]
-	connectedMutex.Unlock()
+	s.connectedMutex.Lock()
+	isConnected := s.connectedUsers[statsReq.UserID]
+	s.connectedMutex.Unlock()
 
-	var response []byte
+	var response *protocol.Message
+	var errorCode string
 
 	if !isConnected {
 		// Usuário não está conectado/autenticado
-		response, err = protocol.CreateStatsResponse(false, "Usuário não está conectado!", "", 0, 0, 0.0)
+		response, err = protocol.NewStatsResponse(false, "Usuário não está conectado!", "", 0, 0, 0.0)
+		errorCode = protocol.ERR_NOT_LOGGED_IN
 		fmt.Printf("Estatísticas negadas - usuário %d não está conectado\n", statsReq.UserID)
 	} else {
 		// Busca o player
-		player, found := findPlayerByID(statsReq.UserID)
+		player, found := s.findPlayerByID(statsReq.UserID)
 		if !found {
-			response, err = protocol.CreateStatsResponse(false, "Usuário não encontrado!", "", 0, 0, 0.0)
+			response, err = protocol.NewStatsResponse(false, "Usuário não encontrado!", "", 0, 0, 0.0)
+			errorCode = protocol.ERR_USER_NOT_FOUND
 			fmt.Printf("Estatísticas negadas - usuário %d não encontrado\n", statsReq.UserID)
 		} else {
 			// Usuário conectado, retorna estatísticas
@@ -724,7 +2112,7 @@ func handleStats(conn net.Conn, message *protocol.Message) {
 			winRate := player.GetWinRate()
 			message := "Estatísticas obtidas com sucesso!"
 			
-			response, err = protocol.CreateStatsResponse(true, message, player.GetUserName(), wins, losses, winRate)
+			response, err = protocol.NewStatsResponse(true, message, player.GetUserName(), wins, losses, winRate)
 			fmt.Printf("Estatísticas enviadas para usuário %d: %dW-%dL (%.1f%%)\n", 
 				statsReq.UserID, wins, losses, winRate)
 		}
@@ -734,18 +2122,68 @@ func handleStats(conn net.Conn, message *protocol.Message) {
 		fmt.Println("Erro ao criar resposta de estatísticas:", err)
 		return
 	}
+	response.ErrorCode = errorCode
 
 	// Envia a resposta
-	response = append(response, '\n')
-	_, err = conn.Write(response)
+	err = conn.WriteMessage(response)
 	if err != nil {
 		fmt.Println("Erro ao enviar resposta de estatísticas:", err)
 	}
 }
 
-// Função para verificar se usuário já existe
-func userExists(userName string) bool {
-	for _, player := range players {
+// Envia o estado completo do jogador: inventário, estatísticas, moedas, fila e a
+// partida em andamento, se houver
+func (s *Server) sendSessionState(conn *session, p *player.Player) {
+	state := protocol.SessionState{
+		UserID:    p.GetID(),
+		UserName:  p.GetUserName(),
+		Inventory: inventoryCards(p),
+		Wins:      p.GetWins(),
+		Losses:    p.GetLosses(),
+		WinRate:   p.GetWinRate(),
+		Coins:     p.GetCoins(),
+
+		ServerSeedHash: p.GetSeeds().ServerSeedHash,
+	}
+
+	s.queueMutex.Lock()
+	state.InQueue = slices.Contains(s.queue, p.GetID())
+	if state.InQueue {
+		state.QueueSize = len(s.queue)
+	}
+	s.queueMutex.Unlock()
+
+	if current, found := s.matches.GetPlayerMatchSnapshot(p.GetID()); found {
+		own, opponent, opponentCard := current.Player1Card, current.Player2, current.Player2Card
+		if current.Player2.GetID() == p.GetID() {
+			own, opponent, opponentCard = current.Player2Card, current.Player1, current.Player1Card
+		}
+		snapshot := &protocol.MatchSnapshot{
+			MatchID:        current.ID,
+			OpponentID:     opponent.GetID(),
+			OpponentName:   opponent.GetUserName(),
+			Status:         current.Status,
+			YourTurn:       current.GameStarted && current.CurrentTurn == p.GetID() && own == nil,
+			OpponentPlayed: opponentCard != nil,
+		}
+		if own != nil {
+			snapshot.PlayedCard = own.Type
+		}
+		state.Match = snapshot
+	}
+
+	message, err := protocol.CreateSessionState(state)
+	if err != nil {
+		fmt.Println("Erro ao criar estado da sessão:", err)
+		return
+	}
+	message = append(message, '\n')
+	conn.Write(message)
+}
+
+// Função para verificar se usuário já existe (chamada com playersMutex travado)
+func (s *Server) userExists(userName string) bool {
+	for _, player := range s.players {
 		if player.GetUserName() == userName {
 			return true
 		}
@@ -754,60 +2192,74 @@ func userExists(userName string) bool {
 }
 
 // Função para buscar um player pelos credentials
-func findPlayer(userName, password string) (player.Player, bool) {
-	for _, player := range players {
-		if player.GetUserName() == userName && player.GetPassword() == password {
-			return player, true
+func (s *Server) findPlayer(userName, password string) (*player.Player, bool) {
+	s.playersMutex.Lock()
+	defer s.playersMutex.Unlock()
+
+	for _, p := range s.players {
+		if p.GetUserName() == userName && p.GetPassword() == password {
+			return p, true
 		}
 	}
-	return player.Player{}, false
+	return nil, false
 }
 
 // Função para buscar um player pelo ID
-func findPlayerByID(playerID int) (*player.Player, bool) {
-    for i := range players {
-        if players[i].GetID() == playerID {
-            return &players[i], true  // Retorna PONTEIRO para o original
-        }
-    }
-    return nil, false
+func (s *Server) findPlayerByID(playerID int) (*player.Player, bool) {
+	s.playersMutex.Lock()
+	defer s.playersMutex.Unlock()
+
+	for _, p := range s.players {
+		if p.GetID() == playerID {
+			return p, true // Retorna PONTEIRO para o original
+		}
+	}
+	return nil, false
 }
 
-func cleanupOrphanedMatches() {
-	ticker := time.NewTicker(5 * time.Second) // Verifica a cada 5 segundos
+func (s *Server) cleanupOrphanedMatches() {
+	defer s.wg.Done()
+
+	ticker := s.clock.NewTicker(s.config.CleanupInterval) // Verifica a cada 5 segundos
 	defer ticker.Stop()
 
-	for range ticker.C {
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C():
+		}
+
 		// Obtém todas as partidas ativas
-		activeMatches := match.GetManager().GetAllActiveMatches()
+		activeMatches := s.matches.GetAllActiveMatches()
 		
-		connectionsMutex.Lock()
-		connectedMutex.Lock()
+		s.connectionsMutex.Lock()
+		s.connectedMutex.Lock()
 		
 		for _, currentMatch := range activeMatches {
 			player1ID := currentMatch.Player1.GetID()
 			player2ID := currentMatch.Player2.GetID()
 			
-			player1Connected := connectedUsers[player1ID]
-			player2Connected := connectedUsers[player2ID]
+			player1Connected := s.connectedUsers[player1ID]
+			player2Connected := s.connectedUsers[player2ID]
 			
 			// Se ambos jogadores desconectaram, cancela a partida
 			if !player1Connected && !player2Connected {
 				fmt.Printf("🧹 Partida %d cancelada - ambos jogadores desconectaram\n", currentMatch.ID)
-				match.GetManager().CancelMatch(currentMatch.ID)
+				s.matches.CancelMatch(currentMatch.ID)
 				continue
 			}
 			
 			// Se apenas um jogador desconectou, declara o outro vencedor
 			if !player1Connected && player2Connected {
 				fmt.Printf("🏆 Player 2 vence partida %d por desconexão do oponente\n", currentMatch.ID)
-				match.GetManager().ForceWin(currentMatch.ID, currentMatch.Player2.GetID())
+				s.matches.ForceWin(currentMatch.ID, currentMatch.Player2.GetID())
 				
 				// Atualiza estatísticas
-				updatePlayerStats(currentMatch.Player1.GetID(), currentMatch.Player2.GetID(), currentMatch.Player2.GetID())
+				s.updatePlayerStats(currentMatch.Player1.GetID(), currentMatch.Player2.GetID(), currentMatch.Player2.GetID())
 				
 				// Notifica o jogador restante
-				if conn, exists := userConnections[currentMatch.Player2.GetID()]; exists {
+				if conn, exists := s.userConnections[currentMatch.Player2.GetID()]; exists {
 					message := "Seu oponente desconectou. Você venceu por abandono!"
 					response, _ := protocol.CreateMatchEnd(currentMatch.ID, currentMatch.Player2.GetID(), 
 						currentMatch.Player2.GetUserName(), message)
@@ -817,13 +2269,13 @@ func cleanupOrphanedMatches() {
 				
 			} else if player1Connected && !player2Connected {
 				fmt.Printf("🏆 Player 1 vence partida %d por desconexão do oponente\n", currentMatch.ID)
-				match.GetManager().ForceWin(currentMatch.ID, currentMatch.Player1.GetID())
+				s.matches.ForceWin(currentMatch.ID, currentMatch.Player1.GetID())
 				
 				// Atualiza estatísticas
-				updatePlayerStats(currentMatch.Player1.GetID(), currentMatch.Player2.GetID(), currentMatch.Player1.GetID())
+				s.updatePlayerStats(currentMatch.Player1.GetID(), currentMatch.Player2.GetID(), currentMatch.Player1.GetID())
 				
 				// Notifica o jogador restante
-				if conn, exists := userConnections[currentMatch.Player1.GetID()]; exists {
+				if conn, exists := s.userConnections[currentMatch.Player1.GetID()]; exists {
 					message := "Seu oponente desconectou. Você venceu por abandono!"
 					response, _ := protocol.CreateMatchEnd(currentMatch.ID, currentMatch.Player1.GetID(), 
 						currentMatch.Player1.GetUserName(), message)
@@ -833,7 +2285,13 @@ func cleanupOrphanedMatches() {
 			}
 		}
 		
-		connectedMutex.Unlock()
-		connectionsMutex.Unlock()
+		s.connectedMutex.Unlock()
+		s.connectionsMutex.Unlock()
+
+		// Descarta os limites de IPs que já recuperaram todo o orçamento e as
+		// falhas de login expiradas
+		s.ipLimits.Prune()
+		s.userFailures.Prune()
+		s.ipFailures.Prune()
 	}
 }
\ No newline at end of file
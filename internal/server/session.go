@@ -0,0 +1,212 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+	"top-card/internal/protocol"
+)
+
+// Erros retornados por session.Write
+var (
+	errSessionClosed = errors.New("conexão encerrada")
+	errOutboundFull  = errors.New("fila de saída cheia")
+)
+
+// Sessão de uma conexão de cliente. Todas as mensagens enviadas ao cliente passam
+// pela fila de saída e são escritas por uma única goroutine (writeLoop), então
+// mensagens de goroutines diferentes nunca se misturam no socket e um cliente lento
+// não bloqueia quem está enviando.
+type session struct {
+	conn         net.Conn
+	out          chan []byte   // Fila de saída limitada
+	closing      chan struct{} // Fechado quando a sessão deve terminar
+	closeOnce    sync.Once
+	writeTimeout time.Duration
+	offered      []string // Capacidades que o servidor oferece nesta conexão (dependem do transporte)
+
+	protocolMutex   sync.RWMutex    // Protege protocolVersion, capabilities e codec
+	protocolVersion int             // Versão negociada no HELLO (1 se o cliente não enviou HELLO)
+	capabilities    map[string]bool // Capacidades ativas nesta conexão
+	codec           protocol.Codec  // Formato das mensagens no fio (JSON até o WELCOME)
+}
+
+// Função para criar uma sessão com fila de saída de queueSize mensagens
+func newSession(conn net.Conn, queueSize int, writeTimeout time.Duration) *session {
+	return &session{
+		conn:         conn,
+		out:          make(chan []byte, queueSize),
+		closing:      make(chan struct{}),
+		writeTimeout: writeTimeout,
+		offered:      serverCapabilities,
+
+		protocolVersion: 1,
+		codec:           protocol.JSONCodec,
+	}
+}
+
+// Define a versão e as capacidades negociadas no HELLO
+func (ss *session) negotiate(version int, capabilities []string) {
+	ss.protocolMutex.Lock()
+	defer ss.protocolMutex.Unlock()
+
+	ss.protocolVersion = version
+	ss.capabilities = make(map[string]bool, len(capabilities))
+	for _, capability := range capabilities {
+		ss.capabilities[capability] = true
+	}
+}
+
+// Identificador da sessão nos limites de requisições
+func (ss *session) id() string {
+	return fmt.Sprintf("%p", ss)
+}
+
+// IP do cliente, sem a porta
+func (ss *session) remoteIP() string {
+	addr := ss.conn.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// Troca o formato das mensagens enviadas a partir de agora
+func (ss *session) setCodec(codec protocol.Codec) {
+	ss.protocolMutex.Lock()
+	defer ss.protocolMutex.Unlock()
+
+	ss.codec = codec
+}
+
+// Formato atual das mensagens da conexão
+func (ss *session) currentCodec() protocol.Codec {
+	ss.protocolMutex.RLock()
+	defer ss.protocolMutex.RUnlock()
+
+	return ss.codec
+}
+
+// Verifica se uma capacidade foi negociada nesta conexão
+func (ss *session) supports(capability string) bool {
+	ss.protocolMutex.RLock()
+	defer ss.protocolMutex.RUnlock()
+
+	return ss.capabilities[capability]
+}
+
+// Enfileira uma mensagem para o cliente sem bloquear. Se a fila estiver cheia o
+// cliente é considerado lento e a conexão é derrubada.
+// Implementa io.Writer para que os handlers continuem usando conn.Write.
+func (ss *session) Write(data []byte) (int, error) {
+	select {
+	case <-ss.closing:
+		return 0, errSessionClosed
+	default:
+	}
+
+	// Converte para o formato da conexão. O frame é sempre uma cópia, então quem
+	// chamou pode reutilizar o slice depois do retorno.
+	frame, err := ss.currentCodec().Frame(data)
+	if err != nil {
+		return 0, err
+	}
+	if err := ss.enqueue(frame); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Enfileira uma mensagem já decodificada, sem passar o envelope por JSON de novo
+func (ss *session) WriteMessage(message *protocol.Message) error {
+	select {
+	case <-ss.closing:
+		return errSessionClosed
+	default:
+	}
+
+	frame, err := ss.currentCodec().FrameMessage(message)
+	if err != nil {
+		return err
+	}
+	return ss.enqueue(frame)
+}
+
+// Coloca um frame na fila de saída ou derruba o cliente lento
+func (ss *session) enqueue(frame []byte) error {
+	select {
+	case ss.out <- frame:
+		return nil
+	default:
+		ss.abort(fmt.Sprintf("cliente lento, %d mensagens pendentes na fila de saída", cap(ss.out)))
+		return errOutboundFull
+	}
+}
+
+// Goroutine escritora: drena a fila de saída com prazo de escrita por mensagem
+func (ss *session) writeLoop() {
+	defer ss.conn.Close()
+
+	for {
+		select {
+		case message := <-ss.out:
+			if !ss.write(message) {
+				return
+			}
+		case <-ss.closing:
+			// Envia o que ainda estiver na fila antes de fechar (ex.: aviso de encerramento)
+			for {
+				select {
+				case message := <-ss.out:
+					if !ss.write(message) {
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (ss *session) write(message []byte) bool {
+	ss.conn.SetWriteDeadline(time.Now().Add(ss.writeTimeout))
+	if _, err := ss.conn.Write(message); err != nil {
+		ss.abort(fmt.Sprintf("erro de escrita: %v", err))
+		return false
+	}
+	return true
+}
+
+// Encerra a sessão depois de enviar as mensagens pendentes
+func (ss *session) close() {
+	ss.closeOnce.Do(func() {
+		close(ss.closing)
+	})
+}
+
+// Encerra a sessão imediatamente, descartando as mensagens pendentes
+func (ss *session) abort(reason string) {
+	ss.closeOnce.Do(func() {
+		close(ss.closing)
+		fmt.Printf("🔌 Desconectando %s: %s\n", ss.conn.RemoteAddr(), reason)
+	})
+	ss.conn.Close()
+}
+
+// Conexão vista por um handler durante uma requisição: as respostas escritas por ele
+// repetem o request_id da requisição, para o cliente correlacionar a resposta.
+// Notificações assíncronas continuam sendo enviadas direto pela sessão, sem request_id.
+type responder struct {
+	*session
+	requestID string
+}
+
+func (r responder) WriteMessage(message *protocol.Message) error {
+	if r.supports(protocol.CAP_REQUEST_ID) {
+		message.RequestID = r.requestID
+	}
+	return r.session.WriteMessage(message)
+}
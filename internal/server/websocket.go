@@ -0,0 +1,146 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+	"top-card/internal/card"
+	"top-card/internal/protocol"
+	"top-card/internal/webclient"
+
+	"golang.org/x/net/websocket"
+)
+
+// Capacidades oferecidas a clientes WebSocket. O próprio WebSocket já delimita as
+// mensagens, então os frames binários com prefixo de tamanho não são oferecidos.
+var webSocketCapabilities = []string{protocol.CAP_REQUEST_ID, protocol.CAP_ERROR_CODES, protocol.CAP_CHAT, protocol.CAP_INVENTORY, protocol.CAP_SESSION_STATE}
+
+// Interface HTTP do gateway WebSocket, no caminho Config.WebSocketPath. Cada frame
+// de texto carrega exatamente uma mensagem do protocolo (o mesmo JSON do TCP, sem o
+// '\n'); a sessão, o handshake e os handlers são os mesmos do TCP, então jogadores
+// dos dois transportes entram na mesma fila e jogam entre si.
+func (s *Server) WebSocketHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(s.config.WebSocketPath, websocket.Server{
+		// Aceita qualquer origem: o jogo não usa cookies, então outra página não
+		// ganha nada abrindo uma conexão em nome do navegador
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler:   s.handleWebSocket,
+	})
+	return mux
+}
+
+// Atende uma conexão WebSocket até ela terminar (o pacote websocket fecha a conexão
+// quando esta função retorna)
+func (s *Server) handleWebSocket(ws *websocket.Conn) {
+	ws.MaxPayloadBytes = s.config.MaxFrameSize
+
+	sess := newSession(newWebSocketConn(ws), s.config.OutboundQueueSize, s.config.WriteTimeout)
+	sess.offered = webSocketCapabilities
+	if !s.trackConn(sess, true) {
+		return
+	}
+	s.startBackground()
+
+	fmt.Println("Cliente WebSocket conectado")
+	s.wg.Add(1)
+	defer s.wg.Done()
+	s.serveSession(sess)
+}
+
+// Adapta uma conexão WebSocket ao fluxo de linhas JSON lido e escrito pela sessão:
+// cada mensagem recebida vira uma linha e cada linha escrita vira um frame de texto
+type webSocketConn struct {
+	ws      *websocket.Conn
+	remote  net.Addr
+	pending []byte // Resto da mensagem recebida ainda não lido, com o '\n'
+}
+
+func newWebSocketConn(ws *websocket.Conn) *webSocketConn {
+	// O endereço do websocket.Conn é a origem da página; os limites por IP e o
+	// bloqueio de login precisam do endereço do cliente
+	var remote net.Addr = ws.RemoteAddr()
+	if addr, err := net.ResolveTCPAddr("tcp", ws.Request().RemoteAddr); err == nil {
+		remote = addr
+	}
+	return &webSocketConn{ws: ws, remote: remote}
+}
+
+func (c *webSocketConn) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		var data []byte
+		if err := websocket.Message.Receive(c.ws, &data); err != nil {
+			// O frame grande demais é descartado pelo pacote websocket na próxima
+			// leitura, então a conexão pode continuar como nos outros codecs
+			if errors.Is(err, websocket.ErrFrameTooLarge) {
+				return 0, fmt.Errorf("%w: mensagem WebSocket com mais de %d bytes", protocol.ErrFrameTooLarge, c.ws.MaxPayloadBytes)
+			}
+			return 0, err
+		}
+
+		// Quebras de linha só aparecem fora de strings em JSON válido (ex.: JSON
+		// formatado), então trocá-las por espaços mantém uma mensagem por linha
+		data = bytes.ReplaceAll(data, []byte{'\n'}, []byte{' '})
+		data = bytes.ReplaceAll(data, []byte{'\r'}, []byte{' '})
+		c.pending = append(data, '\n')
+	}
+
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// Cada chamada recebe uma mensagem inteira (session.write) e envia um frame de texto
+func (c *webSocketConn) Write(p []byte) (int, error) {
+	if err := websocket.Message.Send(c.ws, string(bytes.TrimRight(p, "\r\n"))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *webSocketConn) Close() error                       { return c.ws.Close() }
+func (c *webSocketConn) LocalAddr() net.Addr                { return c.ws.LocalAddr() }
+func (c *webSocketConn) RemoteAddr() net.Addr               { return c.remote }
+func (c *webSocketConn) SetDeadline(t time.Time) error      { return c.ws.SetDeadline(t) }
+func (c *webSocketConn) SetReadDeadline(t time.Time) error  { return c.ws.SetReadDeadline(t) }
+func (c *webSocketConn) SetWriteDeadline(t time.Time) error { return c.ws.SetWriteDeadline(t) }
+
+// Configuração lida pelo cliente web ao carregar a página
+type webClientConfig struct {
+	WebSocketPath   string              `json:"websocket_path"`
+	ProtocolVersion int                 `json:"protocol_version"`
+	DefaultPackType string              `json:"default_pack_type"`
+	PackTypes       []webClientPackType `json:"pack_types"`
+}
+
+type webClientPackType struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Interface HTTP para navegadores: o cliente web embutido em "/", a configuração
+// dele em /client-config.json e o gateway WebSocket em Config.WebSocketPath
+func (s *Server) WebHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET "+s.config.WebSocketPath, s.WebSocketHandler()) // O upgrade do WebSocket é um GET
+	mux.Handle("GET /", webclient.Handler())
+
+	mux.HandleFunc("GET /client-config.json", func(w http.ResponseWriter, r *http.Request) {
+		config := webClientConfig{
+			WebSocketPath:   s.config.WebSocketPath,
+			ProtocolVersion: protocol.PROTOCOL_VERSION,
+			DefaultPackType: card.PACK_STANDARD,
+		}
+		for _, packType := range s.packTypes.List() {
+			config.PackTypes = append(config.PackTypes, webClientPackType{Name: packType.Name, Description: packType.Description})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(config)
+	})
+
+	return mux
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+	"top-card/internal/card"
+	"top-card/internal/player"
+)
+
+// Estado persistido do servidor: jogadores, estoque e partidas interrompidas no encerramento
+type Snapshot struct {
+	SavedAt     time.Time          `json:"saved_at"`
+	NextID      int                `json:"next_id"`
+	Players     []player.Record    `json:"players"`
+	Stock       card.StockCounts   `json:"stock"`
+	Interrupted []InterruptedMatch `json:"interrupted_matches,omitempty"`
+}
+
+// Partida que não terminou até o prazo do encerramento. As cartas já jogadas são
+// devolvidas aos jogadores e a partida fica registrada aqui.
+type InterruptedMatch struct {
+	MatchID     int        `json:"match_id"`
+	Player1ID   int        `json:"player1_id"`
+	Player2ID   int        `json:"player2_id"`
+	Status      string     `json:"status"`
+	Player1Card *card.Card `json:"player1_card,omitempty"`
+	Player2Card *card.Card `json:"player2_card,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	Interrupted time.Time  `json:"interrupted_at"`
+}
+
+// Função para salvar o estado em disco. Escreve num arquivo temporário e renomeia,
+// para que uma falha no meio da escrita não corrompa o arquivo anterior.
+func Save(path string, snapshot Snapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("erro ao serializar estado: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("erro ao criar arquivo temporário: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("erro ao gravar estado: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("erro ao gravar estado: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("erro ao gravar estado: %v", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Função para carregar o estado salvo. Retorna ok = false se o arquivo não existe.
+func Load(path string) (Snapshot, bool, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Snapshot{}, false, nil
+	}
+	if err != nil {
+		return Snapshot{}, false, fmt.Errorf("erro ao ler estado: %v", err)
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return Snapshot{}, false, fmt.Errorf("erro ao decodificar estado: %v", err)
+	}
+	return snapshot, true, nil
+}
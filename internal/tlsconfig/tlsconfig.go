@@ -0,0 +1,211 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// Hosts do certificado autoassinado quando nenhum é informado
+var DefaultHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// Validade do certificado autoassinado
+const selfSignedValidity = 365 * 24 * time.Hour
+
+// Certificado do servidor diferente do fixado no cliente
+var ErrPinMismatch = errors.New("certificado do servidor não confere com o fixado")
+
+// Opções de TLS do servidor: arquivos de certificado e chave ou um certificado
+// autoassinado gerado na inicialização (só para desenvolvimento)
+type ServerOptions struct {
+	CertFile   string   // Certificado em PEM (pode incluir a cadeia)
+	KeyFile    string   // Chave privada em PEM
+	SelfSigned bool     // Gera um certificado autoassinado se CertFile estiver vazio
+	Hosts      []string // Nomes e IPs do certificado autoassinado (padrão: DefaultHosts)
+}
+
+// Opções de TLS do cliente. Sem CAFile nem Pin o certificado é verificado com as
+// autoridades do sistema.
+type ClientOptions struct {
+	CAFile     string // Autoridades aceitas, em PEM (ex.: o próprio certificado autoassinado)
+	Pin        string // SHA-256 do certificado do servidor em hexadecimal (ver Fingerprint)
+	ServerName string // Nome esperado no certificado (padrão: host do endereço)
+}
+
+// Função para criar a configuração TLS do servidor
+func NewServer(options ServerOptions) (*tls.Config, error) {
+	var certificate tls.Certificate
+	var err error
+	switch {
+	case options.CertFile != "" || options.KeyFile != "":
+		certificate, err = tls.LoadX509KeyPair(options.CertFile, options.KeyFile)
+	case options.SelfSigned:
+		var certPEM, keyPEM []byte
+		if certPEM, keyPEM, err = GenerateSelfSigned(options.Hosts); err == nil {
+			certificate, err = tls.X509KeyPair(certPEM, keyPEM)
+		}
+	default:
+		return nil, errors.New("informe certificado e chave ou use certificado autoassinado")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("certificado do servidor: %w", err)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// Função para criar a configuração TLS do cliente
+func NewClient(options ClientOptions) (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName: options.ServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if options.CAFile != "" {
+		data, err := os.ReadFile(options.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("autoridades do cliente: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("autoridades do cliente: nenhum certificado em %s", options.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if options.Pin != "" {
+		pin, err := normalizePin(options.Pin)
+		if err != nil {
+			return nil, err
+		}
+		// Só com a fixação (sem CAFile) a cadeia não é verificada: o certificado
+		// fixado é a própria confiança, o que permite certificados autoassinados
+		config.InsecureSkipVerify = options.CAFile == ""
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 || Fingerprint(state.PeerCertificates[0]) != pin {
+				return ErrPinMismatch
+			}
+			return nil
+		}
+	}
+	return config, nil
+}
+
+// Função para ler as opções do cliente das variáveis de ambiente. Retorna nil (TCP
+// sem criptografia) se nenhuma estiver definida:
+//
+//	TLS=on              ativa TLS verificando com as autoridades do sistema
+//	TLS_CA_FILE=<pem>   autoridades aceitas
+//	TLS_PIN=<sha256>    certificado fixado
+//	TLS_SERVER_NAME     nome esperado no certificado
+func ClientFromEnv() (*tls.Config, error) {
+	options := ClientOptions{
+		CAFile:     os.Getenv("TLS_CA_FILE"),
+		Pin:        os.Getenv("TLS_PIN"),
+		ServerName: os.Getenv("TLS_SERVER_NAME"),
+	}
+	if os.Getenv("TLS") != "on" && options == (ClientOptions{}) {
+		return nil, nil
+	}
+	return NewClient(options)
+}
+
+// Função para conectar ao servidor, com TLS se config não for nil. O handshake TLS
+// é feito aqui para que erros de certificado apareçam na conexão, e não na primeira
+// mensagem.
+func Dial(addr string, config *tls.Config, timeout time.Duration) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	if config == nil {
+		return dialer.Dial("tcp", addr)
+	}
+	return tls.DialWithDialer(dialer, "tcp", addr, config)
+}
+
+// Função para calcular a impressão digital de um certificado (SHA-256 do DER em
+// hexadecimal, o mesmo valor de "openssl x509 -fingerprint -sha256" sem os ':')
+func Fingerprint(certificate *x509.Certificate) string {
+	sum := sha256.Sum256(certificate.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// Função para obter a impressão digital do certificado de uma configuração do servidor
+func ServerFingerprint(config *tls.Config) string {
+	if len(config.Certificates) == 0 || config.Certificates[0].Leaf == nil {
+		return ""
+	}
+	return Fingerprint(config.Certificates[0].Leaf)
+}
+
+// Aceita a impressão digital com ou sem ':' e em maiúsculas ou minúsculas
+func normalizePin(pin string) (string, error) {
+	pin = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(pin), ":", ""))
+	if decoded, err := hex.DecodeString(pin); err != nil || len(decoded) != sha256.Size {
+		return "", fmt.Errorf("TLS_PIN inválido: esperado SHA-256 em hexadecimal")
+	}
+	return pin, nil
+}
+
+// Função para gerar um certificado autoassinado (ECDSA P-256) para os hosts
+// informados. Retorna certificado e chave em PEM.
+func GenerateSelfSigned(hosts []string) ([]byte, []byte, error) {
+	if len(hosts) == 0 {
+		hosts = DefaultHosts
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"TOP CARD"}, CommonName: hosts[0]},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true, // Permite usar o próprio certificado como TLS_CA_FILE
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
@@ -0,0 +1,275 @@
+package udpecho
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"net"
+	"slices"
+	"sync"
+	"time"
+	"top-card/internal/clock"
+)
+
+// Formato das sondas (big-endian, PACKET_SIZE bytes):
+//
+//	0  magic "TCEP"
+//	4  versão do formato
+//	5  reservado (3 bytes)
+//	8  número de sequência (uint32)
+//	12 instante de envio do cliente (ns Unix)
+//	20 instante de recebimento do servidor (ns Unix; zero na sonda)
+//	28 reservado (4 bytes)
+//
+// A resposta tem o mesmo tamanho da sonda: o serviço não amplifica tráfego.
+const (
+	PACKET_SIZE = 32
+	VERSION     = 1
+)
+
+// Tipo usado nos limites por IP do servidor para as sondas recebidas
+const RATE_KEY = "UDP_ECHO"
+
+var magic = [4]byte{'T', 'C', 'E', 'P'}
+
+// Nenhuma sonda respondida dentro do prazo
+var ErrNoReplies = errors.New("nenhuma resposta do serviço de eco UDP")
+
+// Sonda ou resposta decodificada
+type packet struct {
+	seq        uint32
+	clientTime int64
+	serverTime int64
+}
+
+func (p packet) encode() []byte {
+	data := make([]byte, PACKET_SIZE)
+	copy(data, magic[:])
+	data[4] = VERSION
+	binary.BigEndian.PutUint32(data[8:], p.seq)
+	binary.BigEndian.PutUint64(data[12:], uint64(p.clientTime))
+	binary.BigEndian.PutUint64(data[20:], uint64(p.serverTime))
+	return data
+}
+
+// Função para decodificar um pacote; pacotes de outro tamanho, formato ou versão são recusados
+func decode(data []byte) (packet, bool) {
+	if len(data) != PACKET_SIZE || [4]byte(data[:4]) != magic || data[4] != VERSION {
+		return packet{}, false
+	}
+	return packet{
+		seq:        binary.BigEndian.Uint32(data[8:]),
+		clientTime: int64(binary.BigEndian.Uint64(data[12:])),
+		serverTime: int64(binary.BigEndian.Uint64(data[20:])),
+	}, true
+}
+
+// Serviço de eco: devolve cada sonda válida com o instante de recebimento do servidor
+type Server struct {
+	Clock clock.Clock          // Relógio do instante de recebimento (padrão: relógio real)
+	Allow func(ip string) bool // Se definido, sondas de IPs recusados são descartadas
+}
+
+// Função para responder às sondas recebidas em conn até conn ser fechada (retorna nil
+// nesse caso). Pacotes inválidos são descartados sem resposta.
+func (s *Server) Serve(conn net.PacketConn) error {
+	clk := s.Clock
+	if clk == nil {
+		clk = clock.Real()
+	}
+	buffer := make([]byte, PACKET_SIZE+1) // Um byte a mais para detectar pacotes maiores
+	for {
+		n, addr, err := conn.ReadFrom(buffer)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return err
+			}
+			continue // Erros transitórios (ex.: ICMP de porta inalcançável)
+		}
+		probe, ok := decode(buffer[:n])
+		if !ok || probe.serverTime != 0 {
+			continue
+		}
+		if s.Allow != nil {
+			ip := addr.String()
+			if udpAddr, ok := addr.(*net.UDPAddr); ok {
+				ip = udpAddr.IP.String()
+			}
+			if !s.Allow(ip) {
+				continue
+			}
+		}
+		probe.serverTime = clk.Now().UnixNano()
+		conn.WriteTo(probe.encode(), addr)
+	}
+}
+
+// Opções da medição. Campos vazios usam os valores padrão.
+type ProbeOptions struct {
+	Count    int           // Sondas enviadas (padrão: 10)
+	Interval time.Duration // Intervalo entre sondas (padrão: 200ms)
+	Timeout  time.Duration // Espera pelas respostas depois da última sonda (padrão: 1s)
+}
+
+// Resultado da medição. Os tempos consideram apenas as sondas respondidas.
+type Result struct {
+	Sent     int           `json:"sent"`
+	Received int           `json:"received"`
+	Loss     float64       `json:"loss"` // Perda de pacotes em %
+	Min      time.Duration `json:"min"`
+	Avg      time.Duration `json:"avg"`
+	Max      time.Duration `json:"max"`
+	StdDev   time.Duration `json:"stddev"`
+	Jitter   time.Duration `json:"jitter"`       // Média da diferença entre RTTs consecutivos
+	Offset   time.Duration `json:"clock_offset"` // Estimativa de quanto o relógio do servidor está adiantado
+}
+
+// Função para medir a latência até o serviço de eco em addr (host:porta UDP).
+// Retorna o resultado com ErrNoReplies se nenhuma sonda for respondida.
+func Probe(ctx context.Context, addr string, options ProbeOptions) (*Result, error) {
+	if options.Count <= 0 {
+		options.Count = 10
+	}
+	if options.Interval <= 0 {
+		options.Interval = 200 * time.Millisecond
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = time.Second
+	}
+
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var mutex sync.Mutex
+	sent := make([]time.Time, options.Count)
+	rtts := make([]time.Duration, options.Count)
+	offsets := make([]time.Duration, options.Count)
+	received := 0
+
+	// Leitura das respostas: termina com todas as sondas respondidas, no prazo ou ao fechar conn
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buffer := make([]byte, PACKET_SIZE+1)
+		for {
+			n, err := conn.Read(buffer)
+			if err != nil {
+				var netErr net.Error
+				if errors.Is(err, net.ErrClosed) || errors.As(err, &netErr) && netErr.Timeout() {
+					return
+				}
+				continue // Porta inalcançável: a sonda conta como perdida
+			}
+			reply, ok := decode(buffer[:n])
+			if !ok || int(reply.seq) >= options.Count {
+				continue
+			}
+
+			mutex.Lock()
+			start := sent[reply.seq]
+			if start.IsZero() || rtts[reply.seq] != 0 || reply.clientTime != start.UnixNano() {
+				mutex.Unlock()
+				continue // Resposta duplicada ou que não corresponde à sonda
+			}
+			rtt := max(time.Since(start), time.Nanosecond)
+			rtts[reply.seq] = rtt
+			offsets[reply.seq] = time.Duration(reply.serverTime - start.Add(rtt/2).UnixNano())
+			received++
+			all := received == options.Count
+			mutex.Unlock()
+			if all {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(options.Interval)
+	defer ticker.Stop()
+	for i := range options.Count {
+		if i > 0 {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				conn.Close()
+				<-done
+				return nil, ctx.Err()
+			case <-done:
+			}
+		}
+		mutex.Lock()
+		sent[i] = time.Now()
+		probe := packet{seq: uint32(i), clientTime: sent[i].UnixNano()}
+		mutex.Unlock()
+		conn.Write(probe.encode()) // Uma falha no envio conta como perda
+	}
+
+	conn.SetReadDeadline(time.Now().Add(options.Timeout))
+	select {
+	case <-done:
+	case <-ctx.Done():
+		conn.Close()
+		<-done
+		return nil, ctx.Err()
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	result := summarize(rtts, offsets)
+	if result.Received == 0 {
+		return result, ErrNoReplies
+	}
+	return result, nil
+}
+
+// Função para calcular as estatísticas das sondas (RTT zero = sonda perdida)
+func summarize(rtts, offsets []time.Duration) *Result {
+	result := &Result{Sent: len(rtts)}
+	var answered, clockOffsets []time.Duration
+	for i, rtt := range rtts {
+		if rtt != 0 {
+			answered = append(answered, rtt)
+			clockOffsets = append(clockOffsets, offsets[i])
+		}
+	}
+	result.Received = len(answered)
+	if result.Sent > 0 {
+		result.Loss = float64(result.Sent-result.Received) * 100 / float64(result.Sent)
+	}
+	if result.Received == 0 {
+		return result
+	}
+
+	var sum, jitter time.Duration
+	for i, rtt := range answered {
+		sum += rtt
+		if i > 0 {
+			jitter += (rtt - answered[i-1]).Abs()
+		}
+	}
+	result.Min = slices.Min(answered)
+	result.Max = slices.Max(answered)
+	result.Avg = sum / time.Duration(len(answered))
+	if len(answered) > 1 {
+		result.Jitter = jitter / time.Duration(len(answered)-1)
+	}
+
+	var variance float64
+	for _, rtt := range answered {
+		diff := float64(rtt - result.Avg)
+		variance += diff * diff
+	}
+	result.StdDev = time.Duration(math.Sqrt(variance / float64(len(answered))))
+
+	// A mediana descarta sondas atrasadas por filas, que distorcem a estimativa
+	slices.Sort(clockOffsets)
+	result.Offset = clockOffsets[len(clockOffsets)/2]
+	return result
+}
@@ -0,0 +1,582 @@
+'use strict';
+
+// Cliente web do TOP CARD. Fala o mesmo protocolo do cliente de terminal pelo gateway
+// WebSocket do servidor: cada frame de texto carrega uma mensagem JSON
+// {type, data, request_id, error_code}.
+
+const CLIENT_NAME = 'top-card-web';
+const CLIENT_VERSION = '1.0.0';
+const REQUEST_TIMEOUT_MS = 5000;
+
+// Cartas e o que cada uma vence (mesma ordem do menu do terminal)
+const CARDS = [
+  { type: 'HYDRA', beats: 'devora QUIMERA' },
+  { type: 'QUIMERA', beats: 'destrói GORGONA' },
+  { type: 'GORGONA', beats: 'petrifica HYDRA' },
+];
+
+const state = {
+  config: null,       // /client-config.json
+  ws: null,
+  connected: false,
+  nextRequest: 0,
+  pending: new Map(), // request_id -> {resolve, reject, timer}
+  userID: 0,
+  userName: '',
+  inventory: [],      // Inventário enviado pelo servidor (INVENTORY_UPDATE)
+  matchID: 0,
+  inMatch: false,
+  myTurn: false,
+};
+
+const $ = (id) => document.getElementById(id);
+
+// ===== Conexão =====
+
+async function start() {
+  try {
+    const response = await fetch('client-config.json');
+    state.config = await response.json();
+  } catch (err) {
+    log(`Erro ao carregar a configuração: ${err}`, 'error');
+    return;
+  }
+
+  const select = $('pack-type');
+  for (const pack of state.config.pack_types) {
+    const option = document.createElement('option');
+    option.value = pack.name;
+    option.textContent = `${pack.name}: ${pack.description}`;
+    option.selected = pack.name === state.config.default_pack_type;
+    select.append(option);
+  }
+
+  bindActions();
+  connect();
+}
+
+function connect() {
+  const scheme = location.protocol === 'https:' ? 'wss' : 'ws';
+  const ws = new WebSocket(`${scheme}://${location.host}${state.config.websocket_path}`);
+  state.ws = ws;
+
+  ws.onopen = async () => {
+    setConnected(true);
+    try {
+      const welcome = await request('HELLO', {
+        protocol_version: state.config.protocol_version,
+        client_name: CLIENT_NAME,
+        client_version: CLIENT_VERSION,
+        capabilities: ['request_id', 'error_codes', 'inventory', 'session_state'],
+      });
+      if (!welcome.data.accepted) {
+        log(`Servidor recusou o cliente: ${welcome.data.message}`, 'error');
+        ws.close();
+        return;
+      }
+      log(`Conectado a ${welcome.data.server_name} ${welcome.data.server_version}`, 'ok');
+    } catch (err) {
+      log(`Falha no handshake: ${err.message}`, 'error');
+    }
+  };
+
+  ws.onmessage = (event) => {
+    let message;
+    try {
+      message = JSON.parse(event.data);
+    } catch (err) {
+      log(`Mensagem inválida do servidor: ${err}`, 'error');
+      return;
+    }
+    dispatch(message);
+  };
+
+  ws.onclose = () => {
+    if (state.ws !== ws) {
+      return;
+    }
+    setConnected(false);
+    for (const [, pending] of state.pending) {
+      clearTimeout(pending.timer);
+      pending.reject(new Error('conexão encerrada'));
+    }
+    state.pending.clear();
+    if (state.userID) {
+      log('Conexão perdida. Reconecte e faça login novamente.', 'error');
+    }
+    resetPlayer();
+  };
+}
+
+function setConnected(connected) {
+  state.connected = connected;
+  const status = $('status');
+  status.textContent = connected ? 'Conectado' : 'Desconectado';
+  status.className = `status ${connected ? 'online' : 'offline'}`;
+  $('reconnect').hidden = connected;
+  render();
+}
+
+// Envia uma mensagem sem esperar resposta
+function send(type, data, requestID) {
+  if (!state.connected) {
+    throw new Error('sem conexão com o servidor');
+  }
+  const message = { type, data };
+  if (requestID) {
+    message.request_id = requestID;
+  }
+  state.ws.send(JSON.stringify(message));
+}
+
+// Envia uma requisição e aguarda a resposta com o mesmo request_id
+function request(type, data) {
+  return new Promise((resolve, reject) => {
+    const requestID = `web-${++state.nextRequest}`;
+    const timer = setTimeout(() => {
+      state.pending.delete(requestID);
+      reject(new Error('servidor não respondeu'));
+    }, REQUEST_TIMEOUT_MS);
+    state.pending.set(requestID, { resolve, reject, timer });
+    try {
+      send(type, data, requestID);
+    } catch (err) {
+      clearTimeout(timer);
+      state.pending.delete(requestID);
+      reject(err);
+    }
+  });
+}
+
+// Respostas vão para quem fez a requisição; o resto são notificações do servidor
+function dispatch(message) {
+  const pending = message.request_id && state.pending.get(message.request_id);
+  if (pending) {
+    state.pending.delete(message.request_id);
+    clearTimeout(pending.timer);
+    pending.resolve(message);
+    return;
+  }
+
+  const handler = notificationHandlers[message.type];
+  if (handler) {
+    handler(message.data || {}, message);
+  } else {
+    log(`Mensagem não tratada: ${message.type}`);
+  }
+}
+
+// Mensagem ERROR recebida como resposta de uma requisição (ex.: RATE_LIMITED)
+function failed(message) {
+  if (message.type !== 'ERROR') {
+    return false;
+  }
+  logError(message.data, message.error_code);
+  return true;
+}
+
+function logError(data, code) {
+  let text = `${data.message} [${code}]`;
+  if (data.retry_after_ms) {
+    text += ` Tente novamente em ${Math.ceil(data.retry_after_ms / 1000)}s.`;
+  }
+  log(text, 'error');
+}
+
+// ===== Notificações =====
+
+const notificationHandlers = {
+  MATCH_FOUND(data) {
+    state.matchID = data.match_id;
+    state.inMatch = true;
+    state.myTurn = false;
+    $('opponent').textContent = `Oponente: ${data.opponent_name} (ID: ${data.opponent_id})`;
+    log(`Partida encontrada! ${data.message}`, 'ok');
+    render();
+  },
+
+  MATCH_START(data) {
+    state.matchID = data.match_id;
+    state.inMatch = true;
+    state.myTurn = false;
+    log(`Partida iniciada: ${data.message}`, 'ok');
+    render();
+  },
+
+  GAME_STATE(data) {
+    state.myTurn = data.your_turn && !data.game_over;
+    log(data.message);
+    render();
+  },
+
+  TURN_UPDATE(data) {
+    state.myTurn = data.your_turn;
+    log(data.message);
+    render();
+  },
+
+  MOVE_REJECTED(data, message) {
+    log(`Jogada recusada: ${data.message} [${message.error_code}]`, 'error');
+    switch (message.error_code) {
+      case 'NOT_YOUR_TURN':
+      case 'ALREADY_PLAYED':
+        state.myTurn = false;
+        break;
+      case 'MATCH_NOT_FOUND':
+      case 'MATCH_NOT_IN_PROGRESS':
+      case 'NOT_IN_MATCH':
+        state.inMatch = false;
+        state.myTurn = false;
+        break;
+      default:
+        state.myTurn = true;
+    }
+    render();
+  },
+
+  MATCH_END(data) {
+    if (data.winner_id === 0) {
+      log(`Partida interrompida, sem vencedor. ${data.message}`);
+    } else if (data.winner_id === state.userID) {
+      log(`VITÓRIA! ${data.message}`, 'ok');
+    } else {
+      log(`DERROTA! Vencedor: ${data.winner_name}. ${data.message}`, 'error');
+    }
+    state.inMatch = false;
+    state.matchID = 0;
+    state.myTurn = false;
+    render();
+  },
+
+  // Estado completo enviado antes da resposta do login: a tela é remontada a partir dele
+  SESSION_STATE(data) {
+    state.inventory = data.inventory || [];
+    state.inMatch = Boolean(data.match);
+    state.matchID = data.match ? data.match.match_id : 0;
+    state.myTurn = Boolean(data.match && data.match.your_turn);
+    $('opponent').textContent = data.match ? `Oponente: ${data.match.opponent_name} (ID: ${data.match.opponent_id})` : '';
+    log(`${data.username}: ${data.wins} vitórias, ${data.losses} derrotas, ${data.coins} moedas`);
+    if (data.match) {
+      log(`Partida ${data.match.match_id} em andamento contra ${data.match.opponent_name}`, 'ok');
+    }
+    render();
+  },
+
+  INVENTORY_UPDATE(data) {
+    if (data.success) {
+      state.inventory = data.cards || [];
+    }
+    render();
+  },
+
+  SERVER_SHUTDOWN(data) {
+    let text = `Servidor em encerramento: ${data.message}`;
+    if (data.deadline) {
+      text += ` Prazo: ${new Date(data.deadline).toLocaleTimeString()}`;
+    }
+    log(text, 'error');
+  },
+
+  ERROR(data, message) {
+    logError(data, message.error_code);
+  },
+};
+
+// ===== Ações do menu =====
+
+function bindActions() {
+  $('reconnect').onclick = () => {
+    log('Reconectando...');
+    connect();
+  };
+
+  $('auth-form').onsubmit = (event) => {
+    event.preventDefault();
+    const action = event.submitter ? event.submitter.dataset.action : 'login';
+    const username = $('username').value.trim();
+    const password = $('password').value;
+    run(action === 'register' ? register(username, password) : login(username, password));
+  };
+
+  $('open-pack').onclick = () => run(openPack($('pack-type').value));
+  $('queue').onclick = () => run(joinQueue());
+  $('stats').onclick = () => run(showStats());
+  $('ping').onclick = () => run(ping());
+  $('seeds').onclick = () => run(showSeeds());
+  $('reveal-seed').onclick = () => run(revealSeed());
+  $('client-seed-form').onsubmit = (event) => {
+    event.preventDefault();
+    run(changeClientSeed($('client-seed').value.trim()));
+  };
+}
+
+// Executa uma ação assíncrona mostrando falhas no registro de eventos
+function run(action) {
+  action.catch((err) => log(`Erro: ${err.message}`, 'error'));
+}
+
+async function login(username, password) {
+  const message = await request('LOGIN_REQUEST', { username, password });
+  if (failed(message)) {
+    return;
+  }
+  const data = message.data;
+  if (!data.success) {
+    let text = data.message;
+    if (data.retry_after_ms) {
+      text += ` Tente novamente em ${Math.ceil(data.retry_after_ms / 1000)}s.`;
+    }
+    log(text, 'error');
+    return;
+  }
+  state.userID = data.user_id;
+  state.userName = username;
+  $('password').value = '';
+  log(`${data.message} (ID: ${data.user_id})`, 'ok');
+  render();
+}
+
+async function register(username, password) {
+  const message = await request('REGISTER_REQUEST', { username, password });
+  if (failed(message)) {
+    return;
+  }
+  const data = message.data;
+  if (data.success) {
+    log(`${data.message} Seu ID de usuário é ${data.user_id}. Agora você pode fazer login!`, 'ok');
+  } else {
+    log(data.message, 'error');
+  }
+}
+
+async function openPack(packType) {
+  if (state.inventory.length > 0) {
+    log('Você já possui cartas! Use suas cartas em partidas antes de abrir novos pacotes.', 'error');
+    return;
+  }
+  const message = await request('CARD_PACK_REQUEST', { user_id: state.userID, pack_type: packType });
+  if (failed(message)) {
+    return;
+  }
+  const data = message.data;
+  if (!data.success) {
+    log(data.message, 'error');
+    return;
+  }
+
+  const stock = data.stock_info || {};
+  const lines = [
+    `<p>${escapeHTML(data.message)}</p>`,
+    '<ul>' + (data.cards || []).map((c) => `<li class="rarity-${c.rarity}">${c.type} (${c.rarity})</li>`).join('') + '</ul>',
+    `<p>Estoque global: HYDRA ${stock.hydra_count} | QUIMERA ${stock.quimera_count} | GORGONA ${stock.gorgona_count} (total ${stock.total_cards})</p>`,
+  ];
+  const pity = data.pity || {};
+  if (pity.triggered) {
+    lines.push('<p>Pity ativado! Este pacote garantiu uma carta épica.</p>');
+  }
+  if (pity.threshold > 0) {
+    lines.push(`<p>Pity (${pity.pack_type}): ${pity.packs_without_epic}/${pity.threshold} pacotes sem épico, épico garantido em ${pity.packs_until_epic} pacote(s)</p>`);
+  }
+  if (data.fairness) {
+    lines.push(`<p class="hint">Sorteio #${data.fairness.nonce} com semente do servidor ${data.fairness.server_seed_hash.slice(0, 12)}...</p>`);
+  }
+  showResult('pack-result', lines.join(''));
+  log(data.message, 'ok');
+  render();
+}
+
+async function joinQueue() {
+  if (state.inMatch) {
+    log('Você já está em uma partida!', 'error');
+    return;
+  }
+  const message = await request('QUEUE_REQUEST', { user_id: state.userID });
+  if (failed(message)) {
+    return;
+  }
+  const data = message.data;
+  log(`${data.message}${data.success ? ` (jogadores na fila: ${data.queue_size})` : ''}`, data.success ? 'ok' : 'error');
+}
+
+async function showStats() {
+  const message = await request('STATS_REQUEST', { user_id: state.userID });
+  if (failed(message)) {
+    return;
+  }
+  const data = message.data;
+  if (!data.success) {
+    log(data.message, 'error');
+    return;
+  }
+  const wins = data.wins || 0;
+  const losses = data.losses || 0;
+  showResult('stats-result',
+    `<h3>Estatísticas de ${escapeHTML(data.username)}</h3>` +
+    `<p>Vitórias: ${wins} | Derrotas: ${losses} | Partidas: ${wins + losses} | Taxa de vitória: ${(data.win_rate || 0).toFixed(1)}%</p>`);
+}
+
+// O navegador não envia ICMP: mede a ida e volta de uma requisição pelo WebSocket
+async function ping() {
+  const started = performance.now();
+  const message = await request('STATS_REQUEST', { user_id: state.userID });
+  const elapsed = Math.round(performance.now() - started);
+  if (!failed(message)) {
+    log(`Latência (ida e volta pelo WebSocket): ${elapsed} ms`, 'ok');
+  }
+}
+
+async function requestSeeds(clientSeed, rotate) {
+  const message = await request('SEED_REQUEST', { user_id: state.userID, client_seed: clientSeed || undefined, rotate: rotate || undefined });
+  if (failed(message)) {
+    return null;
+  }
+  if (!message.data.success) {
+    log(message.data.message, 'error');
+    return null;
+  }
+  return message.data;
+}
+
+function showSeedInfo(data) {
+  const rows = [
+    ['Hash da semente do servidor', data.server_seed_hash],
+    ['Semente do cliente', data.client_seed],
+    ['Pacotes sorteados com esta semente', data.nonce],
+  ];
+  if (data.revealed) {
+    rows.push(['Semente revelada', data.revealed.server_seed], ['Hash revelado', data.revealed.server_seed_hash]);
+  }
+  $('seeds-info').innerHTML = rows.map(([k, v]) => `<dt>${k}</dt><dd>${escapeHTML(String(v))}</dd>`).join('');
+  $('seeds-panel').hidden = false;
+}
+
+async function showSeeds() {
+  const data = await requestSeeds();
+  if (data) {
+    showSeedInfo(data);
+  }
+}
+
+async function changeClientSeed(clientSeed) {
+  if (!clientSeed) {
+    log('A semente não pode ser vazia!', 'error');
+    return;
+  }
+  const data = await requestSeeds(clientSeed, false);
+  if (data) {
+    log(data.message, 'ok');
+    $('client-seed').value = '';
+    showSeedInfo(data);
+  }
+}
+
+async function revealSeed() {
+  const data = await requestSeeds('', true);
+  if (data) {
+    log(data.message, 'ok');
+    showSeedInfo(data);
+  }
+}
+
+function playCard(cardType) {
+  if (!state.myTurn) {
+    log('Não é seu turno! Aguarde o oponente jogar.', 'error');
+    return;
+  }
+  if (!state.inventory.some((c) => c.type === cardType)) {
+    log(`Você não possui cartas do tipo ${cardType}!`, 'error');
+    return;
+  }
+  try {
+    send('CARD_MOVE', { user_id: state.userID, match_id: state.matchID, card_type: cardType });
+  } catch (err) {
+    log(`Erro ao enviar jogada: ${err.message}`, 'error');
+    return;
+  }
+  // A carta sai do inventário quando o servidor confirmar (INVENTORY_UPDATE)
+  state.myTurn = false;
+  log(`Carta jogada: ${cardType}. Aguardando resposta do servidor...`);
+  render();
+}
+
+// ===== Interface =====
+
+function resetPlayer() {
+  Object.assign(state, { userID: 0, userName: '', inventory: [], matchID: 0, inMatch: false, myTurn: false });
+  $('pack-result').hidden = true;
+  $('stats-result').hidden = true;
+  $('seeds-panel').hidden = true;
+  render();
+}
+
+function countCards() {
+  const counts = Object.fromEntries(CARDS.map((c) => [c.type, 0]));
+  for (const c of state.inventory) {
+    counts[c.type] = (counts[c.type] || 0) + 1;
+  }
+  return counts;
+}
+
+function render() {
+  const loggedIn = state.userID !== 0;
+  $('auth').hidden = loggedIn;
+  $('lobby').hidden = !loggedIn;
+  $('match').hidden = !state.inMatch;
+  $('player-name').textContent = state.userName;
+
+  for (const button of document.querySelectorAll('button:not(#reconnect)')) {
+    button.disabled = !state.connected;
+  }
+  $('queue').disabled = !state.connected || state.inMatch;
+
+  const counts = countCards();
+  $('inventory').innerHTML = CARDS.map((c) => `<li>${c.type}: ${counts[c.type]}</li>`).join('') +
+    `<li>Total: ${state.inventory.length}</li>`;
+
+  $('match-id').textContent = state.matchID ? `#${state.matchID}` : '';
+  const turn = $('turn');
+  turn.textContent = state.myTurn ? 'É SEU TURNO! Escolha uma carta.' : 'Aguardando o oponente...';
+  turn.className = `turn${state.myTurn ? ' mine' : ''}`;
+
+  const hand = $('hand');
+  hand.innerHTML = '';
+  for (const c of CARDS) {
+    if (counts[c.type] === 0) {
+      continue;
+    }
+    const rarity = (state.inventory.find((card) => card.type === c.type) || {}).rarity || 'comum';
+    const button = document.createElement('button');
+    button.className = `card rarity-${rarity}`;
+    button.innerHTML = `${c.type} ×${counts[c.type]}<small>${c.beats}</small>`;
+    button.disabled = !state.connected || !state.myTurn;
+    button.onclick = () => playCard(c.type);
+    hand.append(button);
+  }
+}
+
+function showResult(id, html) {
+  const element = $(id);
+  element.innerHTML = html;
+  element.hidden = false;
+}
+
+function log(text, kind) {
+  const item = document.createElement('li');
+  item.textContent = `${new Date().toLocaleTimeString()} ${text}`;
+  if (kind) {
+    item.className = kind;
+  }
+  const list = $('log');
+  list.prepend(item);
+  while (list.children.length > 200) {
+    list.lastChild.remove();
+  }
+}
+
+function escapeHTML(text) {
+  const div = document.createElement('div');
+  div.textContent = text;
+  return div.innerHTML;
+}
+
+start();
@@ -0,0 +1,27 @@
+package webclient
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// Arquivos do cliente web, embutidos no binário do servidor
+//
+//go:embed static
+var files embed.FS
+
+// Função para obter os arquivos do cliente web (index.html na raiz)
+func Files() fs.FS {
+	static, err := fs.Sub(files, "static")
+	if err != nil {
+		panic(err) // Só acontece se o diretório embutido mudar de nome
+	}
+	return static
+}
+
+// Interface HTTP que serve o cliente web. O cliente busca a configuração (caminho do
+// WebSocket e tipos de pacote) em /client-config.json, servido pelo servidor do jogo.
+func Handler() http.Handler {
+	return http.FileServerFS(Files())
+}
@@ -0,0 +1,426 @@
+package topcard
+
+import (
+	"context"
+	"errors"
+	"time"
+	"top-card/internal/protocol"
+)
+
+// Carta do inventário ou de um pacote
+type Card struct {
+	Type   string `json:"type"`
+	Rarity string `json:"rarity"`
+}
+
+// Estoque global de cartas
+type Stock struct {
+	HydraCount   int `json:"hydra_count"`
+	QuimeraCount int `json:"quimera_count"`
+	GorgonaCount int `json:"gorgona_count"`
+	TotalCards   int `json:"total_cards"`
+}
+
+// Situação do épico garantido (pity) para um tipo de pacote
+type Pity struct {
+	PackType         string `json:"pack_type"`
+	PacksWithoutEpic int    `json:"packs_without_epic"`
+	Threshold        int    `json:"threshold"`           // 0 = pacote sem pity
+	PacksUntilEpic   int    `json:"packs_until_epic"`    // Pacotes até o épico garantido
+	Triggered        bool   `json:"triggered,omitempty"` // Se o pity garantiu o épico deste pacote
+}
+
+// Dados para verificar o sorteio de um pacote
+type Fairness struct {
+	ServerSeedHash string `json:"server_seed_hash"`
+	ClientSeed     string `json:"client_seed"`
+	Nonce          int    `json:"nonce"`
+	StockBefore    Stock  `json:"stock_before"` // Estoque usado no sorteio; informado pelo servidor, fora do compromisso
+}
+
+// Semente do servidor revelada depois de RotateSeed
+type RevealedSeed struct {
+	ServerSeed     string `json:"server_seed"`
+	ServerSeedHash string `json:"server_seed_hash"`
+	ClientSeed     string `json:"client_seed"`
+	Nonce          int    `json:"nonce"`
+}
+
+// Partida em andamento no estado da sessão
+type MatchSnapshot struct {
+	MatchID        int    `json:"match_id"`
+	OpponentID     int    `json:"opponent_id"`
+	OpponentName   string `json:"opponent_name"`
+	Status         string `json:"status"` // "waiting" ou "playing"
+	YourTurn       bool   `json:"your_turn"`
+	PlayedCard     string `json:"played_card,omitempty"` // Carta já jogada pelo jogador
+	OpponentPlayed bool   `json:"opponent_played"`
+}
+
+// Pacote de cartas aberto
+type Pack struct {
+	Type     string   `json:"type"`
+	Message  string   `json:"message"`
+	Cards    []Card   `json:"cards"`
+	Stock    Stock    `json:"stock"`    // Estoque global depois do sorteio
+	Pity     Pity     `json:"pity"`     // Situação do épico garantido para este tipo de pacote
+	Fairness Fairness `json:"fairness"` // Dados para verificar o sorteio quando a semente for revelada
+
+	// Hash publicado antes do pedido (Client.SeedHash). Um Fairness.ServerSeedHash
+	// diferente indica um sorteio com semente que não foi comprometida antes.
+	CommittedHash string `json:"committed_hash,omitempty"`
+}
+
+// Estatísticas do jogador
+type Stats struct {
+	UserName string  `json:"username"`
+	Wins     int     `json:"wins"`
+	Losses   int     `json:"losses"`
+	WinRate  float64 `json:"win_rate"`
+}
+
+// Sementes do sorteio dos pacotes
+type Seeds struct {
+	Message        string        `json:"message"`
+	ServerSeedHash string        `json:"server_seed_hash"`
+	ClientSeed     string        `json:"client_seed"`
+	Nonce          int           `json:"nonce"`              // Pacotes sorteados com a semente atual
+	Revealed       *RevealedSeed `json:"revealed,omitempty"` // Semente anterior, apenas depois de RotateSeed
+}
+
+// Função para cadastrar um usuário. Retorna o ID do novo usuário; o cadastro não faz login.
+func (c *Client) Register(ctx context.Context, userName, password string) (int, error) {
+	data, err := protocol.CreateRegisterRequest(userName, password)
+	if err != nil {
+		return 0, err
+	}
+	message, err := c.request(ctx, data, protocol.MSG_REGISTER_RESPONSE)
+	if err != nil {
+		return 0, err
+	}
+	response, err := protocol.ExtractRegisterResponse(message)
+	if err != nil {
+		return 0, err
+	}
+	if !response.Success {
+		return 0, &Error{Code: message.ErrorCode, Message: response.Message}
+	}
+	return response.UserID, nil
+}
+
+// Função para fazer login. Retorna o ID do usuário, usado nas requisições seguintes.
+// Com servidores que enviam o estado da sessão, Inventory e Match já estão
+// atualizados quando Login retorna e o estado completo chega como SessionState.
+func (c *Client) Login(ctx context.Context, userName, password string) (int, error) {
+	data, err := protocol.CreateLoginRequest(userName, password)
+	if err != nil {
+		return 0, err
+	}
+	message, err := c.request(ctx, data, protocol.MSG_LOGIN_RESPONSE)
+	if err != nil {
+		return 0, err
+	}
+	response, err := protocol.ExtractLoginResponse(message)
+	if err != nil {
+		return 0, err
+	}
+	if !response.Success {
+		return 0, &Error{Code: message.ErrorCode, Message: response.Message, RetryAfter: time.Duration(response.RetryAfterMs) * time.Millisecond}
+	}
+
+	c.stateMutex.Lock()
+	c.userID = response.UserID
+	c.seedHash = response.ServerSeedHash
+	c.stateMutex.Unlock()
+	if c.Supports(protocol.CAP_SESSION_STATE) {
+		return response.UserID, nil // O SESSION_STATE chegou antes da resposta
+	}
+
+	c.stateMutex.Lock()
+	c.inventory = nil
+	c.match = MatchState{}
+	c.stateMutex.Unlock()
+
+	// O inventário vem do servidor; uma falha aqui não desfaz o login e as mudanças
+	// seguintes chegam como InventoryUpdate
+	if c.Supports(protocol.CAP_INVENTORY) {
+		c.RefreshInventory(ctx)
+	}
+	return response.UserID, nil
+}
+
+// Função para abrir um pacote de cartas (packType vazio = pacote padrão). O servidor
+// envia o inventário novo antes da resposta, então Inventory já inclui as cartas.
+func (c *Client) OpenPack(ctx context.Context, packType string) (*Pack, error) {
+	return c.openPack(ctx, packType, "")
+}
+
+// Função para abrir um pacote trocando antes a semente do cliente
+func (c *Client) OpenPackWithSeed(ctx context.Context, packType, clientSeed string) (*Pack, error) {
+	return c.openPack(ctx, packType, clientSeed)
+}
+
+func (c *Client) openPack(ctx context.Context, packType, clientSeed string) (*Pack, error) {
+	userID, err := c.loggedUser()
+	if err != nil {
+		return nil, err
+	}
+	data, err := protocol.CreateCardPackRequest(userID, packType, clientSeed)
+	if err != nil {
+		return nil, err
+	}
+	committed := c.SeedHash()
+	message, err := c.request(ctx, data, protocol.MSG_CARD_PACK_RESPONSE)
+	if err != nil {
+		return nil, err
+	}
+	response, err := protocol.ExtractCardPackResponse(message)
+	if err != nil {
+		return nil, err
+	}
+	if !response.Success {
+		return nil, &Error{Code: message.ErrorCode, Message: response.Message}
+	}
+
+	return &Pack{
+		Type:     response.PackType,
+		Message:  response.Message,
+		Cards:    cardsFrom(response.Cards),
+		Stock:    Stock(response.StockInfo),
+		Pity:     Pity(response.Pity),
+		Fairness: fairnessFrom(response.Fairness),
+
+		CommittedHash: committed,
+	}, nil
+}
+
+// Função para entrar na fila de partidas. Retorna o tamanho da fila; a partida
+// chega depois como MatchFound e MatchStart em Events.
+func (c *Client) JoinQueue(ctx context.Context) (int, error) {
+	userID, err := c.loggedUser()
+	if err != nil {
+		return 0, err
+	}
+	data, err := protocol.CreateQueueRequest(userID)
+	if err != nil {
+		return 0, err
+	}
+	message, err := c.request(ctx, data, protocol.MSG_QUEUE_RESPONSE)
+	if err != nil {
+		return 0, err
+	}
+	response, err := protocol.ExtractQueueResponse(message)
+	if err != nil {
+		return 0, err
+	}
+	if !response.Success {
+		return 0, &Error{Code: message.ErrorCode, Message: response.Message}
+	}
+	return response.QueueSize, nil
+}
+
+// Função para jogar uma carta na partida atual. A jogada não tem resposta direta:
+// o resultado chega como GameState, TurnUpdate, MatchEnd ou MoveRejected em Events.
+// A carta sai de Inventory quando o servidor aceita a jogada (InventoryUpdate).
+func (c *Client) PlayCard(ctx context.Context, cardType string) error {
+	userID, err := c.loggedUser()
+	if err != nil {
+		return err
+	}
+
+	c.stateMutex.Lock()
+	match := c.match
+	c.stateMutex.Unlock()
+	if !match.InMatch {
+		return ErrNotInMatch
+	}
+
+	data, err := protocol.CreateCardMove(userID, match.MatchID, cardType)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Atualiza o turno antes de enviar: a recusa pode chegar antes de Write retornar
+	c.stateMutex.Lock()
+	c.match.YourTurn = false
+	c.stateMutex.Unlock()
+
+	if err := c.write(data); err != nil {
+		c.stateMutex.Lock()
+		c.match.YourTurn = match.YourTurn
+		c.stateMutex.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Função para pedir ao servidor o inventário do jogador logado. Inventory passa a
+// refletir a resposta; depois disso o servidor avisa cada mudança com InventoryUpdate.
+func (c *Client) RefreshInventory(ctx context.Context) ([]Card, error) {
+	userID, err := c.loggedUser()
+	if err != nil {
+		return nil, err
+	}
+	if !c.Supports(protocol.CAP_INVENTORY) {
+		return nil, ErrUnsupported
+	}
+	data, err := protocol.CreateInventoryRequest(userID)
+	if err != nil {
+		return nil, err
+	}
+	message, err := c.request(ctx, data, protocol.MSG_INVENTORY_UPDATE)
+	if err != nil {
+		return nil, err
+	}
+	response, err := protocol.ExtractInventoryUpdate(message)
+	if err != nil {
+		return nil, err
+	}
+	if !response.Success {
+		return nil, &Error{Code: message.ErrorCode, Message: response.Message}
+	}
+	return cardsFrom(response.Cards), nil
+}
+
+// Função para consultar as estatísticas do jogador logado
+func (c *Client) Stats(ctx context.Context) (*Stats, error) {
+	userID, err := c.loggedUser()
+	if err != nil {
+		return nil, err
+	}
+	data, err := protocol.CreateStatsRequest(userID)
+	if err != nil {
+		return nil, err
+	}
+	message, err := c.request(ctx, data, protocol.MSG_STATS_RESPONSE)
+	if err != nil {
+		return nil, err
+	}
+	response, err := protocol.ExtractStatsResponse(message)
+	if err != nil {
+		return nil, err
+	}
+	if !response.Success {
+		return nil, &Error{Code: message.ErrorCode, Message: response.Message}
+	}
+	return &Stats{UserName: response.UserName, Wins: response.Wins, Losses: response.Losses, WinRate: response.WinRate}, nil
+}
+
+// Função para consultar as sementes do sorteio
+func (c *Client) Seeds(ctx context.Context) (*Seeds, error) {
+	return c.seeds(ctx, "", false)
+}
+
+// Função para trocar a semente do cliente usada nos próximos pacotes
+func (c *Client) SetClientSeed(ctx context.Context, clientSeed string) (*Seeds, error) {
+	return c.seeds(ctx, clientSeed, false)
+}
+
+// Função para revelar a semente atual do servidor (em Seeds.Revealed) e publicar uma nova
+func (c *Client) RotateSeed(ctx context.Context) (*Seeds, error) {
+	return c.seeds(ctx, "", true)
+}
+
+func (c *Client) seeds(ctx context.Context, clientSeed string, rotate bool) (*Seeds, error) {
+	userID, err := c.loggedUser()
+	if err != nil {
+		return nil, err
+	}
+	data, err := protocol.CreateSeedRequest(userID, clientSeed, rotate)
+	if err != nil {
+		return nil, err
+	}
+	message, err := c.request(ctx, data, protocol.MSG_SEED_RESPONSE)
+	if err != nil {
+		return nil, err
+	}
+	response, err := protocol.ExtractSeedResponse(message)
+	if err != nil {
+		return nil, err
+	}
+	if !response.Success {
+		return nil, &Error{Code: message.ErrorCode, Message: response.Message}
+	}
+
+	c.stateMutex.Lock()
+	c.seedHash = response.ServerSeedHash
+	c.stateMutex.Unlock()
+	return &Seeds{
+		Message:        response.Message,
+		ServerSeedHash: response.ServerSeedHash,
+		ClientSeed:     response.ClientSeed,
+		Nonce:          response.Nonce,
+		Revealed:       (*RevealedSeed)(response.Revealed),
+	}, nil
+}
+
+// Função para medir o tempo de ida e volta de uma requisição ao servidor (uma
+// consulta de estatísticas, que não altera nada)
+func (c *Client) Ping(ctx context.Context) (time.Duration, error) {
+	start := time.Now()
+	if _, err := c.Stats(ctx); err != nil {
+		// Uma resposta do servidor, mesmo com erro, também mede a latência
+		var serverErr *Error
+		if !errors.As(err, &serverErr) {
+			return 0, err
+		}
+	}
+	return time.Since(start), nil
+}
+
+// Função para enviar uma mensagem de chat ao oponente da partida atual. O envio não
+// tem resposta: recusas do servidor chegam como ServerError em Events.
+func (c *Client) SendChat(ctx context.Context, text string) error {
+	userID, err := c.loggedUser()
+	if err != nil {
+		return err
+	}
+	if !c.Supports(protocol.CAP_CHAT) {
+		return ErrUnsupported
+	}
+	match := c.Match()
+	if !match.InMatch {
+		return ErrNotInMatch
+	}
+
+	data, err := protocol.CreateChatMessage(userID, match.MatchID, text)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.write(data)
+}
+
+// ID do usuário logado ou ErrNotLoggedIn
+func (c *Client) loggedUser() (int, error) {
+	if userID := c.UserID(); userID != 0 {
+		return userID, nil
+	}
+	return 0, ErrNotLoggedIn
+}
+
+// Funções para converter os tipos do protocolo nos tipos do SDK
+func cardsFrom(cards []protocol.CardInfo) []Card {
+	if cards == nil {
+		return nil
+	}
+	converted := make([]Card, len(cards))
+	for i, c := range cards {
+		converted[i] = Card(c)
+	}
+	return converted
+}
+
+func fairnessFrom(fairness protocol.FairnessInfo) Fairness {
+	return Fairness{
+		ServerSeedHash: fairness.ServerSeedHash,
+		ClientSeed:     fairness.ClientSeed,
+		Nonce:          fairness.Nonce,
+		StockBefore:    Stock(fairness.StockBefore),
+	}
+}
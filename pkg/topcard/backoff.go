@@ -0,0 +1,73 @@
+package topcard
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// Espera exponencial com jitter entre tentativas de reconexão: Initial na primeira
+// tentativa, multiplicada por Multiplier a cada falha até Max. Jitter é a fração da
+// espera sorteada a cada tentativa (0 = sem jitter, 1 = de zero até a espera
+// inteira), para que muitos clientes derrubados juntos não voltem todos ao mesmo tempo.
+type Backoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	Jitter     float64
+}
+
+// Espera padrão: 0,5s, 1s, 2s... até 30s, com metade da espera sorteada
+var DefaultBackoff = Backoff{
+	Initial:    500 * time.Millisecond,
+	Max:        30 * time.Second,
+	Multiplier: 2,
+	Jitter:     0.5,
+}
+
+// Função para calcular a espera antes da tentativa attempt (a partir de 0)
+func (b Backoff) Delay(attempt int) time.Duration {
+	if b.Initial <= 0 {
+		b.Initial = DefaultBackoff.Initial
+	}
+	if b.Max < b.Initial {
+		b.Max = max(b.Initial, DefaultBackoff.Max)
+	}
+	if b.Multiplier < 1 {
+		b.Multiplier = DefaultBackoff.Multiplier
+	}
+
+	delay := float64(b.Initial)
+	for i := 0; i < attempt && delay < float64(b.Max); i++ {
+		delay *= b.Multiplier
+	}
+	delay = min(delay, float64(b.Max))
+
+	jitter := min(max(b.Jitter, 0), 1)
+	return time.Duration(delay * (1 - jitter*rand.Float64()))
+}
+
+// Função para conectar ao servidor tentando de novo com espera exponencial até
+// conseguir ou ctx terminar. onFailure (opcional) é chamada a cada falha com o
+// erro e a espera até a próxima tentativa.
+func Redial(ctx context.Context, addr string, options Options, backoff Backoff, onFailure func(attempt int, err error, delay time.Duration)) (*Client, error) {
+	for attempt := 0; ; attempt++ {
+		client, err := Dial(addr, options)
+		if err == nil {
+			return client, nil
+		}
+
+		delay := backoff.Delay(attempt)
+		if onFailure != nil {
+			onFailure(attempt, err, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
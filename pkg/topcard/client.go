@@ -0,0 +1,543 @@
+package topcard
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+	"top-card/internal/protocol"
+	"top-card/internal/tlsconfig"
+)
+
+// Valores padrão das opções
+const (
+	DefaultClientName     = "top-card-sdk"
+	DefaultClientVersion  = "1.0.0"
+	DefaultDialTimeout    = 10 * time.Second
+	DefaultRequestTimeout = 5 * time.Second
+	DefaultEventBuffer    = 1024
+)
+
+// Opções do cliente. O valor zero é válido: cliente JSON+binário sem TLS com os
+// prazos padrão.
+type Options struct {
+	ClientName          string        // Nome enviado no HELLO (padrão DefaultClientName)
+	ClientVersion       string        // Versão enviada no HELLO (padrão DefaultClientVersion)
+	TLS                 *tls.Config   // nil = TCP puro (ver NewTLSConfig)
+	DialTimeout         time.Duration // Prazo da conexão e do handshake TLS
+	RequestTimeout      time.Duration // Prazo das requisições cujo contexto não tem prazo, e do HELLO
+	DisableBinaryFrames bool          // Mantém a conexão em JSON (útil para inspecionar o tráfego)
+	EventBuffer         int           // Eventos não lidos de Events antes de desconectar (padrão DefaultEventBuffer)
+}
+
+// Capacidades que o servidor pode anunciar no WELCOME (ver Client.Supports)
+const (
+	CAP_REQUEST_ID    = "request_id"    // Respostas associadas às requisições pelo ID
+	CAP_ERROR_CODES   = "error_codes"   // Erros com Error.Code preenchido
+	CAP_BINARY_FRAMES = "binary_frames" // Frames binários depois do handshake
+	CAP_CHAT          = "chat"          // SendChat e ChatMessage
+	CAP_INVENTORY     = "inventory"     // RefreshInventory e InventoryUpdate
+	CAP_SESSION_STATE = "session_state" // SessionState enviado no login
+)
+
+// Informações do servidor recebidas no WELCOME
+type ServerInfo struct {
+	Name            string
+	Version         string
+	ProtocolVersion int      // Versão usada na conexão (1 = servidor sem HELLO)
+	Capabilities    []string // Capacidades ativas na conexão
+}
+
+// Situação da partida do jogador, atualizada pelas notificações do servidor
+type MatchState struct {
+	InMatch      bool
+	MatchID      int
+	OpponentID   int
+	OpponentName string
+	YourTurn     bool
+}
+
+// Cliente do servidor TOP CARD sobre uma conexão. Os métodos podem ser chamados de
+// várias goroutines e cada Client é independente, então um processo pode manter
+// quantos clientes quiser (ex.: bots e testes de carga). As notificações chegam em
+// Events, que deve ser lido até fechar (ou o cliente encerrado com Close).
+type Client struct {
+	conn    net.Conn
+	options Options
+	server  ServerInfo
+
+	writeMutex sync.Mutex
+	codec      protocol.Codec // Formato das mensagens enviadas (JSON até o WELCOME)
+
+	requestCounter uint64
+	pendingMutex   sync.Mutex
+	pending        []*pendingRequest // Em ordem de envio
+
+	events  *eventQueue
+	done    chan struct{} // Fechado quando a conexão termina
+	closed  chan struct{} // Fechado por Close
+	closing sync.Once
+	err     error // Motivo do fim da conexão (válido depois de done)
+
+	stateMutex sync.Mutex
+	userID     int
+	inventory  []Card // Último inventário enviado pelo servidor
+	match      MatchState
+	seedHash   string // Compromisso da semente do sorteio publicado pelo servidor
+}
+
+// Requisição aguardando resposta
+type pendingRequest struct {
+	requestID    string
+	responseType string
+	response     chan *protocol.Message
+}
+
+// Função para conectar ao servidor (com TLS se Options.TLS estiver definida) e fazer
+// o handshake HELLO/WELCOME
+func Dial(addr string, options Options) (*Client, error) {
+	if options.DialTimeout <= 0 {
+		options.DialTimeout = DefaultDialTimeout
+	}
+	conn, err := tlsconfig.Dial(addr, options.TLS, options.DialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	client, err := NewClient(conn, options)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// Função para criar um cliente sobre uma conexão já aberta e fazer o handshake
+// HELLO/WELCOME. Servidores que não respondem ao HELLO dentro de RequestTimeout
+// são tratados como versão 1. Em caso de erro a conexão não é fechada.
+func NewClient(conn net.Conn, options Options) (*Client, error) {
+	if options.ClientName == "" {
+		options.ClientName = DefaultClientName
+	}
+	if options.ClientVersion == "" {
+		options.ClientVersion = DefaultClientVersion
+	}
+	if options.RequestTimeout <= 0 {
+		options.RequestTimeout = DefaultRequestTimeout
+	}
+	if options.EventBuffer <= 0 {
+		options.EventBuffer = DefaultEventBuffer
+	}
+
+	c := &Client{
+		conn:    conn,
+		options: options,
+		server:  ServerInfo{ProtocolVersion: 1},
+		codec:   protocol.JSONCodec,
+		events:  newEventQueue(options.EventBuffer),
+		done:    make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+
+	reader := bufio.NewReader(conn)
+	frames, err := c.handshake(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	go c.events.run(c.closed)
+	go c.readLoop(frames)
+	return c, nil
+}
+
+// Capacidades anunciadas no HELLO
+func (c *Client) capabilities() []string {
+	capabilities := []string{protocol.CAP_REQUEST_ID, protocol.CAP_ERROR_CODES, protocol.CAP_CHAT, protocol.CAP_INVENTORY, protocol.CAP_SESSION_STATE}
+	if !c.options.DisableBinaryFrames {
+		capabilities = append(capabilities, protocol.CAP_BINARY_FRAMES)
+	}
+	return capabilities
+}
+
+// Envia o HELLO e lê as mensagens até o WELCOME, antes de iniciar a leitura em
+// segundo plano: assim a troca de codec acontece exatamente depois do WELCOME.
+// Retorna o leitor de frames no formato negociado.
+func (c *Client) handshake(reader *bufio.Reader) (protocol.FrameReader, error) {
+	frames := protocol.JSONCodec.NewFrameReader(reader, protocol.MAX_FRAME_SIZE)
+
+	hello, err := protocol.CreateHello(c.options.ClientName, c.options.ClientVersion, c.capabilities())
+	if err != nil {
+		return nil, err
+	}
+	if hello, err = protocol.WithRequestID(hello, c.nextRequestID()); err != nil {
+		return nil, err
+	}
+	if err := c.write(hello); err != nil {
+		return nil, err
+	}
+
+	c.conn.SetReadDeadline(time.Now().Add(c.options.RequestTimeout))
+	defer c.conn.SetReadDeadline(time.Time{})
+
+	for {
+		message, err := frames.ReadMessage()
+		if protocol.IsFrameError(err) {
+			continue
+		}
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return frames, nil // Servidor versão 1: ignora o HELLO
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch message.Type {
+		case protocol.MSG_ERROR:
+			return frames, nil // Servidor versão 1: HELLO é um tipo desconhecido
+		case protocol.MSG_WELCOME:
+		default:
+			continue
+		}
+
+		welcome, err := protocol.ExtractWelcome(message)
+		if err != nil {
+			return nil, err
+		}
+		if !welcome.Accepted {
+			return nil, fmt.Errorf("%w: %s", ErrVersionRefused, welcome.Message)
+		}
+
+		c.server = ServerInfo{
+			Name:            welcome.ServerName,
+			Version:         welcome.ServerVersion,
+			ProtocolVersion: min(protocol.PROTOCOL_VERSION, welcome.ProtocolVersion),
+			Capabilities:    welcome.Capabilities,
+		}
+		// A partir do WELCOME as mensagens seguem no formato negociado
+		for _, capability := range welcome.Capabilities {
+			if codec, ok := protocol.CodecByName(capability); ok {
+				c.codec = codec
+				frames = codec.NewFrameReader(reader, protocol.MAX_FRAME_SIZE)
+			}
+		}
+		return frames, nil
+	}
+}
+
+// Lê as mensagens do servidor até a conexão terminar, entregando respostas a quem
+// as espera e notificações em Events
+func (c *Client) readLoop(frames protocol.FrameReader) {
+	var readErr error
+	for {
+		message, err := frames.ReadMessage()
+		if protocol.IsFrameError(err) {
+			continue
+		}
+		if err != nil {
+			if err != io.EOF {
+				readErr = err
+			}
+			break
+		}
+		if err := c.dispatch(message); err != nil {
+			readErr = err
+			break
+		}
+	}
+
+	c.conn.Close()
+	select {
+	case <-c.closed:
+		readErr = nil // Encerrada por Close
+	default:
+		if readErr == nil {
+			readErr = ErrClosed
+		}
+	}
+	c.err = readErr
+	close(c.done)
+	c.events.close(Disconnected{Err: readErr})
+}
+
+// Encaminha uma mensagem recebida. Retorna ErrEventsOverflow se a fila de eventos
+// estiver cheia.
+func (c *Client) dispatch(message *protocol.Message) error {
+	// O inventário é atualizado na ordem em que o servidor o enviou, seja resposta
+	// a RefreshInventory ou aviso de mudança
+	if message.Type == protocol.MSG_INVENTORY_UPDATE {
+		c.applyInventory(message)
+	}
+
+	if message.Type == protocol.MSG_ERROR {
+		// ERROR com request_id é resposta; sem a capacidade request_id, um ERROR só
+		// pode ser resposta à requisição mais antiga
+		if (message.RequestID != "" || !c.Supports(protocol.CAP_REQUEST_ID)) && c.deliver(message, true) {
+			return nil
+		}
+	} else if c.deliver(message, false) {
+		return nil
+	}
+
+	event, err := decodeEvent(message)
+	if err != nil || event == nil {
+		return nil // Mensagem inválida ou resposta que ninguém espera mais (ex.: depois do timeout)
+	}
+	if !c.events.push(c.applyEvent(event)) {
+		return ErrEventsOverflow
+	}
+	return nil
+}
+
+// Entrega uma resposta à requisição com o mesmo request_id ou, sem request_id
+// (servidores antigos), à requisição mais antiga que espera esse tipo de resposta.
+// anyType aceita a requisição mais antiga de qualquer tipo (ERROR).
+func (c *Client) deliver(message *protocol.Message, anyType bool) bool {
+	// Com request_id negociado, mensagens sem ele são avisos (ex.: INVENTORY_UPDATE),
+	// mesmo que o tipo seja o de uma resposta esperada
+	if message.RequestID == "" && c.Supports(protocol.CAP_REQUEST_ID) {
+		return false
+	}
+
+	c.pendingMutex.Lock()
+	defer c.pendingMutex.Unlock()
+
+	index := slices.IndexFunc(c.pending, func(request *pendingRequest) bool {
+		if message.RequestID != "" {
+			return request.requestID == message.RequestID
+		}
+		return anyType || request.responseType == message.Type
+	})
+	if index < 0 {
+		return false
+	}
+	request := c.pending[index]
+	c.pending = slices.Delete(c.pending, index, index+1)
+	request.response <- message
+	return true
+}
+
+// Função para gerar o request_id da próxima requisição
+func (c *Client) nextRequestID() string {
+	return fmt.Sprintf("sdk-%d", atomic.AddUint64(&c.requestCounter, 1))
+}
+
+// Envia uma requisição e aguarda a resposta do tipo indicado. Uma mensagem ERROR em
+// resposta vira *Error.
+func (c *Client) request(ctx context.Context, data []byte, responseType string) (*protocol.Message, error) {
+	request := &pendingRequest{requestID: c.nextRequestID(), responseType: responseType, response: make(chan *protocol.Message, 1)}
+	message, err := protocol.DecodeMessage(data)
+	if err != nil {
+		return nil, err
+	}
+	message.RequestID = request.requestID
+
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.options.RequestTimeout)
+		defer cancel()
+	}
+
+	c.pendingMutex.Lock()
+	c.pending = append(c.pending, request)
+	c.pendingMutex.Unlock()
+	defer c.forget(request)
+
+	if err := c.writeMessage(message); err != nil {
+		return nil, err
+	}
+
+	select {
+	case message := <-request.response:
+		if message.Type == protocol.MSG_ERROR {
+			serverErr, err := decodeError(message)
+			if err != nil {
+				return nil, err
+			}
+			return nil, serverErr
+		}
+		if message.Type != responseType {
+			return nil, fmt.Errorf("%w: %s", ErrUnexpectedReply, message.Type)
+		}
+		return message, nil
+	case <-c.done:
+		return nil, c.Err()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Remove uma requisição da espera (resposta recebida, erro ou prazo esgotado)
+func (c *Client) forget(request *pendingRequest) {
+	c.pendingMutex.Lock()
+	c.pending = slices.DeleteFunc(c.pending, func(pending *pendingRequest) bool { return pending == request })
+	c.pendingMutex.Unlock()
+}
+
+// Envia uma mensagem no formato negociado com o servidor
+func (c *Client) write(data []byte) error {
+	return c.writeFrame(func(codec protocol.Codec) ([]byte, error) { return codec.Frame(data) })
+}
+
+// Envia uma mensagem já decodificada, sem passar o envelope por JSON de novo
+func (c *Client) writeMessage(message *protocol.Message) error {
+	return c.writeFrame(func(codec protocol.Codec) ([]byte, error) { return codec.FrameMessage(message) })
+}
+
+// Monta o frame com o codec atual e o envia
+func (c *Client) writeFrame(frameOf func(protocol.Codec) ([]byte, error)) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	select {
+	case <-c.done:
+		return c.Err()
+	default:
+	}
+
+	frame, err := frameOf(c.codec)
+	if err != nil {
+		return err
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(c.options.RequestTimeout))
+	_, err = c.conn.Write(frame)
+	return err
+}
+
+// Função para encerrar a conexão. Events recebe Disconnected com Err nil (se ainda
+// estiver sendo lido) e é fechado; eventos ainda não lidos são descartados.
+func (c *Client) Close() error {
+	var err error
+	c.closing.Do(func() {
+		close(c.closed)
+		err = c.conn.Close()
+	})
+	<-c.done
+	return err
+}
+
+// Canal fechado quando a conexão termina
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Motivo do fim da conexão: nil enquanto conectado ou depois de Close
+func (c *Client) Err() error {
+	select {
+	case <-c.done:
+		if c.err == nil {
+			return ErrClosed
+		}
+		return c.err
+	default:
+		return nil
+	}
+}
+
+// Canal de notificações do servidor. Fecha depois do evento Disconnected. Com mais de
+// Options.EventBuffer eventos não lidos o cliente desconecta com ErrEventsOverflow.
+func (c *Client) Events() <-chan Event {
+	return c.events.out
+}
+
+// Informações do servidor recebidas no handshake
+func (c *Client) Server() ServerInfo {
+	return c.server
+}
+
+// Função para verificar se uma capacidade está ativa na conexão
+func (c *Client) Supports(capability string) bool {
+	return slices.Contains(c.server.Capabilities, capability)
+}
+
+// Endereço do servidor
+func (c *Client) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// ID do usuário logado (0 antes do login)
+func (c *Client) UserID() int {
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+	return c.userID
+}
+
+// Cópia do inventário do jogador como o servidor o enviou por último (nil antes do
+// login ou com servidores sem a capacidade inventory)
+func (c *Client) Inventory() []Card {
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+	return slices.Clone(c.inventory)
+}
+
+// Hash da semente do sorteio publicado pelo servidor antes dos pacotes (no login e a
+// cada consulta ou rotação das sementes); vazio antes do login
+func (c *Client) SeedHash() string {
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+	return c.seedHash
+}
+
+// Situação atual da partida
+func (c *Client) Match() MatchState {
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+	return c.match
+}
+
+// Atualiza o estado local a partir de uma notificação, antes de ela ser entregue.
+// Retorna o evento completado com o estado local (ex.: MatchEnd.Won).
+func (c *Client) applyEvent(event Event) Event {
+	c.stateMutex.Lock()
+	defer c.stateMutex.Unlock()
+
+	switch e := event.(type) {
+	case MatchFound:
+		c.match = MatchState{InMatch: true, MatchID: e.MatchID, OpponentID: e.OpponentID, OpponentName: e.OpponentName}
+	case MatchStart:
+		c.match.InMatch = true
+		c.match.MatchID = e.MatchID
+		c.match.YourTurn = false // Atualizado pelo GAME_STATE
+	case GameState:
+		c.match.YourTurn = e.YourTurn && !e.GameOver
+	case TurnUpdate:
+		c.match.YourTurn = e.YourTurn
+	case MatchEnd:
+		e.Won = e.WinnerID != 0 && e.WinnerID == c.userID
+		event = e
+		c.match = MatchState{}
+	case SessionState:
+		c.inventory = slices.Clone(e.Inventory)
+		c.seedHash = e.ServerSeedHash
+		c.match = MatchState{}
+		if e.Match != nil {
+			c.match = MatchState{InMatch: true, MatchID: e.Match.MatchID, OpponentID: e.Match.OpponentID, OpponentName: e.Match.OpponentName, YourTurn: e.Match.YourTurn}
+		}
+	case MoveRejected:
+		switch e.Err.Code {
+		case protocol.ERR_NOT_YOUR_TURN, protocol.ERR_ALREADY_PLAYED:
+			c.match.YourTurn = false
+		case protocol.ERR_MATCH_NOT_FOUND, protocol.ERR_MATCH_NOT_IN_PROGRESS, protocol.ERR_NOT_IN_MATCH:
+		default:
+			c.match.YourTurn = true
+		}
+	}
+	return event
+}
+
+// Substitui o inventário pelo enviado num INVENTORY_UPDATE bem-sucedido
+func (c *Client) applyInventory(message *protocol.Message) {
+	update, err := protocol.ExtractInventoryUpdate(message)
+	if err != nil || !update.Success {
+		return
+	}
+	c.stateMutex.Lock()
+	c.inventory = cardsFrom(update.Cards)
+	c.stateMutex.Unlock()
+}
@@ -0,0 +1,103 @@
+package topcard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Erros do próprio cliente (sem resposta do servidor)
+var (
+	ErrClosed          = errors.New("conexão com o servidor encerrada")
+	ErrVersionRefused  = errors.New("versão do cliente recusada pelo servidor")
+	ErrNotLoggedIn     = errors.New("é preciso fazer login primeiro")
+	ErrNotInMatch      = errors.New("nenhuma partida em andamento")
+	ErrUnexpectedReply = errors.New("resposta inesperada do servidor")
+	ErrUnsupported     = errors.New("recurso não suportado pelo servidor")
+	ErrEventsOverflow  = errors.New("eventos acumulados sem leitura em Events")
+)
+
+// Erro devolvido pelo servidor: uma resposta com success=false, uma mensagem ERROR
+// ou uma jogada recusada. Code é o código estável do protocolo (vazio em servidores
+// sem a capacidade error_codes); Message é o texto em português e pode mudar.
+type Error struct {
+	Code       string
+	Message    string
+	RetryAfter time.Duration // RATE_LIMITED, LOGIN_BACKOFF e ACCOUNT_LOCKED: espera sugerida
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s [%s]", e.Message, e.Code)
+}
+
+// Codifica o erro em JSON com os nomes do protocolo (espera em milissegundos)
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Code         string `json:"code,omitempty"`
+		Message      string `json:"message"`
+		RetryAfterMs int64  `json:"retry_after_ms,omitempty"`
+	}{e.Code, e.Message, e.RetryAfter.Milliseconds()})
+}
+
+// Função para obter o código de erro do servidor de um erro retornado pelo cliente
+// (vazio se o erro não veio do servidor)
+func ErrorCode(err error) string {
+	var serverErr *Error
+	if errors.As(err, &serverErr) {
+		return serverErr.Code
+	}
+	return ""
+}
+
+// Códigos de erro do servidor (ver Error.Code e ErrorCode)
+const (
+	// Erros de protocolo (ServerError)
+	ERR_INVALID_MESSAGE      = "INVALID_MESSAGE"
+	ERR_UNKNOWN_MESSAGE_TYPE = "UNKNOWN_MESSAGE_TYPE"
+	ERR_INTERNAL             = "INTERNAL_ERROR"
+	ERR_FRAME_TOO_LARGE      = "FRAME_TOO_LARGE"
+	ERR_TOO_MANY_VIOLATIONS  = "TOO_MANY_VIOLATIONS"
+	ERR_RATE_LIMITED         = "RATE_LIMITED"
+
+	// Handshake
+	ERR_UNSUPPORTED_VERSION    = "UNSUPPORTED_VERSION"
+	ERR_HANDSHAKE_OUT_OF_ORDER = "HANDSHAKE_OUT_OF_ORDER"
+
+	// Cadastro e login
+	ERR_USERNAME_TAKEN      = "USERNAME_TAKEN"
+	ERR_USERNAME_TOO_SHORT  = "USERNAME_TOO_SHORT"
+	ERR_PASSWORD_TOO_SHORT  = "PASSWORD_TOO_SHORT"
+	ERR_INVALID_CREDENTIALS = "INVALID_CREDENTIALS"
+	ERR_ALREADY_CONNECTED   = "ALREADY_CONNECTED"
+	ERR_NOT_LOGGED_IN       = "NOT_LOGGED_IN"
+	ERR_USER_NOT_FOUND      = "USER_NOT_FOUND"
+	ERR_LOGIN_BACKOFF       = "LOGIN_BACKOFF"
+	ERR_ACCOUNT_LOCKED      = "ACCOUNT_LOCKED"
+
+	// Fila
+	ERR_ALREADY_IN_QUEUE     = "ALREADY_IN_QUEUE"
+	ERR_ALREADY_IN_MATCH     = "ALREADY_IN_MATCH"
+	ERR_NO_CARDS             = "NO_CARDS"
+	ERR_SERVER_SHUTTING_DOWN = "SERVER_SHUTTING_DOWN"
+
+	// Pacotes
+	ERR_INVALID_PACK_TYPE   = "INVALID_PACK_TYPE"
+	ERR_OUT_OF_STOCK        = "OUT_OF_STOCK"
+	ERR_INVENTORY_NOT_EMPTY = "INVENTORY_NOT_EMPTY"
+
+	// Partida
+	ERR_MATCH_NOT_FOUND       = "MATCH_NOT_FOUND"
+	ERR_MATCH_NOT_IN_PROGRESS = "MATCH_NOT_IN_PROGRESS"
+	ERR_NOT_IN_MATCH          = "NOT_IN_MATCH"
+	ERR_NOT_YOUR_TURN         = "NOT_YOUR_TURN"
+	ERR_ALREADY_PLAYED        = "ALREADY_PLAYED"
+	ERR_INVALID_CARD          = "INVALID_CARD"
+	ERR_CARD_NOT_OWNED        = "CARD_NOT_OWNED"
+
+	// Chat
+	ERR_INVALID_CHAT = "INVALID_CHAT"
+)
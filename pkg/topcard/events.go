@@ -0,0 +1,307 @@
+package topcard
+
+import (
+	"sync"
+	"time"
+	"top-card/internal/protocol"
+)
+
+// Notificação enviada pelo servidor sem ter sido pedida (partida, turno, aviso de
+// encerramento) ou fim da conexão. Os tipos concretos são os abaixo; use um type
+// switch para tratá-los. Os campos têm nomes JSON iguais aos do protocolo, para
+// registrar os eventos com encoding/json.
+type Event interface {
+	isEvent()
+}
+
+// Oponente encontrado na fila
+type MatchFound struct {
+	MatchID      int    `json:"match_id"`
+	OpponentID   int    `json:"opponent_id"`
+	OpponentName string `json:"opponent_name"`
+	Message      string `json:"message"`
+}
+
+// Partida iniciada
+type MatchStart struct {
+	MatchID int    `json:"match_id"`
+	Message string `json:"message"`
+}
+
+// Estado da partida depois de uma jogada
+type GameState struct {
+	MatchID       int    `json:"match_id"`
+	Message       string `json:"message"`
+	YourTurn      bool   `json:"your_turn"`
+	OpponentMoved bool   `json:"opponent_moved"`
+	GameOver      bool   `json:"game_over"`
+}
+
+// Mudança de turno
+type TurnUpdate struct {
+	MatchID  int    `json:"match_id"`
+	Message  string `json:"message"`
+	YourTurn bool   `json:"your_turn"`
+}
+
+// Fim da partida. WinnerID é 0 quando a partida foi interrompida sem vencedor;
+// Won indica se o vencedor é o usuário logado neste cliente.
+type MatchEnd struct {
+	MatchID    int    `json:"match_id"`
+	WinnerID   int    `json:"winner_id"`
+	WinnerName string `json:"winner_name"`
+	Message    string `json:"message"`
+	Won        bool   `json:"won"`
+}
+
+// Jogada recusada pelo servidor. A carta continua no inventário e, salvo
+// NOT_YOUR_TURN e ALREADY_PLAYED, o turno continua sendo do jogador.
+type MoveRejected struct {
+	MatchID  int    `json:"match_id"`
+	CardType string `json:"card_type"`
+	Err      *Error `json:"error"`
+}
+
+// Inventário do jogador depois de uma mudança no servidor (pacote aberto, carta
+// jogada ou devolvida). Inventory já reflete Cards quando o evento é entregue.
+type InventoryUpdate struct {
+	Cards  []Card `json:"cards"`
+	Reason string `json:"reason"` // Um dos INVENTORY_*
+}
+
+// Motivos de InventoryUpdate
+const (
+	INVENTORY_REQUESTED      = "request"        // Resposta a RefreshInventory
+	INVENTORY_PACK_OPENED    = "pack_opened"    // Cartas de um pacote entraram no inventário
+	INVENTORY_CARD_PLAYED    = "card_played"    // Uma carta saiu do inventário numa jogada
+	INVENTORY_CARDS_RETURNED = "cards_returned" // Cartas jogadas voltaram (partida interrompida)
+)
+
+// Estado completo do jogador enviado pelo servidor no login (também no login refeito
+// depois de uma queda). Inventory e Match já refletem o estado quando o evento é
+// entregue; Match é nil fora de partida.
+type SessionState struct {
+	UserID    int            `json:"user_id"`
+	Inventory []Card         `json:"inventory"`
+	Stats     Stats          `json:"stats"`
+	Coins     int            `json:"coins"`
+	InQueue   bool           `json:"in_queue"`
+	QueueSize int            `json:"queue_size,omitempty"`
+	Match     *MatchSnapshot `json:"match,omitempty"`
+
+	ServerSeedHash string `json:"server_seed_hash"` // Compromisso da semente do sorteio (ver Client.SeedHash)
+}
+
+// Mensagem de chat do oponente na partida atual
+type ChatMessage struct {
+	MatchID  int    `json:"match_id"`
+	FromID   int    `json:"from_id"`
+	FromName string `json:"from_name"`
+	Text     string `json:"text"`
+}
+
+// Aviso de que o servidor está encerrando
+type ServerShutdown struct {
+	Message  string    `json:"message"`
+	Deadline time.Time `json:"deadline,omitempty"` // Prazo para as partidas em andamento terminarem (zero = sem prazo)
+}
+
+// Erro enviado pelo servidor fora de uma requisição (ex.: mensagem grande demais)
+type ServerError struct {
+	Err *Error `json:"error"`
+}
+
+// Fim da conexão; é sempre o último evento antes de Events ser fechado. Err é nil
+// quando a conexão foi encerrada por Close.
+type Disconnected struct {
+	Err error `json:"-"`
+}
+
+func (MatchFound) isEvent()      {}
+func (MatchStart) isEvent()      {}
+func (GameState) isEvent()       {}
+func (TurnUpdate) isEvent()      {}
+func (MatchEnd) isEvent()        {}
+func (MoveRejected) isEvent()    {}
+func (InventoryUpdate) isEvent() {}
+func (SessionState) isEvent()    {}
+func (ChatMessage) isEvent()     {}
+func (ServerShutdown) isEvent()  {}
+func (ServerError) isEvent()     {}
+func (Disconnected) isEvent()    {}
+
+// Fila de eventos entre a leitura da conexão e o canal de Events: a leitura nunca
+// bloqueia esperando a aplicação consumir os eventos. A fila guarda até limit
+// eventos; cheia, a aplicação é considerada parada e o cliente desconecta com
+// ErrEventsOverflow, como o servidor faz com clientes lentos. Descartar eventos
+// deixaria a partida do cliente diferente da do servidor.
+type eventQueue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending []Event
+	limit   int
+	sending bool // Um evento já saiu de pending e aguarda a leitura em out
+	closed  bool
+	out     chan Event
+}
+
+func newEventQueue(limit int) *eventQueue {
+	q := &eventQueue{out: make(chan Event), limit: limit}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// Função para adicionar um evento ao fim da fila. Retorna false se a fila estiver
+// cheia (o evento é descartado).
+func (q *eventQueue) push(event Event) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queued := len(q.pending)
+	if q.sending {
+		queued++
+	}
+	if queued >= q.limit {
+		return false
+	}
+	if !q.closed {
+		q.pending = append(q.pending, event)
+		q.cond.Signal()
+	}
+	return true
+}
+
+// Função para encerrar a fila depois de um último evento, aceito mesmo com a fila
+// cheia para a aplicação saber o motivo
+func (q *eventQueue) close(last Event) {
+	q.mu.Lock()
+	if !q.closed {
+		q.pending = append(q.pending, last)
+		q.closed = true
+		q.cond.Signal()
+	}
+	q.mu.Unlock()
+}
+
+// Entrega os eventos em ordem no canal de saída e o fecha depois do último. Se
+// abandon for fechado, os eventos restantes são descartados.
+func (q *eventQueue) run(abandon <-chan struct{}) {
+	defer close(q.out)
+	for {
+		q.mu.Lock()
+		for len(q.pending) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if len(q.pending) == 0 {
+			q.mu.Unlock()
+			return
+		}
+		event := q.pending[0]
+		q.pending[0] = nil
+		q.pending = q.pending[1:]
+		q.sending = true
+		q.mu.Unlock()
+
+		select {
+		case q.out <- event:
+		case <-abandon:
+			return
+		}
+		q.mu.Lock()
+		q.sending = false
+		q.mu.Unlock()
+	}
+}
+
+// Função para converter uma notificação do servidor em evento (nil se o tipo não é
+// uma notificação)
+func decodeEvent(message *protocol.Message) (Event, error) {
+	switch message.Type {
+	case protocol.MSG_MATCH_FOUND:
+		found, err := protocol.ExtractMatchFound(message)
+		if err != nil {
+			return nil, err
+		}
+		return MatchFound{MatchID: found.MatchID, OpponentID: found.OpponentID, OpponentName: found.OpponentName, Message: found.Message}, nil
+	case protocol.MSG_MATCH_START:
+		start, err := protocol.ExtractMatchStart(message)
+		if err != nil {
+			return nil, err
+		}
+		return MatchStart{MatchID: start.MatchID, Message: start.Message}, nil
+	case protocol.MSG_GAME_STATE:
+		state, err := protocol.ExtractGameState(message)
+		if err != nil {
+			return nil, err
+		}
+		return GameState{MatchID: state.MatchID, Message: state.Message, YourTurn: state.YourTurn, OpponentMoved: state.OpponentMoved, GameOver: state.GameOver}, nil
+	case protocol.MSG_TURN_UPDATE:
+		update, err := protocol.ExtractTurnUpdate(message)
+		if err != nil {
+			return nil, err
+		}
+		return TurnUpdate{MatchID: update.MatchID, Message: update.Message, YourTurn: update.YourTurn}, nil
+	case protocol.MSG_MATCH_END:
+		end, err := protocol.ExtractMatchEnd(message)
+		if err != nil {
+			return nil, err
+		}
+		return MatchEnd{MatchID: end.MatchID, WinnerID: end.WinnerID, WinnerName: end.WinnerName, Message: end.Message}, nil
+	case protocol.MSG_MOVE_REJECTED:
+		rejected, err := protocol.ExtractMoveRejected(message)
+		if err != nil {
+			return nil, err
+		}
+		return MoveRejected{MatchID: rejected.MatchID, CardType: rejected.CardType, Err: &Error{Code: message.ErrorCode, Message: rejected.Message}}, nil
+	case protocol.MSG_INVENTORY_UPDATE:
+		update, err := protocol.ExtractInventoryUpdate(message)
+		if err != nil || !update.Success {
+			return nil, err
+		}
+		return InventoryUpdate{Cards: cardsFrom(update.Cards), Reason: update.Reason}, nil
+	case protocol.MSG_SESSION_STATE:
+		state, err := protocol.ExtractSessionState(message)
+		if err != nil {
+			return nil, err
+		}
+		return SessionState{
+			UserID:    state.UserID,
+			Inventory: cardsFrom(state.Inventory),
+			Stats:     Stats{UserName: state.UserName, Wins: state.Wins, Losses: state.Losses, WinRate: state.WinRate},
+			Coins:     state.Coins,
+			InQueue:   state.InQueue,
+			QueueSize: state.QueueSize,
+			Match:     (*MatchSnapshot)(state.Match),
+
+			ServerSeedHash: state.ServerSeedHash,
+		}, nil
+	case protocol.MSG_CHAT_MESSAGE:
+		chat, err := protocol.ExtractChatMessage(message)
+		if err != nil {
+			return nil, err
+		}
+		return ChatMessage{MatchID: chat.MatchID, FromID: chat.FromID, FromName: chat.FromName, Text: chat.Text}, nil
+	case protocol.MSG_SERVER_SHUTDOWN:
+		shutdown, err := protocol.ExtractServerShutdown(message)
+		if err != nil {
+			return nil, err
+		}
+		return ServerShutdown{Message: shutdown.Message, Deadline: shutdown.Deadline}, nil
+	case protocol.MSG_ERROR:
+		serverErr, err := decodeError(message)
+		if err != nil {
+			return nil, err
+		}
+		return ServerError{Err: serverErr}, nil
+	}
+	return nil, nil
+}
+
+// Função para converter uma mensagem ERROR em *Error
+func decodeError(message *protocol.Message) (*Error, error) {
+	info, err := protocol.ExtractError(message)
+	if err != nil {
+		return nil, err
+	}
+	return &Error{Code: message.ErrorCode, Message: info.Message, RetryAfter: time.Duration(info.RetryAfterMs) * time.Millisecond}, nil
+}
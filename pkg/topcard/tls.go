@@ -0,0 +1,40 @@
+package topcard
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"top-card/internal/tlsconfig"
+)
+
+// Certificado do servidor diferente do fixado em TLSOptions.Pin
+var ErrPinMismatch = tlsconfig.ErrPinMismatch
+
+// Opções de TLS do cliente. Sem CAFile nem Pin o certificado é verificado com as
+// autoridades do sistema.
+type TLSOptions struct {
+	CAFile     string // Autoridades aceitas, em PEM (ex.: o próprio certificado autoassinado)
+	Pin        string // SHA-256 do certificado do servidor em hexadecimal, com ou sem ':' (ver Fingerprint)
+	ServerName string // Nome esperado no certificado (padrão: host do endereço)
+}
+
+// Função para criar a configuração TLS do cliente (Options.TLS)
+func NewTLSConfig(options TLSOptions) (*tls.Config, error) {
+	return tlsconfig.NewClient(tlsconfig.ClientOptions{
+		CAFile:     options.CAFile,
+		Pin:        options.Pin,
+		ServerName: options.ServerName,
+	})
+}
+
+// Função para ler a configuração TLS do cliente das variáveis de ambiente TLS,
+// TLS_CA_FILE, TLS_PIN e TLS_SERVER_NAME (as mesmas do cliente de terminal). Retorna
+// nil (TCP sem criptografia) se nenhuma estiver definida.
+func TLSConfigFromEnv() (*tls.Config, error) {
+	return tlsconfig.ClientFromEnv()
+}
+
+// Função para calcular a impressão digital de um certificado, no formato de
+// TLSOptions.Pin (SHA-256 do DER em hexadecimal)
+func Fingerprint(certificate *x509.Certificate) string {
+	return tlsconfig.Fingerprint(certificate)
+}
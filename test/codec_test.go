@@ -0,0 +1,376 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+	"top-card/internal/card"
+	"top-card/internal/protocol"
+)
+
+// Mensagem de exemplo usada nos testes e benchmarks dos codecs
+type codecFixture struct {
+	name string
+	data []byte
+}
+
+// Uma mensagem de cada tipo registrado, com request_id e error_code em algumas
+func codecFixtures(tb testing.TB) []codecFixture {
+	tb.Helper()
+
+	cards := []protocol.CardInfo{{Type: card.HYDRA, Rarity: card.RARO}, {Type: card.GORGONA, Rarity: card.EPICO}, {Type: card.QUIMERA, Rarity: card.COMUM}}
+	stock := protocol.StockInfo{HydraCount: 9998, QuimeraCount: 6999, GorgonaCount: 2999, TotalCards: 19996}
+	deadline := time.Date(2025, 3, 14, 15, 9, 26, 0, time.UTC)
+
+	build := []struct {
+		name  string
+		build func() ([]byte, error)
+	}{
+		{protocol.MSG_LOGIN_REQUEST, func() ([]byte, error) { return protocol.CreateLoginRequest("alice", "senha \"secreta\"\n") }},
+		{protocol.MSG_LOGIN_RESPONSE, func() ([]byte, error) {
+			return protocol.CreateLoginResponse(true, "Login realizado com sucesso", 42, "9f2c")
+		}},
+		{protocol.MSG_PING_REQUEST, func() ([]byte, error) { return protocol.CreatePingRequest(42) }},
+		{protocol.MSG_PING_RESPONSE, func() ([]byte, error) { return protocol.CreatePingResponse(true, "pong") }},
+		{protocol.MSG_REGISTER_REQUEST, func() ([]byte, error) { return protocol.CreateRegisterRequest("joão", "senha123") }},
+		{protocol.MSG_REGISTER_RESPONSE, func() ([]byte, error) { return protocol.CreateRegisterResponse(false, "Usuário já existe", 0) }},
+		{protocol.MSG_QUEUE_REQUEST, func() ([]byte, error) { return protocol.CreateQueueRequest(42) }},
+		{protocol.MSG_QUEUE_RESPONSE, func() ([]byte, error) { return protocol.CreateQueueResponse(true, "Na fila", 3) }},
+		{protocol.MSG_MATCH_FOUND, func() ([]byte, error) { return protocol.CreateMatchFound(7, 43, "bruno", "Partida encontrada!") }},
+		{protocol.MSG_MATCH_START, func() ([]byte, error) { return protocol.CreateMatchStart(7, "A partida começou!") }},
+		{protocol.MSG_MATCH_END, func() ([]byte, error) { return protocol.CreateMatchEnd(7, 0, "", "Partida interrompida") }},
+		{protocol.MSG_GAME_MOVE, func() ([]byte, error) {
+			return protocol.Encode(protocol.MSG_GAME_MOVE, protocol.GameMove{UserID: 42, MatchID: 7, CardType: card.HYDRA})
+		}},
+		{protocol.MSG_GAME_STATE, func() ([]byte, error) { return protocol.CreateGameState(7, "Sua vez!", true, false, false) }},
+		{protocol.MSG_TURN_UPDATE, func() ([]byte, error) { return protocol.CreateTurnUpdate(7, "Aguardando oponente", false) }},
+		{protocol.MSG_STATS_REQUEST, func() ([]byte, error) { return protocol.CreateStatsRequest(42) }},
+		{protocol.MSG_STATS_RESPONSE, func() ([]byte, error) {
+			return protocol.CreateStatsResponse(true, "Estatísticas", "alice", 12, 5, 70.58823529411765)
+		}},
+		{protocol.MSG_CARD_PACK_REQUEST, func() ([]byte, error) {
+			return protocol.CreateCardPackRequest(42, card.PACK_PREMIUM, "semente-do-cliente")
+		}},
+		{protocol.MSG_CARD_PACK_RESPONSE, func() ([]byte, error) {
+			return protocol.CreateCardPackResponse(true, "Pacote aberto com sucesso!", card.PACK_STANDARD, cards, stock,
+				protocol.PityInfo{PackType: card.PACK_STANDARD, Threshold: 10, PacksUntilEpic: 10},
+				protocol.FairnessInfo{ServerSeedHash: "ab12cd34", ClientSeed: "semente", Nonce: 3, StockBefore: stock})
+		}},
+		{protocol.MSG_CARD_MOVE, func() ([]byte, error) { return protocol.CreateCardMove(42, 7, card.GORGONA) }},
+		{protocol.MSG_SEED_REQUEST, func() ([]byte, error) { return protocol.CreateSeedRequest(42, "nova-semente", true) }},
+		{protocol.MSG_SEED_RESPONSE, func() ([]byte, error) {
+			return protocol.CreateSeedResponse(true, "Semente rotacionada", "ffee", "nova-semente", 0,
+				&protocol.RevealedSeed{ServerSeed: "0011", ServerSeedHash: "aabb", ClientSeed: "semente", Nonce: 1 << 40})
+		}},
+		{protocol.MSG_SERVER_SHUTDOWN, func() ([]byte, error) { return protocol.CreateServerShutdown("Servidor encerrando", deadline) }},
+		{protocol.MSG_ERROR, func() ([]byte, error) { return protocol.CreateError(protocol.ERR_INVALID_MESSAGE, "Mensagem inválida: \x01") }},
+		{protocol.MSG_MOVE_REJECTED, func() ([]byte, error) {
+			return protocol.CreateMoveRejected(7, card.HYDRA, protocol.ERR_NOT_YOUR_TURN, "Não é sua vez")
+		}},
+		{protocol.MSG_HELLO, func() ([]byte, error) {
+			return protocol.CreateHello("top-card-cli", "2.0.0", []string{protocol.CAP_REQUEST_ID, protocol.CAP_BINARY_FRAMES})
+		}},
+		{protocol.MSG_WELCOME, func() ([]byte, error) {
+			return protocol.CreateWelcome(true, "Bem-vindo", protocol.MIN_PROTOCOL_VERSION, "top-card-server", "2.0.0", []string{protocol.CAP_REQUEST_ID})
+		}},
+		{protocol.MSG_CHAT_MESSAGE, func() ([]byte, error) {
+			return protocol.CreateChatRelay(7, 1, "alice", "boa sorte! ✨")
+		}},
+		{protocol.MSG_INVENTORY_REQUEST, func() ([]byte, error) {
+			return protocol.CreateInventoryRequest(1)
+		}},
+		{protocol.MSG_INVENTORY_UPDATE, func() ([]byte, error) {
+			return protocol.CreateInventoryUpdate(true, "Inventário atualizado", protocol.INVENTORY_PACK_OPENED, []protocol.CardInfo{
+				{Type: card.HYDRA, Rarity: card.COMUM},
+				{Type: card.GORGONA, Rarity: card.EPICO},
+			})
+		}},
+		{protocol.MSG_SESSION_STATE, func() ([]byte, error) {
+			return protocol.CreateSessionState(protocol.SessionState{
+				UserID: 1, UserName: "alice", Wins: 3, Losses: 1, WinRate: 75, Coins: 30,
+				Inventory: []protocol.CardInfo{{Type: card.QUIMERA, Rarity: card.RARO}},
+				Match:     &protocol.MatchSnapshot{MatchID: 7, OpponentID: 2, OpponentName: "bruno", Status: "playing", PlayedCard: card.HYDRA},
+			})
+		}},
+	}
+
+	fixtures := make([]codecFixture, 0, len(build))
+	for i, fixture := range build {
+		data, err := fixture.build()
+		if err != nil {
+			tb.Fatalf("Erro ao criar %s: %v", fixture.name, err)
+		}
+		if i%2 == 0 {
+			if data, err = protocol.WithRequestID(data, "req-17"); err != nil {
+				tb.Fatalf("Erro ao definir request_id de %s: %v", fixture.name, err)
+			}
+		}
+		fixtures = append(fixtures, codecFixture{name: fixture.name, data: data})
+	}
+	return fixtures
+}
+
+var codecs = []protocol.Codec{protocol.JSONCodec, protocol.BinaryCodec}
+
+// Compara duas mensagens pelo envelope e pelo payload decodificado na struct registrada
+func sameMessage(t *testing.T, want, got *protocol.Message) {
+	t.Helper()
+
+	if want.Type != got.Type || want.RequestID != got.RequestID || want.ErrorCode != got.ErrorCode {
+		t.Fatalf("Envelope diferente: esperado %s/%q/%q, recebido %s/%q/%q",
+			want.Type, want.RequestID, want.ErrorCode, got.Type, got.RequestID, got.ErrorCode)
+	}
+	payloadType, _ := protocol.PayloadType(want.Type)
+	wantPayload := reflect.New(payloadType).Interface()
+	gotPayload := reflect.New(payloadType).Interface()
+	if err := json.Unmarshal(want.Data, wantPayload); err != nil {
+		t.Fatalf("Payload original inválido: %v", err)
+	}
+	if err := json.Unmarshal(got.Data, gotPayload); err != nil {
+		t.Fatalf("Payload decodificado inválido: %v (%s)", err, got.Data)
+	}
+	if !reflect.DeepEqual(wantPayload, gotPayload) {
+		t.Fatalf("Payload diferente:\nesperado %+v\nrecebido %+v", wantPayload, gotPayload)
+	}
+}
+
+// Teste de ida e volta: todas as mensagens passam pelos dois codecs sem perda,
+// inclusive escritas em sequência na mesma conexão
+func TestCodecRoundTrip(t *testing.T) {
+	fixtures := codecFixtures(t)
+
+	covered := make(map[string]bool)
+	for _, fixture := range fixtures {
+		covered[fixture.name] = true
+	}
+	for _, msgType := range protocol.MessageTypes() {
+		if !covered[msgType] {
+			t.Errorf("Tipo %s sem mensagem de exemplo nos testes de codec", msgType)
+		}
+	}
+
+	for _, codec := range codecs {
+		t.Run(codec.Name(), func(t *testing.T) {
+			var stream bytes.Buffer
+			for _, fixture := range fixtures {
+				frame, err := codec.Frame(fixture.data)
+				if err != nil {
+					t.Fatalf("Erro ao codificar %s: %v", fixture.name, err)
+				}
+				stream.Write(frame)
+			}
+
+			// A mesma sequência montada a partir das mensagens decodificadas
+			for _, fixture := range fixtures {
+				message, _ := protocol.DecodeMessage(fixture.data)
+				frame, err := codec.FrameMessage(message)
+				if err != nil {
+					t.Fatalf("Erro ao codificar a mensagem %s: %v", fixture.name, err)
+				}
+				stream.Write(frame)
+			}
+
+			frames := codec.NewFrameReader(bufio.NewReader(&stream), 0)
+			for _, fixture := range append(fixtures, fixtures...) {
+				got, err := frames.ReadMessage()
+				if err != nil {
+					t.Fatalf("Erro ao decodificar %s: %v", fixture.name, err)
+				}
+				want, _ := protocol.DecodeMessage(fixture.data)
+				sameMessage(t, want, got)
+			}
+			if _, err := frames.ReadMessage(); err != io.EOF {
+				t.Fatalf("Esperado io.EOF no fim do fluxo, recebido %v", err)
+			}
+		})
+	}
+}
+
+// Teste da conversão do envelope: Frame (a partir do JSON) e FrameMessage (a partir
+// da mensagem decodificada) geram os mesmos bytes, qualquer que seja a ordem das chaves
+func TestBinaryFrameEnvelope(t *testing.T) {
+	envelopes := []string{
+		`{"type":"PING_REQUEST","data":{"user_id":42},"request_id":"r1","error_code":"X"}`,
+		`{"error_code":"X","request_id":"r1","data":{"user_id":42},"type":"PING_REQUEST"}`,
+		` { "type" : "PING_REQUEST" , "extra" : [1, {"a": null}], "data" : { "user_id" : -7 } } `,
+		`{"type":"PING_REQUEST","data":{"user_id":1},"data":{"user_id":2}}`, // A última chave prevalece
+		`{"type":"PING_REQUEST","request_id":null}`,                         // Sem data
+		`{"type":"PING_\u0052EQUEST","request_id":"r\"2","data":{"user_id":3.5e1}}`,
+	}
+	for _, envelope := range envelopes {
+		message, err := protocol.DecodeMessage([]byte(envelope))
+		if err != nil {
+			t.Fatalf("Envelope %s inválido: %v", envelope, err)
+		}
+		want, err := protocol.BinaryCodec.FrameMessage(message)
+		if err != nil {
+			t.Fatalf("Erro ao codificar a mensagem de %s: %v", envelope, err)
+		}
+		got, err := protocol.BinaryCodec.Frame([]byte(envelope))
+		if err != nil || !bytes.Equal(got, want) {
+			t.Fatalf("Frame de %s diferente de FrameMessage: %v\n% x\n% x", envelope, err, got, want)
+		}
+	}
+
+	for _, envelope := range []string{`[]`, `{"type":1}`, `{"type":"PING_REQUEST","data":{`, `{"type":"PING_REQUEST"} {}`} {
+		if _, err := protocol.BinaryCodec.Frame([]byte(envelope)); !errors.Is(err, protocol.ErrInvalidMessage) {
+			t.Fatalf("Envelope %s deveria falhar com ErrInvalidMessage: %v", envelope, err)
+		}
+	}
+}
+
+// Teste de frames binários inválidos: o erro afeta só o frame, não a leitura seguinte
+func TestBinaryCodecMalformed(t *testing.T) {
+	valid, err := protocol.BinaryCodec.Frame(codecFixtures(t)[0].data)
+	if err != nil {
+		t.Fatalf("Erro ao codificar: %v", err)
+	}
+
+	malformed := [][]byte{
+		{0x00, 0x00, 0x00, 0x01, 0xc1},             // Marcador não suportado
+		{0x00, 0x00, 0x00, 0x02, 0x94, 0xa5},       // String truncada dentro do frame
+		{0x00, 0x00, 0x00, 0x03, 0x92, 0xa0, 0xa0}, // Envelope com 2 elementos
+	}
+	for _, frame := range malformed {
+		stream := append(append([]byte{}, frame...), valid...)
+		frames := protocol.BinaryCodec.NewFrameReader(bufio.NewReader(bytes.NewReader(stream)), 0)
+		if _, err := frames.ReadMessage(); !errors.Is(err, protocol.ErrInvalidMessage) {
+			t.Fatalf("Frame % x deveria falhar com ErrInvalidMessage: %v", frame, err)
+		}
+		if message, err := frames.ReadMessage(); err != nil || message.Type != protocol.MSG_LOGIN_REQUEST {
+			t.Fatalf("Frame seguinte deveria ser lido normalmente: %v", err)
+		}
+	}
+
+	// Frame cortado no meio é erro de conexão, não de mensagem
+	frames := protocol.BinaryCodec.NewFrameReader(bufio.NewReader(bytes.NewReader(valid[:len(valid)-1])), 0)
+	if _, err := frames.ReadMessage(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("Frame incompleto deveria falhar com io.ErrUnexpectedEOF: %v", err)
+	}
+}
+
+// Teste do limite de tamanho: o frame grande é descartado e o seguinte é lido normalmente
+func TestCodecFrameSizeLimit(t *testing.T) {
+	big, err := protocol.CreateLoginRequest(string(bytes.Repeat([]byte("a"), 2048)), "senha123")
+	if err != nil {
+		t.Fatalf("Erro ao criar mensagem: %v", err)
+	}
+	small, err := protocol.CreateStatsRequest(42)
+	if err != nil {
+		t.Fatalf("Erro ao criar mensagem: %v", err)
+	}
+
+	for _, codec := range codecs {
+		t.Run(codec.Name(), func(t *testing.T) {
+			var stream bytes.Buffer
+			for _, data := range [][]byte{big, small} {
+				frame, err := codec.Frame(data)
+				if err != nil {
+					t.Fatalf("Erro ao codificar: %v", err)
+				}
+				stream.Write(frame)
+			}
+
+			// bufio.Reader menor que o frame: a linha JSON chega em vários pedaços
+			frames := codec.NewFrameReader(bufio.NewReaderSize(&stream, 16), 1024)
+			if _, err := frames.ReadMessage(); !errors.Is(err, protocol.ErrFrameTooLarge) || !protocol.IsFrameError(err) {
+				t.Fatalf("Frame de mais de 1024 bytes deveria falhar com ErrFrameTooLarge: %v", err)
+			}
+			if message, err := frames.ReadMessage(); err != nil || message.Type != protocol.MSG_STATS_REQUEST {
+				t.Fatalf("Frame seguinte deveria ser lido normalmente: %v", err)
+			}
+		})
+	}
+}
+
+// Benchmark de codificação: bytes por mensagem e custo para montar o frame
+func BenchmarkCodecEncode(b *testing.B) {
+	fixtures := codecFixtures(b)
+	for _, codec := range codecs {
+		b.Run(codec.Name(), func(b *testing.B) {
+			total := 0
+			for _, fixture := range fixtures {
+				frame, err := codec.Frame(fixture.data)
+				if err != nil {
+					b.Fatal(err)
+				}
+				total += len(frame)
+			}
+
+			b.ReportAllocs()
+			for b.Loop() {
+				for _, fixture := range fixtures {
+					if _, err := codec.Frame(fixture.data); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+			b.ReportMetric(float64(total)/float64(len(fixtures)), "bytes/msg")
+		})
+	}
+}
+
+// Benchmark de codificação a partir da mensagem já decodificada (ex.: resposta com
+// request_id): o envelope não passa por JSON
+func BenchmarkCodecEncodeMessage(b *testing.B) {
+	fixtures := codecFixtures(b)
+	messages := make([]*protocol.Message, len(fixtures))
+	for i, fixture := range fixtures {
+		messages[i], _ = protocol.DecodeMessage(fixture.data)
+	}
+	for _, codec := range codecs {
+		b.Run(codec.Name(), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				for _, message := range messages {
+					if _, err := codec.FrameMessage(message); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// Benchmark de decodificação: leitura do frame e extração do payload tipado
+func BenchmarkCodecDecode(b *testing.B) {
+	fixtures := codecFixtures(b)
+	for _, codec := range codecs {
+		b.Run(codec.Name(), func(b *testing.B) {
+			var stream bytes.Buffer
+			for _, fixture := range fixtures {
+				frame, err := codec.Frame(fixture.data)
+				if err != nil {
+					b.Fatal(err)
+				}
+				stream.Write(frame)
+			}
+			encoded := stream.Bytes()
+
+			source := bytes.NewReader(encoded)
+			reader := bufio.NewReader(source)
+			b.ReportAllocs()
+			for b.Loop() {
+				source.Reset(encoded)
+				reader.Reset(source)
+				frames := codec.NewFrameReader(reader, 0)
+				for range fixtures {
+					message, err := frames.ReadMessage()
+					if err != nil {
+						b.Fatal(err)
+					}
+					payloadType, _ := protocol.PayloadType(message.Type)
+					if err := json.Unmarshal(message.Data, reflect.New(payloadType).Interface()); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+			b.ReportMetric(float64(len(encoded))/float64(len(fixtures)), "bytes/msg")
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package test
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+	"top-card/internal/card"
+	"top-card/internal/clock"
+	"top-card/internal/match"
+	"top-card/internal/player"
+	"top-card/internal/protocol"
+	"top-card/internal/server"
+)
+
+// Abre uma sequência de pacotes num estoque com fonte aleatória de semente fixa
+func openPacksWithSeed(t *testing.T, seed int64, packTypes []string) [][]card.Card {
+	stock := card.NewCardStock(card.DefaultStockCounts, rand.New(rand.NewSource(seed)))
+
+	seeds, err := stock.NewSeedState("")
+	if err != nil {
+		t.Fatalf("Erro ao gerar sementes: %v", err)
+	}
+
+	var packs [][]card.Card
+	for _, name := range packTypes {
+		packType, _ := card.GetPackType(name)
+		cards, _, ok := stock.OpenPack(packType, false, &seeds)
+		if !ok {
+			t.Fatalf("Pacote %s não foi aberto", name)
+		}
+		packs = append(packs, cards)
+	}
+	return packs
+}
+
+// Teste de reprodução exata dos pacotes com a mesma fonte aleatória
+func TestPackDrawReplay(t *testing.T) {
+	packTypes := []string{card.PACK_STANDARD, card.PACK_PREMIUM, card.PACK_STANDARD, card.PACK_GORGONA}
+
+	first := openPacksWithSeed(t, 42, packTypes)
+	second := openPacksWithSeed(t, 42, packTypes)
+	for i := range first {
+		if !card.SamePack(first[i], second[i]) {
+			t.Fatalf("Pacote %d diferente entre execuções: %v vs %v", i, first[i], second[i])
+		}
+	}
+
+	// Outra semente deve gerar uma sequência diferente
+	other := openPacksWithSeed(t, 7, packTypes)
+	same := true
+	for i := range first {
+		if !card.SamePack(first[i], other[i]) {
+			same = false
+		}
+	}
+	if same {
+		t.Fatalf("Sementes diferentes geraram exatamente os mesmos pacotes")
+	}
+}
+
+// Teste de verificação de pacote após a revelação da semente do servidor
+func TestPackVerification(t *testing.T) {
+	stock := card.NewCardStock(card.DefaultStockCounts, rand.New(rand.NewSource(1)))
+	seeds, err := stock.NewSeedState("semente-do-cliente")
+	if err != nil {
+		t.Fatalf("Erro ao gerar sementes: %v", err)
+	}
+	committedHash := seeds.ServerSeedHash
+
+	premium, _ := card.GetPackType(card.PACK_PREMIUM)
+	cards, before, ok := stock.OpenPack(premium, true, &seeds)
+	if !ok {
+		t.Fatalf("Pacote premium não foi aberto")
+	}
+	if !card.HasEpic(cards) {
+		t.Fatalf("Pity ativo deveria garantir carta épica: %v", cards)
+	}
+
+	revealed, err := stock.RotateSeeds(&seeds)
+	if err != nil {
+		t.Fatalf("Erro ao rotacionar sementes: %v", err)
+	}
+	if revealed.ServerSeedHash != committedHash || seeds.ServerSeedHash == committedHash {
+		t.Fatalf("Rotação deveria revelar a semente publicada e gerar uma nova")
+	}
+
+	expected, err := card.VerifyPack(revealed.ServerSeed, committedHash, revealed.ClientSeed, 0, card.PACK_PREMIUM, before, true)
+	if err != nil {
+		t.Fatalf("Erro ao verificar pacote: %v", err)
+	}
+	if !card.SamePack(cards, expected) {
+		t.Fatalf("Pacote verificado diferente: %v vs %v", cards, expected)
+	}
+
+	// Semente adulterada não corresponde ao hash publicado
+	if _, err := card.VerifyPack(revealed.ServerSeed+"0", committedHash, revealed.ClientSeed, 0, card.PACK_PREMIUM, before, true); err == nil {
+		t.Fatalf("Semente adulterada deveria ser rejeitada")
+	}
+}
+
+// Teste do relógio falso: tickers e sleeps só avançam com Advance
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+
+	ticker := fake.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	woke := make(chan time.Time, 1)
+	go func() {
+		fake.Sleep(2 * time.Second)
+		woke <- fake.Now()
+	}()
+	fake.BlockUntil(2)
+
+	fake.Advance(time.Second)
+	select {
+	case tick := <-ticker.C():
+		if !tick.Equal(start.Add(time.Second)) {
+			t.Fatalf("Tick no horário errado: %v", tick)
+		}
+	default:
+		t.Fatalf("Ticker deveria ter disparado após 1s")
+	}
+	select {
+	case <-woke:
+		t.Fatalf("Sleep de 2s não deveria ter terminado após 1s")
+	default:
+	}
+
+	fake.Advance(time.Second)
+	select {
+	case now := <-woke:
+		if !now.Equal(start.Add(2 * time.Second)) {
+			t.Fatalf("Sleep terminou no horário errado: %v", now)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Sleep de 2s deveria ter terminado")
+	}
+}
+
+// Teste dos horários da partida com o relógio falso
+func TestMatchManagerClock(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	manager := match.NewMatchManager(fake)
+
+	player1 := player.NewPlayer(1, "alice", "senha")
+	player2 := player.NewPlayer(2, "bruno", "senha")
+	player1.AddCards([]card.Card{{Type: card.HYDRA, Rarity: card.COMUM}})
+	player2.AddCards([]card.Card{{Type: card.GORGONA, Rarity: card.EPICO}})
+
+	created := manager.CreateMatch(player1, player2)
+	fake.Advance(2 * time.Second)
+	manager.StartMatch(created.ID)
+	manager.StartGame(created.ID)
+	fake.Advance(5 * time.Second)
+	manager.MakeCardMove(created.ID, 1, card.HYDRA)
+	fake.Advance(3 * time.Second)
+	manager.MakeCardMove(created.ID, 2, card.GORGONA)
+
+	finished := manager.GetMatch(created.ID)
+	if !finished.CreatedAt.Equal(start) {
+		t.Fatalf("CreatedAt incorreto: %v", finished.CreatedAt)
+	}
+	if !finished.StartedAt.Equal(start.Add(2 * time.Second)) {
+		t.Fatalf("StartedAt incorreto: %v", finished.StartedAt)
+	}
+	if !finished.FinishedAt.Equal(start.Add(10 * time.Second)) {
+		t.Fatalf("FinishedAt incorreto: %v", finished.FinishedAt)
+	}
+	if finished.Winner != 2 {
+		t.Fatalf("GORGONA deveria vencer HYDRA, vencedor: %d", finished.Winner)
+	}
+}
+
+// Teste do matchmaker do servidor com o relógio falso: a fila, a espera até o início
+// da partida e a espera até o primeiro turno só andam com Advance, sem sleeps reais
+func TestServerMatchmakingFakeClock(t *testing.T) {
+	fake := clock.NewFake(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	_, addr := startServer(t, server.Config{Clock: fake}) // Tempos padrão: 1s, 2s e 1s no relógio falso
+	started := time.Now()
+
+	// Tickers do matchmaker e da limpeza de partidas órfãs
+	fake.BlockUntil(2)
+	tickers := fake.Waiters()
+
+	alice := dialTestClient(t, addr)
+	bruno := dialTestClient(t, addr)
+	alice.registerAndOpenPack("alice")
+	bruno.registerAndOpenPack("bruno")
+	alice.send(protocol.CreateQueueRequest(alice.userID))
+	alice.expect(protocol.MSG_QUEUE_RESPONSE)
+	bruno.send(protocol.CreateQueueRequest(bruno.userID))
+	bruno.expect(protocol.MSG_QUEUE_RESPONSE)
+
+	// Tick do matchmaker: partida encontrada, que aguarda MatchStartDelay para começar
+	fake.Advance(time.Second)
+	found, err := protocol.ExtractMatchFound(alice.expect(protocol.MSG_MATCH_FOUND))
+	if err != nil || found.OpponentID != bruno.userID {
+		t.Fatalf("Alice deveria enfrentar Bruno: %v %+v", err, found)
+	}
+	bruno.expect(protocol.MSG_MATCH_FOUND)
+	fake.BlockUntil(tickers + 1)
+
+	// Fim de MatchStartDelay: a partida começa e aguarda GameStartDelay para o primeiro turno
+	fake.Advance(2 * time.Second)
+	fake.BlockUntil(tickers + 1)
+	fake.Advance(time.Second)
+	matchStart, err := protocol.ExtractMatchStart(alice.expect(protocol.MSG_MATCH_START))
+	if err != nil || matchStart.MatchID != found.MatchID {
+		t.Fatalf("Início da partida %d esperado: %v %+v", found.MatchID, err, matchStart)
+	}
+	bruno.expect(protocol.MSG_MATCH_START)
+
+	// Alice (primeira da fila) joga primeiro; a partida termina sem depender do relógio
+	state, err := protocol.ExtractGameState(alice.expect(protocol.MSG_GAME_STATE))
+	if err != nil || !state.YourTurn {
+		t.Fatalf("Alice deveria jogar primeiro: %v %+v", err, state)
+	}
+	alice.send(protocol.CreateCardMove(alice.userID, matchStart.MatchID, alice.cards[0].Type))
+	bruno.expect(protocol.MSG_TURN_UPDATE)
+	bruno.send(protocol.CreateCardMove(bruno.userID, matchStart.MatchID, bruno.cards[0].Type))
+	alice.expect(protocol.MSG_MATCH_END)
+	bruno.expect(protocol.MSG_MATCH_END)
+
+	// Os 4s de espera da partida correram só no relógio falso
+	if elapsed := time.Since(started); elapsed >= 3*time.Second {
+		t.Fatalf("Partida deveria começar sem esperas reais, levou %v", elapsed)
+	}
+}
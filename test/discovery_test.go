@@ -0,0 +1,91 @@
+package test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+	"top-card/internal/discovery"
+	"top-card/internal/protocol"
+	"top-card/internal/server"
+	"top-card/pkg/topcard"
+)
+
+// Teste da decodificação dos anúncios: endereço sem host recebe o IP de origem
+func TestDiscoveryParse(t *testing.T) {
+	from := &net.UDPAddr{IP: net.ParseIP("192.168.0.20"), Port: 40000}
+
+	cases := []struct {
+		data string
+		addr string // Vazio = anúncio recusado
+	}{
+		{`{"service":"top-card","name":"sala","addr":":8080"}`, "192.168.0.20:8080"},
+		{`{"service":"top-card","name":"sala","addr":"0.0.0.0:8080"}`, "192.168.0.20:8080"},
+		{`{"service":"top-card","name":"sala","addr":"10.0.0.7:9000"}`, "10.0.0.7:9000"},
+		{`{"service":"outro-jogo","name":"sala","addr":":8080"}`, ""},
+		{`{"service":"top-card","name":"sala","addr":"sem-porta"}`, ""},
+		{`não é JSON`, ""},
+	}
+	for _, c := range cases {
+		announcement, ok := discovery.Parse([]byte(c.data), from)
+		if ok != (c.addr != "") || ok && announcement.Addr != c.addr {
+			t.Fatalf("Anúncio %s: esperado %q, recebido %q (aceito=%v)", c.data, c.addr, announcement.Addr, ok)
+		}
+	}
+}
+
+// Teste dos anúncios do servidor: nome, endereço, versão e jogadores online, e fim
+// dos anúncios no encerramento
+func TestDiscoveryAnnounce(t *testing.T) {
+	srv, addr := startServer(t, server.Config{Name: "sala-de-teste", AnnounceInterval: 20 * time.Millisecond})
+
+	// Os anúncios vão direto para o socket de quem procura, sem depender de multicast
+	listener, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Erro ao abrir socket UDP: %v", err)
+	}
+	defer listener.Close()
+	sender, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Erro ao abrir socket UDP: %v", err)
+	}
+	announced := make(chan error, 1)
+	go func() { announced <- srv.Announce(sender, listener.LocalAddr()) }()
+
+	servers, err := discovery.Listen(context.Background(), listener, 200*time.Millisecond)
+	if err != nil || len(servers) != 1 {
+		t.Fatalf("Esperado um servidor: %v %+v", err, servers)
+	}
+	found := servers[0]
+	if found.Name != "sala-de-teste" || found.Addr != addr || found.Players != 0 || found.ProtocolVersion != protocol.PROTOCOL_VERSION || found.Version == "" || found.TLS {
+		t.Fatalf("Anúncio incorreto: %+v", found)
+	}
+
+	// O endereço anunciado aceita conexões e o login aparece no anúncio seguinte
+	client := dialSDKPlayer(t, found.Addr, "descoberto", topcard.Options{})
+	defer client.Close()
+	servers, err = discovery.Listen(context.Background(), listener, 200*time.Millisecond)
+	if err != nil || len(servers) != 1 || servers[0].Players != 1 {
+		t.Fatalf("Esperado um jogador online: %v %+v", err, servers)
+	}
+
+	// Servidor encerrando para de se anunciar
+	srv.Shutdown(context.Background())
+	select {
+	case err := <-announced:
+		if err != server.ErrServerClosed {
+			t.Fatalf("Announce deveria terminar com ErrServerClosed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Anúncios continuaram depois do encerramento")
+	}
+	listener.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	for {
+		if _, _, err := listener.ReadFrom(make([]byte, 1024)); err != nil {
+			break // Descarta os anúncios enviados antes do encerramento
+		}
+	}
+	if servers, err := discovery.Listen(context.Background(), listener, 100*time.Millisecond); err != nil || len(servers) != 0 {
+		t.Fatalf("Nenhum anúncio esperado depois do encerramento: %v %+v", err, servers)
+	}
+}
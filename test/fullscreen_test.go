@@ -0,0 +1,183 @@
+package test
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"top-card/internal/client"
+	"top-card/internal/server"
+)
+
+// Saída da tela cheia gravada em memória. Cada quadro começa limpando a tela.
+type screenOutput struct {
+	mutex sync.Mutex
+	data  strings.Builder
+}
+
+func (o *screenOutput) Write(p []byte) (int, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.data.Write(p)
+}
+
+// Último quadro desenhado
+func (o *screenOutput) lastFrame() string {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	frames := strings.Split(o.data.String(), "\x1b[H\x1b[2J")
+	return frames[len(frames)-1]
+}
+
+// Tela cheia de teste: teclas por um pipe e quadros num buffer
+type testScreen struct {
+	t      *testing.T
+	keys   *io.PipeWriter
+	output *screenOutput
+	done   chan error
+}
+
+// Abre a tela cheia conectada ao servidor com o tamanho informado
+func startFullScreen(t *testing.T, addr string, width, height int) *testScreen {
+	t.Helper()
+	keys, keysWriter := io.Pipe()
+	screen := &testScreen{t: t, keys: keysWriter, output: &screenOutput{}, done: make(chan error, 1)}
+	go func() {
+		screen.done <- client.RunFullScreen(addr, client.ScreenOptions{
+			Keys:   keys,
+			Output: screen.output,
+			Size:   func() (int, int) { return width, height },
+		})
+	}()
+	t.Cleanup(func() { keysWriter.Close() })
+	return screen
+}
+
+// Digita as teclas, como chegam do terminal em modo raw
+func (s *testScreen) press(keys string) {
+	s.t.Helper()
+	if _, err := io.WriteString(s.keys, keys); err != nil {
+		s.t.Fatalf("Erro ao enviar teclas %q: %v", keys, err)
+	}
+}
+
+// Espera um quadro com todos os textos e o retorna
+func (s *testScreen) waitFor(texts ...string) string {
+	s.t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		frame := s.output.lastFrame()
+		missing := ""
+		for _, text := range texts {
+			if !strings.Contains(frame, text) {
+				missing = text
+				break
+			}
+		}
+		if missing == "" {
+			return frame
+		}
+		if time.Now().After(deadline) {
+			s.t.Fatalf("Tela sem %q; último quadro: %q", missing, frame)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Espera a tela cheia terminar e devolver o terminal
+func (s *testScreen) waitExit() {
+	s.t.Helper()
+	select {
+	case err := <-s.done:
+		if err != nil {
+			s.t.Fatalf("Tela cheia terminou com erro: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		s.t.Fatalf("Tela cheia não terminou")
+	}
+	if frame := s.output.lastFrame(); !strings.HasSuffix(frame, "\x1b[?25h\x1b[?1049l") {
+		s.t.Fatalf("Terminal não foi restaurado ao sair: %q", frame)
+	}
+}
+
+// Teste da escolha da interface: tela cheia só num terminal que entende ANSI
+func TestFullScreenSupported(t *testing.T) {
+	cases := []struct {
+		term, ui    string
+		interactive bool
+		want        bool
+	}{
+		{"xterm-256color", "", true, true},
+		{"xterm", "tela", true, true},
+		{"xterm", "menu", true, false},
+		{"dumb", "", true, false},
+		{"", "", true, false},
+		{"xterm", "", false, false},
+	}
+	for _, c := range cases {
+		env := map[string]string{"TERM": c.term, "CLIENT_UI": c.ui}
+		getenv := func(name string) string { return env[name] }
+		if got := client.FullScreenSupported(getenv, c.interactive); got != c.want {
+			t.Errorf("TERM=%q CLIENT_UI=%q terminal=%v: tela cheia=%v, esperado %v", c.term, c.ui, c.interactive, got, c.want)
+		}
+	}
+}
+
+// Teste dos atalhos da tela cheia: mensagens sem login, campos de texto (senha
+// mascarada, Esc cancela), cadastro, login, pacote e estatísticas, sequências de
+// escape ignoradas e q para sair
+func TestFullScreenShortcuts(t *testing.T) {
+	_, addr := startServer(t, withFastTimings(server.Config{}))
+	screen := startFullScreen(t, addr, 120, 40)
+
+	screen.waitFor("sem login", "Faça login (l) ou cadastre-se (r)", "Pressione uma tecla de atalho")
+	screen.press("p")
+	screen.waitFor("Você precisa estar logado para abrir os pacotes de cartas!")
+
+	screen.press("r")
+	screen.waitFor("> Usuário: ")
+	screen.press("ana\r")
+	screen.waitFor("> Senha: ")
+	screen.press("senha123")
+	if frame := screen.waitFor("> Senha: ********"); strings.Contains(frame, "senha123") {
+		t.Fatalf("Senha deveria aparecer mascarada: %q", frame)
+	}
+	screen.press("\r")
+	screen.waitFor("Cadastro realizado (ID: 1)")
+
+	screen.press("l")
+	screen.press("ana\r")
+	screen.press("senha123\r")
+	screen.waitFor("Login realizado como ana (ID: 1)", "ana (ID 1)", "Sem cartas: abra um pacote (p)")
+
+	// Esc fecha o campo sem enviar
+	screen.press("p")
+	screen.waitFor("> Pacote (")
+	screen.press("\x1b")
+	screen.waitFor("Pressione uma tecla de atalho")
+
+	// F1 (ESC O P) não pode virar o atalho p
+	screen.press("\x1bOPe")
+	if frame := screen.waitFor("📊 ana: 0 vitória(s), 0 derrota(s)"); !strings.Contains(frame, "Pressione uma tecla de atalho") {
+		t.Fatalf("Sequência de escape abriu um campo: %q", frame)
+	}
+
+	screen.press("p\r")
+	screen.waitFor("🎒 Pacote standard:", "[1] HYDRA", "[2] QUIMERA", "[3] GORGONA")
+	screen.press("2")
+	screen.waitFor("Você precisa estar em uma partida para jogar!")
+
+	screen.press("q")
+	screen.waitExit()
+}
+
+// Teste da tela pequena demais e do fim das teclas
+func TestFullScreenTooSmall(t *testing.T) {
+	_, addr := startServer(t, server.Config{})
+	screen := startFullScreen(t, addr, 40, 10)
+
+	screen.waitFor("Aumente o terminal para pelo menos 60x18")
+	screen.keys.Close()
+	screen.waitExit()
+}
@@ -0,0 +1,241 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"top-card/internal/clock"
+	"top-card/internal/lockout"
+	"top-card/internal/protocol"
+	"top-card/internal/server"
+)
+
+// Teste do registro de falhas: espera exponencial, bloqueio, fim do bloqueio e reset
+func TestLockoutTracker(t *testing.T) {
+	fake := clock.NewFake(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	tracker := lockout.NewTracker(fake, lockout.Policy{
+		MaxFailures:     4,
+		BaseDelay:       time.Second,
+		MaxDelay:        3 * time.Second,
+		LockoutDuration: time.Minute,
+		ForgetAfter:     time.Hour,
+	})
+
+	if wait, _ := tracker.Wait("alice"); wait != 0 {
+		t.Fatalf("Sem falhas não deveria haver espera: %v", wait)
+	}
+
+	// Espera dobra a cada falha, limitada a MaxDelay
+	for i, want := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		tracker.Fail("alice")
+		if wait, locked := tracker.Wait("alice"); wait != want || locked {
+			t.Fatalf("Falha %d: esperado %v sem bloqueio, recebido %v bloqueado=%v", i+1, want, wait, locked)
+		}
+		fake.Advance(want)
+	}
+
+	// Quarta falha bloqueia por LockoutDuration
+	entry := tracker.Fail("alice")
+	if !entry.Locked(fake.Now()) || entry.Failures != 4 {
+		t.Fatalf("Quarta falha deveria bloquear: %+v", entry)
+	}
+	if wait, locked := tracker.Wait("alice"); wait != time.Minute || !locked {
+		t.Fatalf("Esperado bloqueio de 1m, recebido %v bloqueado=%v", wait, locked)
+	}
+	if entries := tracker.Entries(); len(entries) != 1 || entries[0].Key != "alice" {
+		t.Fatalf("Bloqueio deveria aparecer na listagem: %+v", entries)
+	}
+
+	// Terminado o bloqueio, as falhas recomeçam do zero
+	fake.Advance(time.Minute)
+	if wait, _ := tracker.Wait("alice"); wait != 0 {
+		t.Fatalf("Bloqueio deveria ter terminado: %v", wait)
+	}
+	if entry := tracker.Fail("alice"); entry.Failures != 1 {
+		t.Fatalf("Falhas deveriam recomeçar depois do bloqueio: %+v", entry)
+	}
+
+	// Reset (login bem-sucedido) e falhas antigas
+	if !tracker.Reset("alice") || tracker.Reset("alice") {
+		t.Fatalf("Reset deveria remover as falhas uma única vez")
+	}
+	tracker.Fail("bruno")
+	fake.Advance(time.Hour)
+	tracker.Prune()
+	if entries := tracker.Entries(); len(entries) != 0 {
+		t.Fatalf("Falhas antigas deveriam ser esquecidas: %+v", entries)
+	}
+}
+
+// Teste da proteção do login: espera entre tentativas, bloqueio, visão de administrador
+// e reset das falhas no login bem-sucedido
+func TestLoginLockout(t *testing.T) {
+	fake := clock.NewFake(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	srv, addr := startServer(t, server.Config{
+		Clock:       fake,
+		AdminToken:  "segredo",
+		UserLockout: lockout.Policy{MaxFailures: 3, BaseDelay: time.Second, MaxDelay: 4 * time.Second, LockoutDuration: time.Minute},
+		IPLockout:   lockout.Policy{MaxFailures: 100, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, LockoutDuration: time.Minute},
+	})
+
+	client := dialTestClient(t, addr)
+	client.send(protocol.CreateRegisterRequest("marta", "senha123"))
+	client.expect(protocol.MSG_REGISTER_RESPONSE)
+
+	login := func(password, wantCode string, wantRetry time.Duration) {
+		t.Helper()
+		client.send(protocol.CreateLoginRequest("marta", password))
+		message := client.expect(protocol.MSG_LOGIN_RESPONSE)
+		response, err := protocol.ExtractLoginResponse(message)
+		if err != nil || message.ErrorCode != wantCode || response.RetryAfterMs != wantRetry.Milliseconds() {
+			t.Fatalf("Login com %q: esperado %q com espera %v, recebido %q %+v", password, wantCode, wantRetry, message.ErrorCode, response)
+		}
+	}
+
+	// Depois de uma falha, nem a senha correta é aceita antes da espera
+	login("errada", protocol.ERR_INVALID_CREDENTIALS, 0)
+	login("senha123", protocol.ERR_LOGIN_BACKOFF, time.Second)
+	fake.Advance(time.Second)
+	login("errada", protocol.ERR_INVALID_CREDENTIALS, 0)
+	fake.Advance(2 * time.Second)
+	login("errada", protocol.ERR_INVALID_CREDENTIALS, 0)
+
+	// Terceira falha: bloqueio de 1 minuto, visível para o administrador
+	login("senha123", protocol.ERR_ACCOUNT_LOCKED, time.Minute)
+	lockouts := srv.LoginLockouts()
+	if len(lockouts.Users) != 1 || lockouts.Users[0].Key != "marta" || !lockouts.Users[0].Locked(fake.Now()) {
+		t.Fatalf("Bloqueio de marta deveria aparecer para o administrador: %+v", lockouts)
+	}
+	if len(lockouts.IPs) != 1 || lockouts.IPs[0].Failures != 3 {
+		t.Fatalf("Falhas do IP deveriam aparecer para o administrador: %+v", lockouts.IPs)
+	}
+
+	// Interface HTTP de administração: consulta e desbloqueio
+	admin := httptest.NewServer(srv.AdminHandler())
+	defer admin.Close()
+	resp, err := adminRequest(admin.URL, http.MethodGet, "/admin/lockouts", "segredo")
+	if err != nil {
+		t.Fatalf("Erro ao consultar bloqueios: %v", err)
+	}
+	var viaHTTP server.LoginLockouts
+	err = json.NewDecoder(resp.Body).Decode(&viaHTTP)
+	resp.Body.Close()
+	if err != nil || len(viaHTTP.Users) != 1 || viaHTTP.Users[0].Key != "marta" {
+		t.Fatalf("Bloqueio deveria aparecer em /admin/lockouts: %v %+v", err, viaHTTP)
+	}
+	resp, err = adminRequest(admin.URL, http.MethodPost, "/admin/unlock?user=marta", "segredo")
+	if err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Desbloqueio deveria responder 204: %v %v", err, resp)
+	}
+	resp.Body.Close()
+
+	// Login bem-sucedido zera as falhas do usuário, mas não as do IP
+	fake.Advance(time.Millisecond)
+	client.send(protocol.CreateLoginRequest("marta", "senha123"))
+	if response, err := protocol.ExtractLoginResponse(client.expect(protocol.MSG_LOGIN_RESPONSE)); err != nil || !response.Success {
+		t.Fatalf("Login deveria ser aceito após o desbloqueio: %v %+v", err, response)
+	}
+	if lockouts := srv.LoginLockouts(); len(lockouts.Users) != 0 || len(lockouts.IPs) != 1 || lockouts.IPs[0].Failures != 3 {
+		t.Fatalf("Login bem-sucedido deveria zerar só as falhas do usuário: %+v", lockouts)
+	}
+}
+
+// Teste do limite por IP com logins válidos no meio das tentativas: entrar na própria
+// conta não zera as falhas do IP, então as adivinhações continuam contando até o bloqueio
+func TestIPLockoutIgnoresValidLogins(t *testing.T) {
+	fake := clock.NewFake(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	srv, addr := startServer(t, server.Config{
+		Clock:       fake,
+		RateLimits:  server.RateLimits{Disabled: true}, // O relógio falso não reabastece os limites
+		UserLockout: lockout.Policy{MaxFailures: 100, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, LockoutDuration: time.Minute},
+		IPLockout:   lockout.Policy{MaxFailures: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, LockoutDuration: time.Minute},
+	})
+
+	guesser := dialTestClient(t, addr)
+	for _, userName := range []string{"vitima", "cumplice1", "cumplice2", "cumplice3"} {
+		guesser.send(protocol.CreateRegisterRequest(userName, "senha123"))
+		guesser.expect(protocol.MSG_REGISTER_RESPONSE)
+	}
+
+	login := func(client *testClient, userName, password, wantCode string) {
+		t.Helper()
+		fake.Advance(time.Millisecond) // Passa o intervalo entre tentativas
+		client.send(protocol.CreateLoginRequest(userName, password))
+		if message := client.expect(protocol.MSG_LOGIN_RESPONSE); message.ErrorCode != wantCode {
+			t.Fatalf("Login de %s: esperado %q, recebido %q", userName, wantCode, message.ErrorCode)
+		}
+	}
+
+	// Cada senha errada é seguida de um login válido em outra conta, do mesmo IP
+	login(guesser, "vitima", "errada1", protocol.ERR_INVALID_CREDENTIALS)
+	login(dialTestClient(t, addr), "cumplice1", "senha123", "")
+	login(guesser, "vitima", "errada2", protocol.ERR_INVALID_CREDENTIALS)
+	login(dialTestClient(t, addr), "cumplice2", "senha123", "")
+	login(guesser, "vitima", "errada3", protocol.ERR_INVALID_CREDENTIALS)
+
+	// A terceira falha bloqueia o IP, inclusive para senhas corretas
+	if lockouts := srv.LoginLockouts(); len(lockouts.IPs) != 1 || lockouts.IPs[0].Failures != 3 {
+		t.Fatalf("Falhas do IP deveriam somar as três tentativas: %+v", lockouts.IPs)
+	}
+	login(dialTestClient(t, addr), "cumplice3", "senha123", protocol.ERR_ACCOUNT_LOCKED)
+}
+
+// Função para enviar uma requisição à interface de administração com o token informado
+// (vazio = sem cabeçalho Authorization)
+func adminRequest(baseURL, method, path, token string) (*http.Response, error) {
+	req, err := http.NewRequest(method, baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return http.DefaultClient.Do(req)
+}
+
+// Teste da autenticação da interface de administração: sem o token correto a resposta
+// é 401 e nada é desbloqueado; sem token configurado nenhuma requisição é aceita
+func TestAdminRequiresToken(t *testing.T) {
+	srv := server.New(server.Config{AdminToken: "segredo"})
+	admin := httptest.NewServer(srv.AdminHandler())
+	defer admin.Close()
+
+	for _, c := range []struct {
+		method, path, token string
+	}{
+		{http.MethodGet, "/admin/lockouts", ""},
+		{http.MethodGet, "/admin/lockouts", "errado"},
+		{http.MethodGet, "/admin/lockouts", "segredo-mais-longo"},
+		{http.MethodPost, "/admin/unlock?user=marta", ""},
+		{http.MethodPost, "/admin/unlock?user=marta", "errado"},
+	} {
+		resp, err := adminRequest(admin.URL, c.method, c.path, c.token)
+		if err != nil {
+			t.Fatalf("Erro na requisição %s %s: %v", c.method, c.path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("WWW-Authenticate") == "" {
+			t.Fatalf("%s %s com token %q: esperado 401, recebido %d", c.method, c.path, c.token, resp.StatusCode)
+		}
+	}
+
+	// Com o token correto a requisição chega à interface
+	resp, err := adminRequest(admin.URL, http.MethodGet, "/admin/lockouts", "segredo")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Token correto deveria ser aceito: %v %v", err, resp)
+	}
+	resp.Body.Close()
+
+	// Servidor sem token configurado recusa tudo, inclusive um token vazio
+	open := httptest.NewServer(server.New(server.Config{}).AdminHandler())
+	defer open.Close()
+	req, _ := http.NewRequest(http.MethodGet, open.URL+"/admin/lockouts", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Sem ADMIN_TOKEN a interface deveria recusar: %v %v", err, resp)
+	}
+	resp.Body.Close()
+}
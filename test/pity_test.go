@@ -0,0 +1,199 @@
+package test
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+	"top-card/internal/card"
+	"top-card/internal/protocol"
+	"top-card/internal/server"
+)
+
+// Nome do pacote que o parceiro abre para cada partida
+const partnerPack = "parceiro-teste"
+
+// Ordem das raridades, para conferir os slots garantidos
+var rarityOrder = map[string]int{card.COMUM: 0, card.RARO: 1, card.EPICO: 2}
+
+// Pacote de uma carta HYDRA que o parceiro abre para cada partida
+var partnerPackType = card.PackType{Name: partnerPack, Size: 1, CardTypes: []string{card.HYDRA}}
+
+// Sobe um servidor rápido com estoque folgado, sem limites de requisições, com os
+// pacotes padrão, o do parceiro e os informados, e retorna um jogador logado e o
+// parceiro com quem ele gasta as cartas (um pacote só abre com o inventário vazio)
+func startPackServer(t *testing.T, packTypes ...card.PackType) (*testClient, *testClient) {
+	t.Helper()
+
+	_, addr := startServer(t, withFastTimings(server.Config{
+		Stock:      card.StockCounts{Hydra: 500, Quimera: 500, Gorgona: 500},
+		PackTypes:  append(append(card.DefaultPackTypes(), partnerPackType), packTypes...),
+		Random:     rand.New(rand.NewSource(1)),       // Sorteios iguais a cada execução
+		RateLimits: server.RateLimits{Disabled: true}, // Um pacote por partida passa do orçamento de pacotes
+	}))
+	player := dialTestClient(t, addr)
+	partner := dialTestClient(t, addr)
+	player.registerAndLogin("colecionador")
+	partner.registerAndLogin("parceiro")
+	return player, partner
+}
+
+// Cadastra e faz login, sem abrir pacote
+func (c *testClient) registerAndLogin(userName string) {
+	c.t.Helper()
+
+	c.send(protocol.CreateRegisterRequest(userName, "senha123"))
+	registerResp, err := protocol.ExtractRegisterResponse(c.expect(protocol.MSG_REGISTER_RESPONSE))
+	if err != nil || !registerResp.Success {
+		c.t.Fatalf("Cadastro falhou: %v %+v", err, registerResp)
+	}
+	c.userID = registerResp.UserID
+
+	c.send(protocol.CreateLoginRequest(userName, "senha123"))
+	if loginResp, err := protocol.ExtractLoginResponse(c.expect(protocol.MSG_LOGIN_RESPONSE)); err != nil || !loginResp.Success {
+		c.t.Fatalf("Login falhou: %v %+v", err, loginResp)
+	}
+}
+
+// Abre um pacote do tipo informado e guarda as cartas recebidas
+func (c *testClient) openPack(packType string) *protocol.CardPackResponse {
+	c.t.Helper()
+
+	c.send(protocol.CreateCardPackRequest(c.userID, packType, ""))
+	packResp, err := protocol.ExtractCardPackResponse(c.expect(protocol.MSG_CARD_PACK_RESPONSE))
+	if err != nil || !packResp.Success {
+		c.t.Fatalf("Pacote %s falhou: %v %+v", packType, err, packResp)
+	}
+	c.cards = packResp.Cards
+	return packResp
+}
+
+// Joga todas as cartas do jogador, uma partida por carta contra o parceiro
+func spendCards(t *testing.T, player, partner *testClient) {
+	t.Helper()
+
+	for _, played := range player.cards {
+		partner.openPack(partnerPack)
+		moves := map[*testClient]string{player: played.Type, partner: partner.cards[0].Type}
+
+		player.send(protocol.CreateQueueRequest(player.userID))
+		player.expect(protocol.MSG_QUEUE_RESPONSE)
+		partner.send(protocol.CreateQueueRequest(partner.userID))
+		partner.expect(protocol.MSG_QUEUE_RESPONSE)
+		matchStart, err := protocol.ExtractMatchStart(player.expect(protocol.MSG_MATCH_START))
+		if err != nil {
+			t.Fatalf("Erro ao extrair início de partida: %v", err)
+		}
+
+		// Quem tem o turno joga primeiro; o outro joga depois do TURN_UPDATE
+		first, second := partner, player
+		if state, err := protocol.ExtractGameState(player.expect(protocol.MSG_GAME_STATE)); err == nil && state.YourTurn {
+			first, second = player, partner
+		}
+		partner.expect(protocol.MSG_GAME_STATE)
+		first.send(protocol.CreateCardMove(first.userID, matchStart.MatchID, moves[first]))
+		second.expect(protocol.MSG_TURN_UPDATE)
+		second.send(protocol.CreateCardMove(second.userID, matchStart.MatchID, moves[second]))
+		player.expect(protocol.MSG_MATCH_END)
+		partner.expect(protocol.MSG_MATCH_END)
+	}
+	player.cards = nil
+}
+
+// Verifica se o pacote trouxe alguma carta épica
+func hasEpic(cards []protocol.CardInfo) bool {
+	return slices.ContainsFunc(cards, func(c protocol.CardInfo) bool { return c.Rarity == card.EPICO })
+}
+
+// Teste do pity de ponta a ponta: o contador sobe a cada pacote sem épico, um épico
+// sorteado zera o contador e o épico é garantido exatamente no limite. Metade dos
+// sorteios do pacote traz épico; o teste segue até ver os dois casos.
+func TestPityEndToEnd(t *testing.T) {
+	const threshold = 3
+	pityPack := card.PackType{Name: "pity-teste", Size: 1, Weights: map[string]int{card.COMUM: 1, card.EPICO: 1}, PityThreshold: threshold}
+
+	player, partner := startPackServer(t, pityPack)
+
+	without := 0 // Pacotes seguidos sem épico
+	triggered, drawn := false, false
+	for i := 1; !triggered || !drawn; i++ {
+		if i > 50 {
+			t.Fatalf("Depois de %d pacotes: épico garantido=%v, épico sorteado=%v", i-1, triggered, drawn)
+		}
+
+		forced := without+1 >= threshold
+		packResp := player.openPack(pityPack.Name)
+		epic := hasEpic(packResp.Cards)
+		if forced && !epic {
+			t.Fatalf("Pacote %d: o pity deveria garantir o épico, cartas %+v", i, packResp.Cards)
+		}
+		if epic {
+			without = 0
+		} else {
+			without++
+		}
+		triggered = triggered || forced
+		drawn = drawn || (epic && !forced)
+
+		pity := packResp.Pity
+		if pity.PackType != pityPack.Name || pity.Threshold != threshold || pity.Triggered != forced ||
+			pity.PacksWithoutEpic != without || pity.PacksUntilEpic != threshold-without {
+			t.Fatalf("Pacote %d: pity esperado sem épico=%d faltam=%d garantido=%v, recebido %+v",
+				i, without, threshold-without, forced, pity)
+		}
+		spendCards(t, player, partner)
+	}
+}
+
+// Teste do isolamento dos pacotes: um tipo configurado num servidor não existe em
+// outro servidor do mesmo processo
+func TestPackTypesPerServer(t *testing.T) {
+	startPackServer(t, card.PackType{Name: "so-neste", Size: 1})
+
+	player, _ := startPackServer(t)
+	player.send(protocol.CreateCardPackRequest(player.userID, "so-neste", ""))
+	if message := player.expect(protocol.MSG_CARD_PACK_RESPONSE); message.ErrorCode != protocol.ERR_INVALID_PACK_TYPE {
+		t.Fatalf("Pacote de outro servidor deveria ser recusado, recebido %q", message.ErrorCode)
+	}
+}
+
+// Teste dos pacotes padrão de ponta a ponta: tamanho, tipos permitidos, slots
+// garantidos e status de pity de cada tipo
+func TestPackTypesEndToEnd(t *testing.T) {
+	player, partner := startPackServer(t)
+
+	for _, name := range []string{card.PACK_STANDARD, card.PACK_PREMIUM, card.PACK_HYDRA, card.PACK_QUIMERA, card.PACK_GORGONA} {
+		packType, ok := card.GetPackType(name)
+		if !ok {
+			t.Fatalf("Pacote padrão %s não registrado", name)
+		}
+
+		packResp := player.openPack(name)
+		if packResp.PackType != name || len(packResp.Cards) != packType.Size {
+			t.Fatalf("Pacote %s: esperadas %d cartas, recebido %+v", name, packType.Size, packResp)
+		}
+		for slot, c := range packResp.Cards {
+			if len(packType.CardTypes) > 0 && !slices.Contains(packType.CardTypes, c.Type) {
+				t.Fatalf("Pacote %s: carta %s fora dos tipos permitidos %v", name, c.Type, packType.CardTypes)
+			}
+			if slot < len(packType.Guaranteed) && rarityOrder[c.Rarity] < rarityOrder[packType.Guaranteed[slot]] {
+				t.Fatalf("Pacote %s: slot %d garante %s, recebido %+v", name, slot, packType.Guaranteed[slot], c)
+			}
+		}
+
+		// Primeiro pacote do tipo: o contador fica em 0 com épico ou 1 sem épico
+		pity := packResp.Pity
+		without := 1
+		if hasEpic(packResp.Cards) {
+			without = 0
+		}
+		until := 0
+		if packType.PityThreshold > 0 {
+			until = packType.PityThreshold - without
+		}
+		if pity.PackType != name || pity.Threshold != packType.PityThreshold || pity.Triggered ||
+			pity.PacksWithoutEpic != without || pity.PacksUntilEpic != until {
+			t.Fatalf("Pacote %s: pity esperado sem épico=%d faltam=%d, recebido %+v", name, without, until, pity)
+		}
+		spendCards(t, player, partner)
+	}
+}
@@ -0,0 +1,222 @@
+package test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"top-card/internal/card"
+	"top-card/internal/protocol"
+	"top-card/internal/server"
+)
+
+// Teste do envelope: ida e volta, tipos desconhecidos e payloads incompatíveis
+func TestEnvelopeRegistry(t *testing.T) {
+	data, err := protocol.Encode(protocol.MSG_LOGIN_REQUEST, protocol.LoginRequest{UserName: "alice", Password: "senha123"})
+	if err != nil {
+		t.Fatalf("Erro ao codificar: %v", err)
+	}
+	message, err := protocol.DecodeMessage(data)
+	if err != nil {
+		t.Fatalf("Erro ao decodificar envelope: %v", err)
+	}
+	login, err := protocol.Decode[protocol.LoginRequest](message)
+	if err != nil || login.UserName != "alice" || login.Password != "senha123" {
+		t.Fatalf("Payload decodificado incorreto: %v %+v", err, login)
+	}
+
+	// Payload de outro tipo é rejeitado na codificação e na decodificação
+	if _, err := protocol.Encode(protocol.MSG_LOGIN_REQUEST, protocol.QueueRequest{UserID: 1}); !errors.Is(err, protocol.ErrPayloadMismatch) {
+		t.Fatalf("Encode com payload errado deveria falhar com ErrPayloadMismatch: %v", err)
+	}
+	if _, err := protocol.Decode[protocol.QueueRequest](message); !errors.Is(err, protocol.ErrPayloadMismatch) {
+		t.Fatalf("Decode com payload errado deveria falhar com ErrPayloadMismatch: %v", err)
+	}
+
+	// Tipo não registrado e JSON malformado
+	if _, err := protocol.DecodeMessage([]byte(`{"type":"DANCE","data":{}}`)); !errors.Is(err, protocol.ErrUnknownType) {
+		t.Fatalf("Tipo desconhecido deveria falhar com ErrUnknownType: %v", err)
+	}
+	if _, err := protocol.DecodeMessage([]byte(`{"type":`)); !errors.Is(err, protocol.ErrInvalidMessage) {
+		t.Fatalf("JSON malformado deveria falhar com ErrInvalidMessage: %v", err)
+	}
+	bad, _ := protocol.DecodeMessage([]byte(`{"type":"LOGIN_REQUEST","data":{"username":42}}`))
+	if _, err := protocol.ExtractLoginRequest(bad); !errors.Is(err, protocol.ErrInvalidMessage) {
+		t.Fatalf("Payload com campo de tipo errado deveria falhar com ErrInvalidMessage: %v", err)
+	}
+}
+
+// Envelope antigo (Data como interface{}), mantido aqui só para comparação nos benchmarks
+type legacyMessage struct {
+	Type      string      `json:"type"`
+	Data      interface{} `json:"data"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// Decodificação antiga: envelope com interface{} + Marshal + Unmarshal do payload
+func legacyDecode[T any](data []byte) (*T, error) {
+	var message legacyMessage
+	if err := json.Unmarshal(data, &message); err != nil {
+		return nil, err
+	}
+	dataBytes, err := json.Marshal(message.Data)
+	if err != nil {
+		return nil, err
+	}
+	var payload T
+	if err := json.Unmarshal(dataBytes, &payload); err != nil {
+		return nil, err
+	}
+	return &payload, nil
+}
+
+func cardPackRequestBytes(b *testing.B) []byte {
+	data, err := protocol.CreateCardPackRequest(42, card.PACK_PREMIUM, "semente-do-cliente")
+	if err != nil {
+		b.Fatal(err)
+	}
+	data, err = protocol.WithRequestID(data, "req-7")
+	if err != nil {
+		b.Fatal(err)
+	}
+	return data
+}
+
+func cardPackResponseBytes(b *testing.B) []byte {
+	cards := []protocol.CardInfo{{Type: card.HYDRA, Rarity: card.RARO}, {Type: card.GORGONA, Rarity: card.EPICO}, {Type: card.QUIMERA, Rarity: card.COMUM}}
+	stock := protocol.StockInfo{HydraCount: 9998, QuimeraCount: 6999, GorgonaCount: 2999, TotalCards: 19996}
+	data, err := protocol.CreateCardPackResponse(true, "Pacote aberto com sucesso!", card.PACK_STANDARD, cards, stock,
+		protocol.PityInfo{PackType: card.PACK_STANDARD, Threshold: 10, PacksUntilEpic: 10},
+		protocol.FairnessInfo{ServerSeedHash: "ab12cd34", ClientSeed: "semente", Nonce: 3, StockBefore: stock})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return data
+}
+
+// Caminho quente do servidor: decodifica a requisição recebida e extrai o payload
+func BenchmarkDecodeRequest(b *testing.B) {
+	data := cardPackRequestBytes(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		message, err := protocol.DecodeMessage(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := protocol.ExtractCardPackRequest(message); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeRequestLegacy(b *testing.B) {
+	data := cardPackRequestBytes(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := legacyDecode[protocol.CardPackRequest](data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Lado do cliente: resposta de pacote, a maior mensagem do protocolo
+func BenchmarkDecodeResponse(b *testing.B) {
+	data := cardPackResponseBytes(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		message, err := protocol.DecodeMessage(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := protocol.ExtractCardPackResponse(message); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeResponseLegacy(b *testing.B) {
+	data := cardPackResponseBytes(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := legacyDecode[protocol.CardPackResponse](data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Conecta ao servidor com o codec informado, cadastra e faz login. Retorna a conexão,
+// o leitor de frames do codec e o ID do usuário.
+func dialBenchClient(b *testing.B, addr string, codec protocol.Codec, userName string) (net.Conn, protocol.FrameReader, int) {
+	b.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { conn.Close() })
+	reader := bufio.NewReader(conn)
+
+	capabilities := []string{protocol.CAP_REQUEST_ID, protocol.CAP_ERROR_CODES}
+	if codec != protocol.JSONCodec {
+		capabilities = append(capabilities, codec.Name())
+	}
+	hello, _ := protocol.CreateHello("top-card-bench", "1.0", capabilities)
+	frame, _ := protocol.JSONCodec.Frame(hello)
+	conn.Write(frame)
+	if welcome, err := protocol.JSONCodec.NewFrameReader(reader, 0).ReadMessage(); err != nil || welcome.Type != protocol.MSG_WELCOME {
+		b.Fatalf("Handshake falhou: %v %+v", err, welcome)
+	}
+	frames := codec.NewFrameReader(reader, 0)
+
+	roundTrip := func(data []byte, err error) *protocol.Message {
+		if err != nil {
+			b.Fatal(err)
+		}
+		frame, _ := codec.Frame(data)
+		conn.Write(frame)
+		message, err := frames.ReadMessage()
+		if err != nil {
+			b.Fatal(err)
+		}
+		return message
+	}
+	registerResp, err := protocol.ExtractRegisterResponse(roundTrip(protocol.CreateRegisterRequest(userName, "senha123")))
+	if err != nil || !registerResp.Success {
+		b.Fatalf("Cadastro falhou: %v %+v", err, registerResp)
+	}
+	if loginResp, err := protocol.ExtractLoginResponse(roundTrip(protocol.CreateLoginRequest(userName, "senha123"))); err != nil || !loginResp.Success {
+		b.Fatalf("Login falhou: %v %+v", err, loginResp)
+	}
+	userID := registerResp.UserID
+	return conn, frames, userID
+}
+
+// Caminho completo de uma resposta no servidor: requisição com request_id, handler,
+// montagem da mensagem e frame no codec da conexão, até a leitura pelo cliente
+func BenchmarkServerResponse(b *testing.B) {
+	_, addr := startServer(b, server.Config{RateLimits: server.RateLimits{Disabled: true}})
+
+	for _, codec := range codecs {
+		b.Run(codec.Name(), func(b *testing.B) {
+			conn, frames, userID := dialBenchClient(b, addr, codec, "bench-"+codec.Name())
+			data, _ := protocol.CreateStatsRequest(userID)
+			data, _ = protocol.WithRequestID(data, "req-bench")
+			request, err := codec.Frame(data)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := conn.Write(request); err != nil {
+					b.Fatal(err)
+				}
+				message, err := frames.ReadMessage()
+				if err != nil || message.Type != protocol.MSG_STATS_RESPONSE || message.RequestID != "req-bench" {
+					b.Fatalf("Resposta inesperada: %v %+v", err, message)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package test
+
+import (
+	"testing"
+	"time"
+	"top-card/internal/clock"
+	"top-card/internal/protocol"
+	"top-card/internal/ratelimit"
+	"top-card/internal/server"
+)
+
+// Teste do balde de fichas: rajada, espera sugerida, reposição e limites por tipo
+func TestRateLimiter(t *testing.T) {
+	fake := clock.NewFake(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	limiter := ratelimit.NewLimiter(fake, ratelimit.Limits{
+		protocol.MSG_LOGIN_REQUEST: {Rate: 0.5, Burst: 2},
+		ratelimit.ANY:              {Rate: 10, Burst: 1},
+	})
+
+	// Rajada de 2 logins; o terceiro espera 2s (taxa de 0,5 por segundo)
+	for i := 0; i < 2; i++ {
+		if allowed, _ := limiter.Allow("alice", protocol.MSG_LOGIN_REQUEST); !allowed {
+			t.Fatalf("Login %d deveria estar dentro da rajada", i+1)
+		}
+	}
+	allowed, retryAfter := limiter.Allow("alice", protocol.MSG_LOGIN_REQUEST)
+	if allowed || retryAfter != 2*time.Second {
+		t.Fatalf("Terceiro login deveria esperar 2s: permitido=%v espera=%v", allowed, retryAfter)
+	}
+
+	// Outros ids e outros tipos têm baldes próprios
+	if allowed, _ := limiter.Allow("bruno", protocol.MSG_LOGIN_REQUEST); !allowed {
+		t.Fatalf("Login de outro id não deveria ser limitado")
+	}
+	if allowed, _ := limiter.Allow("alice", protocol.MSG_STATS_REQUEST); !allowed {
+		t.Fatalf("Outro tipo de mensagem não deveria ser limitado")
+	}
+	if allowed, retryAfter := limiter.Allow("alice", protocol.MSG_STATS_REQUEST); allowed || retryAfter != 100*time.Millisecond {
+		t.Fatalf("Tipo sem limite próprio deveria usar o limite de ANY: permitido=%v espera=%v", allowed, retryAfter)
+	}
+
+	// A ficha volta depois da espera sugerida
+	fake.Advance(2 * time.Second)
+	if allowed, _ := limiter.Allow("alice", protocol.MSG_LOGIN_REQUEST); !allowed {
+		t.Fatalf("Login deveria ser permitido depois da espera")
+	}
+
+	// Baldes cheios são descartados; o de alice ainda está se recuperando
+	if remaining := limiter.Prune(); remaining != 1 {
+		t.Fatalf("Só os baldes de alice deveriam continuar, restaram %d ids", remaining)
+	}
+	limiter.Forget("alice")
+	if remaining := limiter.Prune(); remaining != 0 {
+		t.Fatalf("Forget deveria descartar os baldes de alice, restaram %d ids", remaining)
+	}
+
+	// Sem limites, tudo é permitido
+	unlimited := ratelimit.NewLimiter(fake, nil)
+	for i := 0; i < 100; i++ {
+		if allowed, _ := unlimited.Allow("alice", protocol.MSG_CARD_PACK_REQUEST); !allowed {
+			t.Fatalf("Limitador sem limites não deveria recusar requisições")
+		}
+	}
+}
+
+// Teste dos limites no servidor: por conexão e por IP, com RATE_LIMITED e retry_after_ms
+func TestRateLimitedRequests(t *testing.T) {
+	fake := clock.NewFake(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	_, addr := startServer(t, server.Config{
+		Clock: fake,
+		RateLimits: server.RateLimits{
+			PerSession: ratelimit.Limits{protocol.MSG_STATS_REQUEST: {Rate: 1, Burst: 2}},
+			PerIP:      ratelimit.Limits{protocol.MSG_REGISTER_REQUEST: {Rate: 0.25, Burst: 2}},
+		},
+	})
+
+	expectRateLimited := func(c *testClient, wantRetry time.Duration) {
+		t.Helper()
+		response := c.next()
+		errorInfo, err := protocol.ExtractError(response)
+		if err != nil || response.ErrorCode != protocol.ERR_RATE_LIMITED || errorInfo.RetryAfterMs != wantRetry.Milliseconds() {
+			t.Fatalf("Esperado RATE_LIMITED com espera de %v, recebido %s %q %+v", wantRetry, response.Type, response.ErrorCode, errorInfo)
+		}
+	}
+
+	// Por conexão: a terceira consulta seguida é recusada, a de outra conexão não
+	alice := dialTestClient(t, addr)
+	for i := 0; i < 2; i++ {
+		alice.send(protocol.CreateStatsRequest(1))
+		alice.expect(protocol.MSG_STATS_RESPONSE)
+	}
+	alice.send(protocol.CreateStatsRequest(1))
+	expectRateLimited(alice, time.Second)
+
+	bruno := dialTestClient(t, addr)
+	bruno.send(protocol.CreateStatsRequest(1))
+	bruno.expect(protocol.MSG_STATS_RESPONSE)
+
+	fake.Advance(time.Second)
+	alice.send(protocol.CreateStatsRequest(1))
+	alice.expect(protocol.MSG_STATS_RESPONSE)
+
+	// Por IP: cadastros de conexões diferentes somam no mesmo orçamento
+	alice.send(protocol.CreateRegisterRequest("alice", "senha123"))
+	alice.expect(protocol.MSG_REGISTER_RESPONSE)
+	bruno.send(protocol.CreateRegisterRequest("bruno", "senha123"))
+	bruno.expect(protocol.MSG_REGISTER_RESPONSE)
+	carla := dialTestClient(t, addr)
+	carla.send(protocol.CreateRegisterRequest("carla", "senha123"))
+	expectRateLimited(carla, 4*time.Second)
+
+	fake.Advance(4 * time.Second)
+	carla.send(protocol.CreateRegisterRequest("carla", "senha123"))
+	if response, err := protocol.ExtractRegisterResponse(carla.expect(protocol.MSG_REGISTER_RESPONSE)); err != nil || !response.Success {
+		t.Fatalf("Cadastro deveria ser aceito depois da espera: %v %+v", err, response)
+	}
+}
@@ -0,0 +1,103 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+	"top-card/internal/server"
+	"top-card/pkg/topcard"
+)
+
+// Teste da espera exponencial entre reconexões
+func TestBackoffDelay(t *testing.T) {
+	backoff := topcard.Backoff{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2}
+	want := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for attempt, delay := range want {
+		if got := backoff.Delay(attempt); got != delay*time.Millisecond {
+			t.Fatalf("Tentativa %d: espera %v, esperada %v", attempt, got, delay*time.Millisecond)
+		}
+	}
+	if got := backoff.Delay(1000); got != time.Second {
+		t.Fatalf("Espera deveria parar no máximo: %v", got)
+	}
+
+	// Com jitter a espera fica entre (1-Jitter) e 100% da espera sem jitter
+	backoff.Jitter = 0.5
+	varied := false
+	for range 100 {
+		got := backoff.Delay(3)
+		if got < 400*time.Millisecond || got > 800*time.Millisecond {
+			t.Fatalf("Espera com jitter fora do intervalo: %v", got)
+		}
+		varied = varied || got != backoff.Delay(3)
+	}
+	if !varied {
+		t.Fatal("Jitter não variou a espera")
+	}
+
+	// Valores zerados usam o padrão
+	if got := (topcard.Backoff{}).Delay(0); got != topcard.DefaultBackoff.Initial {
+		t.Fatalf("Espera padrão inesperada: %v", got)
+	}
+}
+
+// Teste da reconexão: Redial tenta de novo até o servidor voltar e para quando o
+// contexto termina
+func TestRedial(t *testing.T) {
+	// Reserva um endereço e deixa a porta fechada
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Erro ao abrir listener: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	backoff := topcard.Backoff{Initial: 10 * time.Millisecond, Max: 50 * time.Millisecond, Multiplier: 2, Jitter: 0.5}
+
+	// Sem servidor, Redial só termina com o contexto
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	var failures atomic.Int32
+	_, err = topcard.Redial(ctx, addr, topcard.Options{}, backoff, func(int, error, time.Duration) { failures.Add(1) })
+	if !errors.Is(err, context.DeadlineExceeded) || failures.Load() < 2 {
+		t.Fatalf("Redial sem servidor: %v depois de %d falhas", err, failures.Load())
+	}
+
+	// O servidor sobe depois de algumas tentativas
+	type result struct {
+		client *topcard.Client
+		err    error
+	}
+	done := make(chan result, 1)
+	failures.Store(0)
+	go func() {
+		client, err := topcard.Redial(context.Background(), addr, topcard.Options{}, backoff, func(int, error, time.Duration) { failures.Add(1) })
+		done <- result{client, err}
+	}()
+	for failures.Load() < 2 {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("Erro ao reabrir %s: %v", addr, err)
+	}
+	srv := server.New(server.Config{})
+	runServer(t, srv, func() error { return srv.Serve(ln) }) // Mesmo endereço das tentativas anteriores
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatalf("Redial falhou: %v", r.err)
+		}
+		defer r.client.Close()
+		if _, err := r.client.Register(context.Background(), "rita", "senha123"); err != nil {
+			t.Fatalf("Cliente reconectado não funciona: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Redial não reconectou depois que o servidor voltou")
+	}
+}
@@ -0,0 +1,142 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"top-card/internal/client"
+	"top-card/internal/protocol"
+	"top-card/internal/server"
+)
+
+// Linha da saída --json dos comandos do cliente
+type scriptLine struct {
+	Type    string          `json:"type"`
+	Command string          `json:"command"`
+	Data    json.RawMessage `json:"data"`
+	Error   json.RawMessage `json:"error"`
+}
+
+// Executa comandos do cliente com --json e decodifica a saída
+func runScript(t *testing.T, args ...string) (int, []scriptLine) {
+	t.Helper()
+	var out bytes.Buffer
+	code := client.RunCommands(append([]string{"--json"}, args...), &out)
+
+	var lines []scriptLine
+	for _, raw := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if raw == "" {
+			continue
+		}
+		var line scriptLine
+		if err := json.Unmarshal([]byte(raw), &line); err != nil {
+			t.Fatalf("Linha JSON inválida %q: %v", raw, err)
+		}
+		lines = append(lines, line)
+	}
+	return code, lines
+}
+
+// Procura o resultado de um comando na saída
+func scriptResult(t *testing.T, lines []scriptLine, command string, result any) {
+	t.Helper()
+	for _, line := range lines {
+		if line.Type == "RESULT" && line.Command == command {
+			if err := json.Unmarshal(line.Data, result); err != nil {
+				t.Fatalf("Resultado de %s inválido: %v", command, err)
+			}
+			return
+		}
+	}
+	t.Fatalf("Sem resultado de %s na saída: %+v", command, lines)
+}
+
+// Teste dos comandos não interativos: cadastro, pacote e estatísticas numa mesma
+// conexão, falhas com código de saída e partida jogada por dois scripts
+func TestScriptCommands(t *testing.T) {
+	_, addr := startServer(t, withFastTimings(server.Config{}))
+
+	code, lines := runScript(t, "--server", addr, "--user", "ana", "--password", "senha123", "register", "login", "open-pack", "--pack", "standard", "stats")
+	if code != 0 {
+		t.Fatalf("Código de saída %d: %+v", code, lines)
+	}
+	var pack struct {
+		Type  string              `json:"type"`
+		Cards []protocol.CardInfo `json:"cards"`
+	}
+	scriptResult(t, lines, "open-pack", &pack)
+	if pack.Type != "standard" || len(pack.Cards) == 0 {
+		t.Fatalf("Pacote inesperado: %+v", pack)
+	}
+	var stats struct {
+		UserName string `json:"username"`
+	}
+	scriptResult(t, lines, "stats", &stats)
+	if stats.UserName != "ana" {
+		t.Fatalf("Estatísticas inesperadas: %+v", stats)
+	}
+
+	// Falha do servidor: código de saída 1 e ERROR com o código do protocolo
+	code, lines = runScript(t, "--server", addr, "--user", "ana", "--password", "senha123", "register")
+	if code != 1 || len(lines) == 0 || lines[len(lines)-1].Type != "ERROR" || !strings.Contains(string(lines[len(lines)-1].Error), protocol.ERR_USERNAME_TAKEN) {
+		t.Fatalf("Cadastro repetido deveria falhar com %s: %d %+v", protocol.ERR_USERNAME_TAKEN, code, lines)
+	}
+
+	// Erros de uso não conectam
+	if code, _ := runScript(t, "--server", addr, "voar"); code != 2 {
+		t.Fatalf("Comando desconhecido deveria sair com 2: %d", code)
+	}
+	if code, _ := runScript(t, "--server", addr, "queue", "--play", "DRAGAO"); code != 2 {
+		t.Fatalf("Estratégia inválida deveria sair com 2: %d", code)
+	}
+
+	// Dois scripts jogam uma partida; bia abre o pacote em outra execução, então
+	// joga sem conhecer o inventário
+	if code, lines := runScript(t, "--server", addr, "--user", "bia", "--password", "senha123", "register", "open-pack"); code != 0 {
+		t.Fatalf("Código de saída %d: %+v", code, lines)
+	}
+	var wg sync.WaitGroup
+	results := make([][]scriptLine, 2)
+	codes := make([]int, 2)
+	for i, args := range [][]string{
+		{"--user", "ana", "--password", "senha123", "queue", "--play", "first"},
+		{"--user", "bia", "--password", "senha123", "queue", "--wait", "30s"},
+	} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i], results[i] = runScript(t, append([]string{"--server", addr}, args...)...)
+		}()
+	}
+	wg.Wait()
+
+	var won []bool
+	for i, lines := range results {
+		if codes[i] != 0 {
+			t.Fatalf("Partida por script falhou (%d): %+v", codes[i], lines)
+		}
+		var end struct {
+			MatchID int    `json:"match_id"`
+			Won     bool   `json:"won"`
+			Played  string `json:"played"`
+		}
+		scriptResult(t, lines, "queue", &end)
+		if end.MatchID == 0 || end.Played == "" {
+			t.Fatalf("Resultado da partida inesperado: %+v", end)
+		}
+		won = append(won, end.Won)
+
+		found := false
+		for _, line := range lines {
+			found = found || line.Type == protocol.MSG_MATCH_FOUND
+		}
+		if !found {
+			t.Fatalf("Eventos da partida ausentes na saída: %+v", lines)
+		}
+	}
+	if won[0] == won[1] {
+		t.Fatalf("A partida deveria ter exatamente um vencedor: %v", won)
+	}
+}
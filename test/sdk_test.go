@@ -0,0 +1,566 @@
+package test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"top-card/internal/card"
+	"top-card/internal/player"
+	"top-card/internal/protocol"
+	"top-card/internal/server"
+	"top-card/pkg/topcard"
+)
+
+// Conecta um cliente do SDK, cadastra o usuário, faz login e abre um pacote
+func dialSDKPlayer(t *testing.T, addr, userName string, options topcard.Options) *topcard.Client {
+	t.Helper()
+
+	client, err := topcard.Dial(addr, options)
+	if err != nil {
+		t.Fatalf("Erro ao conectar %s: %v", userName, err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	ctx := context.Background()
+	if _, err := client.Register(ctx, userName, "senha123"); err != nil {
+		t.Fatalf("Erro no cadastro de %s: %v", userName, err)
+	}
+	if _, err := client.Login(ctx, userName, "senha123"); err != nil {
+		t.Fatalf("Erro no login de %s: %v", userName, err)
+	}
+	pack, err := client.OpenPack(ctx, card.PACK_STANDARD)
+	if err != nil {
+		t.Fatalf("Erro ao abrir pacote de %s: %v", userName, err)
+	}
+	if len(client.Inventory()) != len(pack.Cards) || len(pack.Cards) == 0 {
+		t.Fatalf("Inventário de %s (%d cartas) não confere com o pacote (%d)", userName, len(client.Inventory()), len(pack.Cards))
+	}
+	return client
+}
+
+// Bot simples: joga a primeira carta do inventário sempre que for o turno e
+// retorna o fim da partida
+func playMatch(client *topcard.Client) (topcard.MatchEnd, error) {
+	ctx := context.Background()
+	for event := range client.Events() {
+		switch event := event.(type) {
+		case topcard.GameState:
+			if event.YourTurn && !event.GameOver {
+				if err := client.PlayCard(ctx, client.Inventory()[0].Type); err != nil {
+					return topcard.MatchEnd{}, err
+				}
+			}
+		case topcard.TurnUpdate:
+			if event.YourTurn {
+				if err := client.PlayCard(ctx, client.Inventory()[0].Type); err != nil {
+					return topcard.MatchEnd{}, err
+				}
+			}
+		case topcard.MoveRejected:
+			return topcard.MatchEnd{}, event.Err
+		case topcard.MatchEnd:
+			return event, nil
+		}
+	}
+	return topcard.MatchEnd{}, client.Err()
+}
+
+// Teste do SDK: dois bots jogam uma partida inteira, um em frames binários e outro em JSON
+func TestSDKMatch(t *testing.T) {
+	_, addr := startServer(t, withFastTimings(server.Config{}))
+	alice := dialSDKPlayer(t, addr, "alice", topcard.Options{ClientName: "bot-alice"})
+	bruno := dialSDKPlayer(t, addr, "bruno", topcard.Options{DisableBinaryFrames: true})
+
+	if server := alice.Server(); server.ProtocolVersion != protocol.PROTOCOL_VERSION || !alice.Supports(topcard.CAP_BINARY_FRAMES) {
+		t.Fatalf("Handshake inesperado: %+v", server)
+	}
+	if bruno.Supports(topcard.CAP_BINARY_FRAMES) {
+		t.Fatal("Frames binários ativos apesar de DisableBinaryFrames")
+	}
+
+	ctx := context.Background()
+	if _, err := alice.JoinQueue(ctx); err != nil {
+		t.Fatalf("Erro ao entrar na fila: %v", err)
+	}
+	if _, err := alice.JoinQueue(ctx); topcard.ErrorCode(err) != topcard.ERR_ALREADY_IN_QUEUE {
+		t.Fatalf("Fila repetida deveria falhar com %s: %v", topcard.ERR_ALREADY_IN_QUEUE, err)
+	}
+	if _, err := bruno.JoinQueue(ctx); err != nil {
+		t.Fatalf("Erro ao entrar na fila: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	results := make([]topcard.MatchEnd, 2)
+	errs := make([]error, 2)
+	for i, client := range []*topcard.Client{alice, bruno} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = playMatch(client)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("Erro durante a partida: %v", err)
+		}
+	}
+	if results[0].MatchID != results[1].MatchID || results[0].Won == results[1].Won {
+		t.Fatalf("Resultado inconsistente: %+v %+v", results[0], results[1])
+	}
+	if alice.Match().InMatch || bruno.Match().InMatch {
+		t.Fatal("Estado da partida não foi limpo no fim")
+	}
+
+	stats, err := alice.Stats(ctx)
+	if err != nil || stats.Wins+stats.Losses != 1 {
+		t.Fatalf("Estatísticas inesperadas: %+v %v", stats, err)
+	}
+	if rtt, err := bruno.Ping(ctx); err != nil || rtt <= 0 {
+		t.Fatalf("Ping inesperado: %v %v", rtt, err)
+	}
+}
+
+// Teste dos erros do SDK: erros do servidor com código, erros locais e fim da conexão
+func TestSDKErrors(t *testing.T) {
+	_, addr := startServer(t, withFastTimings(server.Config{}))
+	ctx := context.Background()
+
+	client, err := topcard.Dial(addr, topcard.Options{})
+	if err != nil {
+		t.Fatalf("Erro ao conectar: %v", err)
+	}
+	if _, err := client.OpenPack(ctx, ""); !errors.Is(err, topcard.ErrNotLoggedIn) {
+		t.Fatalf("Pacote sem login deveria falhar com ErrNotLoggedIn: %v", err)
+	}
+	if _, err := client.Register(ctx, "ca", "senha123"); topcard.ErrorCode(err) != topcard.ERR_USERNAME_TOO_SHORT {
+		t.Fatalf("Código esperado %s: %v", topcard.ERR_USERNAME_TOO_SHORT, err)
+	}
+	if _, err := client.Register(ctx, "carla", "senha123"); err != nil {
+		t.Fatalf("Erro no cadastro: %v", err)
+	}
+	if _, err := client.Login(ctx, "carla", "senha123"); err != nil {
+		t.Fatalf("Erro no login: %v", err)
+	}
+	if err := client.PlayCard(ctx, "HYDRA"); !errors.Is(err, topcard.ErrNotInMatch) {
+		t.Fatalf("Jogada fora de partida deveria falhar com ErrNotInMatch: %v", err)
+	}
+	if _, err := client.OpenPack(ctx, "lendario"); topcard.ErrorCode(err) != topcard.ERR_INVALID_PACK_TYPE {
+		t.Fatalf("Código esperado %s: %v", topcard.ERR_INVALID_PACK_TYPE, err)
+	}
+
+	// Login errado por último: as tentativas seguintes ficariam em espera
+	if _, err := client.Login(ctx, "carla", "errada"); topcard.ErrorCode(err) != topcard.ERR_INVALID_CREDENTIALS {
+		t.Fatalf("Código esperado %s: %v", topcard.ERR_INVALID_CREDENTIALS, err)
+	}
+
+	// Prazo do contexto esgotado antes da resposta
+	expired, cancel := context.WithDeadline(ctx, time.Now().Add(-time.Second))
+	defer cancel()
+	if _, err := client.Stats(expired); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Contexto vencido deveria falhar com DeadlineExceeded: %v", err)
+	}
+
+	// Close: Events termina com Disconnected sem erro e as requisições falham
+	client.Close()
+	var last topcard.Event
+	for event := range client.Events() {
+		last = event
+	}
+	if disconnected, ok := last.(topcard.Disconnected); ok && disconnected.Err != nil {
+		t.Fatalf("Close deveria gerar Disconnected sem erro: %+v", disconnected)
+	}
+	if _, err := client.Stats(ctx); !errors.Is(err, topcard.ErrClosed) {
+		t.Fatalf("Requisição depois de Close deveria falhar com ErrClosed: %v", err)
+	}
+}
+
+// Teste do SDK com a conexão encerrada pelo servidor: Disconnected com erro
+func TestSDKServerShutdown(t *testing.T) {
+	srv, addr := startServer(t, server.Config{})
+
+	client, err := topcard.Dial(addr, topcard.Options{})
+	if err != nil {
+		t.Fatalf("Erro ao conectar: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go srv.Shutdown(ctx)
+
+	var events []topcard.Event
+	for event := range client.Events() {
+		events = append(events, event)
+	}
+	if len(events) == 0 {
+		t.Fatal("Events fechado sem Disconnected")
+	}
+	if disconnected, ok := events[len(events)-1].(topcard.Disconnected); !ok || disconnected.Err == nil {
+		t.Fatalf("Último evento deveria ser Disconnected com erro: %+v", events)
+	}
+	<-client.Done()
+	if client.Err() == nil {
+		t.Fatal("Err deveria informar o fim da conexão")
+	}
+}
+
+// Teste de vários clientes independentes no mesmo processo
+func TestSDKManyClients(t *testing.T) {
+	addr := stressServer(t)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client, err := topcard.Dial(addr, sdkOptions(addr))
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer client.Close()
+
+			ctx := context.Background()
+			userName := fmt.Sprintf("bot_%d_%d", i, time.Now().UnixNano())
+			if _, err := client.Register(ctx, userName, "senha123"); err != nil {
+				errs <- err
+				return
+			}
+			userID, err := client.Login(ctx, userName, "senha123")
+			if err != nil {
+				errs <- err
+				return
+			}
+			if _, err := client.OpenPack(ctx, ""); err != nil {
+				errs <- err
+				return
+			}
+			if client.UserID() != userID {
+				errs <- fmt.Errorf("%s: ID %d, esperado %d", userName, client.UserID(), userID)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("Erro em um dos clientes: %v", err)
+	}
+}
+
+// Espera um evento do tipo T, descartando os demais
+func waitEvent[T topcard.Event](t *testing.T, client *topcard.Client) T {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event, ok := <-client.Events():
+			if !ok {
+				t.Fatalf("Conexão encerrada esperando %T: %v", *new(T), client.Err())
+			}
+			if typed, ok := event.(T); ok {
+				return typed
+			}
+		case <-timeout:
+			t.Fatalf("Tempo esgotado esperando %T", *new(T))
+		}
+	}
+}
+
+// Teste do chat da partida: a mensagem chega só ao oponente, com o nome de quem enviou
+func TestSDKChat(t *testing.T) {
+	_, addr := startServer(t, withFastTimings(server.Config{}))
+	alice := dialSDKPlayer(t, addr, "alice", topcard.Options{})
+	bruno := dialSDKPlayer(t, addr, "bruno", topcard.Options{DisableBinaryFrames: true})
+
+	ctx := context.Background()
+	if err := alice.SendChat(ctx, "oi"); !errors.Is(err, topcard.ErrNotInMatch) {
+		t.Fatalf("Chat fora de partida deveria falhar com ErrNotInMatch: %v", err)
+	}
+	if !alice.Supports(topcard.CAP_CHAT) {
+		t.Fatal("Capacidade chat não negociada")
+	}
+
+	for _, client := range []*topcard.Client{alice, bruno} {
+		if _, err := client.JoinQueue(ctx); err != nil {
+			t.Fatalf("Erro ao entrar na fila: %v", err)
+		}
+	}
+	found := waitEvent[topcard.MatchFound](t, alice)
+	waitEvent[topcard.MatchFound](t, bruno)
+
+	if err := alice.SendChat(ctx, "  boa sorte, bruno!  "); err != nil {
+		t.Fatalf("Erro ao enviar chat: %v", err)
+	}
+	chat := waitEvent[topcard.ChatMessage](t, bruno)
+	if chat.Text != "boa sorte, bruno!" || chat.FromName != "alice" || chat.FromID != alice.UserID() || chat.MatchID != found.MatchID {
+		t.Fatalf("Mensagem de chat inesperada: %+v", chat)
+	}
+
+	// Texto vazio ou longo demais é recusado com INVALID_CHAT
+	if err := bruno.SendChat(ctx, strings.Repeat("a", protocol.MAX_CHAT_LENGTH+1)); err != nil {
+		t.Fatalf("Erro ao enviar chat: %v", err)
+	}
+	rejected := waitEvent[topcard.ServerError](t, bruno)
+	if rejected.Err.Code != topcard.ERR_INVALID_CHAT {
+		t.Fatalf("Código esperado %s: %+v", topcard.ERR_INVALID_CHAT, rejected.Err)
+	}
+}
+
+// Teste do inventário mantido pelo servidor: a resposta do INVENTORY_REQUEST confere
+// com o pacote, uma jogada recusada não mexe no inventário e a aceita chega como
+// INVENTORY_UPDATE com uma carta a menos
+func TestSDKInventory(t *testing.T) {
+	_, addr := startServer(t, withFastTimings(server.Config{}))
+	alice := dialSDKPlayer(t, addr, "alice", topcard.Options{})
+	bruno := dialSDKPlayer(t, addr, "bruno", topcard.Options{DisableBinaryFrames: true})
+
+	ctx := context.Background()
+	inventory, err := alice.RefreshInventory(ctx)
+	if err != nil {
+		t.Fatalf("Erro ao pedir o inventário: %v", err)
+	}
+	if len(inventory) != len(alice.Inventory()) {
+		t.Fatalf("Inventário do servidor (%d cartas) difere do local (%d)", len(inventory), len(alice.Inventory()))
+	}
+
+	for _, client := range []*topcard.Client{alice, bruno} {
+		if _, err := client.JoinQueue(ctx); err != nil {
+			t.Fatalf("Erro ao entrar na fila: %v", err)
+		}
+	}
+	waitEvent[topcard.MatchFound](t, alice)
+	waitEvent[topcard.MatchFound](t, bruno)
+
+	mover, waiting := alice, bruno
+	if !waitEvent[topcard.GameState](t, alice).YourTurn {
+		mover, waiting = bruno, alice
+	}
+
+	// Fora do turno: a carta continua no inventário
+	before := len(waiting.Inventory())
+	if err := waiting.PlayCard(ctx, waiting.Inventory()[0].Type); err != nil {
+		t.Fatalf("Erro ao enviar jogada: %v", err)
+	}
+	if rejected := waitEvent[topcard.MoveRejected](t, waiting); rejected.Err.Code != topcard.ERR_NOT_YOUR_TURN {
+		t.Fatalf("Código esperado %s: %+v", topcard.ERR_NOT_YOUR_TURN, rejected.Err)
+	}
+	if len(waiting.Inventory()) != before {
+		t.Fatalf("Jogada recusada alterou o inventário: %d -> %d cartas", before, len(waiting.Inventory()))
+	}
+
+	before = len(mover.Inventory())
+	if err := mover.PlayCard(ctx, mover.Inventory()[0].Type); err != nil {
+		t.Fatalf("Erro ao enviar jogada: %v", err)
+	}
+	update := waitEvent[topcard.InventoryUpdate](t, mover)
+	if update.Reason != topcard.INVENTORY_CARD_PLAYED || len(update.Cards) != before-1 || len(mover.Inventory()) != before-1 {
+		t.Fatalf("Atualização inesperada depois da jogada (%d cartas antes): %+v", before, update)
+	}
+}
+
+// Função para refazer o login num cliente novo, esperando o servidor notar a queda
+// da conexão anterior
+func reloginSDK(t *testing.T, addr, userName string) *topcard.Client {
+	t.Helper()
+
+	client, err := topcard.Dial(addr, topcard.Options{})
+	if err != nil {
+		t.Fatalf("Erro ao reconectar %s: %v", userName, err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	for attempt := 0; ; attempt++ {
+		_, err := client.Login(context.Background(), userName, "senha123")
+		if err == nil {
+			return client
+		}
+		if topcard.ErrorCode(err) != topcard.ERR_ALREADY_CONNECTED || attempt == 50 {
+			t.Fatalf("Erro ao refazer o login de %s: %v", userName, err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// Teste do estado da sessão: o login refeito no meio de uma partida recebe a partida,
+// a carta já jogada e o inventário; depois da partida o vencedor tem as moedas
+func TestSDKSessionState(t *testing.T) {
+	_, addr := startServer(t, withFastTimings(server.Config{}))
+	alice := dialSDKPlayer(t, addr, "alice", topcard.Options{})
+	bruno := dialSDKPlayer(t, addr, "bruno", topcard.Options{DisableBinaryFrames: true})
+
+	state := waitEvent[topcard.SessionState](t, alice)
+	if state.Stats.UserName != "alice" || state.UserID != alice.UserID() || len(state.Inventory) != 0 || state.Match != nil || state.Coins != 0 {
+		t.Fatalf("Estado inesperado no primeiro login: %+v", state)
+	}
+
+	ctx := context.Background()
+	for _, client := range []*topcard.Client{alice, bruno} {
+		if _, err := client.JoinQueue(ctx); err != nil {
+			t.Fatalf("Erro ao entrar na fila: %v", err)
+		}
+	}
+	found := waitEvent[topcard.MatchFound](t, alice)
+	waitEvent[topcard.MatchFound](t, bruno)
+
+	mover, moverName, waiting := alice, "alice", bruno
+	if !waitEvent[topcard.GameState](t, alice).YourTurn {
+		mover, moverName, waiting = bruno, "bruno", alice
+	}
+	played := mover.Inventory()[0].Type
+	if err := mover.PlayCard(ctx, played); err != nil {
+		t.Fatalf("Erro ao enviar jogada: %v", err)
+	}
+	remaining := len(waitEvent[topcard.InventoryUpdate](t, mover).Cards)
+
+	// Queda da conexão no meio da partida: o login refeito reconstrói tudo
+	mover.Close()
+	mover = reloginSDK(t, addr, moverName)
+	state = waitEvent[topcard.SessionState](t, mover)
+	if state.Match == nil || state.Match.MatchID != found.MatchID || state.Match.PlayedCard != played || state.Match.YourTurn {
+		t.Fatalf("Partida inesperada no estado da sessão: %+v", state.Match)
+	}
+	if len(state.Inventory) != remaining || len(mover.Inventory()) != remaining {
+		t.Fatalf("Inventário restaurado com %d cartas (cliente: %d), esperado %d", len(state.Inventory), len(mover.Inventory()), remaining)
+	}
+	if match := mover.Match(); !match.InMatch || match.MatchID != found.MatchID || match.YourTurn {
+		t.Fatalf("Situação da partida não restaurada no cliente: %+v", match)
+	}
+
+	// A partida continua com a nova conexão
+	waitEvent[topcard.TurnUpdate](t, waiting)
+	if err := waiting.PlayCard(ctx, waiting.Inventory()[0].Type); err != nil {
+		t.Fatalf("Erro ao enviar jogada: %v", err)
+	}
+	end := waitEvent[topcard.MatchEnd](t, mover)
+	if end.WinnerID == 0 {
+		return // Empate: ninguém ganha moedas
+	}
+
+	winner, winnerName := mover, moverName
+	if end.WinnerID != mover.UserID() {
+		winner, winnerName = waiting, map[string]string{"alice": "bruno", "bruno": "alice"}[moverName]
+	}
+	winner.Close()
+	state = waitEvent[topcard.SessionState](t, reloginSDK(t, addr, winnerName))
+	if state.Coins != player.WIN_COINS || state.Stats.Wins != 1 || state.Match != nil {
+		t.Fatalf("Estado do vencedor inesperado: %+v", state)
+	}
+}
+
+// Teste da fila de eventos cheia: sem ninguém lendo Events, o cliente desconecta com
+// ErrEventsOverflow depois de EventBuffer eventos e o motivo chega como último evento
+func TestSDKEventsOverflow(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+
+	// Servidor falso: responde ao HELLO e envia notificações até a conexão cair
+	go func() {
+		if _, err := bufio.NewReader(serverConn).ReadBytes('\n'); err != nil {
+			return
+		}
+		welcome, _ := protocol.CreateWelcome(true, "Bem-vindo", protocol.PROTOCOL_VERSION, "falso", "1.0", []string{protocol.CAP_REQUEST_ID})
+		frame, _ := protocol.JSONCodec.Frame(welcome)
+		serverConn.Write(frame)
+		for matchID := 1; ; matchID++ {
+			start, _ := protocol.CreateMatchStart(matchID, "A partida começou!")
+			frame, _ := protocol.JSONCodec.Frame(start)
+			if _, err := serverConn.Write(frame); err != nil {
+				return
+			}
+		}
+	}()
+
+	client, err := topcard.NewClient(clientConn, topcard.Options{EventBuffer: 4})
+	if err != nil {
+		t.Fatalf("Erro no handshake: %v", err)
+	}
+	select {
+	case <-client.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Cliente deveria desconectar com a fila de eventos cheia")
+	}
+	if !errors.Is(client.Err(), topcard.ErrEventsOverflow) {
+		t.Fatalf("Esperado ErrEventsOverflow, recebido %v", client.Err())
+	}
+
+	// Os eventos já enfileirados continuam disponíveis, seguidos de Disconnected
+	var events []topcard.Event
+	for event := range client.Events() {
+		events = append(events, event)
+	}
+	if len(events) != 5 {
+		t.Fatalf("Esperados 4 eventos e Disconnected, recebidos %+v", events)
+	}
+	for i, event := range events[:4] {
+		if start, ok := event.(topcard.MatchStart); !ok || start.MatchID != i+1 {
+			t.Fatalf("Evento %d deveria ser o início da partida %d: %+v", i, i+1, event)
+		}
+	}
+	if disconnected, ok := events[4].(topcard.Disconnected); !ok || !errors.Is(disconnected.Err, topcard.ErrEventsOverflow) {
+		t.Fatalf("Último evento deveria ser Disconnected com ErrEventsOverflow: %+v", events[4])
+	}
+}
+
+// Teste das constantes do SDK: códigos de erro, motivos de inventário e capacidades
+// precisam ser os mesmos do protocolo do servidor
+func TestSDKProtocolConstants(t *testing.T) {
+	for sdk, wire := range map[string]string{
+		topcard.ERR_INVALID_MESSAGE:        protocol.ERR_INVALID_MESSAGE,
+		topcard.ERR_UNKNOWN_MESSAGE_TYPE:   protocol.ERR_UNKNOWN_MESSAGE_TYPE,
+		topcard.ERR_INTERNAL:               protocol.ERR_INTERNAL,
+		topcard.ERR_FRAME_TOO_LARGE:        protocol.ERR_FRAME_TOO_LARGE,
+		topcard.ERR_TOO_MANY_VIOLATIONS:    protocol.ERR_TOO_MANY_VIOLATIONS,
+		topcard.ERR_RATE_LIMITED:           protocol.ERR_RATE_LIMITED,
+		topcard.ERR_UNSUPPORTED_VERSION:    protocol.ERR_UNSUPPORTED_VERSION,
+		topcard.ERR_HANDSHAKE_OUT_OF_ORDER: protocol.ERR_HANDSHAKE_OUT_OF_ORDER,
+		topcard.ERR_USERNAME_TAKEN:         protocol.ERR_USERNAME_TAKEN,
+		topcard.ERR_USERNAME_TOO_SHORT:     protocol.ERR_USERNAME_TOO_SHORT,
+		topcard.ERR_PASSWORD_TOO_SHORT:     protocol.ERR_PASSWORD_TOO_SHORT,
+		topcard.ERR_INVALID_CREDENTIALS:    protocol.ERR_INVALID_CREDENTIALS,
+		topcard.ERR_ALREADY_CONNECTED:      protocol.ERR_ALREADY_CONNECTED,
+		topcard.ERR_NOT_LOGGED_IN:          protocol.ERR_NOT_LOGGED_IN,
+		topcard.ERR_USER_NOT_FOUND:         protocol.ERR_USER_NOT_FOUND,
+		topcard.ERR_LOGIN_BACKOFF:          protocol.ERR_LOGIN_BACKOFF,
+		topcard.ERR_ACCOUNT_LOCKED:         protocol.ERR_ACCOUNT_LOCKED,
+		topcard.ERR_ALREADY_IN_QUEUE:       protocol.ERR_ALREADY_IN_QUEUE,
+		topcard.ERR_ALREADY_IN_MATCH:       protocol.ERR_ALREADY_IN_MATCH,
+		topcard.ERR_NO_CARDS:               protocol.ERR_NO_CARDS,
+		topcard.ERR_SERVER_SHUTTING_DOWN:   protocol.ERR_SERVER_SHUTTING_DOWN,
+		topcard.ERR_INVALID_PACK_TYPE:      protocol.ERR_INVALID_PACK_TYPE,
+		topcard.ERR_OUT_OF_STOCK:           protocol.ERR_OUT_OF_STOCK,
+		topcard.ERR_INVENTORY_NOT_EMPTY:    protocol.ERR_INVENTORY_NOT_EMPTY,
+		topcard.ERR_MATCH_NOT_FOUND:        protocol.ERR_MATCH_NOT_FOUND,
+		topcard.ERR_MATCH_NOT_IN_PROGRESS:  protocol.ERR_MATCH_NOT_IN_PROGRESS,
+		topcard.ERR_NOT_IN_MATCH:           protocol.ERR_NOT_IN_MATCH,
+		topcard.ERR_NOT_YOUR_TURN:          protocol.ERR_NOT_YOUR_TURN,
+		topcard.ERR_ALREADY_PLAYED:         protocol.ERR_ALREADY_PLAYED,
+		topcard.ERR_INVALID_CARD:           protocol.ERR_INVALID_CARD,
+		topcard.ERR_CARD_NOT_OWNED:         protocol.ERR_CARD_NOT_OWNED,
+		topcard.ERR_INVALID_CHAT:           protocol.ERR_INVALID_CHAT,
+
+		topcard.INVENTORY_REQUESTED:      protocol.INVENTORY_REQUESTED,
+		topcard.INVENTORY_PACK_OPENED:    protocol.INVENTORY_PACK_OPENED,
+		topcard.INVENTORY_CARD_PLAYED:    protocol.INVENTORY_CARD_PLAYED,
+		topcard.INVENTORY_CARDS_RETURNED: protocol.INVENTORY_CARDS_RETURNED,
+
+		topcard.CAP_REQUEST_ID:    protocol.CAP_REQUEST_ID,
+		topcard.CAP_ERROR_CODES:   protocol.CAP_ERROR_CODES,
+		topcard.CAP_BINARY_FRAMES: protocol.CAP_BINARY_FRAMES,
+		topcard.CAP_CHAT:          protocol.CAP_CHAT,
+		topcard.CAP_INVENTORY:     protocol.CAP_INVENTORY,
+		topcard.CAP_SESSION_STATE: protocol.CAP_SESSION_STATE,
+	} {
+		if sdk != wire {
+			t.Errorf("Constante do SDK %q diferente da do protocolo %q", sdk, wire)
+		}
+	}
+}
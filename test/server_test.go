@@ -0,0 +1,615 @@
+package test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"top-card/internal/card"
+	"top-card/internal/player"
+	"top-card/internal/protocol"
+	"top-card/internal/server"
+	"top-card/internal/storage"
+)
+
+// Cliente de teste simples: envia requisições e lê mensagens até encontrar o tipo esperado
+type testClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+	codec  protocol.Codec
+	frames protocol.FrameReader
+	userID int
+	cards  []protocol.CardInfo
+}
+
+// Conecta e faz o handshake com as capacidades do protocolo atual, em JSON
+func dialTestClient(t *testing.T, addr string) *testClient {
+	t.Helper()
+	return dialWithCapabilities(t, addr, protocol.CAP_REQUEST_ID, protocol.CAP_ERROR_CODES)
+}
+
+// Conecta, envia HELLO com as capacidades informadas e passa a usar o codec negociado
+func dialWithCapabilities(t *testing.T, addr string, capabilities ...string) *testClient {
+	t.Helper()
+
+	c := dialLegacyClient(t, addr)
+	c.send(protocol.CreateHello("top-card-test", "1.0", capabilities))
+	welcome, err := protocol.ExtractWelcome(c.expect(protocol.MSG_WELCOME))
+	if err != nil || !welcome.Accepted {
+		t.Fatalf("Handshake falhou: %v %+v", err, welcome)
+	}
+	for _, capability := range welcome.Capabilities {
+		if codec, ok := protocol.CodecByName(capability); ok {
+			c.codec = codec
+			c.frames = codec.NewFrameReader(c.reader, protocol.MAX_FRAME_SIZE)
+		}
+	}
+	return c
+}
+
+// Conecta sem enviar HELLO, como um cliente da versão 1
+func dialLegacyClient(t *testing.T, addr string) *testClient {
+	t.Helper()
+
+	conn, err := dialServer(addr)
+	if err != nil {
+		t.Fatalf("Erro ao conectar: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	reader := bufio.NewReader(conn)
+	return &testClient{t: t, conn: conn, reader: reader, codec: protocol.JSONCodec, frames: protocol.JSONCodec.NewFrameReader(reader, protocol.MAX_FRAME_SIZE)}
+}
+
+func (c *testClient) send(data []byte, err error) {
+	c.t.Helper()
+	if err != nil {
+		c.t.Fatalf("Erro ao criar mensagem: %v", err)
+	}
+	frame, err := c.codec.Frame(data)
+	if err != nil {
+		c.t.Fatalf("Erro ao codificar mensagem: %v", err)
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		c.t.Fatalf("Erro ao enviar mensagem: %v", err)
+	}
+}
+
+// Lê mensagens até chegar uma do tipo informado, descartando as demais
+func (c *testClient) expect(msgType string) *protocol.Message {
+	c.t.Helper()
+
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		message, err := c.frames.ReadMessage()
+		if err != nil {
+			c.t.Fatalf("Mensagem %s não recebida: %v", msgType, err)
+		}
+		if message.Type == msgType {
+			return message
+		}
+	}
+}
+
+// Lê a próxima mensagem, qualquer que seja o tipo
+func (c *testClient) next() *protocol.Message {
+	c.t.Helper()
+
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	message, err := c.frames.ReadMessage()
+	if err != nil {
+		c.t.Fatalf("Nenhuma mensagem recebida: %v", err)
+	}
+	return message
+}
+
+// Verifica que o servidor fechou a conexão
+func (c *testClient) expectClosed() {
+	c.t.Helper()
+
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.Copy(io.Discard, c.reader); err != nil {
+		c.t.Fatalf("Servidor deveria ter fechado a conexão: %v", err)
+	}
+}
+
+// Cadastra, faz login e abre um pacote padrão
+func (c *testClient) registerAndOpenPack(userName string) {
+	c.t.Helper()
+
+	c.send(protocol.CreateRegisterRequest(userName, "senha123"))
+	registerResp, err := protocol.ExtractRegisterResponse(c.expect(protocol.MSG_REGISTER_RESPONSE))
+	if err != nil || !registerResp.Success {
+		c.t.Fatalf("Cadastro falhou: %v %+v", err, registerResp)
+	}
+	c.userID = registerResp.UserID
+
+	c.send(protocol.CreateLoginRequest(userName, "senha123"))
+	loginResp, err := protocol.ExtractLoginResponse(c.expect(protocol.MSG_LOGIN_RESPONSE))
+	if err != nil || !loginResp.Success {
+		c.t.Fatalf("Login falhou: %v %+v", err, loginResp)
+	}
+
+	c.send(protocol.CreateCardPackRequest(c.userID, card.PACK_STANDARD, ""))
+	packResp, err := protocol.ExtractCardPackResponse(c.expect(protocol.MSG_CARD_PACK_RESPONSE))
+	if err != nil || !packResp.Success {
+		c.t.Fatalf("Pacote falhou: %v %+v", err, packResp)
+	}
+	c.cards = packResp.Cards
+}
+
+// Teste do acesso concorrente a um jogador: os handlers alteram inventário, pity,
+// sementes e moedas enquanto o estado é exportado (rode com -race)
+func TestPlayerConcurrentRecord(t *testing.T) {
+	p := player.NewPlayer(1, "alice", "senha123")
+	pack := []card.Card{{Type: card.HYDRA, Rarity: card.COMUM}}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			p.ReceivePack(card.PACK_STANDARD, pack, card.SeedState{Nonce: i})
+			p.AddCoins(player.WIN_COINS)
+			p.TakeCard(card.HYDRA)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			p.Record()
+		}
+	}()
+	wg.Wait()
+
+	record := p.Record()
+	if record.Pity[card.PACK_STANDARD] != 200 || record.Coins != 200*player.WIN_COINS || len(record.Inventory) != 0 {
+		t.Fatalf("Estado do jogador incorreto depois do acesso concorrente: %+v", record)
+	}
+}
+
+// Teste do encerramento gracioso: aviso aos clientes, interrupção da partida no prazo,
+// devolução da carta jogada e estado salvo em disco
+func TestGracefulShutdown(t *testing.T) {
+	dataFile := filepath.Join(t.TempDir(), "estado.json")
+	srv, addr := startServer(t, withFastTimings(server.Config{
+		DrainPollInterval: 10 * time.Millisecond,
+		DataFile:          dataFile,
+	}))
+
+	alice := dialTestClient(t, addr)
+	bruno := dialTestClient(t, addr)
+	alice.registerAndOpenPack("alice")
+	bruno.registerAndOpenPack("bruno")
+
+	alice.send(protocol.CreateQueueRequest(alice.userID))
+	alice.expect(protocol.MSG_QUEUE_RESPONSE)
+	bruno.send(protocol.CreateQueueRequest(bruno.userID))
+	bruno.expect(protocol.MSG_QUEUE_RESPONSE)
+
+	matchStart, err := protocol.ExtractMatchStart(alice.expect(protocol.MSG_MATCH_START))
+	if err != nil {
+		t.Fatalf("Erro ao extrair início de partida: %v", err)
+	}
+	bruno.expect(protocol.MSG_MATCH_START)
+	alice.expect(protocol.MSG_GAME_STATE)
+
+	// Alice (Player1) joga uma carta; Bruno nunca joga, então a partida não termina no prazo
+	alice.send(protocol.CreateCardMove(alice.userID, matchStart.MatchID, alice.cards[0].Type))
+	bruno.expect(protocol.MSG_TURN_UPDATE)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := srv.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown deveria informar que a partida foi interrompida, retornou: %v", err)
+	}
+
+	alice.expect(protocol.MSG_SERVER_SHUTDOWN)
+	matchEnd, err := protocol.ExtractMatchEnd(alice.expect(protocol.MSG_MATCH_END))
+	if err != nil || matchEnd.WinnerID != 0 {
+		t.Fatalf("Partida interrompida não deveria ter vencedor: %v %+v", err, matchEnd)
+	}
+
+	saved, ok, err := storage.Load(dataFile)
+	if err != nil || !ok {
+		t.Fatalf("Estado não foi salvo: %v", err)
+	}
+	if len(saved.Players) != 2 || len(saved.Interrupted) != 1 {
+		t.Fatalf("Estado salvo incorreto: %d jogadores, %d partidas interrompidas", len(saved.Players), len(saved.Interrupted))
+	}
+	total := 0
+	for _, record := range saved.Players {
+		total += len(record.Inventory)
+	}
+	if total != 6 {
+		t.Fatalf("A carta jogada deveria ter sido devolvida: %d cartas nos inventários", total)
+	}
+	if saved.Stock.Total() != card.DefaultStockCounts.Total()-6 {
+		t.Fatalf("Estoque salvo incorreto: %+v", saved.Stock)
+	}
+
+	// Um novo servidor restaura os jogadores e continua a numeração
+	restored := server.New(server.Config{DataFile: dataFile})
+	if err := restored.LoadState(); err != nil {
+		t.Fatalf("Erro ao carregar estado: %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Erro ao abrir listener: %v", err)
+	}
+	runServer(t, restored, func() error { return restored.Serve(ln) })
+
+	client := dialTestClient(t, ln.Addr().String())
+	client.send(protocol.CreateLoginRequest("alice", "senha123"))
+	loginResp, err := protocol.ExtractLoginResponse(client.expect(protocol.MSG_LOGIN_RESPONSE))
+	if err != nil || !loginResp.Success || loginResp.UserID != alice.userID {
+		t.Fatalf("Jogador salvo não foi restaurado: %v %+v", err, loginResp)
+	}
+	client.send(protocol.CreateRegisterRequest("carla", "senha123"))
+	registerResp, err := protocol.ExtractRegisterResponse(client.expect(protocol.MSG_REGISTER_RESPONSE))
+	if err != nil || registerResp.UserID != 3 {
+		t.Fatalf("Numeração dos jogadores deveria continuar em 3: %v %+v", err, registerResp)
+	}
+}
+
+// Teste de cliente lento: quem não lê as respostas enche a fila de saída e é desconectado
+func TestSlowConsumerDisconnected(t *testing.T) {
+	_, addr := startServer(t, server.Config{
+		WriteTimeout:      100 * time.Millisecond,
+		OutboundQueueSize: 4,
+	})
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Erro ao conectar: %v", err)
+	}
+	defer conn.Close()
+
+	// Envia requisições sem nunca ler as respostas até o servidor derrubar a conexão
+	request, _ := protocol.CreateStatsRequest(1)
+	request = append(request, '\n')
+	deadline := time.Now().Add(20 * time.Second)
+	for time.Now().Before(deadline) {
+		conn.SetWriteDeadline(time.Now().Add(time.Second))
+		if _, err := conn.Write(request); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue // Buffer do servidor cheio; tenta de novo até a conexão cair
+			}
+			return
+		}
+	}
+	t.Fatalf("Cliente lento deveria ter sido desconectado")
+}
+
+// Teste do request_id: o servidor repete o ID da requisição na resposta
+func TestRequestIDEchoed(t *testing.T) {
+	_, addr := startServer(t, server.Config{})
+	client := dialTestClient(t, addr)
+
+	register, err := protocol.CreateRegisterRequest("diana", "senha123")
+	if err != nil {
+		t.Fatalf("Erro ao criar mensagem: %v", err)
+	}
+	client.send(protocol.WithRequestID(register, "req-42"))
+	response := client.expect(protocol.MSG_REGISTER_RESPONSE)
+	if response.RequestID != "req-42" {
+		t.Fatalf("Resposta deveria repetir o request_id, recebido: %q", response.RequestID)
+	}
+
+	// Sem request_id na requisição, a resposta também vem sem
+	client.send(protocol.CreateLoginRequest("diana", "senha123"))
+	response = client.expect(protocol.MSG_LOGIN_RESPONSE)
+	if response.RequestID != "" {
+		t.Fatalf("Resposta não deveria ter request_id, recebido: %q", response.RequestID)
+	}
+}
+
+// Teste dos códigos de erro: respostas de falha, ERROR e MOVE_REJECTED
+func TestErrorCodes(t *testing.T) {
+	_, addr := startServer(t, withFastTimings(server.Config{}))
+	alice := dialTestClient(t, addr)
+	bruno := dialTestClient(t, addr)
+	alice.registerAndOpenPack("alice")
+	bruno.registerAndOpenPack("bruno")
+
+	// Falha numa resposta comum traz o código no envelope
+	alice.send(protocol.CreateRegisterRequest("alice", "senha123"))
+	if response := alice.expect(protocol.MSG_REGISTER_RESPONSE); response.ErrorCode != protocol.ERR_USERNAME_TAKEN {
+		t.Fatalf("Código esperado %s, recebido %q", protocol.ERR_USERNAME_TAKEN, response.ErrorCode)
+	}
+
+	// Tipo desconhecido e mensagem malformada viram ERROR
+	alice.send([]byte(`{"type":"DANCE","data":{}}`), nil)
+	if response := alice.expect(protocol.MSG_ERROR); response.ErrorCode != protocol.ERR_UNKNOWN_MESSAGE_TYPE {
+		t.Fatalf("Código esperado %s, recebido %q", protocol.ERR_UNKNOWN_MESSAGE_TYPE, response.ErrorCode)
+	}
+	alice.send([]byte(`{"type":`), nil)
+	if response := alice.expect(protocol.MSG_ERROR); response.ErrorCode != protocol.ERR_INVALID_MESSAGE {
+		t.Fatalf("Código esperado %s, recebido %q", protocol.ERR_INVALID_MESSAGE, response.ErrorCode)
+	}
+
+	alice.send(protocol.CreateQueueRequest(alice.userID))
+	alice.expect(protocol.MSG_QUEUE_RESPONSE)
+	bruno.send(protocol.CreateQueueRequest(bruno.userID))
+	bruno.expect(protocol.MSG_QUEUE_RESPONSE)
+	matchStart, err := protocol.ExtractMatchStart(bruno.expect(protocol.MSG_MATCH_START))
+	if err != nil {
+		t.Fatalf("Erro ao extrair início de partida: %v", err)
+	}
+	alice.expect(protocol.MSG_GAME_STATE)
+	bruno.expect(protocol.MSG_GAME_STATE)
+
+	// Bruno joga fora do turno: recebe MOVE_REJECTED (não GAME_STATE) e a carta continua com ele
+	bruno.send(protocol.CreateCardMove(bruno.userID, matchStart.MatchID, bruno.cards[0].Type))
+	response := bruno.expect(protocol.MSG_MOVE_REJECTED)
+	rejected, err := protocol.ExtractMoveRejected(response)
+	if err != nil || response.ErrorCode != protocol.ERR_NOT_YOUR_TURN || rejected.CardType != bruno.cards[0].Type {
+		t.Fatalf("Jogada fora do turno deveria ser recusada com %s: %v %q %+v", protocol.ERR_NOT_YOUR_TURN, err, response.ErrorCode, rejected)
+	}
+
+	// A partida segue normalmente: Alice joga, depois Bruno
+	alice.send(protocol.CreateCardMove(alice.userID, matchStart.MatchID, alice.cards[0].Type))
+	bruno.expect(protocol.MSG_TURN_UPDATE)
+	bruno.send(protocol.CreateCardMove(bruno.userID, matchStart.MatchID, bruno.cards[0].Type))
+	alice.expect(protocol.MSG_MATCH_END)
+	bruno.expect(protocol.MSG_MATCH_END)
+}
+
+// Teste do handshake: negociação de capacidades, HELLO fora de ordem e clientes da versão 1
+func TestHandshake(t *testing.T) {
+	_, addr := startServer(t, server.Config{MinProtocolVersion: protocol.MIN_PROTOCOL_VERSION})
+
+	// Só as capacidades conhecidas pelos dois lados ficam ativas
+	client := dialLegacyClient(t, addr)
+	client.send(protocol.Encode(protocol.MSG_HELLO, protocol.Hello{
+		ProtocolVersion: protocol.PROTOCOL_VERSION + 1,
+		ClientName:      "cliente-do-futuro",
+		Capabilities:    []string{"compression", protocol.CAP_REQUEST_ID},
+	}))
+	welcome, err := protocol.ExtractWelcome(client.next())
+	if err != nil || !welcome.Accepted || welcome.ProtocolVersion != protocol.PROTOCOL_VERSION {
+		t.Fatalf("HELLO deveria ser aceito na versão do servidor: %v %+v", err, welcome)
+	}
+	if len(welcome.Capabilities) != 1 || welcome.Capabilities[0] != protocol.CAP_REQUEST_ID {
+		t.Fatalf("Capacidades negociadas incorretas: %v", welcome.Capabilities)
+	}
+
+	// Um segundo HELLO é recusado sem derrubar a conexão
+	client.send(protocol.CreateHello("cliente-do-futuro", "", nil))
+	response := client.next()
+	welcome, err = protocol.ExtractWelcome(response)
+	if err != nil || welcome.Accepted || response.ErrorCode != protocol.ERR_HANDSHAKE_OUT_OF_ORDER {
+		t.Fatalf("Segundo HELLO deveria ser recusado com %s: %v %q %+v", protocol.ERR_HANDSHAKE_OUT_OF_ORDER, err, response.ErrorCode, welcome)
+	}
+
+	// Sem error_codes o servidor não envia ERROR: a próxima mensagem é a resposta do cadastro
+	client.send([]byte(`{"type":"DANCE","data":{}}`), nil)
+	register, _ := protocol.CreateRegisterRequest("helena", "senha123")
+	client.send(protocol.WithRequestID(register, "req-1"))
+	if response := client.next(); response.Type != protocol.MSG_REGISTER_RESPONSE || response.RequestID != "req-1" {
+		t.Fatalf("Esperado REGISTER_RESPONSE com request_id, recebido %s %q", response.Type, response.RequestID)
+	}
+
+	// Cliente da versão 1 (sem HELLO) não recebe request_id nem ERROR
+	legacy := dialLegacyClient(t, addr)
+	legacy.send([]byte(`{"type":"DANCE","data":{}}`), nil)
+	register, _ = protocol.CreateRegisterRequest("igor", "senha123")
+	legacy.send(protocol.WithRequestID(register, "req-1"))
+	if response := legacy.next(); response.Type != protocol.MSG_REGISTER_RESPONSE || response.RequestID != "" {
+		t.Fatalf("Esperado REGISTER_RESPONSE sem request_id, recebido %s %q", response.Type, response.RequestID)
+	}
+}
+
+// Teste da recusa de versões antigas com mensagem de atualização
+func TestHandshakeUnsupportedVersion(t *testing.T) {
+	_, addr := startServer(t, server.Config{MinProtocolVersion: protocol.PROTOCOL_VERSION})
+
+	// HELLO com versão antiga: WELCOME recusado e conexão encerrada
+	client := dialLegacyClient(t, addr)
+	client.send(protocol.Encode(protocol.MSG_HELLO, protocol.Hello{ProtocolVersion: protocol.PROTOCOL_VERSION - 1}))
+	response := client.next()
+	welcome, err := protocol.ExtractWelcome(response)
+	if err != nil || welcome.Accepted || response.ErrorCode != protocol.ERR_UNSUPPORTED_VERSION || welcome.MinProtocolVersion != protocol.PROTOCOL_VERSION {
+		t.Fatalf("Versão antiga deveria ser recusada com %s: %v %q %+v", protocol.ERR_UNSUPPORTED_VERSION, err, response.ErrorCode, welcome)
+	}
+	client.expectClosed()
+
+	// Cliente sem HELLO: ERROR com a mensagem de atualização e conexão encerrada
+	legacy := dialLegacyClient(t, addr)
+	legacy.send(protocol.CreateLoginRequest("joana", "senha123"))
+	if response := legacy.next(); response.Type != protocol.MSG_ERROR || response.ErrorCode != protocol.ERR_UNSUPPORTED_VERSION {
+		t.Fatalf("Cliente sem HELLO deveria receber ERROR %s, recebido %s %q", protocol.ERR_UNSUPPORTED_VERSION, response.Type, response.ErrorCode)
+	}
+	legacy.expectClosed()
+
+	// Cliente atual continua funcionando
+	dialTestClient(t, addr)
+}
+
+// Teste dos frames binários: depois do WELCOME cliente e servidor trocam mensagens
+// com prefixo de tamanho, inclusive erros de protocolo
+func TestBinaryFrames(t *testing.T) {
+	_, addr := startServer(t, server.Config{})
+	client := dialWithCapabilities(t, addr, protocol.CAP_REQUEST_ID, protocol.CAP_ERROR_CODES, protocol.CAP_BINARY_FRAMES)
+	if client.codec != protocol.BinaryCodec {
+		t.Fatalf("Servidor deveria aceitar frames binários, codec negociado: %s", client.codec.Name())
+	}
+	client.registerAndOpenPack("kiara")
+	if len(client.cards) == 0 {
+		t.Fatalf("Pacote deveria trazer cartas")
+	}
+
+	stats, _ := protocol.CreateStatsRequest(client.userID)
+	client.send(protocol.WithRequestID(stats, "req-9"))
+	response := client.expect(protocol.MSG_STATS_RESPONSE)
+	if statsResp, err := protocol.ExtractStatsResponse(response); err != nil || response.RequestID != "req-9" || statsResp.UserName != "kiara" {
+		t.Fatalf("Resposta de estatísticas incorreta: %v %q %+v", err, response.RequestID, statsResp)
+	}
+
+	// Frame com corpo inválido recebe ERROR e a conexão continua
+	if _, err := client.conn.Write([]byte{0x00, 0x00, 0x00, 0x01, 0xc1}); err != nil {
+		t.Fatalf("Erro ao enviar frame: %v", err)
+	}
+	if response := client.expect(protocol.MSG_ERROR); response.ErrorCode != protocol.ERR_INVALID_MESSAGE {
+		t.Fatalf("Código esperado %s, recebido %q", protocol.ERR_INVALID_MESSAGE, response.ErrorCode)
+	}
+	client.send(protocol.CreateStatsRequest(client.userID))
+	client.expect(protocol.MSG_STATS_RESPONSE)
+}
+
+// Teste dos limites de entrada: mensagens grandes demais, malformadas, de tipo
+// desconhecido ou HELLO repetido recebem erro, e a conexão cai ao atingir o limite
+// de violações
+func TestProtocolViolations(t *testing.T) {
+	_, addr := startServer(t, server.Config{MaxFrameSize: 1024, MaxViolations: 4})
+
+	oversized, _ := protocol.CreateRegisterRequest(strings.Repeat("x", 4096), "senha123")
+
+	// Linha JSON grande demais: ERROR e a conexão segue funcionando
+	client := dialTestClient(t, addr)
+	client.send(oversized, nil)
+	if response := client.next(); response.Type != protocol.MSG_ERROR || response.ErrorCode != protocol.ERR_FRAME_TOO_LARGE {
+		t.Fatalf("Esperado ERROR %s, recebido %s %q", protocol.ERR_FRAME_TOO_LARGE, response.Type, response.ErrorCode)
+	}
+	client.send(protocol.CreateRegisterRequest("luana", "senha123"))
+	if response := client.next(); response.Type != protocol.MSG_REGISTER_RESPONSE {
+		t.Fatalf("Conexão deveria continuar após frame grande, recebido %s", response.Type)
+	}
+
+	// JSON malformado e tipo desconhecido também contam como violação
+	client.send([]byte(`{"type":"STATS_REQUEST","data":`), nil)
+	if response := client.next(); response.ErrorCode != protocol.ERR_INVALID_MESSAGE {
+		t.Fatalf("Esperado ERROR %s, recebido %s %q", protocol.ERR_INVALID_MESSAGE, response.Type, response.ErrorCode)
+	}
+	client.send([]byte(`{"type":"DANCE","data":{}}`), nil)
+	if response := client.next(); response.ErrorCode != protocol.ERR_UNKNOWN_MESSAGE_TYPE {
+		t.Fatalf("Esperado ERROR %s, recebido %s %q", protocol.ERR_UNKNOWN_MESSAGE_TYPE, response.Type, response.ErrorCode)
+	}
+
+	// Quarta violação: ERROR da mensagem, aviso de desconexão e conexão encerrada
+	client.send([]byte("lixo"), nil)
+	if response := client.next(); response.ErrorCode != protocol.ERR_INVALID_MESSAGE {
+		t.Fatalf("Esperado ERROR %s, recebido %s %q", protocol.ERR_INVALID_MESSAGE, response.Type, response.ErrorCode)
+	}
+	if response := client.next(); response.ErrorCode != protocol.ERR_TOO_MANY_VIOLATIONS {
+		t.Fatalf("Esperado ERROR %s, recebido %s %q", protocol.ERR_TOO_MANY_VIOLATIONS, response.Type, response.ErrorCode)
+	}
+	client.expectClosed()
+
+	// Frame binário grande demais é descartado sem derrubar a conexão
+	binaryClient := dialWithCapabilities(t, addr, protocol.CAP_ERROR_CODES, protocol.CAP_BINARY_FRAMES)
+	binaryClient.send(oversized, nil)
+	if response := binaryClient.next(); response.ErrorCode != protocol.ERR_FRAME_TOO_LARGE {
+		t.Fatalf("Esperado ERROR %s, recebido %s %q", protocol.ERR_FRAME_TOO_LARGE, response.Type, response.ErrorCode)
+	}
+	binaryClient.send(protocol.CreateLoginRequest("luana", "senha123"))
+	if response := binaryClient.next(); response.Type != protocol.MSG_LOGIN_RESPONSE {
+		t.Fatalf("Conexão binária deveria continuar após frame grande, recebido %s", response.Type)
+	}
+
+	// HELLO repetido recebe o WELCOME recusado e também conta como violação
+	repeater := dialWithCapabilities(t, addr, protocol.CAP_ERROR_CODES)
+	for i := 1; i <= 4; i++ {
+		repeater.send(protocol.CreateHello("cliente-insistente", "", nil))
+		if response := repeater.next(); response.Type != protocol.MSG_WELCOME || response.ErrorCode != protocol.ERR_HANDSHAKE_OUT_OF_ORDER {
+			t.Fatalf("HELLO %d: esperado WELCOME %s, recebido %s %q", i, protocol.ERR_HANDSHAKE_OUT_OF_ORDER, response.Type, response.ErrorCode)
+		}
+	}
+	if response := repeater.next(); response.ErrorCode != protocol.ERR_TOO_MANY_VIOLATIONS {
+		t.Fatalf("Esperado ERROR %s, recebido %s %q", protocol.ERR_TOO_MANY_VIOLATIONS, response.Type, response.ErrorCode)
+	}
+	repeater.expectClosed()
+
+	// Cliente da versão 1 não recebe ERROR, mas também é desconectado no limite
+	legacy := dialLegacyClient(t, addr)
+	for range 4 {
+		legacy.send([]byte("lixo"), nil)
+	}
+	legacy.expectClosed()
+}
+
+// Teste de posse da sessão: outra conexão não altera as sementes nem abre pacotes
+// de um jogador informando o UserID dele
+func TestSeedRequiresOwnSession(t *testing.T) {
+	_, addr := startServer(t, withFastTimings(server.Config{}))
+	alice := dialTestClient(t, addr)
+	bruno := dialTestClient(t, addr)
+	alice.registerAndOpenPack("alice")
+	bruno.registerAndOpenPack("bruno")
+
+	alice.send(protocol.CreateSeedRequest(alice.userID, "", false))
+	before, err := protocol.ExtractSeedResponse(alice.expect(protocol.MSG_SEED_RESPONSE))
+	if err != nil || !before.Success {
+		t.Fatalf("Consulta das sementes falhou: %v %+v", err, before)
+	}
+
+	// Rotação (revelaria a semente) e troca da semente do cliente com o ID da alice
+	for _, request := range []struct {
+		clientSeed string
+		rotate     bool
+	}{{"", true}, {"semente-do-bruno", false}} {
+		bruno.send(protocol.CreateSeedRequest(alice.userID, request.clientSeed, request.rotate))
+		message := bruno.expect(protocol.MSG_SEED_RESPONSE)
+		response, err := protocol.ExtractSeedResponse(message)
+		if err != nil || response.Success || response.Revealed != nil || message.ErrorCode != protocol.ERR_NOT_LOGGED_IN {
+			t.Fatalf("Sementes de outro jogador deveriam ser recusadas com %s: %v %+v %q", protocol.ERR_NOT_LOGGED_IN, err, response, message.ErrorCode)
+		}
+	}
+
+	bruno.send(protocol.CreateCardPackRequest(alice.userID, card.PACK_STANDARD, ""))
+	if message := bruno.expect(protocol.MSG_CARD_PACK_RESPONSE); message.ErrorCode != protocol.ERR_NOT_LOGGED_IN {
+		t.Fatalf("Pacote para outro jogador deveria ser recusado com %s: %q", protocol.ERR_NOT_LOGGED_IN, message.ErrorCode)
+	}
+
+	// Sem login, o pedido também é recusado
+	anonymous := dialTestClient(t, addr)
+	anonymous.send(protocol.CreateSeedRequest(alice.userID, "", true))
+	if message := anonymous.expect(protocol.MSG_SEED_RESPONSE); message.ErrorCode != protocol.ERR_NOT_LOGGED_IN {
+		t.Fatalf("Sementes sem login deveriam ser recusadas com %s: %q", protocol.ERR_NOT_LOGGED_IN, message.ErrorCode)
+	}
+
+	alice.send(protocol.CreateSeedRequest(alice.userID, "", false))
+	after, err := protocol.ExtractSeedResponse(alice.expect(protocol.MSG_SEED_RESPONSE))
+	if err != nil || after.ServerSeedHash != before.ServerSeedHash || after.ClientSeed != before.ClientSeed || after.Nonce != before.Nonce {
+		t.Fatalf("Sementes da alice não deveriam mudar: antes %+v, depois %+v", before, after)
+	}
+}
+
+// Teste do compromisso da semente: o hash vai no login, antes do primeiro pacote, e é
+// o mesmo usado no sorteio
+func TestSeedCommittedAtLogin(t *testing.T) {
+	_, addr := startServer(t, withFastTimings(server.Config{}))
+	client := dialWithCapabilities(t, addr, protocol.CAP_REQUEST_ID, protocol.CAP_ERROR_CODES, protocol.CAP_SESSION_STATE)
+
+	client.send(protocol.CreateRegisterRequest("carla", "senha123"))
+	registerResp, err := protocol.ExtractRegisterResponse(client.expect(protocol.MSG_REGISTER_RESPONSE))
+	if err != nil || !registerResp.Success {
+		t.Fatalf("Cadastro falhou: %v %+v", err, registerResp)
+	}
+
+	client.send(protocol.CreateLoginRequest("carla", "senha123"))
+	state, err := protocol.ExtractSessionState(client.expect(protocol.MSG_SESSION_STATE))
+	if err != nil {
+		t.Fatalf("Estado da sessão inválido: %v", err)
+	}
+	loginResp, err := protocol.ExtractLoginResponse(client.expect(protocol.MSG_LOGIN_RESPONSE))
+	if err != nil || !loginResp.Success || loginResp.ServerSeedHash == "" || state.ServerSeedHash != loginResp.ServerSeedHash {
+		t.Fatalf("Hash da semente deveria ser publicado no login: %v %+v %q", err, loginResp, state.ServerSeedHash)
+	}
+
+	client.send(protocol.CreateCardPackRequest(registerResp.UserID, card.PACK_STANDARD, "semente-da-carla"))
+	packResp, err := protocol.ExtractCardPackResponse(client.expect(protocol.MSG_CARD_PACK_RESPONSE))
+	if err != nil || !packResp.Success {
+		t.Fatalf("Pacote falhou: %v %+v", err, packResp)
+	}
+	if packResp.Fairness.ServerSeedHash != loginResp.ServerSeedHash || packResp.Fairness.ClientSeed != "semente-da-carla" || packResp.Fairness.Nonce != 0 {
+		t.Fatalf("Sorteio deveria usar a semente publicada no login: %+v", packResp.Fairness)
+	}
+}
@@ -1,28 +1,138 @@
 package test
 
 import (
-	"bufio"
+	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"os"
 	"sync"
 	"testing"
 	"time"
-	"top-card/internal/protocol"
+	"top-card/internal/card"
+	"top-card/internal/server"
+	"top-card/internal/tlsconfig"
+	"top-card/pkg/topcard"
 )
 
-// getServerAddr retorna o endereço do servidor a partir da variável de ambiente
-func getServerAddr() string {
-	serverAddr := os.Getenv("SERVER_ADDR")
-	if serverAddr == "" {
-		serverAddr = "localhost:8080" // fallback para desenvolvimento local
+// startServer sobe um servidor em processo com a configuração informada num endereço
+// livre de 127.0.0.1, encerrado no fim do teste, e retorna o servidor e o endereço
+func startServer(tb testing.TB, config server.Config) (*server.Server, string) {
+	tb.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatalf("Erro ao abrir listener: %v", err)
+	}
+	srv := server.New(config)
+	runServer(tb, srv, func() error { return srv.Serve(ln) })
+	return srv, ln.Addr().String()
+}
+
+// runServer executa serve (Serve ou ServeEcho de srv) em segundo plano e, no fim do
+// teste, encerra o servidor (interrompendo em 1s as partidas ainda em andamento) e
+// confere que serve terminou com ErrServerClosed
+func runServer(tb testing.TB, srv *server.Server, serve func() error) {
+	tb.Helper()
+
+	served := make(chan error, 1)
+	go func() { served <- serve() }()
+	tb.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+		if err := <-served; err != server.ErrServerClosed {
+			tb.Errorf("Servidor deveria terminar com ErrServerClosed: %v", err)
+		}
+	})
+}
+
+// withFastTimings encurta as esperas do matchmaker e do início das partidas para os
+// testes que jogam partidas inteiras
+func withFastTimings(config server.Config) server.Config {
+	config.MatchmakerInterval = 10 * time.Millisecond
+	config.MatchStartDelay = 10 * time.Millisecond
+	config.GameStartDelay = 10 * time.Millisecond
+	return config
+}
+
+// stressServer retorna o endereço do servidor usado nos testes de stress. Se
+// SERVER_ADDR estiver definida usa o servidor externo (com TLS se TLS, TLS_CA_FILE ou
+// TLS_PIN estiverem definidas); caso contrário sobe um servidor próprio em processo,
+// com certificado autoassinado se TLS=on.
+func stressServer(t *testing.T) string {
+	t.Helper()
+
+	if serverAddr := os.Getenv("SERVER_ADDR"); serverAddr != "" {
+		clientTLS, err := tlsconfig.ClientFromEnv()
+		if err != nil {
+			t.Fatalf("Erro na configuração TLS: %v", err)
+		}
+		registerTLS(serverAddr, clientTLS)
+		return serverAddr
+	}
+
+	// Os testes de stress abrem centenas de conexões do mesmo IP: sem limites de requisições
+	config := server.Config{RateLimits: server.RateLimits{Disabled: true}}
+	var clientTLS *tls.Config
+	if os.Getenv("TLS") == "on" {
+		config.TLS, clientTLS = selfSignedTLS(t)
+	}
+	_, serverAddr := startServer(t, config)
+	if clientTLS != nil {
+		registerTLS(serverAddr, clientTLS)
 	}
 	return serverAddr
 }
 
+// Configuração TLS do cliente para cada endereço de servidor com TLS
+var clientTLSByAddr sync.Map
+
+// Função para registrar a configuração TLS usada por dialServer para o endereço
+func registerTLS(addr string, config *tls.Config) {
+	if config != nil {
+		clientTLSByAddr.Store(addr, config)
+	}
+}
+
+// Conecta ao servidor com TLS se o endereço tiver configuração registrada, ou TCP puro
+func dialServer(addr string) (net.Conn, error) {
+	var config *tls.Config
+	if value, ok := clientTLSByAddr.Load(addr); ok {
+		config = value.(*tls.Config)
+	}
+	return tlsconfig.Dial(addr, config, 5*time.Second)
+}
+
+// Opções do SDK para o endereço, com a configuração TLS registrada para ele
+func sdkOptions(addr string) topcard.Options {
+	var options topcard.Options
+	if value, ok := clientTLSByAddr.Load(addr); ok {
+		options.TLS = value.(*tls.Config)
+	}
+	return options
+}
+
+// Gera um certificado autoassinado e as configurações do servidor e do cliente
+// (com o certificado fixado)
+func selfSignedTLS(t *testing.T) (*tls.Config, *tls.Config) {
+	t.Helper()
+
+	serverTLS, err := tlsconfig.NewServer(tlsconfig.ServerOptions{SelfSigned: true})
+	if err != nil {
+		t.Fatalf("Erro ao gerar certificado: %v", err)
+	}
+	clientTLS, err := tlsconfig.NewClient(tlsconfig.ClientOptions{Pin: tlsconfig.ServerFingerprint(serverTLS)})
+	if err != nil {
+		t.Fatalf("Erro na configuração do cliente: %v", err)
+	}
+	return serverTLS, clientTLS
+}
+
 // Teste de stress para abertura de pacotes
 func TestStressCardPacks(t *testing.T) {
-	serverAddr := getServerAddr()
+	t.Parallel()
+	serverAddr := stressServer(t)
 	t.Logf("Conectando ao servidor: %s", serverAddr)
 	
 	numUsers := 100
@@ -44,7 +154,7 @@ func TestStressCardPacks(t *testing.T) {
 			
 			username := fmt.Sprintf("pack_user_%d_%d", userNum, time.Now().UnixNano())
 			
-			conn, err := net.DialTimeout("tcp", serverAddr, 5*time.Second)
+			client, err := topcard.Dial(serverAddr, sdkOptions(serverAddr))
 			if err != nil {
 				mutex.Lock()
 				erroConexao++
@@ -52,122 +162,39 @@ func TestStressCardPacks(t *testing.T) {
 				t.Logf("User%d: ERRO CONEXAO - %v", userNum, err)
 				return
 			}
-			defer conn.Close()
+			defer client.Close()
 			
-			conn.SetDeadline(time.Now().Add(15 * time.Second))
-			scanner := bufio.NewScanner(conn)
+			ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+			defer cancel()
 			
 			// 1. REGISTRO
-			registerMsg, _ := protocol.CreateRegisterRequest(username, "pass123")
-			registerMsg = append(registerMsg, '\n')
-			if _, err = conn.Write(registerMsg); err != nil {
-				mutex.Lock()
-				erroConexao++
-				mutex.Unlock()
-				return
-			}
-			
-			if !scanner.Scan() {
-				mutex.Lock()
-				erroRegistro++
-				mutex.Unlock()
-				t.Logf("User%d: ERRO SCAN REGISTRO", userNum)
-				return
-			}
-			
-			response, err := protocol.DecodeMessage(scanner.Bytes())
-			if err != nil {
-				mutex.Lock()
-				erroRegistro++
-				mutex.Unlock()
-				return
-			}
-			
-			registerResp, err := protocol.ExtractRegisterResponse(response)
-			if err != nil || !registerResp.Success {
+			if _, err := client.Register(ctx, username, "pass123"); err != nil {
 				mutex.Lock()
 				erroRegistro++
 				mutex.Unlock()
-				t.Logf("User%d: ERRO REGISTRO - %s", userNum, registerResp.Message)
+				t.Logf("User%d: ERRO REGISTRO - %v", userNum, err)
 				return
 			}
 			
-			userID := registerResp.UserID
-			
 			// 2. LOGIN
-			loginMsg, _ := protocol.CreateLoginRequest(username, "pass123")
-			loginMsg = append(loginMsg, '\n')
-			if _, err = conn.Write(loginMsg); err != nil {
-				mutex.Lock()
-				erroLogin++
-				mutex.Unlock()
-				return
-			}
-			
-			if !scanner.Scan() {
-				mutex.Lock()
-				erroLogin++
-				mutex.Unlock()
-				return
-			}
-			
-			response, err = protocol.DecodeMessage(scanner.Bytes())
-			if err != nil {
+			if _, err := client.Login(ctx, username, "pass123"); err != nil {
 				mutex.Lock()
 				erroLogin++
 				mutex.Unlock()
-				return
-			}
-			
-			loginResp, err := protocol.ExtractLoginResponse(response)
-			if err != nil || !loginResp.Success {
-				mutex.Lock()
-				erroLogin++
-				mutex.Unlock()
-				t.Logf("User%d: ERRO LOGIN - %s", userNum, loginResp.Message)
+				t.Logf("User%d: ERRO LOGIN - %v", userNum, err)
 				return
 			}
 			
 			// 3. ABRIR PACOTE
-			packMsg, _ := protocol.CreateCardPackRequest(userID)
-			packMsg = append(packMsg, '\n')
-			if _, err = conn.Write(packMsg); err != nil {
-				mutex.Lock()
-				pacoteFalha++
-				mutex.Unlock()
-				return
-			}
-			
-			if !scanner.Scan() {
-				mutex.Lock()
-				pacoteFalha++
-				mutex.Unlock()
-				return
-			}
-			
-			response, err = protocol.DecodeMessage(scanner.Bytes())
-			if err != nil {
-				mutex.Lock()
-				pacoteFalha++
-				mutex.Unlock()
-				return
-			}
-			
-			packResp, err := protocol.ExtractCardPackResponse(response)
-			if err != nil {
-				mutex.Lock()
-				pacoteFalha++
-				mutex.Unlock()
-				return
-			}
+			pack, err := client.OpenPack(ctx, card.PACK_STANDARD)
 			
 			mutex.Lock()
-			if packResp.Success {
+			if err == nil {
 				pacoteSucesso++
-				t.Logf("User%d: PACOTE ABERTO - %d cartas", userNum, len(packResp.Cards))
+				t.Logf("User%d: PACOTE ABERTO - %d cartas", userNum, len(pack.Cards))
 			} else {
 				pacoteFalha++
-				t.Logf("User%d: PACOTE NEGADO - %s", userNum, packResp.Message)
+				t.Logf("User%d: PACOTE NEGADO - %v", userNum, err)
 			}
 			mutex.Unlock()
 			
@@ -206,7 +233,8 @@ func TestStressCardPacks(t *testing.T) {
 
 // Teste de stress para login
 func TestStressLogin(t *testing.T) {
-	serverAddr := getServerAddr()
+	t.Parallel()
+	serverAddr := stressServer(t)
 	t.Logf("Conectando ao servidor: %s", serverAddr)
 	
 	numUsers := 200
@@ -228,7 +256,7 @@ func TestStressLogin(t *testing.T) {
 			
 			username := fmt.Sprintf("login_user_%d_%d", userNum, time.Now().UnixNano())
 			
-			conn, err := net.DialTimeout("tcp", serverAddr, 5*time.Second)
+			client, err := topcard.Dial(serverAddr, sdkOptions(serverAddr))
 			if err != nil {
 				mutex.Lock()
 				erroConexao++
@@ -236,116 +264,46 @@ func TestStressLogin(t *testing.T) {
 				t.Logf("User%d: ERRO CONEXAO - %v", userNum, err)
 				return
 			}
-			defer conn.Close()
+			defer client.Close()
 			
-			conn.SetDeadline(time.Now().Add(10 * time.Second))
-			scanner := bufio.NewScanner(conn)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
 			
 			// 1. REGISTRO
-			registerMsg, _ := protocol.CreateRegisterRequest(username, "pass123")
-			registerMsg = append(registerMsg, '\n')
-			if _, err = conn.Write(registerMsg); err != nil {
-				mutex.Lock()
-				erroConexao++
-				mutex.Unlock()
-				return
-			}
-			
-			if !scanner.Scan() {
-				mutex.Lock()
-				erroRegistro++
-				mutex.Unlock()
-				t.Logf("User%d: ERRO SCAN REGISTRO", userNum)
-				return
-			}
-			
-			response, err := protocol.DecodeMessage(scanner.Bytes())
-			if err != nil {
-				mutex.Lock()
-				erroRegistro++
-				mutex.Unlock()
-				return
-			}
-			
-			registerResp, err := protocol.ExtractRegisterResponse(response)
-			if err != nil || !registerResp.Success {
+			if _, err := client.Register(ctx, username, "pass123"); err != nil {
 				mutex.Lock()
 				erroRegistro++
 				mutex.Unlock()
-				t.Logf("User%d: ERRO REGISTRO - %s", userNum, registerResp.Message)
+				t.Logf("User%d: ERRO REGISTRO - %v", userNum, err)
 				return
 			}
 			
 			// 2. LOGIN
-			loginMsg, _ := protocol.CreateLoginRequest(username, "pass123")
-			loginMsg = append(loginMsg, '\n')
-			if _, err = conn.Write(loginMsg); err != nil {
-				mutex.Lock()
-				loginFalha++
-				mutex.Unlock()
-				return
-			}
-			
-			if !scanner.Scan() {
-				mutex.Lock()
-				loginFalha++
-				mutex.Unlock()
-				return
-			}
-			
-			response, err = protocol.DecodeMessage(scanner.Bytes())
-			if err != nil {
-				mutex.Lock()
-				loginFalha++
-				mutex.Unlock()
-				return
-			}
-			
-			loginResp, err := protocol.ExtractLoginResponse(response)
+			userID, err := client.Login(ctx, username, "pass123")
 			if err != nil {
 				mutex.Lock()
 				loginFalha++
 				mutex.Unlock()
+				t.Logf("User%d: LOGIN FALHOU - %v", userNum, err)
 				return
 			}
 			
 			mutex.Lock()
-			if loginResp.Success {
-				loginSucesso++
-				t.Logf("User%d: LOGIN SUCESSO - ID: %d", userNum, loginResp.UserID)
-				
-				// 3. TENTA LOGIN DUPLICADO (deve falhar)
-				mutex.Unlock()
-				
-				// Abre segunda conexão para testar login duplicado
-				conn2, err := net.DialTimeout("tcp", serverAddr, 5*time.Second)
-				if err == nil {
-					defer conn2.Close()
-					conn2.SetDeadline(time.Now().Add(5 * time.Second))
-					scanner2 := bufio.NewScanner(conn2)
-					
-					loginMsg2, _ := protocol.CreateLoginRequest(username, "pass123")
-					loginMsg2 = append(loginMsg2, '\n')
-					if _, err = conn2.Write(loginMsg2); err == nil {
-						if scanner2.Scan() {
-							response2, err := protocol.DecodeMessage(scanner2.Bytes())
-							if err == nil {
-								loginResp2, err := protocol.ExtractLoginResponse(response2)
-								if err == nil && !loginResp2.Success {
-									mutex.Lock()
-									loginDuplicado++
-									t.Logf("User%d: LOGIN DUPLICADO BLOQUEADO - %s", userNum, loginResp2.Message)
-									mutex.Unlock()
-								}
-							}
-						}
-					}
+			loginSucesso++
+			t.Logf("User%d: LOGIN SUCESSO - ID: %d", userNum, userID)
+			mutex.Unlock()
+			
+			// 3. TENTA LOGIN DUPLICADO (deve falhar)
+			// Abre segunda conexão para testar login duplicado
+			client2, err := topcard.Dial(serverAddr, sdkOptions(serverAddr))
+			if err == nil {
+				defer client2.Close()
+				if _, err := client2.Login(ctx, username, "pass123"); topcard.ErrorCode(err) != "" {
+					mutex.Lock()
+					loginDuplicado++
+					t.Logf("User%d: LOGIN DUPLICADO BLOQUEADO - %v", userNum, err)
+					mutex.Unlock()
 				}
-				
-			} else {
-				loginFalha++
-				t.Logf("User%d: LOGIN FALHOU - %s", userNum, loginResp.Message)
-				mutex.Unlock()
 			}
 			
 		}(i+1)
@@ -383,7 +341,8 @@ func TestStressLogin(t *testing.T) {
 
 // Teste de stress para entrar na fila
 func TestStressQueue(t *testing.T) {
-	serverAddr := getServerAddr()
+	t.Parallel()
+	serverAddr := stressServer(t)
 	t.Logf("Conectando ao servidor: %s", serverAddr)
 	
 	numUsers := 200
@@ -408,7 +367,7 @@ func TestStressQueue(t *testing.T) {
 			
 			username := fmt.Sprintf("queue_user_%d_%d", userNum, time.Now().UnixNano())
 			
-			conn, err := net.DialTimeout("tcp", serverAddr, 5*time.Second)
+			client, err := topcard.Dial(serverAddr, sdkOptions(serverAddr))
 			if err != nil {
 				mutex.Lock()
 				erroConexao++
@@ -416,72 +375,21 @@ func TestStressQueue(t *testing.T) {
 				t.Logf("User%d: ERRO CONEXAO - %v", userNum, err)
 				return
 			}
-			defer conn.Close()
+			defer client.Close()
 			
-			conn.SetDeadline(time.Now().Add(20 * time.Second))
-			scanner := bufio.NewScanner(conn)
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+			defer cancel()
 			
 			// 1. REGISTRO
-			registerMsg, _ := protocol.CreateRegisterRequest(username, "pass123")
-			registerMsg = append(registerMsg, '\n')
-			if _, err = conn.Write(registerMsg); err != nil {
+			if _, err := client.Register(ctx, username, "pass123"); err != nil {
 				mutex.Lock()
 				erroRegistro++
 				mutex.Unlock()
 				return
 			}
 			
-			if !scanner.Scan() {
-				mutex.Lock()
-				erroRegistro++
-				mutex.Unlock()
-				return
-			}
-			
-			response, err := protocol.DecodeMessage(scanner.Bytes())
-			if err != nil {
-				mutex.Lock()
-				erroRegistro++
-				mutex.Unlock()
-				return
-			}
-			
-			registerResp, err := protocol.ExtractRegisterResponse(response)
-			if err != nil || !registerResp.Success {
-				mutex.Lock()
-				erroRegistro++
-				mutex.Unlock()
-				return
-			}
-			userID := registerResp.UserID
-			
 			// 2. LOGIN
-			loginMsg, _ := protocol.CreateLoginRequest(username, "pass123")
-			loginMsg = append(loginMsg, '\n')
-			if _, err = conn.Write(loginMsg); err != nil {
-				mutex.Lock()
-				erroLogin++
-				mutex.Unlock()
-				return
-			}
-			
-			if !scanner.Scan() {
-				mutex.Lock()
-				erroLogin++
-				mutex.Unlock()
-				return
-			}
-			
-			response, err = protocol.DecodeMessage(scanner.Bytes())
-			if err != nil {
-				mutex.Lock()
-				erroLogin++
-				mutex.Unlock()
-				return
-			}
-			
-			loginResp, err := protocol.ExtractLoginResponse(response)
-			if err != nil || !loginResp.Success {
+			if _, err := client.Login(ctx, username, "pass123"); err != nil {
 				mutex.Lock()
 				erroLogin++
 				mutex.Unlock()
@@ -489,72 +397,20 @@ func TestStressQueue(t *testing.T) {
 			}
 			
 			// 3. TENTA ENTRAR NA FILA SEM CARTAS (deve falhar)
-			queueMsg1, _ := protocol.CreateQueueRequest(userID)
-			queueMsg1 = append(queueMsg1, '\n')
-			if _, err = conn.Write(queueMsg1); err != nil {
+			if _, err := client.JoinQueue(ctx); topcard.ErrorCode(err) != "" {
 				mutex.Lock()
-				filaFalha++
-				mutex.Unlock()
-				return
-			}
-			
-			if !scanner.Scan() {
-				mutex.Lock()
-				filaFalha++
-				mutex.Unlock()
-				return
-			}
-			
-			response, err = protocol.DecodeMessage(scanner.Bytes())
-			if err != nil {
-				mutex.Lock()
-				filaFalha++
+				filaSemCartas++
+				t.Logf("User%d: FILA NEGADA SEM CARTAS - %v", userNum, err)
 				mutex.Unlock()
-				return
-			}
-			
-			queueResp1, err := protocol.ExtractQueueResponse(response)
-			if err != nil {
+			} else if err != nil {
 				mutex.Lock()
 				filaFalha++
 				mutex.Unlock()
 				return
 			}
 			
-			if !queueResp1.Success {
-				mutex.Lock()
-				filaSemCartas++
-				t.Logf("User%d: FILA NEGADA SEM CARTAS - %s", userNum, queueResp1.Message)
-				mutex.Unlock()
-			}
-			
 			// 4. ABRIR PACOTE PARA TER CARTAS
-			packMsg, _ := protocol.CreateCardPackRequest(userID)
-			packMsg = append(packMsg, '\n')
-			if _, err = conn.Write(packMsg); err != nil {
-				mutex.Lock()
-				erroPacote++
-				mutex.Unlock()
-				return
-			}
-			
-			if !scanner.Scan() {
-				mutex.Lock()
-				erroPacote++
-				mutex.Unlock()
-				return
-			}
-			
-			response, err = protocol.DecodeMessage(scanner.Bytes())
-			if err != nil {
-				mutex.Lock()
-				erroPacote++
-				mutex.Unlock()
-				return
-			}
-			
-			packResp, err := protocol.ExtractCardPackResponse(response)
-			if err != nil || !packResp.Success {
+			if _, err := client.OpenPack(ctx, card.PACK_STANDARD); err != nil {
 				mutex.Lock()
 				erroPacote++
 				mutex.Unlock()
@@ -562,65 +418,25 @@ func TestStressQueue(t *testing.T) {
 			}
 			
 			// 5. ENTRAR NA FILA COM CARTAS (deve funcionar)
-			queueMsg2, _ := protocol.CreateQueueRequest(userID)
-			queueMsg2 = append(queueMsg2, '\n')
-			if _, err = conn.Write(queueMsg2); err != nil {
-				mutex.Lock()
-				filaFalha++
-				mutex.Unlock()
-				return
-			}
-			
-			if !scanner.Scan() {
-				mutex.Lock()
-				filaFalha++
-				mutex.Unlock()
-				return
-			}
-			
-			response, err = protocol.DecodeMessage(scanner.Bytes())
-			if err != nil {
-				mutex.Lock()
-				filaFalha++
-				mutex.Unlock()
-				return
-			}
-			
-			queueResp2, err := protocol.ExtractQueueResponse(response)
+			queueSize, err := client.JoinQueue(ctx)
 			if err != nil {
 				mutex.Lock()
 				filaFalha++
+				t.Logf("User%d: FILA FALHOU - %v", userNum, err)
 				mutex.Unlock()
 				return
 			}
 			
 			mutex.Lock()
-			if queueResp2.Success {
-				filaSucesso++
-				t.Logf("User%d: FILA SUCESSO - tamanho: %d", userNum, queueResp2.QueueSize)
-				mutex.Unlock()
-				
-				// 6. TENTA ENTRAR NA FILA NOVAMENTE (deve falhar - duplicado)
-				queueMsg3, _ := protocol.CreateQueueRequest(userID)
-				queueMsg3 = append(queueMsg3, '\n')
-				if _, err = conn.Write(queueMsg3); err == nil {
-					if scanner.Scan() {
-						response, err := protocol.DecodeMessage(scanner.Bytes())
-						if err == nil {
-							queueResp3, err := protocol.ExtractQueueResponse(response)
-							if err == nil && !queueResp3.Success {
-								mutex.Lock()
-								filaDuplicada++
-								t.Logf("User%d: FILA DUPLICADA BLOQUEADA - %s", userNum, queueResp3.Message)
-								mutex.Unlock()
-							}
-						}
-					}
-				}
-				
-			} else {
-				filaFalha++
-				t.Logf("User%d: FILA FALHOU - %s", userNum, queueResp2.Message)
+			filaSucesso++
+			t.Logf("User%d: FILA SUCESSO - tamanho: %d", userNum, queueSize)
+			mutex.Unlock()
+			
+			// 6. TENTA ENTRAR NA FILA NOVAMENTE (deve falhar - duplicado)
+			if _, err := client.JoinQueue(ctx); topcard.ErrorCode(err) != "" {
+				mutex.Lock()
+				filaDuplicada++
+				t.Logf("User%d: FILA DUPLICADA BLOQUEADA - %v", userNum, err)
 				mutex.Unlock()
 			}
 			
@@ -0,0 +1,131 @@
+package test
+
+import (
+	"bufio"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"top-card/internal/protocol"
+	"top-card/internal/server"
+	"top-card/internal/tlsconfig"
+	"top-card/pkg/topcard"
+)
+
+// Teste do TLS: certificado lido de arquivos, cliente com autoridade própria ou com
+// certificado fixado, e recusa de certificados desconhecidos e de clientes sem TLS
+func TestTLS(t *testing.T) {
+	// Certificado autoassinado gravado em arquivos, como em produção
+	certPEM, keyPEM, err := tlsconfig.GenerateSelfSigned([]string{"127.0.0.1"})
+	if err != nil {
+		t.Fatalf("Erro ao gerar certificado: %v", err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, certPEM, 0o600); err != nil {
+		t.Fatalf("Erro ao gravar certificado: %v", err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		t.Fatalf("Erro ao gravar chave: %v", err)
+	}
+	serverTLS, err := tlsconfig.NewServer(tlsconfig.ServerOptions{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("Erro na configuração do servidor: %v", err)
+	}
+
+	_, addr := startServer(t, server.Config{TLS: serverTLS})
+
+	block, _ := pem.Decode(certPEM)
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("Erro ao ler certificado: %v", err)
+	}
+	pin := tlsconfig.Fingerprint(certificate)
+	if pin != tlsconfig.ServerFingerprint(serverTLS) {
+		t.Fatalf("Impressão digital do servidor diferente da do arquivo")
+	}
+
+	// Cliente com o certificado fixado: protocolo completo sobre TLS
+	pinned, err := tlsconfig.NewClient(tlsconfig.ClientOptions{Pin: pin})
+	if err != nil {
+		t.Fatalf("Erro na configuração do cliente: %v", err)
+	}
+	registerTLS(addr, pinned)
+	client := dialTestClient(t, addr)
+	client.send(protocol.CreateRegisterRequest("heitor", "senha123"))
+	if response, err := protocol.ExtractRegisterResponse(client.expect(protocol.MSG_REGISTER_RESPONSE)); err != nil || !response.Success {
+		t.Fatalf("Cadastro sobre TLS falhou: %v %+v", err, response)
+	}
+
+	// Cliente com o certificado como autoridade (e fixação com ':' e maiúsculas)
+	var pairs []string
+	for i := 0; i < len(pin); i += 2 {
+		pairs = append(pairs, strings.ToUpper(pin[i:i+2]))
+	}
+	colonPin := strings.Join(pairs, ":")
+	withCA, err := tlsconfig.NewClient(tlsconfig.ClientOptions{CAFile: certFile, Pin: colonPin})
+	if err != nil {
+		t.Fatalf("Erro na configuração do cliente: %v", err)
+	}
+	conn, err := tlsconfig.Dial(addr, withCA, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Cliente com autoridade própria deveria conectar: %v", err)
+	}
+	conn.Close()
+
+	// Certificado fixado diferente
+	otherPEM, _, err := tlsconfig.GenerateSelfSigned(nil)
+	if err != nil {
+		t.Fatalf("Erro ao gerar certificado: %v", err)
+	}
+	block, _ = pem.Decode(otherPEM)
+	other, _ := x509.ParseCertificate(block.Bytes)
+	wrongPin, _ := tlsconfig.NewClient(tlsconfig.ClientOptions{Pin: tlsconfig.Fingerprint(other)})
+	if _, err := tlsconfig.Dial(addr, wrongPin, 5*time.Second); !errors.Is(err, tlsconfig.ErrPinMismatch) {
+		t.Fatalf("Certificado diferente do fixado deveria ser recusado: %v", err)
+	}
+
+	// SDK com as opções de TLS do próprio pacote
+	sdkTLS, err := topcard.NewTLSConfig(topcard.TLSOptions{Pin: topcard.Fingerprint(certificate)})
+	if err != nil {
+		t.Fatalf("Erro na configuração do SDK: %v", err)
+	}
+	sdkClient, err := topcard.Dial(addr, topcard.Options{TLS: sdkTLS})
+	if err != nil {
+		t.Fatalf("SDK com o certificado fixado deveria conectar: %v", err)
+	}
+	sdkClient.Close()
+	sdkWrongPin, _ := topcard.NewTLSConfig(topcard.TLSOptions{Pin: topcard.Fingerprint(other)})
+	if _, err := topcard.Dial(addr, topcard.Options{TLS: sdkWrongPin}); !errors.Is(err, topcard.ErrPinMismatch) {
+		t.Fatalf("SDK deveria recusar certificado diferente do fixado: %v", err)
+	}
+
+	// Sem autoridade nem fixação, o certificado autoassinado não é aceito
+	systemRoots, _ := tlsconfig.NewClient(tlsconfig.ClientOptions{})
+	var unknownAuthority x509.UnknownAuthorityError
+	if _, err := tlsconfig.Dial(addr, systemRoots, 5*time.Second); !errors.As(err, &unknownAuthority) {
+		t.Fatalf("Certificado autoassinado deveria ser recusado: %v", err)
+	}
+
+	if _, err := tlsconfig.NewClient(tlsconfig.ClientOptions{Pin: "abc"}); err == nil {
+		t.Fatalf("Fixação inválida deveria ser recusada")
+	}
+
+	// Cliente sem TLS não recebe nenhuma mensagem do protocolo
+	plain, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Erro ao conectar: %v", err)
+	}
+	defer plain.Close()
+	hello, _ := protocol.CreateHello("top-card-test", "1.0", nil)
+	plain.Write(append(hello, '\n'))
+	plain.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if message, err := protocol.JSONCodec.NewFrameReader(bufio.NewReader(plain), protocol.MAX_FRAME_SIZE).ReadMessage(); err == nil {
+		t.Fatalf("Cliente sem TLS não deveria receber mensagens: %+v", message)
+	}
+}
@@ -0,0 +1,90 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+	"top-card/internal/ratelimit"
+	"top-card/internal/server"
+	"top-card/internal/udpecho"
+)
+
+// Função para iniciar o eco UDP de um servidor de teste e retornar o endereço
+func startEchoServer(t *testing.T, config server.Config) (*server.Server, string) {
+	t.Helper()
+
+	srv := server.New(config)
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Erro ao abrir socket UDP: %v", err)
+	}
+	runServer(t, srv, func() error { return srv.ServeEcho(conn) })
+	return srv, conn.LocalAddr().String()
+}
+
+// Teste da medição de latência: todas as sondas respondidas e estatísticas coerentes
+func TestUDPEchoProbe(t *testing.T) {
+	_, addr := startEchoServer(t, server.Config{})
+
+	result, err := udpecho.Probe(context.Background(), addr, udpecho.ProbeOptions{Count: 5, Interval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Erro na medição: %v", err)
+	}
+	if result.Sent != 5 || result.Received != 5 || result.Loss != 0 {
+		t.Fatalf("Esperadas 5 sondas respondidas sem perda: %+v", result)
+	}
+	if result.Min <= 0 || result.Min > result.Avg || result.Avg > result.Max || result.StdDev < 0 || result.Jitter < 0 {
+		t.Fatalf("Estatísticas incoerentes: %+v", result)
+	}
+	if result.Offset.Abs() > time.Second {
+		t.Fatalf("Mesmo relógio nos dois lados deveria dar diferença pequena: %v", result.Offset)
+	}
+}
+
+// Teste dos pacotes inválidos: o eco só responde a sondas do tamanho e formato esperados
+func TestUDPEchoIgnoresInvalidPackets(t *testing.T) {
+	_, addr := startEchoServer(t, server.Config{})
+
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		t.Fatalf("Erro ao abrir socket UDP: %v", err)
+	}
+	defer conn.Close()
+
+	for _, data := range [][]byte{
+		[]byte("TCEP"),                       // Curto demais
+		make([]byte, udpecho.PACKET_SIZE),    // Sem o magic
+		make([]byte, 10*udpecho.PACKET_SIZE), // Grande demais (amplificação)
+		append([]byte("TCEP\x09"), make([]byte, udpecho.PACKET_SIZE-5)...), // Versão desconhecida
+	} {
+		conn.Write(data)
+	}
+	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	buffer := make([]byte, 1024)
+	if n, err := conn.Read(buffer); err == nil {
+		t.Fatalf("Pacote inválido não deveria ter resposta: %q", buffer[:n])
+	}
+}
+
+// Teste do limite por IP: sondas acima do orçamento são descartadas e contam como perda
+func TestUDPEchoRateLimited(t *testing.T) {
+	_, addr := startEchoServer(t, server.Config{RateLimits: server.RateLimits{
+		PerIP: ratelimit.Limits{udpecho.RATE_KEY: {Rate: 0.001, Burst: 2}},
+	}})
+
+	result, err := udpecho.Probe(context.Background(), addr, udpecho.ProbeOptions{Count: 4, Interval: time.Millisecond, Timeout: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Erro na medição: %v", err)
+	}
+	if result.Received != 2 || result.Loss != 50 {
+		t.Fatalf("Esperadas 2 de 4 sondas respondidas: %+v", result)
+	}
+
+	// Sem nenhuma resposta a medição retorna ErrNoReplies
+	result, err = udpecho.Probe(context.Background(), addr, udpecho.ProbeOptions{Count: 2, Interval: time.Millisecond, Timeout: 100 * time.Millisecond})
+	if !errors.Is(err, udpecho.ErrNoReplies) || result.Received != 0 || result.Loss != 100 {
+		t.Fatalf("Esperado ErrNoReplies com perda total: %v %+v", err, result)
+	}
+}
@@ -0,0 +1,67 @@
+package test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"top-card/internal/card"
+	"top-card/internal/protocol"
+	"top-card/internal/server"
+)
+
+// Teste do cliente web embutido: página, scripts, configuração e WebSocket servidos
+// pelo mesmo endereço HTTP do servidor do jogo
+func TestWebClientServed(t *testing.T) {
+	srv := server.New(server.Config{})
+	web := httptest.NewServer(srv.WebHandler())
+	defer web.Close()
+
+	get := func(path string) (string, string) {
+		t.Helper()
+		resp, err := http.Get(web.URL + path)
+		if err != nil {
+			t.Fatalf("Erro ao buscar %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s respondeu %d", path, resp.StatusCode)
+		}
+		return string(body), resp.Header.Get("Content-Type")
+	}
+
+	page, contentType := get("/")
+	if !strings.HasPrefix(contentType, "text/html") || !strings.Contains(page, `<script src="app.js">`) {
+		t.Fatalf("Página inicial inesperada (%s): %.200s", contentType, page)
+	}
+	for _, asset := range []string{"/app.js", "/style.css"} {
+		if body, _ := get(asset); body == "" {
+			t.Fatalf("%s vazio", asset)
+		}
+	}
+
+	body, _ := get("/client-config.json")
+	var config struct {
+		WebSocketPath   string `json:"websocket_path"`
+		ProtocolVersion int    `json:"protocol_version"`
+		PackTypes       []struct {
+			Name string `json:"name"`
+		} `json:"pack_types"`
+	}
+	if err := json.Unmarshal([]byte(body), &config); err != nil {
+		t.Fatalf("Configuração inválida: %v", err)
+	}
+	if config.WebSocketPath != "/ws" || config.ProtocolVersion != protocol.PROTOCOL_VERSION || len(config.PackTypes) != len(card.ListPackTypes()) {
+		t.Fatalf("Configuração inesperada: %+v", config)
+	}
+
+	// O caminho informado na configuração aceita o protocolo do jogo
+	client, _ := dialWebSocketClient(t, "ws"+strings.TrimPrefix(web.URL, "http")+config.WebSocketPath)
+	client.send(protocol.CreateRegisterRequest("vera", "senha123"))
+	if response, err := protocol.ExtractRegisterResponse(client.expect(protocol.MSG_REGISTER_RESPONSE)); err != nil || !response.Success {
+		t.Fatalf("Cadastro pelo WebSocket do cliente web falhou: %v %+v", err, response)
+	}
+}
@@ -0,0 +1,135 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"top-card/internal/protocol"
+	"top-card/internal/server"
+
+	"golang.org/x/net/websocket"
+)
+
+// Lado cliente de uma conexão WebSocket visto como fluxo de linhas JSON, para reusar
+// o testClient: cada frame recebido vira uma linha e cada escrita vira um frame
+type webSocketLines struct {
+	*websocket.Conn
+	pending []byte
+}
+
+func (c *webSocketLines) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		var frame string
+		if err := websocket.Message.Receive(c.Conn, &frame); err != nil {
+			return 0, err
+		}
+		c.pending = []byte(frame + "\n")
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+func (c *webSocketLines) Write(p []byte) (int, error) {
+	if err := websocket.Message.Send(c.Conn, string(bytes.TrimRight(p, "\n"))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Conecta pelo gateway WebSocket e faz o handshake pedindo também frames binários
+func dialWebSocketClient(t *testing.T, url string) (*testClient, *websocket.Conn) {
+	t.Helper()
+
+	ws, err := websocket.Dial(url, "", "http://localhost/")
+	if err != nil {
+		t.Fatalf("Erro ao conectar por WebSocket: %v", err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	lines := &webSocketLines{Conn: ws}
+	reader := bufio.NewReader(lines)
+	c := &testClient{t: t, conn: lines, reader: reader, codec: protocol.JSONCodec, frames: protocol.JSONCodec.NewFrameReader(reader, protocol.MAX_FRAME_SIZE)}
+
+	c.send(protocol.CreateHello("top-card-web", "1.0", []string{protocol.CAP_REQUEST_ID, protocol.CAP_ERROR_CODES, protocol.CAP_BINARY_FRAMES}))
+	welcome, err := protocol.ExtractWelcome(c.expect(protocol.MSG_WELCOME))
+	if err != nil || !welcome.Accepted {
+		t.Fatalf("Handshake por WebSocket falhou: %v %+v", err, welcome)
+	}
+	// O WebSocket já separa as mensagens: nada de frames binários
+	if slices.Contains(welcome.Capabilities, protocol.CAP_BINARY_FRAMES) {
+		t.Fatalf("Frames binários não deveriam ser oferecidos por WebSocket: %v", welcome.Capabilities)
+	}
+	return c, ws
+}
+
+// Teste do gateway WebSocket: um jogador no navegador e outro no TCP entram na mesma
+// fila e jogam entre si; cada frame carrega uma mensagem e frames grandes demais são
+// recusados sem derrubar a conexão
+func TestWebSocketGateway(t *testing.T) {
+	srv, addr := startServer(t, withFastTimings(server.Config{
+		MaxFrameSize:  1024,
+		WebSocketPath: "/jogo",
+	}))
+	gateway := httptest.NewServer(srv.WebSocketHandler())
+	t.Cleanup(gateway.Close)
+	url := "ws" + strings.TrimPrefix(gateway.URL, "http") + "/jogo"
+
+	web, ws := dialWebSocketClient(t, url)
+	tcp := dialTestClient(t, addr)
+	web.registerAndOpenPack("wanda")
+	tcp.registerAndOpenPack("tiago")
+
+	// Uma mensagem por frame, mesmo com JSON formatado em várias linhas
+	stats, err := protocol.CreateStatsRequest(web.userID)
+	if err == nil {
+		stats, err = protocol.WithRequestID(stats, "web-1")
+	}
+	if err != nil {
+		t.Fatalf("Erro ao criar mensagem: %v", err)
+	}
+	var pretty bytes.Buffer
+	json.Indent(&pretty, stats, "", "  ")
+	if err := websocket.Message.Send(ws, pretty.String()); err != nil {
+		t.Fatalf("Erro ao enviar frame: %v", err)
+	}
+	if response := web.expect(protocol.MSG_STATS_RESPONSE); response.RequestID != "web-1" {
+		t.Fatalf("Resposta deveria repetir o request_id, recebido: %q", response.RequestID)
+	}
+
+	// Frame maior que MaxFrameSize: erro, e a conexão continua
+	if err := websocket.Message.Send(ws, `{"type":"STATS_REQUEST","data":"`+strings.Repeat("x", 2048)+`"}`); err != nil {
+		t.Fatalf("Erro ao enviar frame: %v", err)
+	}
+	if message := web.expect(protocol.MSG_ERROR); message.ErrorCode != protocol.ERR_FRAME_TOO_LARGE {
+		t.Fatalf("Esperado %s, recebido %q", protocol.ERR_FRAME_TOO_LARGE, message.ErrorCode)
+	}
+
+	// Os dois transportes entram na mesma fila
+	web.send(protocol.CreateQueueRequest(web.userID))
+	web.expect(protocol.MSG_QUEUE_RESPONSE)
+	tcp.send(protocol.CreateQueueRequest(tcp.userID))
+	tcp.expect(protocol.MSG_QUEUE_RESPONSE)
+
+	found, err := protocol.ExtractMatchFound(web.expect(protocol.MSG_MATCH_FOUND))
+	if err != nil || found.OpponentName != "tiago" {
+		t.Fatalf("Jogador WebSocket deveria enfrentar o jogador TCP: %v %+v", err, found)
+	}
+	matchStart, err := protocol.ExtractMatchStart(tcp.expect(protocol.MSG_MATCH_START))
+	if err != nil {
+		t.Fatalf("Erro ao extrair início de partida: %v", err)
+	}
+	web.expect(protocol.MSG_MATCH_START)
+
+	if matchStart.MatchID != found.MatchID {
+		t.Fatalf("Partidas diferentes: %d e %d", matchStart.MatchID, found.MatchID)
+	}
+
+	// Jogada pelo WebSocket (primeiro da fila, Player1) chega ao adversário no TCP
+	web.expect(protocol.MSG_GAME_STATE)
+	web.send(protocol.CreateCardMove(web.userID, matchStart.MatchID, web.cards[0].Type))
+	tcp.expect(protocol.MSG_TURN_UPDATE)
+}